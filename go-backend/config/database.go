@@ -80,12 +80,38 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.UserProfile{},
 		&models.Role{},
 		&models.UserRole{},
+		&models.Permission{},
+		&models.RolePermission{},
 		&models.LoginLog{},
+		&models.UserSession{},
+		&models.RefreshToken{},
+		&models.LoginAttempt{},
+		&models.LoginLockout{},
+		&models.UserTwoFactor{},
+		&models.TwoFactorRecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.PersonalAccessToken{},
+		&models.PasswordResetToken{},
+		&models.PasswordHistory{},
+		&models.UserIdentity{},
+		&models.SSOLoginState{},
+		&models.LDAPSyncRun{},
+		&models.AcademicRollover{},
+		&models.OrgUnit{},
+		&models.UserMerge{},
+		&models.SystemLog{},
 		&models.Project{},
 		&models.ProjectMember{},
 		&models.ProjectFile{},
 		&models.ProjectReview{},
+		&models.ProjectReviewFlow{},
+		&models.ReviewDelegation{},
 		&models.ProjectType{},
+		&models.ProjectWorkflowState{},
+		&models.ProjectWorkflowTransition{},
+		&models.ProjectStatusHistory{},
+		&models.ProjectMilestone{},
+		&models.ProjectNotification{},
 		&models.StudentTeacher{},
 	)
 
@@ -93,6 +119,19 @@ func AutoMigrate(db *gorm.DB) error {
 		return fmt.Errorf("数据库迁移失败: %v", err)
 	}
 
+	// 竞赛相关表由 sql 脚本维护，只补充组织机构限定字段，不按模型改动原有字段定义
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.Competition{}, "OrgUnitID") {
+		if err := migrator.AddColumn(&models.Competition{}, "OrgUnitID"); err != nil {
+			return fmt.Errorf("添加 competitions.org_unit_id 失败: %v", err)
+		}
+	}
+	if !migrator.HasIndex(&models.Competition{}, "OrgUnitID") {
+		if err := migrator.CreateIndex(&models.Competition{}, "OrgUnitID"); err != nil {
+			return fmt.Errorf("创建 competitions.org_unit_id 索引失败: %v", err)
+		}
+	}
+
 	log.Println("数据库表迁移完成")
 	return nil
 }
@@ -113,16 +152,19 @@ func InitDefaultData(db *gorm.DB) error {
 				RoleKey:     "admin",
 				RoleName:    "系统管理员",
 				Description: "拥有系统所有权限",
+				DataScope:   models.DataScopeAll,
 			},
 			{
 				RoleKey:     "teacher",
 				RoleName:    "教师",
 				Description: "可以管理项目和指导学生",
+				DataScope:   models.DataScopeAll,
 			},
 			{
 				RoleKey:     "student",
 				RoleName:    "学生",
 				Description: "可以参与项目和竞赛",
+				DataScope:   models.DataScopeAll,
 			},
 		}
 
@@ -194,10 +236,208 @@ func InitDefaultData(db *gorm.DB) error {
 		log.Println("管理员用户已存在，跳过创建")
 	}
 
+	// 补充缺失的系统设置项
+	if err := initDefaultSettings(db); err != nil {
+		return err
+	}
+
+	// 补充缺失的权限项
+	if err := initDefaultPermissions(db); err != nil {
+		return err
+	}
+
+	// 默认项目状态流转
+	if err := initDefaultProjectWorkflow(db); err != nil {
+		return err
+	}
+
 	log.Println("默认数据初始化完成")
 	return nil
 }
 
+// defaultSettings 系统运行所需的默认设置项，已存在的设置不会被覆盖
+var defaultSettings = []models.SystemSetting{
+	{SettingKey: "max_login_attempts", SettingValue: "5", Description: "最大登录尝试次数", Category: "security"},
+	{SettingKey: "max_login_attempts_per_ip", SettingValue: "20", Description: "单个IP最大登录失败次数", Category: "security"},
+	{SettingKey: "login_attempt_window_minutes", SettingValue: "15", Description: "登录失败次数统计窗口（分钟）", Category: "security"},
+	{SettingKey: "login_lockout_minutes", SettingValue: "15", Description: "登录锁定时长（分钟）", Category: "security"},
+	{SettingKey: "login_delay_base_seconds", SettingValue: "1", Description: "登录失败渐进延迟基数（秒）", Category: "security"},
+	{SettingKey: "login_alert_threshold", SettingValue: "50", Description: "窗口期内全站登录失败告警阈值", Category: "security"},
+	{SettingKey: "password_min_length", SettingValue: "8", Description: "密码最小长度", Category: "security"},
+	{SettingKey: "password_require_letter", SettingValue: "true", Description: "密码必须包含字母", Category: "security"},
+	{SettingKey: "password_require_upper", SettingValue: "false", Description: "密码必须包含大写字母", Category: "security"},
+	{SettingKey: "password_require_lower", SettingValue: "false", Description: "密码必须包含小写字母", Category: "security"},
+	{SettingKey: "password_require_digit", SettingValue: "true", Description: "密码必须包含数字", Category: "security"},
+	{SettingKey: "password_require_symbol", SettingValue: "false", Description: "密码必须包含特殊字符", Category: "security"},
+	{SettingKey: "password_history_count", SettingValue: "5", Description: "禁止重复使用最近几次的密码（0表示不检查）", Category: "security"},
+	{SettingKey: "password_expire_days", SettingValue: "0", Description: "密码有效期（天，0表示永不过期），过期后登录须先修改密码", Category: "security"},
+	{SettingKey: "password_reset_url", SettingValue: "http://localhost:5173/reset-password", Description: "找回密码邮件中的重置页面地址（令牌以 token 参数附加）", Category: "security"},
+	{SettingKey: "password_reset_token_minutes", SettingValue: "30", Description: "找回密码链接有效期（分钟）", Category: "security"},
+	{SettingKey: "password_reset_window_minutes", SettingValue: "60", Description: "找回密码限流统计窗口（分钟）", Category: "security"},
+	{SettingKey: "password_reset_max_per_account", SettingValue: "3", Description: "窗口期内单个账号最多发送的重置邮件数", Category: "security"},
+	{SettingKey: "password_reset_max_per_ip", SettingValue: "10", Description: "窗口期内单个IP最多提交的找回密码请求数", Category: "security"},
+	{SettingKey: "ldap_sync_conflict_policy", SettingValue: "skip", Description: "目录同步时用户名或邮箱已被未关联的本地账号使用的处理方式：skip 记为冲突跳过，link 关联该账号", Category: "security"},
+	{SettingKey: "ldap_sync_deactivate_missing", SettingValue: "true", Description: "目录同步时停用目录中已不存在的已关联账号", Category: "security"},
+	{SettingKey: "user_import_welcome_link_hours", SettingValue: "72", Description: "批量导入开通邮件中设置密码链接的有效期（小时）", Category: "security"},
+	{SettingKey: "user_import_chunk_size", SettingValue: "200", Description: "批量导入每个事务写入的用户数", Category: "user"},
+	{SettingKey: "user_grades", SettingValue: "大一,大二,大三,大四,研一,研二,研三", Description: "可选年级（逗号分隔，按升级顺序排列）", Category: "user"},
+	{SettingKey: "rollover_graduate_grades", SettingValue: "大四,研三", Description: "学年升级时毕业的年级（逗号分隔）", Category: "user"},
+	{SettingKey: "rollover_graduate_status", SettingValue: "alumni", Description: "毕业生账号状态：alumni 校友（不能登录）或 inactive 停用", Category: "user"},
+	{SettingKey: "rollover_project_action", SettingValue: "close", Description: "毕业生未结束项目的处理：close 已立项的结题、其余驳回，reject 全部驳回，keep 不处理", Category: "user"},
+	{SettingKey: "rollover_binding_action", SettingValue: "remove", Description: "毕业生师生绑定的处理：remove 解除，keep 保留", Category: "user"},
+	{SettingKey: "rollover_undo_days", SettingValue: "7", Description: "学年升级后可撤销的天数", Category: "user"},
+	{SettingKey: "rollover_auto_date", SettingValue: "", Description: "每年自动执行学年升级的日期（MM-DD，如 08-31），为空表示只手动执行", Category: "user"},
+	{SettingKey: "user_recycle_retention_days", SettingValue: "30", Description: "已删除用户在回收站中的保留天数，超过后自动永久删除（仍有引用数据的除外），0 表示不自动清理", Category: "user"},
+	{SettingKey: "project_default_max_members", SettingValue: "5", Description: "未关联项目分类的项目团队人数上限（含队长），0 表示不限", Category: "project"},
+	{SettingKey: "review_reminder_hours", SettingValue: "24", Description: "审核截止前多少小时提醒审核人，0 表示不提醒", Category: "project"},
+	{SettingKey: "review_escalation_role", SettingValue: "admin", Description: "审核超期且同级没有后续审核人时，升级给项目负责人所在院系的哪个角色", Category: "project"},
+	{SettingKey: "two_factor_required_roles", SettingValue: "", Description: "强制启用两步验证的角色（逗号分隔，如 admin,teacher）", Category: "security"},
+}
+
+// initDefaultSettings 初始化默认系统设置
+func initDefaultSettings(db *gorm.DB) error {
+	for _, setting := range defaultSettings {
+		setting := setting
+		if err := db.Where("setting_key = ?", setting.SettingKey).FirstOrCreate(&setting).Error; err != nil {
+			return fmt.Errorf("初始化系统设置失败 %s: %v", setting.SettingKey, err)
+		}
+	}
+	log.Println("默认系统设置检查完成")
+	return nil
+}
+
+// defaultPermission 内置权限及默认授予的角色
+type defaultPermission struct {
+	Permission models.Permission
+	Roles      []string
+}
+
+// defaultPermissions 与 routes.RegisterRoutes 中的路由分组一一对应
+var defaultPermissions = []defaultPermission{
+	{models.Permission{PermKey: models.PermissionAll, Name: "全部权限", Module: "system", Description: "拥有系统全部权限"}, []string{"admin"}},
+	{models.Permission{PermKey: "user.manage", Name: "用户管理", Module: "user", Description: "/users 用户增删改查、重置密码、导入导出、回收站"}, nil},
+	{models.Permission{PermKey: "user.impersonate", Name: "模拟登录", Module: "user", Description: "/users/:id/impersonate 以用户身份查看（限时、默认只读、全程审计）"}, nil},
+	{models.Permission{PermKey: "user.sync", Name: "目录同步", Module: "user", Description: "/admin/ldap LDAP目录同步、试运行与同步记录"}, nil},
+	{models.Permission{PermKey: "user.rollover", Name: "学年升级", Module: "user", Description: "/admin/rollover 学年升级预览、执行与撤销"}, nil},
+	{models.Permission{PermKey: "user.purge", Name: "永久删除用户", Module: "user", Description: "/users/recycle-bin/:id 永久删除回收站中的用户"}, nil},
+	{models.Permission{PermKey: "user.merge", Name: "合并账号", Module: "user", Description: "/users/merge 合并重复账号"}, nil},
+	{models.Permission{PermKey: "user.anonymize", Name: "匿名化用户", Module: "user", Description: "/users/:id/anonymize 清除离校用户的个人信息"}, nil},
+	{models.Permission{PermKey: "org.manage", Name: "组织机构管理", Module: "org", Description: "/admin/org-units 组织机构节点维护与旧院系/专业文本映射"}, nil},
+	{models.Permission{PermKey: "project_type.manage", Name: "项目分类管理", Module: "project_type", Description: "/project-types 项目分类管理"}, nil},
+	{models.Permission{PermKey: "teacher.access", Name: "教师工作台", Module: "teacher", Description: "/teachers 教师列表、师生绑定、延期审批"}, []string{"teacher"}},
+	{models.Permission{PermKey: "project.review", Name: "项目审核", Module: "project", Description: "/teacher-projects 项目列表、审核、文件审核、委托审核"}, []string{"teacher"}},
+	{models.Permission{PermKey: "project.admin", Name: "项目管理", Module: "project", Description: "/admin/projects 强制状态、统计、导出、分类、状态流转、审核流程配置与审核时效统计"}, nil},
+	{models.Permission{PermKey: "student.bind_teacher", Name: "学生绑定教师", Module: "student", Description: "/students 学生绑定指导教师"}, []string{"student"}},
+	{models.Permission{PermKey: "competition.judge", Name: "竞赛评审", Module: "competition", Description: "/teacher-competitions 查看作品、评语、评分"}, []string{"teacher"}},
+	{models.Permission{PermKey: "competition.manage", Name: "竞赛管理", Module: "competition", Description: "/admin/competitions 竞赛增删改、报名、成绩、评审分配"}, nil},
+	{models.Permission{PermKey: "competition.finalize", Name: "竞赛成绩确认", Module: "competition", Description: "/admin/competitions/:id/finalize 最终确认成绩"}, nil},
+	{models.Permission{PermKey: "notification.manage", Name: "通知管理", Module: "notification", Description: "/admin/notifications 通知模板与发送"}, nil},
+	{models.Permission{PermKey: "system.dashboard", Name: "仪表板", Module: "system", Description: "/admin/dashboard 仪表板与用户概览"}, nil},
+	{models.Permission{PermKey: "system.logs", Name: "系统日志", Module: "system", Description: "/admin/logs 系统日志与健康日志"}, nil},
+	{models.Permission{PermKey: "system.settings", Name: "系统设置", Module: "system", Description: "/admin/settings 系统设置、配置、维护模式"}, nil},
+	{models.Permission{PermKey: "system.monitor", Name: "系统监控", Module: "system", Description: "/admin/health 健康、性能、告警、诊断"}, nil},
+	{models.Permission{PermKey: "system.backup", Name: "备份管理", Module: "system", Description: "/admin/backups 备份管理"}, nil},
+	{models.Permission{PermKey: "security.manage", Name: "登录安全管理", Module: "security", Description: "/admin/security 登录锁定管理"}, nil},
+	{models.Permission{PermKey: "rbac.manage", Name: "角色权限管理", Module: "rbac", Description: "/admin/roles、/admin/permissions 角色与权限管理"}, nil},
+}
+
+// initDefaultPermissions 初始化内置权限，仅在权限首次创建时授予默认角色，不覆盖管理员后续调整
+func initDefaultPermissions(db *gorm.DB) error {
+	// 权限表与种子数据一起建立：初始化SQL脚本不含这两张表，也不含 roles.is_system 等字段
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}, &models.RolePermission{}); err != nil {
+		return fmt.Errorf("创建权限表失败: %v", err)
+	}
+
+	if err := db.Model(&models.Role{}).Where("role_key IN ?", []string{"admin", "teacher", "student"}).
+		Update("is_system", true).Error; err != nil {
+		return fmt.Errorf("标记内置角色失败: %v", err)
+	}
+
+	for _, item := range defaultPermissions {
+		permission := item.Permission
+		permission.IsSystem = true
+		result := db.Where("perm_key = ?", permission.PermKey).FirstOrCreate(&permission)
+		if result.Error != nil {
+			return fmt.Errorf("初始化权限失败 %s: %v", permission.PermKey, result.Error)
+		}
+		if result.RowsAffected == 0 || len(item.Roles) == 0 {
+			continue
+		}
+
+		var roles []models.Role
+		db.Where("role_key IN ?", item.Roles).Find(&roles)
+		for _, role := range roles {
+			link := models.RolePermission{RoleID: role.ID, PermissionID: permission.ID}
+			if err := db.Where(link).FirstOrCreate(&link).Error; err != nil {
+				return fmt.Errorf("授予角色权限失败 %s -> %s: %v", role.RoleKey, permission.PermKey, err)
+			}
+		}
+	}
+	log.Println("默认权限检查完成")
+	return nil
+}
+
+// defaultProjectStates 默认项目流程的状态，与原 projects.status 枚举一致
+var defaultProjectStates = []models.ProjectWorkflowState{
+	{StateKey: "draft", Name: "草稿", IsInitial: true, SortOrder: 1},
+	{StateKey: "submitted", Name: "已提交", SortOrder: 2},
+	{StateKey: "reviewing", Name: "审核中", SortOrder: 3},
+	{StateKey: "approved", Name: "已立项", SortOrder: 4},
+	{StateKey: "rejected", Name: "已驳回", SortOrder: 5},
+	{StateKey: "completed", Name: "已结题", IsFinal: true, SortOrder: 6},
+}
+
+// defaultProjectTransitions 默认项目流程的流转规则
+var defaultProjectTransitions = []models.ProjectWorkflowTransition{
+	{Action: "submit", Name: "提交审核", FromStatus: "draft", ToStatus: "submitted", AllowedRoles: models.JSONArray{"leader"},
+		RequiredFields: models.JSONArray{"title", "description", "teacher_id"}, SideEffects: models.JSONArray{"mark_submitted", "notify_teacher"}, SortOrder: 1},
+	{Action: "withdraw", Name: "撤回", FromStatus: "submitted", ToStatus: "draft", AllowedRoles: models.JSONArray{"leader"},
+		SideEffects: models.JSONArray{"notify_teacher"}, SortOrder: 2},
+	{Action: "start_review", Name: "开始审核", FromStatus: "submitted", ToStatus: "reviewing", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		SideEffects: models.JSONArray{"notify_team"}, SortOrder: 3},
+	{Action: "approve", Name: "审核通过", FromStatus: "submitted", ToStatus: "approved", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		SideEffects: models.JSONArray{"mark_approved", "notify_team", "create_milestone"}, MilestoneTitle: "中期检查", MilestoneDueDays: 90, SortOrder: 4},
+	{Action: "approve", Name: "审核通过", FromStatus: "reviewing", ToStatus: "approved", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		SideEffects: models.JSONArray{"mark_approved", "notify_team", "create_milestone"}, MilestoneTitle: "中期检查", MilestoneDueDays: 90, SortOrder: 5},
+	{Action: "reject", Name: "驳回", FromStatus: "submitted", ToStatus: "rejected", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		RequireReason: true, SideEffects: models.JSONArray{"mark_rejected", "notify_team"}, SortOrder: 6},
+	{Action: "reject", Name: "驳回", FromStatus: "reviewing", ToStatus: "rejected", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		RequireReason: true, SideEffects: models.JSONArray{"mark_rejected", "notify_team"}, SortOrder: 7},
+	{Action: "revise", Name: "修改后重新编辑", FromStatus: "rejected", ToStatus: "draft", AllowedRoles: models.JSONArray{"leader"}, SortOrder: 8},
+	{Action: "complete", Name: "结题", FromStatus: "approved", ToStatus: "completed", AllowedRoles: models.JSONArray{"advisor", "admin"},
+		RequiredFields: models.JSONArray{"progress"}, SideEffects: models.JSONArray{"notify_team"}, SortOrder: 9},
+}
+
+// initDefaultProjectWorkflow 默认流程不存在时创建，已有配置不覆盖
+func initDefaultProjectWorkflow(db *gorm.DB) error {
+	var count int64
+	db.Model(&models.ProjectWorkflowState{}).Where("project_type_id IS NULL").Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, state := range defaultProjectStates {
+			state := state
+			if err := tx.Create(&state).Error; err != nil {
+				return err
+			}
+		}
+		for _, transition := range defaultProjectTransitions {
+			transition := transition
+			if err := tx.Create(&transition).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("初始化默认项目状态流转失败: %v", err)
+	}
+	log.Println("默认项目状态流转创建完成")
+	return nil
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"yunmeng-backend/utils"
+)
+
+// defaultJWTSecret 仅用于本地开发，须设置 JWT_ALLOW_DEV_SECRET=true 才会启用
+const defaultJWTSecret = "yunmeng-secret-key"
+
+// JWTConfig JWT签名密钥配置
+type JWTConfig struct {
+	KeyDir         string // 密钥目录，文件命名规则见 utils.LoadSigningKeyFile
+	ActiveKID      string // 用于签发新令牌的密钥ID，其余密钥仅用于验签
+	Secret         string // 未配置密钥目录时使用的HS256共享密钥
+	AllowDevSecret bool   // 两者都未配置时是否允许使用内置开发密钥，仅限本地开发
+}
+
+// NewJWTConfig 创建JWT配置
+func NewJWTConfig() *JWTConfig {
+	return &JWTConfig{
+		KeyDir:         getEnv("JWT_KEY_DIR", ""),
+		ActiveKID:      getEnv("JWT_ACTIVE_KID", ""),
+		Secret:         getEnv("JWT_SECRET", ""),
+		AllowDevSecret: getEnv("JWT_ALLOW_DEV_SECRET", "false") == "true",
+	}
+}
+
+// LoadJWTKeys 装载JWT签名密钥
+//
+// 轮换密钥时，将新密钥放入密钥目录并把 JWT_ACTIVE_KID 指向它；旧密钥保留在目录中，
+// 直到其签发的令牌全部过期后再移除，这样轮换期间已登录用户不会被强制下线。
+func LoadJWTKeys(config *JWTConfig) error {
+	if config.KeyDir == "" {
+		secret := config.Secret
+		if secret == "" {
+			if !config.AllowDevSecret {
+				return fmt.Errorf("未配置 JWT_KEY_DIR 或 JWT_SECRET（本地开发可设置 JWT_ALLOW_DEV_SECRET=true 使用内置开发密钥）")
+			}
+			log.Println("警告: 未配置 JWT_KEY_DIR / JWT_SECRET，使用内置开发密钥，请勿用于生产环境")
+			secret = defaultJWTSecret
+		}
+		kid := config.ActiveKID
+		if kid == "" {
+			kid = "default"
+		}
+		return utils.SetSigningKeys([]*utils.SigningKey{utils.NewHMACKey(kid, []byte(secret))}, kid)
+	}
+
+	var paths []string
+	for _, pattern := range []string{"*.key", "*.pem", "*.pub"} {
+		matches, err := filepath.Glob(filepath.Join(config.KeyDir, pattern))
+		if err != nil {
+			return fmt.Errorf("读取密钥目录失败: %v", err)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("密钥目录中没有可用的密钥: %s", config.KeyDir)
+	}
+	sort.Strings(paths)
+
+	var keys []*utils.SigningKey
+	for _, path := range paths {
+		key, err := utils.LoadSigningKeyFile(path)
+		if err != nil {
+			return fmt.Errorf("加载密钥失败: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if config.ActiveKID == "" {
+		return fmt.Errorf("配置了 JWT_KEY_DIR 时必须同时配置 JWT_ACTIVE_KID")
+	}
+	if err := utils.SetSigningKeys(keys, config.ActiveKID); err != nil {
+		return err
+	}
+
+	active := utils.ActiveSigningKey()
+	log.Printf("JWT密钥加载完成 - 密钥数量: %d, 当前签名密钥: %s (%s)", len(keys), active.KID, active.Method.Alg())
+	return nil
+}
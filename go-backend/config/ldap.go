@@ -0,0 +1,87 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"yunmeng-backend/utils"
+)
+
+// LDAPConfig LDAP目录同步配置
+type LDAPConfig struct {
+	URL                string
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	Filter             string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Mapping            utils.LDAPAttributeMapping
+	RoleMap            string // 人员类型=角色，逗号分隔，例如 student=student,staff=teacher
+	DefaultRole        string
+	SyncInterval       int // 定时同步间隔（分钟），0 表示只手动同步
+}
+
+// NewLDAPConfig 创建LDAP配置，属性名默认值按 OpenLDAP 的 inetOrgPerson 设置
+func NewLDAPConfig() *LDAPConfig {
+	interval, _ := strconv.Atoi(getEnv("LDAP_SYNC_INTERVAL_MINUTES", "0"))
+	return &LDAPConfig{
+		URL:                getEnv("LDAP_URL", ""),
+		BindDN:             getEnv("LDAP_BIND_DN", ""),
+		BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		BaseDN:             getEnv("LDAP_BASE_DN", ""),
+		Filter:             getEnv("LDAP_USER_FILTER", "(objectClass=inetOrgPerson)"),
+		StartTLS:           getEnv("LDAP_START_TLS", "false") == "true",
+		InsecureSkipVerify: getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+		Mapping: utils.LDAPAttributeMapping{
+			ID:         getEnv("LDAP_ATTR_ID", "entryUUID"),
+			Username:   getEnv("LDAP_ATTR_USERNAME", "uid"),
+			Email:      getEnv("LDAP_ATTR_EMAIL", "mail"),
+			RealName:   getEnv("LDAP_ATTR_REAL_NAME", "cn"),
+			Phone:      getEnv("LDAP_ATTR_PHONE", "telephoneNumber"),
+			StudentID:  getEnv("LDAP_ATTR_STUDENT_ID", "employeeNumber"),
+			Department: getEnv("LDAP_ATTR_DEPARTMENT", "departmentNumber"),
+			Title:      getEnv("LDAP_ATTR_TITLE", "title"),
+			Grade:      getEnv("LDAP_ATTR_GRADE", ""),
+			Major:      getEnv("LDAP_ATTR_MAJOR", ""),
+			Type:       getEnv("LDAP_ATTR_TYPE", "employeeType"),
+		},
+		RoleMap:      getEnv("LDAP_ROLE_MAP", "student=student,staff=teacher,teacher=teacher"),
+		DefaultRole:  getEnv("LDAP_DEFAULT_ROLE", ""),
+		SyncInterval: interval,
+	}
+}
+
+// LoadLDAPDirectory 装载LDAP目录配置，未配置 LDAP_URL 时不启用目录同步
+//
+// 本地调试可运行 OpenLDAP 容器并导入 scripts/ldap/sample.ldif，见该文件头部说明。
+func LoadLDAPDirectory(config *LDAPConfig) bool {
+	if config.URL == "" {
+		log.Println("未配置 LDAP_URL，LDAP目录同步未启用")
+		return false
+	}
+
+	roleMap := make(map[string]string)
+	for _, pair := range strings.Split(config.RoleMap, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			roleMap[strings.ToLower(parts[0])] = parts[1]
+		}
+	}
+
+	utils.SetLDAPDirectory(&utils.LDAPDirectory{
+		URL:                config.URL,
+		BindDN:             config.BindDN,
+		BindPassword:       config.BindPassword,
+		BaseDN:             config.BaseDN,
+		Filter:             config.Filter,
+		StartTLS:           config.StartTLS,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		Mapping:            config.Mapping,
+		RoleMap:            roleMap,
+		DefaultRole:        config.DefaultRole,
+	})
+	log.Printf("LDAP目录同步已配置 - %s, BaseDN: %s", config.URL, config.BaseDN)
+	return true
+}
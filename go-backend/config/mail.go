@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log"
+
+	"yunmeng-backend/utils"
+)
+
+// MailConfig SMTP邮件配置
+type MailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// NewMailConfig 创建邮件配置
+func NewMailConfig() *MailConfig {
+	return &MailConfig{
+		Host:     getEnv("SMTP_HOST", ""),
+		Port:     getEnv("SMTP_PORT", "25"),
+		Username: getEnv("SMTP_USERNAME", ""),
+		Password: getEnv("SMTP_PASSWORD", ""),
+		From:     getEnv("SMTP_FROM", "noreply@yunmeng.edu.cn"),
+	}
+}
+
+// LoadMailSender 装载邮件发送器，未配置 SMTP_HOST 时邮件只写入日志
+//
+// 本地调试可运行 MailHog/Mailpit 等邮件捕获工具，并设置 SMTP_HOST=127.0.0.1、SMTP_PORT=1025。
+func LoadMailSender(config *MailConfig) {
+	if config.Host == "" {
+		log.Println("未配置 SMTP_HOST，邮件将只写入日志")
+		return
+	}
+	utils.SetMailSender(&utils.SMTPSender{
+		Host:     config.Host,
+		Port:     config.Port,
+		Username: config.Username,
+		Password: config.Password,
+		From:     config.From,
+	})
+	log.Printf("邮件发送已配置 - SMTP: %s:%s", config.Host, config.Port)
+}
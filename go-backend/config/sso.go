@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"yunmeng-backend/utils"
+)
+
+// SSOConfig 统一身份认证配置
+type SSOConfig struct {
+	File string // 认证提供方配置文件（JSON），为空表示不启用统一身份认证
+}
+
+// SSOProviderConfig 单个认证提供方配置
+type SSOProviderConfig struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Type    string `json:"type"` // oidc 或 cas
+	Enabled *bool  `json:"enabled"`
+
+	// OIDC
+	Issuer                string   `json:"issuer"`
+	ClientID              string   `json:"clientId"`
+	ClientSecret          string   `json:"clientSecret"`
+	RedirectURL           string   `json:"redirectUrl"`
+	Scopes                []string `json:"scopes"`
+	AuthorizationEndpoint string   `json:"authorizationEndpoint"`
+	TokenEndpoint         string   `json:"tokenEndpoint"`
+	UserInfoEndpoint      string   `json:"userInfoEndpoint"`
+	JWKSURI               string   `json:"jwksUri"`
+	SubjectClaim          string   `json:"subjectClaim"`
+
+	// CAS
+	BaseURL    string `json:"baseUrl"`
+	ServiceURL string `json:"serviceUrl"`
+
+	// GroupsAttribute OIDC 组声明 / CAS 组属性名，默认 groups
+	GroupsAttribute string           `json:"groupsAttribute"`
+	Mapping         utils.SSOMapping `json:"mapping"`
+}
+
+// NewSSOConfig 创建统一身份认证配置
+func NewSSOConfig() *SSOConfig {
+	return &SSOConfig{
+		File: getEnv("SSO_CONFIG_FILE", ""),
+	}
+}
+
+// LoadSSOProviders 装载统一身份认证提供方
+//
+// 配置文件格式为 {"providers": [...]}，示例见 scripts/mock_idp/sso.example.json；
+// 本地调试可运行 go run ./scripts/mock_idp 启动模拟身份平台。
+// clientSecret 以 env: 开头时从对应环境变量读取，避免把密钥写入文件。
+func LoadSSOProviders(config *SSOConfig) error {
+	if config.File == "" {
+		log.Println("未配置 SSO_CONFIG_FILE，统一身份认证未启用")
+		return nil
+	}
+
+	data, err := os.ReadFile(config.File)
+	if err != nil {
+		return fmt.Errorf("读取统一身份认证配置失败: %v", err)
+	}
+	var file struct {
+		Providers []SSOProviderConfig `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析统一身份认证配置失败: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var registrations []*utils.SSORegistration
+	for _, item := range file.Providers {
+		if item.Enabled != nil && !*item.Enabled {
+			continue
+		}
+		if item.Key == "" || seen[item.Key] {
+			return fmt.Errorf("认证提供方标识为空或重复: %q", item.Key)
+		}
+		seen[item.Key] = true
+
+		provider, err := newSSOProvider(item)
+		if err != nil {
+			return err
+		}
+		registrations = append(registrations, &utils.SSORegistration{Provider: provider, Mapping: item.Mapping})
+		log.Printf("统一身份认证已配置 - %s (%s)", item.Key, item.Type)
+	}
+
+	utils.SetSSOProviders(registrations)
+	return nil
+}
+
+func newSSOProvider(item SSOProviderConfig) (utils.SSOProvider, error) {
+	name := item.Name
+	if name == "" {
+		name = item.Key
+	}
+
+	switch item.Type {
+	case "oidc":
+		if item.Issuer == "" || item.ClientID == "" || item.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC 认证提供方 %s 缺少 issuer/clientId/redirectUrl", item.Key)
+		}
+		secret := item.ClientSecret
+		if strings.HasPrefix(secret, "env:") {
+			secret = os.Getenv(strings.TrimPrefix(secret, "env:"))
+		}
+		return &utils.OIDCProvider{
+			ProviderKey:           item.Key,
+			DisplayName:           name,
+			Issuer:                item.Issuer,
+			ClientID:              item.ClientID,
+			ClientSecret:          secret,
+			RedirectURL:           item.RedirectURL,
+			Scopes:                item.Scopes,
+			AuthorizationEndpoint: item.AuthorizationEndpoint,
+			TokenEndpoint:         item.TokenEndpoint,
+			UserInfoEndpoint:      item.UserInfoEndpoint,
+			JWKSURI:               item.JWKSURI,
+			SubjectClaim:          item.SubjectClaim,
+			GroupsClaim:           item.GroupsAttribute,
+		}, nil
+	case "cas":
+		if item.BaseURL == "" || item.ServiceURL == "" {
+			return nil, fmt.Errorf("CAS 认证提供方 %s 缺少 baseUrl/serviceUrl", item.Key)
+		}
+		return &utils.CASProvider{
+			ProviderKey:     item.Key,
+			DisplayName:     name,
+			BaseURL:         item.BaseURL,
+			ServiceURL:      item.ServiceURL,
+			GroupsAttribute: item.GroupsAttribute,
+		}, nil
+	}
+	return nil, fmt.Errorf("不支持的认证提供方类型: %s", item.Type)
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AcademicRolloverController struct {
+	rolloverService *services.AcademicRolloverService
+}
+
+func NewAcademicRolloverController(db *gorm.DB) *AcademicRolloverController {
+	return &AcademicRolloverController{rolloverService: services.NewAcademicRolloverService(db)}
+}
+
+// bindRules 读取升级规则；院系管理员只能处理本院系
+func (c *AcademicRolloverController) bindRules(ctx *gin.Context) (models.RolloverRules, bool) {
+	var rules models.RolloverRules
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&rules); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "参数错误: " + err.Error(),
+			})
+			return rules, false
+		}
+	}
+
+	scope := utils.GetDataScope(ctx)
+	if !scope.IsGlobal() {
+		if rules.Department == "" {
+			rules.Department = scope.Department
+		}
+		if rules.Department != scope.Department || scope.Department == "" {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "只能处理本院系的学生",
+			})
+			return rules, false
+		}
+	}
+	return rules, true
+}
+
+// PreviewRollover 预览学年升级（不修改数据）
+func (c *AcademicRolloverController) PreviewRollover(ctx *gin.Context) {
+	rules, ok := c.bindRules(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.rolloverService.Preview(rules)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成学年升级预览失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "生成学年升级预览成功",
+		"data":    report,
+	})
+}
+
+// ApplyRollover 执行学年升级
+func (c *AcademicRolloverController) ApplyRollover(ctx *gin.Context) {
+	rules, ok := c.bindRules(ctx)
+	if !ok {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	report, err := c.rolloverService.Apply(rules, "manual", &operatorID)
+	if err != nil {
+		if errors.Is(err, services.ErrRolloverExists) {
+			ctx.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "执行学年升级失败",
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, report.RolloverID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "学年升级完成",
+		"data":    report,
+	})
+}
+
+// UndoRollover 在撤销期限内撤销学年升级
+func (c *AcademicRolloverController) UndoRollover(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的学年升级记录ID")
+	if !ok {
+		return
+	}
+
+	if !c.ensureRolloverInScope(ctx, id) {
+		return
+	}
+
+	result, err := c.rolloverService.Undo(id, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "学年升级已撤销",
+		"data":    result,
+	})
+}
+
+// GetRollovers 获取学年升级记录（按数据范围过滤）
+func (c *AcademicRolloverController) GetRollovers(ctx *gin.Context) {
+	records, err := c.rolloverService.GetRollovers(utils.GetDataScope(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取学年升级记录失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取学年升级记录成功",
+		"data":    records,
+	})
+}
+
+// GetRollover 获取学年升级记录详情（含变更明细）
+func (c *AcademicRolloverController) GetRollover(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的学年升级记录ID")
+	if !ok {
+		return
+	}
+
+	record, report, err := c.rolloverService.GetRollover(id)
+	if err == nil && !rolloverInScope(ctx, record) {
+		err = errors.New("学年升级记录不存在")
+	}
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取学年升级记录成功",
+		"data": gin.H{
+			"rollover": record,
+			"report":   report,
+		},
+	})
+}
+
+// ensureRolloverInScope 院系管理员只能查看和撤销本院系的学年升级，范围外按不存在处理
+func (c *AcademicRolloverController) ensureRolloverInScope(ctx *gin.Context, id uint) bool {
+	record, _, err := c.rolloverService.GetRollover(id)
+	if err == nil && rolloverInScope(ctx, record) {
+		return true
+	}
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"code":    404,
+		"message": "学年升级记录不存在",
+	})
+	return false
+}
+
+func rolloverInScope(ctx *gin.Context, record *models.AcademicRollover) bool {
+	scope := utils.GetDataScope(ctx)
+	return scope.IsGlobal() || (scope.Department != "" && record.Department == scope.Department)
+}
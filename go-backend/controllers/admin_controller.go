@@ -25,7 +25,7 @@ func (c *AdminController) GetDashboardStats(ctx *gin.Context) {
 	log.Printf("获取管理员仪表板统计数据")
 
 	// 获取用户统计
-	userStats, err := c.userService.GetUserStats()
+	userStats, err := c.userService.GetUserStats(queryOrgUnitID(ctx))
 	if err != nil {
 		log.Printf("获取用户统计失败: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
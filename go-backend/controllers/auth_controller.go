@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"yunmeng-backend/models"
+	"yunmeng-backend/services"
 	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -11,53 +14,104 @@ import (
 )
 
 type AuthController struct {
-	db *gorm.DB
+	db          *gorm.DB
+	authService *services.AuthService
+	systemLogs  *services.SystemLogService
 }
 
-func NewAuthController(db *gorm.DB) *AuthController {
-	return &AuthController{db: db}
+func NewAuthController(db *gorm.DB, authService *services.AuthService) *AuthController {
+	return &AuthController{
+		db:          db,
+		authService: authService,
+		systemLogs:  services.NewSystemLogService(db),
+	}
 }
 
-// RefreshToken 刷新Token接口
+// RefreshToken 刷新Token接口（使用刷新令牌换取新令牌对，旧刷新令牌随即失效）
 func (c *AuthController) RefreshToken(ctx *gin.Context) {
-	// 从请求头获取当前Token
-	authHeader := ctx.GetHeader("Authorization")
-	if authHeader == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "未提供认证令牌",
+	var req models.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "未提供刷新令牌",
 		})
 		return
 	}
 
-	// 解析当前Token
-	claims, err := utils.ParseToken(authHeader[7:]) // 去掉"Bearer "前缀
+	tokens, err := c.authService.RefreshSession(req.RefreshToken)
 	if err != nil {
-		log.Printf("Token解析失败: %v", err)
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "Token无效或已过期",
+		log.Printf("Token刷新失败: %v", err)
+		c.systemLogs.RecordSecurity("token_refresh", "刷新令牌", "failed", nil, err.Error(), ctx.ClientIP(), ctx.Request.UserAgent())
+		if errors.Is(err, services.ErrRefreshTokenInvalid) ||
+			errors.Is(err, services.ErrRefreshTokenReused) ||
+			errors.Is(err, services.ErrSessionRevoked) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "Token刷新失败",
 		})
 		return
 	}
 
-	// 生成新的Token
-	newToken, err := utils.GenerateToken(claims.UserID, claims.Username, claims.Role)
-	if err != nil {
-		log.Printf("新Token生成失败: %v", err)
+	log.Printf("Token刷新成功 - 会话ID: %d", tokens.SessionID)
+	var session models.UserSession
+	var userID *uint
+	if c.db.Select("user_id").First(&session, tokens.SessionID).Error == nil {
+		userID = &session.UserID
+	}
+	c.systemLogs.RecordSecurity("token_refresh", "刷新令牌", "success", userID,
+		fmt.Sprintf("会话ID: %d", tokens.SessionID), ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":          200,
+		"message":       "Token刷新成功",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout 退出登录接口（撤销当前会话）
+func (c *AuthController) Logout(ctx *gin.Context) {
+	sessionID, _ := ctx.Get("sessionID")
+	sid, _ := sessionID.(uint)
+
+	if err := c.authService.RevokeSession(sid, "logout"); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
-			"message": "Token刷新失败",
+			"message": "退出登录失败",
 		})
 		return
 	}
 
-	log.Printf("Token刷新成功 - 用户: %s, 角色: %s", claims.Username, claims.Role)
+	log.Printf("用户退出登录 - 用户ID: %d, 会话ID: %d", utils.GetCurrentUserID(ctx), sid)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "退出登录成功",
+	})
+}
+
+// StopImpersonation 结束模拟登录（撤销当前模拟会话）
+func (c *AuthController) StopImpersonation(ctx *gin.Context) {
+	err := services.NewImpersonationService(c.db).Stop(currentSessionID(ctx), utils.GetImpersonatorID(ctx),
+		utils.GetCurrentUserID(ctx), ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "Token刷新成功",
-		"token":   newToken,
+		"message": "已退出模拟登录",
 	})
 }
 
@@ -84,6 +138,15 @@ func (c *AuthController) ValidateToken(ctx *gin.Context) {
 		return
 	}
 
+	// 检查会话是否已被撤销
+	if _, err := c.authService.ValidateSession(claims.SessionID, claims.UserID, claims.Role); err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// 检查用户是否存在
 	var userCount int64
 	c.db.Model(&models.User{}).Where("id = ? AND status = ?", claims.UserID, "active").Count(&userCount)
@@ -108,11 +171,17 @@ func (c *AuthController) ValidateToken(ctx *gin.Context) {
 	})
 }
 
-// GetUserInfo 获取用户信息接口
+// GetJWKS 公开JWT验签公钥（供校内其他服务离线验证令牌）
+func (c *AuthController) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, utils.JWKS())
+}
+
+// GetUserInfo 获取用户信息接口（包含当前角色和全部可用角色）
 func (c *AuthController) GetUserInfo(ctx *gin.Context) {
 	// 从上下文获取用户信息（由中间件设置）
-	userID, exists := ctx.Get("userID")
-	if !exists {
+	userID := utils.GetCurrentUserID(ctx)
+	if userID == 0 {
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
 			"message": "用户未登录",
@@ -131,8 +200,8 @@ func (c *AuthController) GetUserInfo(ctx *gin.Context) {
 	}
 
 	// 获取用户角色
-	var roles []models.Role
-	if err := c.db.Model(&user).Association("Roles").Find(&roles); err != nil {
+	roles, err := c.authService.GetUserRoles(user.ID)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "获取用户角色失败",
@@ -146,13 +215,26 @@ func (c *AuthController) GetUserInfo(ctx *gin.Context) {
 	}
 
 	userInfo := gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"status":     user.Status,
-		"roles":      roleNames,
-		"real_name":  user.Profile.RealName,
-		"department": user.Profile.Department,
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"status":         user.Status,
+		"role":           ctx.GetString("role"),
+		"roles":          roleNames,
+		"availableRoles": availableRoles(roles),
+	}
+	if user.Profile != nil {
+		userInfo["real_name"] = user.Profile.RealName
+		userInfo["department"] = user.Profile.Department
+	}
+	// 模拟登录期间返回发起模拟的管理员，前端据此展示提示条和“退出模拟”按钮
+	if impersonatorID := utils.GetImpersonatorID(ctx); impersonatorID != 0 {
+		var impersonator models.User
+		c.db.Select("id", "username").First(&impersonator, impersonatorID)
+		userInfo["impersonator"] = gin.H{
+			"id":       impersonatorID,
+			"username": impersonator.Username,
+		}
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
@@ -161,3 +243,89 @@ func (c *AuthController) GetUserInfo(ctx *gin.Context) {
 		"data":    userInfo,
 	})
 }
+
+// GetMyRoles 获取当前用户可切换的角色列表
+func (c *AuthController) GetMyRoles(ctx *gin.Context) {
+	roles, err := c.authService.GetUserRoles(utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取用户角色失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取用户角色成功",
+		"data": gin.H{
+			"currentRole": ctx.GetString("role"),
+			"roles":       availableRoles(roles),
+		},
+	})
+}
+
+// SwitchRole 切换到用户拥有的另一个角色，返回新的令牌对（旧访问令牌随即失效）
+func (c *AuthController) SwitchRole(ctx *gin.Context) {
+	var req models.SwitchRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	currentRole := ctx.GetString("role")
+	if req.Role == currentRole {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "已经是当前角色",
+		})
+		return
+	}
+
+	sessionID := ctx.GetUint("sessionID")
+	tokens, previousRole, err := c.authService.SwitchRole(sessionID, userID, req.Role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrRoleNotHeld) {
+			status = http.StatusForbidden
+		} else if errors.Is(err, services.ErrSessionRevoked) {
+			status = http.StatusUnauthorized
+		}
+		c.systemLogs.RecordSecurity("role_switch", "切换角色", "failed", &userID,
+			fmt.Sprintf("会话ID: %d, %s -> %s, 原因: %v", sessionID, currentRole, req.Role, err),
+			ctx.ClientIP(), ctx.Request.UserAgent())
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.systemLogs.RecordSecurity("role_switch", "切换角色", "success", &userID,
+		fmt.Sprintf("会话ID: %d, %s -> %s", sessionID, previousRole, req.Role),
+		ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":          200,
+		"message":       "角色切换成功",
+		"role":          req.Role,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func availableRoles(roles []models.Role) []gin.H {
+	result := make([]gin.H, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, gin.H{
+			"roleKey":  role.RoleKey,
+			"roleName": role.RoleName,
+		})
+	}
+	return result
+}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"time"
 	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -55,6 +57,9 @@ func (c *CompetitionController) GetCompetitionList(ctx *gin.Context) {
 		}
 	}
 
+	// 数据范围：院系管理员只能查看本院系的竞赛
+	query = c.applyCompetitionScope(query, utils.GetDataScope(ctx))
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		log.Printf("获取竞赛总数失败: %v", err)
@@ -314,8 +319,20 @@ func (c *CompetitionController) CreateCompetition(ctx *gin.Context) {
 		Attachment:        req.Attachment,
 		IsOpen:            req.IsOpen,
 		MaxParticipants:   req.MaxParticipants,
+		OrgUnitID:         req.OrgUnitID,
 		CreatedBy:         userID.(uint),
 	}
+	if req.OrgUnitID != nil {
+		department, _, err := services.OrgUnitLabels(c.db, *req.OrgUnitID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		competition.DepartmentLimit = department
+	}
 
 	if err := c.db.Create(&competition).Error; err != nil {
 		log.Printf("创建竞赛失败: %v", err)
@@ -325,6 +342,7 @@ func (c *CompetitionController) CreateCompetition(ctx *gin.Context) {
 		})
 		return
 	}
+	utils.SetAuditEntity(ctx, competition.ID)
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
@@ -456,6 +474,18 @@ func (c *CompetitionController) UpdateCompetition(ctx *gin.Context) {
 	if req.MaxParticipants != nil {
 		updates["max_participants"] = *req.MaxParticipants
 	}
+	if req.OrgUnitID != nil {
+		department, _, err := services.OrgUnitLabels(c.db, *req.OrgUnitID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		updates["org_unit_id"] = *req.OrgUnitID
+		updates["department_limit"] = department
+	}
 
 	if err := c.db.Model(&competition).Updates(updates).Error; err != nil {
 		log.Printf("更新竞赛失败: %v", err)
@@ -522,20 +552,33 @@ func (c *CompetitionController) DeleteCompetition(ctx *gin.Context) {
 	})
 }
 
-// GetCompetitionStats 获取竞赛统计信息
+// GetCompetitionStats 获取竞赛统计信息（orgUnitId：只统计限定该组织机构及其下级的竞赛和该范围内学生的报名）
 func (c *CompetitionController) GetCompetitionStats(ctx *gin.Context) {
 	var stats []gin.H
 
+	orgUnitID := queryOrgUnitID(ctx)
+	competitions := func() *gorm.DB {
+		query := c.db.Model(&models.Competition{})
+		if orgUnitID > 0 {
+			query = query.Where("org_unit_id IN (?)", services.OrgUnitSubtreeIDs(c.db, orgUnitID))
+		}
+		return query
+	}
+	byStudent := func(model interface{}) *gorm.DB {
+		query := c.db.Model(model)
+		if orgUnitID > 0 {
+			query = query.Where("student_id IN (?)", c.db.Model(&models.User{}).Select("id").
+				Where("org_unit_id IN (?)", services.OrgUnitSubtreeIDs(c.db, orgUnitID)))
+		}
+		return query
+	}
+
 	// 获取各类型竞赛数量
-	rows, err := c.db.Raw(`
-		SELECT 
-			type,
-			COUNT(*) as count,
-			SUM(CASE WHEN is_open = true THEN 1 ELSE 0 END) as open_count
-		FROM competitions
-		GROUP BY type
-		ORDER BY count DESC
-	`).Rows()
+	rows, err := competitions().
+		Select("type, COUNT(*) as count, SUM(CASE WHEN is_open = true THEN 1 ELSE 0 END) as open_count").
+		Group("type").
+		Order("count DESC").
+		Rows()
 	if err != nil {
 		log.Printf("获取竞赛统计失败: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -559,10 +602,10 @@ func (c *CompetitionController) GetCompetitionStats(ctx *gin.Context) {
 
 	// 获取总体统计
 	var totalCompetitions, totalRegistrations, totalSubmissions, totalResults int64
-	c.db.Model(&models.Competition{}).Count(&totalCompetitions)
-	c.db.Model(&models.CompetitionRegistration{}).Count(&totalRegistrations)
-	c.db.Model(&models.CompetitionSubmission{}).Count(&totalSubmissions)
-	c.db.Model(&models.CompetitionResult{}).Count(&totalResults)
+	competitions().Count(&totalCompetitions)
+	byStudent(&models.CompetitionRegistration{}).Count(&totalRegistrations)
+	byStudent(&models.CompetitionSubmission{}).Count(&totalSubmissions)
+	byStudent(&models.CompetitionResult{}).Count(&totalResults)
 
 	overallStats := gin.H{
 		"total_competitions":  totalCompetitions,
@@ -1391,9 +1434,10 @@ func (c *CompetitionController) ExportCompetitionData(ctx *gin.Context) {
 		return
 	}
 
-	// 检查竞赛是否存在
+	// 检查竞赛是否存在（且在数据范围内）
+	scope := utils.GetDataScope(ctx)
 	var competition models.Competition
-	if err := c.db.First(&competition, id).Error; err != nil {
+	if err := c.applyCompetitionScope(c.db.Model(&models.Competition{}), scope).First(&competition, id).Error; err != nil {
 		log.Printf("竞赛不存在: %v", err)
 		ctx.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -1407,7 +1451,8 @@ func (c *CompetitionController) ExportCompetitionData(ctx *gin.Context) {
 	if exportType == "registrations" {
 		// 导出报名数据
 		var registrations []models.CompetitionRegistration
-		if err := c.db.Where("competition_id = ?", id).Preload("Student").Find(&registrations).Error; err != nil {
+		query := services.ApplyUserScope(c.db, c.db.Where("competition_id = ?", id), scope, "student_id")
+		if err := query.Preload("Student").Find(&registrations).Error; err != nil {
 			log.Printf("获取报名数据失败: %v", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"code":    500,
@@ -1439,7 +1484,8 @@ func (c *CompetitionController) ExportCompetitionData(ctx *gin.Context) {
 	} else {
 		// 导出结果数据
 		var results []models.CompetitionResult
-		if err := c.db.Where("competition_id = ?", id).Preload("Student").Find(&results).Error; err != nil {
+		query := services.ApplyUserScope(c.db, c.db.Where("competition_id = ?", id), scope, "student_id")
+		if err := query.Preload("Student").Find(&results).Error; err != nil {
 			log.Printf("获取结果数据失败: %v", err)
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"code":    500,
@@ -1888,3 +1934,16 @@ func (c *CompetitionController) FinalizeResults(ctx *gin.Context) {
 		"message": "最终确认成绩成功",
 	})
 }
+
+// applyCompetitionScope 院系管理员只能看到限定本院系或由本院系人员创建的竞赛
+func (c *CompetitionController) applyCompetitionScope(query *gorm.DB, scope *models.DataScope) *gorm.DB {
+	if scope.IsGlobal() {
+		return query
+	}
+	if scope.Department == "" {
+		return query.Where("1 = 0")
+	}
+	// 院系限制为逗号分隔的院系列表，按完整条目匹配，避免“计算机”匹配到“计算机应用”
+	return query.Where("FIND_IN_SET(?, competitions.department_limit) > 0 OR competitions.created_by IN (?)",
+		scope.Department, services.DepartmentUserIDs(c.db, scope.Department))
+}
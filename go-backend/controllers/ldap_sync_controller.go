@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LDAPSyncController struct {
+	syncService *services.LDAPSyncService
+}
+
+func NewLDAPSyncController(db *gorm.DB) *LDAPSyncController {
+	return &LDAPSyncController{syncService: services.NewLDAPSyncService(db)}
+}
+
+// Sync 手动触发目录同步，dryRun 为 true 时只返回差异报告
+func (c *LDAPSyncController) Sync(ctx *gin.Context) {
+	var req models.LDAPSyncRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	run, err := c.syncService.Run("manual", req.DryRun, &operatorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrLDAPNotConfigured):
+			ctx.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		case errors.Is(err, services.ErrLDAPSyncRunning):
+			ctx.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+		default:
+			ctx.JSON(http.StatusBadGateway, gin.H{
+				"code":    502,
+				"message": "目录同步失败: " + err.Error(),
+				"data":    run,
+			})
+		}
+		return
+	}
+
+	message := "目录同步完成"
+	if req.DryRun {
+		message = "目录同步试运行完成"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    run,
+	})
+}
+
+// GetRuns 获取目录同步记录
+func (c *LDAPSyncController) GetRuns(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(ctx.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	runs, total, err := c.syncService.GetRuns(page, size)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取目录同步记录失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取目录同步记录成功",
+		"data": gin.H{
+			"runs":  runs,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
+
+// GetRun 获取目录同步记录详情（含差异明细）
+func (c *LDAPSyncController) GetRun(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的同步记录ID")
+	if !ok {
+		return
+	}
+
+	run, err := c.syncService.GetRun(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取目录同步记录成功",
+		"data":    run,
+	})
+}
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"yunmeng-backend/models"
+	"yunmeng-backend/services"
 	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -21,9 +25,18 @@ type LoginResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 	Token   string      `json:"token,omitempty"`
+	// 刷新令牌为一次性令牌，每次刷新后轮换
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
 }
 
 func LoginHandler(db *gorm.DB) gin.HandlerFunc {
+	authService := services.NewAuthService(db)
+	loginGuard := services.NewLoginGuardService(db)
+	userService := services.NewUserService(db)
+	twoFactor := services.NewTwoFactorService(db)
+	ldapAuth := services.NewLDAPAuthService(db)
+
 	return func(c *gin.Context) {
 		var req LoginRequest
 		// 解析请求参数
@@ -36,6 +49,22 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 		// 打印接收到的登录请求
 		log.Printf("收到登录请求 - 用户名: %s, 角色: %s", req.Username, req.Role)
 
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+
+		// 暴力破解防护：用户名或IP处于锁定/冷却期时直接拒绝
+		if wait := loginGuard.CheckAllowed(req.Username, ipAddress); wait > 0 {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			log.Printf("登录被限制 - 用户名: %s, IP: %s, 需等待: %d秒", req.Username, ipAddress, retryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusOK, LoginResponse{
+				Code:    429,
+				Message: fmt.Sprintf("登录尝试过于频繁，请%d秒后再试", retryAfter),
+				Data:    gin.H{"retry_after": retryAfter},
+			})
+			return
+		}
+
 		var user models.User
 
 		// 查询用户 - 通过用户名查找
@@ -43,6 +72,7 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				log.Printf("用户不存在 - 用户名: %s, 角色: %s", req.Username, req.Role)
+				loginGuard.RecordFailure(req.Username, nil, ipAddress, userAgent, "user_not_found")
 				c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: "账号或密码错误"})
 			} else {
 				log.Printf("数据库查询错误: %v", err)
@@ -55,22 +85,34 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 		log.Printf("查询到用户 - ID: %d, 用户名: %s, 状态: %s",
 			user.ID, user.Username, user.Status)
 
-		// 检查用户状态
+		// 密码校验 - 使用bcrypt比较；目录账号本地密码为随机密码，不匹配时再用目录密码绑定认证
+		loginMethod := services.LoginMethodPassword
+		if utils.CheckPassword(req.Password, user.Password) {
+			log.Printf("密码校验成功 - 用户名: %s", req.Username)
+		} else if ldapAuth.Authenticate(&user, req.Password) {
+			log.Printf("目录密码校验成功 - 用户名: %s", req.Username)
+			loginMethod = services.LoginMethodLDAP
+		} else {
+			log.Printf("密码校验失败 - 用户名: %s", req.Username)
+			loginGuard.RecordFailure(req.Username, &user.ID, ipAddress, userAgent, "bad_password")
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: "账号或密码错误"})
+			return
+		}
+
+		// 检查用户状态（放在密码校验之后，避免未知密码时探测账号状态；同样计入失败次数）
 		if user.Status == "inactive" {
 			log.Printf("用户被禁用 - 用户名: %s", req.Username)
+			loginGuard.RecordFailure(req.Username, &user.ID, ipAddress, userAgent, "account_inactive")
 			c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "账户已被禁用"})
 			return
 		}
-
-		// 密码校验 - 使用bcrypt比较
-		if !utils.CheckPassword(req.Password, user.Password) {
-			log.Printf("密码校验失败 - 用户名: %s", req.Username)
-			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: "账号或密码错误"})
+		if user.Status == "alumni" {
+			log.Printf("校友账号登录 - 用户名: %s", req.Username)
+			loginGuard.RecordFailure(req.Username, &user.ID, ipAddress, userAgent, "account_alumni")
+			c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "账户已毕业归档，如需使用请联系管理员"})
 			return
 		}
 
-		log.Printf("密码校验成功 - 用户名: %s", req.Username)
-
 		// 获取用户角色
 		var roles []models.Role
 		if err := db.Model(&user).Association("Roles").Find(&roles); err != nil {
@@ -92,38 +134,146 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 
 		if !roleMatched {
 			log.Printf("用户角色不匹配 - 用户名: %s, 请求角色: %s", req.Username, req.Role)
+			loginGuard.RecordFailure(req.Username, &user.ID, ipAddress, userAgent, "role_mismatch")
 			c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "用户角色不匹配"})
 			return
 		}
 
-		// 生成JWT Token
-		token, err := utils.GenerateToken(user.ID, user.Username, primaryRole)
+		beginSession(c, db, authService, loginGuard, userService, twoFactor, &user, primaryRole, services.SessionOptions{LoginMethod: loginMethod})
+	}
+}
+
+// beginSession 第一步认证（账号密码或统一身份认证）通过后：已启用两步验证时签发验证挑战，否则直接创建会话
+func beginSession(c *gin.Context, db *gorm.DB, authService *services.AuthService, loginGuard *services.LoginGuardService,
+	userService *services.UserService, twoFactor *services.TwoFactorService, user *models.User, role string, opts services.SessionOptions) {
+	// 已启用两步验证：签发第二步验证挑战，通过后才创建会话
+	if twoFactor.IsEnabled(user.ID) {
+		challengeToken, err := twoFactor.CreateChallenge(user, role, opts.LoginMethod, c.ClientIP(), c.Request.UserAgent())
+		if err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 500, Message: "服务端异常"})
+			return
+		}
+		log.Printf("等待两步验证 - 用户名: %s", user.Username)
+		c.JSON(http.StatusOK, LoginResponse{
+			Code:    202,
+			Message: "需要两步验证",
+			Data: gin.H{
+				"twoFactorRequired": true,
+				"challengeToken":    challengeToken,
+				"expiresIn":         int(twoFactor.ChallengeTTL().Seconds()),
+			},
+		})
+		return
+	}
+
+	// 角色要求两步验证但尚未绑定：签发受限会话，只允许完成绑定
+	if twoFactor.IsEnforced(role) {
+		opts.Restriction = services.SessionRestrictionTwoFactorSetup
+	}
+
+	completeLogin(c, db, authService, loginGuard, userService, user, role, opts)
+}
+
+// LoginTwoFactorHandler 登录第二步：校验TOTP口令或恢复码后签发令牌
+func LoginTwoFactorHandler(db *gorm.DB) gin.HandlerFunc {
+	authService := services.NewAuthService(db)
+	loginGuard := services.NewLoginGuardService(db)
+	userService := services.NewUserService(db)
+	twoFactor := services.NewTwoFactorService(db)
+	systemLogs := services.NewSystemLogService(db)
+
+	return func(c *gin.Context) {
+		var req models.TwoFactorLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 400, Message: "参数错误/缺失字段"})
+			return
+		}
+
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+
+		challenge, err := twoFactor.VerifyChallenge(req.ChallengeToken, req.Code)
 		if err != nil {
-			log.Printf("Token生成失败: %v", err)
-			c.JSON(http.StatusOK, LoginResponse{Code: 500, Message: "Token生成失败"})
+			if challenge != nil {
+				var user models.User
+				if db.Select("id", "username").First(&user, challenge.UserID).Error == nil {
+					loginGuard.RecordFailure(user.Username, &user.ID, ipAddress, userAgent, "bad_totp")
+				}
+			} else {
+				// 挑战令牌无效或已过期，没有对应用户，单独记录
+				systemLogs.RecordSecurity("login_2fa", "两步验证失败", "failed", nil, err.Error(), ipAddress, userAgent)
+			}
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: err.Error()})
 			return
 		}
 
-		// 获取用户详细信息
-		var profile models.UserProfile
-		db.Where("user_id = ?", user.ID).First(&profile)
-
-		// 返回用户信息（不含密码）
-		userInfo := map[string]interface{}{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-			"role":     primaryRole,
-			"realName": profile.RealName,
+		var user models.User
+		if err := db.First(&user, challenge.UserID).Error; err != nil || user.Status != "active" {
+			systemLogs.RecordSecurity("login_2fa", "两步验证通过但账户已被禁用", "failed", &challenge.UserID, "", ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "账户已被禁用"})
+			return
 		}
+		systemLogs.RecordSecurity("login_2fa", "两步验证通过", "success", &user.ID, "", ipAddress, userAgent)
+
+		completeLogin(c, db, authService, loginGuard, userService, &user, challenge.Role, services.SessionOptions{LoginMethod: challenge.LoginMethod})
+	}
+}
 
-		log.Printf("登录成功 - 用户名: %s, 角色: %s", req.Username, req.Role)
+// completeLogin 创建会话、记录登录并返回令牌
+func completeLogin(c *gin.Context, db *gorm.DB, authService *services.AuthService, loginGuard *services.LoginGuardService,
+	userService *services.UserService, user *models.User, role string, opts services.SessionOptions) {
+	ipAddress := c.ClientIP()
+	userAgent := c.Request.UserAgent()
 
-		c.JSON(http.StatusOK, LoginResponse{
-			Code:    200,
-			Message: "登录成功",
-			Data:    userInfo,
-			Token:   token,
-		})
+	// 管理员重置/新建账号或密码过期：签发受限会话，只允许修改密码（统一身份认证和目录密码登录不使用本地密码，不受此限制）
+	isPasswordLogin := opts.LoginMethod == "" || opts.LoginMethod == services.LoginMethodPassword
+	if isPasswordLogin && services.NewPasswordPolicyService(db).MustChange(user) {
+		opts.Restriction = services.SessionRestrictionPasswordChange
+	}
+
+	// 创建登录会话并生成Token
+	tokens, err := authService.CreateSession(user, role, ipAddress, userAgent, opts)
+	if err != nil {
+		log.Printf("Token生成失败: %v", err)
+		c.JSON(http.StatusOK, LoginResponse{Code: 500, Message: "Token生成失败"})
+		return
+	}
+
+	// 获取用户详细信息
+	var profile models.UserProfile
+	db.Where("user_id = ?", user.ID).First(&profile)
+
+	// 返回用户信息（不含密码）
+	userInfo := map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     role,
+		"realName": profile.RealName,
 	}
+	if opts.Restriction != "" {
+		userInfo["restriction"] = opts.Restriction
+	}
+
+	// 记录登录成功并清除失败计数
+	loginGuard.RecordSuccess(user.Username, user.ID, ipAddress, userAgent)
+	loginMethod := opts.LoginMethod
+	if loginMethod == "" {
+		loginMethod = services.LoginMethodPassword
+	}
+	services.NewSystemLogService(db).RecordSecurity("login", "登录成功", "success", &user.ID,
+		fmt.Sprintf("角色: %s, 登录方式: %s, 会话ID: %d", role, loginMethod, tokens.SessionID), ipAddress, userAgent)
+	userService.RecordLoginLog(user.ID, tokens.SessionID, ipAddress, userAgent)
+	userService.UpdateLastLogin(user.ID)
+
+	log.Printf("登录成功 - 用户名: %s, 角色: %s", user.Username, role)
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Code:         200,
+		Message:      "登录成功",
+		Data:         userInfo,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
 }
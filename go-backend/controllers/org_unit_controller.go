@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrgUnitController struct {
+	orgUnitService *services.OrgUnitService
+}
+
+func NewOrgUnitController(db *gorm.DB) *OrgUnitController {
+	return &OrgUnitController{orgUnitService: services.NewOrgUnitService(db)}
+}
+
+// queryOrgUnitID 读取 orgUnitId 查询参数，未填写或无效时为0（不筛选）
+func queryOrgUnitID(ctx *gin.Context) uint {
+	id, err := strconv.ParseUint(ctx.Query("orgUnitId"), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// GetOrgUnitTree 获取组织机构树
+func (c *OrgUnitController) GetOrgUnitTree(ctx *gin.Context) {
+	tree, err := c.orgUnitService.GetTree()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取组织机构失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取组织机构成功",
+		"data":    tree,
+	})
+}
+
+// GetOrgUnit 获取组织机构节点及其直接下级
+func (c *OrgUnitController) GetOrgUnit(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的节点ID")
+	if !ok {
+		return
+	}
+
+	unit, err := c.orgUnitService.GetOrgUnit(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取组织机构节点成功",
+		"data":    unit,
+	})
+}
+
+// CreateOrgUnit 创建组织机构节点
+func (c *OrgUnitController) CreateOrgUnit(ctx *gin.Context) {
+	var req models.OrgUnitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	unit, err := c.orgUnitService.CreateOrgUnit(req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, unit.ID)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"message": "创建组织机构节点成功",
+		"data":    unit,
+	})
+}
+
+// UpdateOrgUnit 更新组织机构节点（可调整上级节点）
+func (c *OrgUnitController) UpdateOrgUnit(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的节点ID")
+	if !ok {
+		return
+	}
+
+	var req models.OrgUnitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	unit, err := c.orgUnitService.UpdateOrgUnit(id, req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新组织机构节点成功",
+		"data":    unit,
+	})
+}
+
+// DeleteOrgUnit 删除组织机构节点
+func (c *OrgUnitController) DeleteOrgUnit(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的节点ID")
+	if !ok {
+		return
+	}
+
+	if err := c.orgUnitService.DeleteOrgUnit(id, utils.GetCurrentUserID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除组织机构节点成功",
+	})
+}
+
+// MapLegacyValues 把旧的院系/专业文本映射到组织机构节点（dryRun 时只返回映射报告）
+func (c *OrgUnitController) MapLegacyValues(ctx *gin.Context) {
+	var req models.OrgMappingRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := c.orgUnitService.MapLegacy(req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "组织机构映射完成"
+	if req.DryRun {
+		message = "组织机构映射预览完成"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    report,
+	})
+}
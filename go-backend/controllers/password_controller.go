@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PasswordController 自助找回密码、修改密码
+type PasswordController struct {
+	resetService  *services.PasswordResetService
+	policyService *services.PasswordPolicyService
+	authService   *services.AuthService
+	twoFactor     *services.TwoFactorService
+	systemLogs    *services.SystemLogService
+}
+
+func NewPasswordController(db *gorm.DB) *PasswordController {
+	return &PasswordController{
+		resetService:  services.NewPasswordResetService(db),
+		policyService: services.NewPasswordPolicyService(db),
+		authService:   services.NewAuthService(db),
+		twoFactor:     services.NewTwoFactorService(db),
+		systemLogs:    services.NewSystemLogService(db),
+	}
+}
+
+// GetPolicy 获取当前密码规则（用于前端提示）
+func (c *PasswordController) GetPolicy(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取密码规则成功",
+		"data":    c.policyService.Describe(),
+	})
+}
+
+// ChangePassword 修改当前用户密码；成功后解除“必须修改密码”限制并下线其他设备
+func (c *PasswordController) ChangePassword(ctx *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	if err := c.policyService.ChangePassword(userID, req.OldPassword, req.NewPassword); err != nil {
+		c.systemLogs.RecordSecurity("password_change", "修改密码", "failed", &userID, err.Error(), ctx.ClientIP(), ctx.Request.UserAgent())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 当前会话继续有效；角色要求两步验证但尚未绑定时，转为两步验证绑定限制
+	sid := currentSessionID(ctx)
+	var revoked int64
+	if sid != 0 {
+		restriction := ""
+		role := ctx.GetString("role")
+		if c.twoFactor.IsEnforced(role) && !c.twoFactor.IsEnabled(userID) {
+			restriction = services.SessionRestrictionTwoFactorSetup
+		}
+		c.authService.SetSessionRestriction(sid, restriction)
+		revoked, _ = c.authService.RevokeOtherSessions(userID, sid, "password_change")
+	}
+
+	c.systemLogs.RecordSecurity("password_change", "修改密码", "success", &userID,
+		fmt.Sprintf("下线其他会话数: %d", revoked), ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "密码修改成功",
+	})
+}
+
+// ForgotPassword 提交找回密码请求，向账号绑定的邮箱发送重置链接
+func (c *PasswordController) ForgotPassword(ctx *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.resetService.RequestReset(req.Account, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrPasswordResetTooMany) {
+			status = http.StatusTooManyRequests
+		}
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 无论账号是否存在都返回相同提示，避免被用来探测账号
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "如果该账号存在并绑定了邮箱，重置密码邮件已发送，请查收",
+	})
+}
+
+// VerifyResetToken 校验重置链接是否仍然有效（打开重置页面时调用）
+func (c *PasswordController) VerifyResetToken(ctx *gin.Context) {
+	record, err := c.resetService.VerifyToken(ctx.Query("token"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "重置链接有效",
+		"data": gin.H{
+			"expiresAt": record.ExpiresAt,
+		},
+	})
+}
+
+// ResetPassword 使用重置链接中的令牌设置新密码
+func (c *PasswordController) ResetPassword(ctx *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.resetService.ResetPassword(req.Token, req.NewPassword, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		c.systemLogs.RecordSecurity("password_reset", "通过邮件链接重置密码", "failed", nil, err.Error(), ctx.ClientIP(), ctx.Request.UserAgent())
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalTokenController struct {
+	tokenService *services.PersonalTokenService
+}
+
+func NewPersonalTokenController(tokenService *services.PersonalTokenService) *PersonalTokenController {
+	return &PersonalTokenController{tokenService: tokenService}
+}
+
+// GetMyTokens 获取当前用户的个人访问令牌列表
+func (c *PersonalTokenController) GetMyTokens(ctx *gin.Context) {
+	tokens, err := c.tokenService.GetUserTokens(utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取访问令牌列表失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取访问令牌列表成功",
+		"data":    tokens,
+	})
+}
+
+// GetScopes 获取可选的令牌作用域
+func (c *PersonalTokenController) GetScopes(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取作用域成功",
+		"data": gin.H{
+			"scopes": c.tokenService.GetAvailableScopes(),
+			"groups": services.PersonalTokenScopeGroups,
+		},
+	})
+}
+
+// CreateToken 创建个人访问令牌（明文令牌只在创建时返回一次）
+func (c *PersonalTokenController) CreateToken(ctx *gin.Context) {
+	if ctx.GetUint("personalTokenID") != 0 {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "不能使用访问令牌创建新的访问令牌",
+		})
+		return
+	}
+
+	var req models.PersonalAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	token, rawToken, err := c.tokenService.CreateToken(userID, ctx.GetString("role"), req, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRoleNotHeld) {
+			status = http.StatusForbidden
+		}
+		log.Printf("创建访问令牌失败 - 用户ID: %d, 错误: %v", userID, err)
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": "创建访问令牌失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "访问令牌创建成功，请立即保存，关闭后无法再次查看",
+		"data": gin.H{
+			"token":       rawToken,
+			"tokenDetail": token,
+		},
+	})
+}
+
+// RevokeToken 撤销个人访问令牌
+func (c *PersonalTokenController) RevokeToken(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的令牌ID")
+	if !ok {
+		return
+	}
+
+	if err := c.tokenService.RevokeToken(utils.GetCurrentUserID(ctx), id, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "访问令牌已撤销",
+	})
+}
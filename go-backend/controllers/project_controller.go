@@ -1,12 +1,14 @@
 package controllers
 
 import (
+	"bytes"
 	"log"
 	"net/http"
 	"strconv"
 
 	"yunmeng-backend/models"
 	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -42,6 +44,7 @@ func (c *ProjectController) GetProjectList(ctx *gin.Context) {
 	if params.Size <= 0 {
 		params.Size = 20
 	}
+	params.Scope = utils.GetDataScope(ctx)
 
 	projects, total, err := c.projectService.GetProjectsForTeacher(params)
 	if err != nil {
@@ -89,6 +92,15 @@ func (c *ProjectController) GetProjectByID(ctx *gin.Context) {
 
 	id := uint32(parsedID)
 
+	// 详情包含团队成员的学号等信息，只对项目相关人员和范围内的管理员开放
+	if !c.projectService.CanViewProject(uint(id), utils.GetCurrentUserID(ctx), ctx.GetString("role"), utils.GetDataScope(ctx)) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "项目不存在",
+		})
+		return
+	}
+
 	project, err := c.projectService.GetProjectByID(uint(id))
 	if err != nil {
 		log.Printf("获取项目详情失败: %v", err)
@@ -137,6 +149,7 @@ func (c *ProjectController) CreateProject(ctx *gin.Context) {
 		})
 		return
 	}
+	utils.SetAuditEntity(ctx, response.ProjectID)
 
 	ctx.JSON(http.StatusCreated, gin.H{
 		"code":    201,
@@ -377,7 +390,7 @@ func (c *ProjectController) ForceUpdateProjectStatus(ctx *gin.Context) {
 	}
 
 	var req struct {
-		Status string `json:"status" binding:"required,oneof=draft submitted approved rejected archived"`
+		Status string `json:"status" binding:"required,max=50"`
 		Reason string `json:"reason" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -389,31 +402,13 @@ func (c *ProjectController) ForceUpdateProjectStatus(ctx *gin.Context) {
 		return
 	}
 
-	// 从JWT中获取操作者ID
-	operatorID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "未授权访问",
-		})
-		return
-	}
-
-	// 检查操作者是否为管理员
-	userRole, exists := ctx.Get("userRole")
-	if !exists || userRole != "admin" {
-		ctx.JSON(http.StatusForbidden, gin.H{
-			"code":    403,
-			"message": "只有管理员可以强制更新项目状态",
-		})
-		return
-	}
-
-	err = c.projectService.ForceUpdateProjectStatus(uint(id), req.Status, req.Reason, operatorID.(uint))
+	// 路由已要求 project.admin 权限
+	err = c.projectService.ForceUpdateProjectStatus(uint(id), req.Status, req.Reason, utils.GetCurrentUserID(ctx),
+		ctx.ClientIP(), ctx.Request.UserAgent())
 	if err != nil {
 		log.Printf("强制更新项目状态失败: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
 			"message": "强制更新项目状态失败: " + err.Error(),
 		})
 		return
@@ -483,15 +478,39 @@ func (c *ProjectController) ExportProjects(ctx *gin.Context) {
 		return
 	}
 
-	// TODO: 实现项目数据导出功能
-	ctx.JSON(http.StatusOK, gin.H{
-		"code":    200,
-		"message": "项目数据导出功能开发中",
-		"data": gin.H{
-			"format":  req.Format,
-			"filters": req.Filters,
-		},
-	})
+	// 获取全部符合条件的项目（不分页），导出范围受数据范围限制
+	req.Filters.Page = 0
+	req.Filters.Size = 0
+	req.Filters.Scope = utils.GetDataScope(ctx)
+	projects, _, err := c.projectService.GetProjectList(req.Filters)
+	if err != nil {
+		log.Printf("导出项目数据失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "导出项目数据失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 先写入缓冲区，生成失败时仍能返回错误信息
+	var buf bytes.Buffer
+	filename, err := services.WriteProjectExport(&buf, req.Format, projects)
+	if err != nil {
+		log.Printf("生成项目导出文件失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "生成导出文件失败",
+		})
+		return
+	}
+
+	log.Printf("项目数据导出成功 - 格式: %s, 项目数量: %d", req.Format, len(projects))
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == "excel" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
 }
 
 // SubmitProject 提交项目审核
@@ -1105,7 +1124,7 @@ func (c *ProjectController) UpdateProjectStatus(ctx *gin.Context) {
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
-			"message": "项目ID格式错1误",
+			"message": "项目ID格式错误",
 		})
 		return
 	}
@@ -1119,14 +1138,18 @@ func (c *ProjectController) UpdateProjectStatus(ctx *gin.Context) {
 		return
 	}
 
-	// 获取当前用户ID
-	userID := ctx.GetUint("user_id")
+	if req.Action == "" && req.Status == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请指定流转动作或目标状态",
+		})
+		return
+	}
 
-	err = c.projectService.UpdateProjectStatus(uint(id), userID, req)
+	err = c.projectService.UpdateProjectStatus(uint(id), utils.GetCurrentUserID(ctx), req)
 	if err != nil {
-		log.Printf("更新项目状态失败: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
 			"message": "更新项目状态失败: " + err.Error(),
 		})
 		return
@@ -1138,6 +1161,29 @@ func (c *ProjectController) UpdateProjectStatus(ctx *gin.Context) {
 	})
 }
 
+// GetAvailableTransitions 获取当前用户可对项目发起的状态流转及尚未满足的要求
+func (c *ProjectController) GetAvailableTransitions(ctx *gin.Context) {
+	projectID, ok := parseIDParam(ctx, "id", "项目ID格式错误")
+	if !ok {
+		return
+	}
+
+	transitions, err := c.projectService.GetAvailableTransitions(projectID, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取可执行操作成功",
+		"data":    transitions,
+	})
+}
+
 // GetProjectStatusHistory 获取项目状态变更历史
 func (c *ProjectController) GetProjectStatusHistory(ctx *gin.Context) {
 	idStr := ctx.Param("id")
@@ -1150,6 +1196,14 @@ func (c *ProjectController) GetProjectStatusHistory(ctx *gin.Context) {
 		return
 	}
 
+	if !c.projectService.CanViewProject(uint(id), utils.GetCurrentUserID(ctx), ctx.GetString("role"), utils.GetDataScope(ctx)) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "项目不存在",
+		})
+		return
+	}
+
 	history, err := c.projectService.GetProjectStatusHistory(uint(id))
 	if err != nil {
 		log.Printf("获取项目状态历史失败: %v", err)
@@ -1605,7 +1659,7 @@ func (c *ProjectController) DelegateReview(ctx *gin.Context) {
 		return
 	}
 
-	userID := ctx.GetUint("user_id")
+	userID := utils.GetCurrentUserID(ctx)
 	delegation, err := c.projectService.DelegateReview(uint(reviewID), userID, req)
 	if err != nil {
 		log.Printf("委托审核失败: %v", err)
@@ -1623,6 +1677,66 @@ func (c *ProjectController) DelegateReview(ctx *gin.Context) {
 	})
 }
 
+// GetReviewSLAReport 审核时效统计（按审核人和审核级别）
+func (c *ProjectController) GetReviewSLAReport(ctx *gin.Context) {
+	var params models.ReviewSLAQueryParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	report, err := c.projectService.GetReviewSLAReport(params)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取审核时效统计成功",
+		"data":    report,
+	})
+}
+
+// DecideReviewTask 处理多级审核任务（通过、驳回或跳过非必审任务）
+func (c *ProjectController) DecideReviewTask(ctx *gin.Context) {
+	reviewID, ok := parseIDParam(ctx, "reviewId", "审核ID格式错误")
+	if !ok {
+		return
+	}
+
+	var req models.ReviewTaskDecisionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	task, err := c.projectService.DecideReviewTask(reviewID, utils.GetCurrentUserID(ctx), req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, task.ProjectID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "审核任务已处理",
+		"data":    task,
+	})
+}
+
 // GetMyReviewTasks 获取我的审核任务
 func (c *ProjectController) GetMyReviewTasks(ctx *gin.Context) {
 	var params models.ReviewTaskQueryParams
@@ -1634,7 +1748,7 @@ func (c *ProjectController) GetMyReviewTasks(ctx *gin.Context) {
 		return
 	}
 
-	userID := ctx.GetUint("user_id")
+	userID := utils.GetCurrentUserID(ctx)
 	tasks, total, err := c.projectService.GetMyReviewTasks(userID, params)
 	if err != nil {
 		log.Printf("获取审核任务失败: %v", err)
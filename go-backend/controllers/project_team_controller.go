@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProjectTeamController struct {
+	teamService *services.ProjectTeamService
+}
+
+func NewProjectTeamController(db *gorm.DB) *ProjectTeamController {
+	return &ProjectTeamController{teamService: services.NewProjectTeamService(db)}
+}
+
+// GetProjectMembers 获取项目团队成员（含待接受的邀请）
+func (c *ProjectTeamController) GetProjectMembers(ctx *gin.Context) {
+	projectID, ok := parseIDParam(ctx, "id", "项目ID格式错误")
+	if !ok {
+		return
+	}
+
+	members, err := c.teamService.GetMembers(projectID, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取团队成员成功",
+		"data":    members,
+	})
+}
+
+// InviteProjectMember 队长邀请学生加入团队
+func (c *ProjectTeamController) InviteProjectMember(ctx *gin.Context) {
+	projectID, ok := parseIDParam(ctx, "id", "项目ID格式错误")
+	if !ok {
+		return
+	}
+
+	var req models.ProjectMemberInviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	member, err := c.teamService.Invite(projectID, utils.GetCurrentUserID(ctx), req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, projectID)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"message": "邀请已发送",
+		"data":    member,
+	})
+}
+
+// RemoveProjectMember 队长移除成员/撤回邀请，或成员自行退出团队
+func (c *ProjectTeamController) RemoveProjectMember(ctx *gin.Context) {
+	projectID, ok := parseIDParam(ctx, "id", "项目ID格式错误")
+	if !ok {
+		return
+	}
+	memberID, ok := parseIDParam(ctx, "memberId", "成员ID格式错误")
+	if !ok {
+		return
+	}
+
+	if err := c.teamService.RemoveMember(projectID, memberID, utils.GetCurrentUserID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, projectID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "团队成员已移除",
+	})
+}
+
+// TransferProjectLeader 转让队长
+func (c *ProjectTeamController) TransferProjectLeader(ctx *gin.Context) {
+	projectID, ok := parseIDParam(ctx, "id", "项目ID格式错误")
+	if !ok {
+		return
+	}
+
+	var req models.ProjectLeaderTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.teamService.TransferLeader(projectID, utils.GetCurrentUserID(ctx), req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, projectID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "队长已转让",
+	})
+}
+
+// GetMyInvitations 获取我收到的待处理团队邀请
+func (c *ProjectTeamController) GetMyInvitations(ctx *gin.Context) {
+	invitations, err := c.teamService.GetMyInvitations(utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取团队邀请失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取团队邀请成功",
+		"data":    invitations,
+	})
+}
+
+// AcceptInvitation 接受团队邀请
+func (c *ProjectTeamController) AcceptInvitation(ctx *gin.Context) {
+	c.respondInvitation(ctx, true)
+}
+
+// DeclineInvitation 拒绝团队邀请
+func (c *ProjectTeamController) DeclineInvitation(ctx *gin.Context) {
+	c.respondInvitation(ctx, false)
+}
+
+func (c *ProjectTeamController) respondInvitation(ctx *gin.Context, accept bool) {
+	memberID, ok := parseIDParam(ctx, "memberId", "邀请ID格式错误")
+	if !ok {
+		return
+	}
+
+	if err := c.teamService.RespondInvitation(memberID, utils.GetCurrentUserID(ctx), accept); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "已拒绝邀请"
+	if accept {
+		message = "已加入团队"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+	})
+}
@@ -47,7 +47,7 @@ func (c *ProjectTypeController) GetProjectTypeList(ctx *gin.Context) {
 	var responses []models.ProjectTypeResponse
 	for _, pt := range projectTypes {
 		var projectCount int64
-		c.db.Model(&models.Project{}).Where("category_id = ?", pt.ID).Count(&projectCount)
+		c.db.Model(&models.Project{}).Where("type_id = ?", pt.ID).Count(&projectCount)
 
 		response := models.ProjectTypeResponse{
 			ID:           pt.ID,
@@ -94,7 +94,7 @@ func (c *ProjectTypeController) GetProjectTypeByID(ctx *gin.Context) {
 
 	// 获取项目数量
 	var projectCount int64
-	c.db.Model(&models.Project{}).Where("category_id = ?", projectType.ID).Count(&projectCount)
+	c.db.Model(&models.Project{}).Where("type_id = ?", projectType.ID).Count(&projectCount)
 
 	response := models.ProjectTypeResponse{
 		ID:           projectType.ID,
@@ -138,6 +138,7 @@ func (c *ProjectTypeController) CreateProjectType(ctx *gin.Context) {
 	projectType := models.ProjectType{
 		Name:        req.Name,
 		Description: req.Description,
+		MaxMembers:  req.MaxMembers,
 	}
 
 	if err := c.db.Create(&projectType).Error; err != nil {
@@ -205,6 +206,9 @@ func (c *ProjectTypeController) UpdateProjectType(ctx *gin.Context) {
 		"name":        req.Name,
 		"description": req.Description,
 	}
+	if req.MaxMembers != nil {
+		updates["max_members"] = *req.MaxMembers
+	}
 
 	if err := c.db.Model(&projectType).Updates(updates).Error; err != nil {
 		log.Printf("更新项目分类失败: %v", err)
@@ -247,7 +251,7 @@ func (c *ProjectTypeController) DeleteProjectType(ctx *gin.Context) {
 
 	// 检查是否有项目使用此分类
 	var projectCount int64
-	c.db.Model(&models.Project{}).Where("category_id = ?", id).Count(&projectCount)
+	c.db.Model(&models.Project{}).Where("type_id = ?", id).Count(&projectCount)
 	if projectCount > 0 {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -292,13 +296,13 @@ func (c *ProjectTypeController) GetProjectTypeStats(ctx *gin.Context) {
 		var projectCount int64
 
 		// 尝试多种查询方式
-		query := c.db.Model(&models.Project{}).Where("category_id = ?", pt.ID)
+		query := c.db.Model(&models.Project{}).Where("type_id = ?", pt.ID)
 
 		// 检查deleted字段是否存在
 		if err := query.Count(&projectCount).Error; err != nil {
 			// 如果失败，尝试不包含deleted字段的查询
 			log.Printf("包含deleted字段的查询失败，尝试简化查询: %v", err)
-			if err := c.db.Model(&models.Project{}).Where("category_id = ?", pt.ID).Count(&projectCount).Error; err != nil {
+			if err := c.db.Model(&models.Project{}).Where("type_id = ?", pt.ID).Count(&projectCount).Error; err != nil {
 				log.Printf("统计分类 %d 项目数量失败: %v", pt.ID, err)
 				projectCount = 0
 			}
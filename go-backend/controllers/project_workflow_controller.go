@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProjectWorkflowController struct {
+	workflowService *services.ProjectWorkflowService
+}
+
+func NewProjectWorkflowController(db *gorm.DB) *ProjectWorkflowController {
+	return &ProjectWorkflowController{workflowService: services.NewProjectWorkflowService(db)}
+}
+
+// workflowTypeID 路由带分类ID时为该分类，否则为默认流程
+func workflowTypeID(ctx *gin.Context) (*uint, bool) {
+	if ctx.Param("id") == "" {
+		return nil, true
+	}
+	typeID, ok := parseIDParam(ctx, "id", "项目分类ID格式错误")
+	if !ok {
+		return nil, false
+	}
+	return &typeID, true
+}
+
+// GetWorkflow 获取默认或项目分类实际生效的状态流转配置
+func (c *ProjectWorkflowController) GetWorkflow(ctx *gin.Context) {
+	typeID, ok := workflowTypeID(ctx)
+	if !ok {
+		return
+	}
+
+	workflow, err := c.workflowService.GetWorkflow(typeID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取状态流转配置成功",
+		"data":    workflow,
+	})
+}
+
+// SaveWorkflow 保存默认或项目分类的状态流转配置（整体替换）
+func (c *ProjectWorkflowController) SaveWorkflow(ctx *gin.Context) {
+	typeID, ok := workflowTypeID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.ProjectWorkflowSaveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	workflow, err := c.workflowService.SaveWorkflow(typeID, req, utils.GetCurrentUserID(ctx), ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if typeID != nil {
+		utils.SetAuditEntity(ctx, *typeID)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "状态流转配置已保存",
+		"data":    workflow,
+	})
+}
+
+// ResetWorkflow 删除项目分类单独配置的流程，改为沿用上级分类或默认流程
+func (c *ProjectWorkflowController) ResetWorkflow(ctx *gin.Context) {
+	typeID, ok := parseIDParam(ctx, "id", "项目分类ID格式错误")
+	if !ok {
+		return
+	}
+
+	workflow, err := c.workflowService.ResetWorkflow(typeID, utils.GetCurrentUserID(ctx), ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, typeID)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已改为沿用上级分类或默认流程",
+		"data":    workflow,
+	})
+}
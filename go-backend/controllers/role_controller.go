@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	permissionService *services.PermissionService
+}
+
+func NewRoleController(permissionService *services.PermissionService) *RoleController {
+	return &RoleController{permissionService: permissionService}
+}
+
+// GetMyPermissions 获取当前角色拥有的权限键和数据范围（前端据此控制菜单和按钮）
+func (c *RoleController) GetMyPermissions(ctx *gin.Context) {
+	role := ctx.GetString("role")
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取权限成功",
+		"data": gin.H{
+			"role":        role,
+			"permissions": c.permissionService.GetRolePermissionKeys(role),
+			"dataScope":   utils.GetDataScope(ctx),
+		},
+	})
+}
+
+// GetRoleList 获取角色列表
+func (c *RoleController) GetRoleList(ctx *gin.Context) {
+	roles, err := c.permissionService.GetRoles()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取角色列表失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取角色列表成功",
+		"data":    roles,
+	})
+}
+
+// GetRoleByID 获取角色详情
+func (c *RoleController) GetRoleByID(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	role, err := c.permissionService.GetRoleByID(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取角色详情成功",
+		"data":    role,
+	})
+}
+
+// CreateRole 创建角色
+func (c *RoleController) CreateRole(ctx *gin.Context) {
+	var req models.RoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := c.permissionService.CreateRole(req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "创建角色失败: " + err.Error(),
+		})
+		return
+	}
+
+	log.Printf("角色创建成功 - 角色: %s", role.RoleKey)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色创建成功",
+		"data":    role,
+	})
+}
+
+// UpdateRole 更新角色
+func (c *RoleController) UpdateRole(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	var req models.RoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := c.permissionService.UpdateRole(id, req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "更新角色失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色更新成功",
+		"data":    role,
+	})
+}
+
+// SetRolePermissions 设置角色权限
+func (c *RoleController) SetRolePermissions(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	var req models.RolePermissionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := c.permissionService.SetRolePermissions(id, req.Permissions, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "设置角色权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色权限设置成功",
+		"data":    role,
+	})
+}
+
+// DeleteRole 删除角色
+func (c *RoleController) DeleteRole(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	if err := c.permissionService.DeleteRole(id, utils.GetCurrentUserID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "删除角色失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "角色删除成功",
+	})
+}
+
+// GetPermissionList 获取权限列表
+func (c *RoleController) GetPermissionList(ctx *gin.Context) {
+	permissions, err := c.permissionService.GetPermissions(ctx.Query("module"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取权限列表失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取权限列表成功",
+		"data":    permissions,
+	})
+}
+
+// CreatePermission 创建权限
+func (c *RoleController) CreatePermission(ctx *gin.Context) {
+	var req models.PermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	permission, err := c.permissionService.CreatePermission(req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "创建权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "权限创建成功",
+		"data":    permission,
+	})
+}
+
+// UpdatePermission 更新权限
+func (c *RoleController) UpdatePermission(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的权限ID")
+	if !ok {
+		return
+	}
+
+	var req models.PermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	permission, err := c.permissionService.UpdatePermission(id, req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "更新权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "权限更新成功",
+		"data":    permission,
+	})
+}
+
+// DeletePermission 删除权限
+func (c *RoleController) DeletePermission(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的权限ID")
+	if !ok {
+		return
+	}
+
+	if err := c.permissionService.DeletePermission(id, utils.GetCurrentUserID(ctx)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "删除权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "权限删除成功",
+	})
+}
+
+// parseIDParam 解析路径中的ID参数，失败时直接返回400
+func parseIDParam(ctx *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
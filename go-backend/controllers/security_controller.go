@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityController struct {
+	loginGuard *services.LoginGuardService
+}
+
+func NewSecurityController(loginGuard *services.LoginGuardService) *SecurityController {
+	return &SecurityController{loginGuard: loginGuard}
+}
+
+// GetLoginLockouts 获取当前被锁定的用户名/IP列表
+func (c *SecurityController) GetLoginLockouts(ctx *gin.Context) {
+	lockouts, err := c.loginGuard.GetActiveLockouts()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取登录锁定列表失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取登录锁定列表成功",
+		"data": gin.H{
+			"lockouts": lockouts,
+			"policy":   c.loginGuard.Policy(),
+		},
+	})
+}
+
+// UnlockLogin 管理员解除登录锁定
+func (c *SecurityController) UnlockLogin(ctx *gin.Context) {
+	var req models.LoginUnlockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	unlocked, err := c.loginGuard.Unlock(req, operatorID)
+	if err != nil {
+		log.Printf("解除登录锁定失败 - 用户名: %s, IP: %s, 错误: %v", req.Username, req.IPAddress, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "解除登录锁定失败: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "解除登录锁定成功",
+		"data": gin.H{
+			"unlocked": unlocked,
+		},
+	})
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionController 我的登录设备（会话）管理
+type SessionController struct {
+	authService *services.AuthService
+	systemLogs  *services.SystemLogService
+}
+
+func NewSessionController(authService *services.AuthService, systemLogs *services.SystemLogService) *SessionController {
+	return &SessionController{
+		authService: authService,
+		systemLogs:  systemLogs,
+	}
+}
+
+// GetMySessions 获取当前用户的有效登录设备
+func (c *SessionController) GetMySessions(ctx *gin.Context) {
+	userID := utils.GetCurrentUserID(ctx)
+	sessions, err := c.authService.GetActiveSessions(userID, currentSessionID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取登录设备成功",
+		"data":    sessions,
+	})
+}
+
+// RevokeMySession 下线当前用户的某个登录设备
+func (c *SessionController) RevokeMySession(ctx *gin.Context) {
+	sessionID, ok := parseIDParam(ctx, "id", "无效的会话ID")
+	if !ok {
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	if err := c.authService.RevokeUserSession(userID, sessionID, "user_revoke"); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.systemLogs.RecordSecurity("session_revoke", "下线登录设备", "success", &userID,
+		fmt.Sprintf("会话ID: %d", sessionID), ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录设备已下线",
+		"data": gin.H{
+			"id":      sessionID,
+			"current": sessionID == currentSessionID(ctx),
+		},
+	})
+}
+
+// RevokeOtherSessions 下线除当前设备外的全部登录设备
+func (c *SessionController) RevokeOtherSessions(ctx *gin.Context) {
+	sid := currentSessionID(ctx)
+	if sid == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "当前请求未关联登录会话",
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	count, err := c.authService.RevokeOtherSessions(userID, sid, "user_revoke_others")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.systemLogs.RecordSecurity("session_revoke", "下线其他登录设备", "success", &userID,
+		fmt.Sprintf("保留会话ID: %d, 下线数量: %d", sid, count), ctx.ClientIP(), ctx.Request.UserAgent())
+	log.Printf("下线其他登录设备 - 用户ID: %d, 数量: %d", userID, count)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "其他登录设备已全部下线",
+		"data": gin.H{
+			"count": count,
+		},
+	})
+}
+
+// currentSessionID 获取当前请求所属的会话ID（个人访问令牌请求为0）
+func currentSessionID(ctx *gin.Context) uint {
+	sid, _ := ctx.Get("sessionID")
+	id, _ := sid.(uint)
+	return id
+}
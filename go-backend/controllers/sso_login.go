@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ssoStateTTL 从跳转身份平台到回调登录的最长时间
+const ssoStateTTL = 10 * time.Minute
+
+// ssoNonceCookie 保存发起登录时 nonce 的Cookie，回调时与状态令牌比对，把状态令牌绑定到发起登录的浏览器
+const ssoNonceCookie = "sso_nonce"
+
+// ssoCookiePath Cookie 只在统一身份认证接口中携带
+const ssoCookiePath = "/api/sso"
+
+// ssoStatePurpose 状态令牌用途，绑定认证提供方，防止跨提供方重放
+func ssoStatePurpose(providerKey string) string {
+	return "sso_state:" + providerKey
+}
+
+// SSOProvidersHandler 获取已启用的统一身份认证方式（登录页展示）
+func SSOProvidersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		providers := make([]gin.H, 0)
+		for _, registration := range utils.ListSSOProviders() {
+			providers = append(providers, gin.H{
+				"key":  registration.Provider.Key(),
+				"name": registration.Provider.Name(),
+				"type": registration.Provider.Type(),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "获取统一身份认证方式成功",
+			"data":    providers,
+		})
+	}
+}
+
+// SSOAuthorizeHandler 发起统一身份认证：签发状态令牌并返回身份平台登录地址。
+// 前端保存 state 后跳转，回调时核对地址中的 state 与保存的一致，再提交到 SSOLoginHandler。
+// nonce 同时记录在服务端（一次性）和 HttpOnly Cookie 中（前端请求须携带Cookie）。
+func SSOAuthorizeHandler(db *gorm.DB) gin.HandlerFunc {
+	ssoService := services.NewSSOService(db)
+
+	return func(c *gin.Context) {
+		registration, err := utils.GetSSOProvider(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+			return
+		}
+
+		nonce, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "服务端异常"})
+			return
+		}
+		state, err := utils.GenerateActionToken(0, ssoStatePurpose(registration.Provider.Key()), nonce, ssoStateTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "服务端异常"})
+			return
+		}
+		if err := ssoService.CreateLoginState(registration.Provider.Key(), nonce, ssoStateTTL, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "服务端异常"})
+			return
+		}
+
+		authURL, err := registration.Provider.AuthURL(state, nonce)
+		if err != nil {
+			log.Printf("生成统一身份认证地址失败 - 认证方式: %s, 错误: %v", registration.Provider.Key(), err)
+			c.JSON(http.StatusBadGateway, gin.H{"code": 502, "message": "统一身份认证平台暂不可用"})
+			return
+		}
+
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(ssoNonceCookie, nonce, int(ssoStateTTL.Seconds()), ssoCookiePath, "", c.Request.TLS != nil, true)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "获取统一身份认证地址成功",
+			"data": gin.H{
+				"url":       authURL,
+				"state":     state,
+				"expiresIn": int(ssoStateTTL.Seconds()),
+			},
+		})
+	}
+}
+
+// SSOLoginHandler 统一身份认证回调登录：校验状态令牌，向身份平台换取身份，
+// 关联或开通本地账号后按账号密码登录相同的流程签发令牌（含两步验证）
+func SSOLoginHandler(db *gorm.DB) gin.HandlerFunc {
+	authService := services.NewAuthService(db)
+	loginGuard := services.NewLoginGuardService(db)
+	userService := services.NewUserService(db)
+	twoFactor := services.NewTwoFactorService(db)
+	ssoService := services.NewSSOService(db)
+	systemLogs := services.NewSystemLogService(db)
+
+	return func(c *gin.Context) {
+		var req models.SSOLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 400, Message: "参数错误/缺失字段"})
+			return
+		}
+
+		registration, err := utils.GetSSOProvider(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 404, Message: err.Error()})
+			return
+		}
+		providerKey := registration.Provider.Key()
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+
+		claims, err := utils.ParseActionToken(req.State, ssoStatePurpose(providerKey))
+		if err != nil {
+			systemLogs.RecordSecurity("sso_login", "统一身份认证状态令牌无效", "failed", nil,
+				"认证方式: "+providerKey, ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: services.ErrSSOStateInvalid.Error()})
+			return
+		}
+
+		// 状态令牌须由当前浏览器发起，且只能使用一次
+		nonce, _ := c.Cookie(ssoNonceCookie)
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(ssoNonceCookie, "", -1, ssoCookiePath, "", c.Request.TLS != nil, true)
+		if nonce == "" || subtle.ConstantTimeCompare([]byte(nonce), []byte(claims.ID)) != 1 {
+			systemLogs.RecordSecurity("sso_login", "统一身份认证状态令牌与浏览器不匹配", "failed", nil,
+				"认证方式: "+providerKey, ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: services.ErrSSOStateInvalid.Error()})
+			return
+		}
+		if err := ssoService.ConsumeLoginState(providerKey, claims.ID); err != nil {
+			systemLogs.RecordSecurity("sso_login", "统一身份认证状态令牌已使用或已过期", "failed", nil,
+				"认证方式: "+providerKey, ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: services.ErrSSOStateInvalid.Error()})
+			return
+		}
+
+		identity, err := registration.Provider.Authenticate(utils.SSOCallback{
+			Code:   req.Code,
+			Ticket: req.Ticket,
+			State:  req.State,
+			Nonce:  claims.ID,
+		})
+		if err != nil {
+			log.Printf("统一身份认证失败 - 认证方式: %s, 错误: %v", providerKey, err)
+			systemLogs.RecordSecurity("sso_login", "统一身份认证失败", "failed", nil,
+				"认证方式: "+providerKey+", 错误: "+err.Error(), ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: "统一身份认证失败，请重新登录"})
+			return
+		}
+
+		user, err := ssoService.ResolveUser(providerKey, registration.Mapping, identity, ipAddress, userAgent)
+		var confirm *services.SSOLinkConfirmError
+		if errors.As(err, &confirm) {
+			// 匹配到的已有账号须本人输入本地密码确认，再提交到 SSOLinkConfirmHandler
+			c.JSON(http.StatusOK, LoginResponse{
+				Code:    409,
+				Message: confirm.Error(),
+				Data: gin.H{
+					"linkRequired": true,
+					"linkToken":    confirm.LinkToken,
+					"username":     confirm.Username,
+				},
+			})
+			return
+		}
+		if err != nil {
+			code := 500
+			if errors.Is(err, services.ErrSSOAccountNotFound) || errors.Is(err, services.ErrSSOAccountConflict) || errors.Is(err, services.ErrSSONoRole) {
+				code = 403
+			}
+			// 账号未开通的情况已由 ResolveUser 记录
+			if !errors.Is(err, services.ErrSSOAccountNotFound) {
+				systemLogs.RecordSecurity("sso_login", "统一身份认证账号关联失败", "failed", nil,
+					"认证方式: "+providerKey+", 错误: "+err.Error(), ipAddress, userAgent)
+			}
+			c.JSON(http.StatusOK, LoginResponse{Code: code, Message: err.Error()})
+			return
+		}
+
+		ssoBeginSession(c, db, authService, loginGuard, userService, twoFactor, systemLogs, user, providerKey, req.Role)
+	}
+}
+
+// SSOLinkConfirmHandler 统一身份认证匹配到需要确认的已有账号时，校验本地密码后关联并登录
+func SSOLinkConfirmHandler(db *gorm.DB) gin.HandlerFunc {
+	authService := services.NewAuthService(db)
+	loginGuard := services.NewLoginGuardService(db)
+	userService := services.NewUserService(db)
+	twoFactor := services.NewTwoFactorService(db)
+	ssoService := services.NewSSOService(db)
+	systemLogs := services.NewSystemLogService(db)
+
+	return func(c *gin.Context) {
+		var req models.SSOLinkConfirmRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 400, Message: "参数错误/缺失字段"})
+			return
+		}
+
+		registration, err := utils.GetSSOProvider(c.Param("provider"))
+		if err != nil {
+			c.JSON(http.StatusOK, LoginResponse{Code: 404, Message: err.Error()})
+			return
+		}
+		providerKey := registration.Provider.Key()
+		ipAddress := c.ClientIP()
+		userAgent := c.Request.UserAgent()
+
+		user, subject, err := ssoService.PendingLink(providerKey, req.LinkToken)
+		if err != nil {
+			systemLogs.RecordSecurity("sso_link", "统一身份认证关联确认令牌无效", "failed", nil,
+				"认证方式: "+providerKey, ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: err.Error()})
+			return
+		}
+
+		// 与账号密码登录共用失败计数和锁定
+		if wait := loginGuard.CheckAllowed(user.Username, ipAddress); wait > 0 {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusOK, LoginResponse{
+				Code:    429,
+				Message: fmt.Sprintf("登录尝试过于频繁，请%d秒后再试", retryAfter),
+				Data:    gin.H{"retry_after": retryAfter},
+			})
+			return
+		}
+		if !utils.CheckPassword(req.Password, user.Password) {
+			loginGuard.RecordFailure(user.Username, &user.ID, ipAddress, userAgent, "sso_link_bad_password")
+			c.JSON(http.StatusOK, LoginResponse{Code: 401, Message: "密码错误"})
+			return
+		}
+
+		if err := ssoService.ConfirmLink(providerKey, subject, user, ipAddress, userAgent); err != nil {
+			code := 500
+			if errors.Is(err, services.ErrSSOAccountConflict) {
+				code = 403
+			}
+			c.JSON(http.StatusOK, LoginResponse{Code: code, Message: err.Error()})
+			return
+		}
+
+		ssoBeginSession(c, db, authService, loginGuard, userService, twoFactor, systemLogs, user, providerKey, req.Role)
+	}
+}
+
+// ssoBeginSession 统一身份认证得到本地账号后检查账号状态和角色，再按账号密码登录相同的流程签发令牌。
+// 未指定角色时使用账号的第一个角色，登录后可通过切换角色接口切换。
+func ssoBeginSession(c *gin.Context, db *gorm.DB, authService *services.AuthService, loginGuard *services.LoginGuardService,
+	userService *services.UserService, twoFactor *services.TwoFactorService, systemLogs *services.SystemLogService,
+	user *models.User, providerKey, requestedRole string) {
+	ipAddress := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	if user.Status != "active" {
+		systemLogs.RecordSecurity("sso_login", "统一身份认证登录账户已被禁用", "failed", &user.ID,
+			"认证方式: "+providerKey, ipAddress, userAgent)
+		c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "账户已被禁用"})
+		return
+	}
+
+	var roles []models.Role
+	if err := db.Model(user).Association("Roles").Find(&roles); err != nil || len(roles) == 0 {
+		systemLogs.RecordSecurity("sso_login", "统一身份认证登录账号未分配角色", "failed", &user.ID,
+			"认证方式: "+providerKey, ipAddress, userAgent)
+		c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: services.ErrSSONoRole.Error()})
+		return
+	}
+	role := roles[0].RoleKey
+	if requestedRole != "" {
+		role = ""
+		for _, item := range roles {
+			if item.RoleKey == requestedRole {
+				role = item.RoleKey
+				break
+			}
+		}
+		if role == "" {
+			systemLogs.RecordSecurity("sso_login", "统一身份认证登录角色不匹配", "failed", &user.ID,
+				"认证方式: "+providerKey+", 请求角色: "+requestedRole, ipAddress, userAgent)
+			c.JSON(http.StatusOK, LoginResponse{Code: 403, Message: "用户角色不匹配"})
+			return
+		}
+	}
+
+	log.Printf("统一身份认证成功 - 认证方式: %s, 用户名: %s, 角色: %s", providerKey, user.Username, role)
+	beginSession(c, db, authService, loginGuard, userService, twoFactor, user, role,
+		services.SessionOptions{LoginMethod: services.SSOLoginMethod(providerKey)})
+}
@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm"
 
 	"yunmeng-backend/models"
+	"yunmeng-backend/services"
 	"yunmeng-backend/utils"
 )
 
@@ -29,6 +30,7 @@ func NewStudentCompetitionController(db *gorm.DB) *StudentCompetitionController
 // @Accept json
 // @Produce json
 // @Param department query string false "院系筛选"
+// @Param orgUnitId query int false "组织机构节点筛选（可报名该节点学生的竞赛）"
 // @Param status query string false "竞赛状态筛选"
 // @Param page query int false "页码" default(1)
 // @Param size query int false "每页数量" default(10)
@@ -45,6 +47,11 @@ func (cc *StudentCompetitionController) GetAvailableCompetitions(c *gin.Context)
 		query = query.Where("department_limit IS NULL OR department_limit = '' OR FIND_IN_SET(?, department_limit) > 0", department)
 	}
 
+	// 组织机构筛选：未限定节点或限定该节点的上级（含自身）的竞赛
+	if orgUnitID := queryOrgUnitID(c); orgUnitID > 0 {
+		query = query.Where("org_unit_id IS NULL OR org_unit_id IN ?", append(services.OrgUnitAncestorIDs(cc.DB, orgUnitID), 0))
+	}
+
 	// 状态筛选
 	if status := c.Query("status"); status != "" {
 		query = query.Where("status = ?", status)
@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -37,6 +39,10 @@ func (c *SystemController) GetSystemLogs(ctx *gin.Context) {
 	ipAddress := ctx.Query("ip_address")
 	startDate := ctx.Query("start_date")
 	endDate := ctx.Query("end_date")
+	entityType := ctx.Query("entity_type")
+	entityID := ctx.Query("entity_id")
+	method := ctx.Query("method")
+	route := ctx.Query("route")
 
 	// 构建查询条件
 	query := c.db.Model(&models.SystemLog{}).Preload("User.Profile")
@@ -67,6 +73,23 @@ func (c *SystemController) GetSystemLogs(ctx *gin.Context) {
 		query = query.Where("ip_address LIKE ?", "%"+ipAddress+"%")
 	}
 
+	// 按实体筛选（审计日志），可查看某个项目/竞赛/用户的完整变更历史
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+
+	if entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+
+	if method != "" {
+		query = query.Where("method = ?", strings.ToUpper(method))
+	}
+
+	if route != "" {
+		query = query.Where("route LIKE ?", "%"+route+"%")
+	}
+
 	if startDate != "" {
 		if start, err := time.Parse("2006-01-02", startDate); err == nil {
 			query = query.Where("created_at >= ?", start)
@@ -366,6 +389,7 @@ func (c *SystemController) UpdateSystemSetting(ctx *gin.Context) {
 
 // GetSystemConfig 获取系统配置结构
 func (c *SystemController) GetSystemConfig(ctx *gin.Context) {
+	signingKey := utils.ActiveSigningKey()
 	config := gin.H{
 		"database": gin.H{
 			"host":     "localhost",
@@ -377,8 +401,10 @@ func (c *SystemController) GetSystemConfig(ctx *gin.Context) {
 			"mode": "debug",
 		},
 		"security": gin.H{
-			"jwt_secret": "***",
-			"jwt_expire": 24,
+			"jwt_secret":    "***",
+			"jwt_algorithm": signingKey.Method.Alg(),
+			"jwt_kid":       signingKey.KID,
+			"jwt_expire":    utils.AccessTokenTTL.String(),
 		},
 	}
 
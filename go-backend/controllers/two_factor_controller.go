@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorController struct {
+	twoFactor *services.TwoFactorService
+}
+
+func NewTwoFactorController(twoFactor *services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactor: twoFactor}
+}
+
+// GetStatus 获取当前用户两步验证状态
+func (c *TwoFactorController) GetStatus(ctx *gin.Context) {
+	userID := utils.GetCurrentUserID(ctx)
+	status, err := c.twoFactor.GetStatus(userID, ctx.GetString("role"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取两步验证状态失败",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取两步验证状态成功",
+		"data":    status,
+	})
+}
+
+// Setup 生成两步验证密钥和扫码URI
+func (c *TwoFactorController) Setup(ctx *gin.Context) {
+	userID := utils.GetCurrentUserID(ctx)
+	setup, err := c.twoFactor.Setup(userID, ctx.GetString("username"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "请使用验证器应用扫码后提交验证码完成绑定",
+		"data":    setup,
+	})
+}
+
+// Enable 提交验证码启用两步验证
+func (c *TwoFactorController) Enable(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	codes, err := c.twoFactor.Enable(userID, req.Code)
+	if err != nil {
+		log.Printf("启用两步验证失败 - 用户ID: %d, 错误: %v", userID, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已启用，请妥善保存恢复码",
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// Disable 关闭两步验证
+func (c *TwoFactorController) Disable(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	if err := c.twoFactor.Disable(userID, ctx.GetString("role"), req.Code); err != nil {
+		log.Printf("关闭两步验证失败 - 用户ID: %d, 错误: %v", userID, err)
+		status := twoFactorCodeErrorStatus(err)
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已关闭",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (c *TwoFactorController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	userID := utils.GetCurrentUserID(ctx)
+	codes, err := c.twoFactor.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		status := twoFactorCodeErrorStatus(err)
+		ctx.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复码已重新生成，旧恢复码已失效",
+		"data": gin.H{
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+// twoFactorCodeErrorStatus 验证码连续输错被暂停校验时返回 429，其余校验失败返回 400
+func twoFactorCodeErrorStatus(err error) int {
+	if errors.Is(err, services.ErrTwoFactorTooManyAttempts) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
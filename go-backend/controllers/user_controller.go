@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"yunmeng-backend/models"
 	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -41,6 +42,8 @@ func (c *UserController) GetUserList(ctx *gin.Context) {
 		params.Size = 20
 	}
 
+	params.Scope = utils.GetDataScope(ctx)
+
 	log.Printf("获取用户列表 - 页码: %d, 每页数量: %d, 搜索: %s, 角色: %s, 状态: %s",
 		params.Page, params.Size, params.Search, params.Role, params.Status)
 
@@ -86,6 +89,10 @@ func (c *UserController) GetUserByID(ctx *gin.Context) {
 		return
 	}
 
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
 	log.Printf("获取用户详情 - 用户ID: %d", id)
 
 	user, err := c.userService.GetUserByID(uint(id))
@@ -121,6 +128,14 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	if !c.resolveOrgUnit(ctx, req.OrgUnitID, &req.Department, &req.Major) {
+		return
+	}
+
+	if !c.ensureDepartmentInScope(ctx, &req.Department, req.RoleKeys) {
+		return
+	}
+
 	log.Printf("创建用户 - 用户名: %s, 邮箱: %s, 角色: %v", req.Username, req.Email, req.RoleKeys)
 
 	user, err := c.userService.CreateUser(req)
@@ -135,6 +150,7 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 	}
 
 	log.Printf("用户创建成功 - 用户ID: %d, 用户名: %s", user.ID, user.Username)
+	utils.SetAuditEntity(ctx, user.ID)
 
 	ctx.JSON(http.StatusCreated, gin.H{
 		"code":    201,
@@ -187,6 +203,10 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
 	var req models.UserUpdateRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Printf("更新用户参数错误 - 用户ID: %d, 错误: %v", id, err)
@@ -198,6 +218,14 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if !c.resolveOrgUnit(ctx, req.OrgUnitID, &req.Department, &req.Major) {
+		return
+	}
+
+	if (req.Department != "" || len(req.RoleKeys) > 0) && !c.ensureDepartmentInScope(ctx, &req.Department, req.RoleKeys) {
+		return
+	}
+
 	log.Printf("更新用户 - 目标用户ID: %d, 当前用户ID: %v, 角色: %v, 真实姓名: %s, 邮箱: %s",
 		id, currentUserID, currentUserRole, req.RealName, req.Email)
 
@@ -235,9 +263,13 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 		return
 	}
 
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
 	log.Printf("删除用户 - 用户ID: %d", id)
 
-	err = c.userService.DeleteUser(uint(id))
+	err = c.userService.DeleteUser(uint(id), utils.GetCurrentUserID(ctx))
 	if err != nil {
 		log.Printf("删除用户失败 - 用户ID: %d, 错误: %v", id, err)
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -252,7 +284,7 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "用户删除成功",
+		"message": "用户已移入回收站",
 		"data":    nil,
 	})
 }
@@ -271,8 +303,12 @@ func (c *UserController) ToggleUserStatus(ctx *gin.Context) {
 		return
 	}
 
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
 	var req struct {
-		Status string `json:"status" binding:"required,oneof=active inactive"`
+		Status string `json:"status" binding:"required,oneof=active inactive alumni"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Printf("切换用户状态参数错误 - 用户ID: %d, 错误: %v", id, err)
@@ -323,6 +359,10 @@ func (c *UserController) ResetUserPassword(ctx *gin.Context) {
 		return
 	}
 
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
 	log.Printf("重置用户密码 - 用户ID: %d", id)
 
 	newPassword, err := c.userService.ResetUserPassword(uint(id))
@@ -363,9 +403,15 @@ func (c *UserController) BatchDeleteUsers(ctx *gin.Context) {
 		return
 	}
 
+	for _, id := range req.UserIDs {
+		if !c.ensureUserInScope(ctx, id) {
+			return
+		}
+	}
+
 	log.Printf("批量删除用户 - 用户ID列表: %v", req.UserIDs)
 
-	deletedCount, err := c.userService.BatchDeleteUsers(req.UserIDs)
+	deletedCount, err := c.userService.BatchDeleteUsers(req.UserIDs, utils.GetCurrentUserID(ctx))
 	if err != nil {
 		log.Printf("批量删除用户失败 - 错误: %v", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -392,7 +438,7 @@ func (c *UserController) BatchDeleteUsers(ctx *gin.Context) {
 func (c *UserController) GetUserStats(ctx *gin.Context) {
 	log.Printf("获取用户统计信息")
 
-	stats, err := c.userService.GetUserStats()
+	stats, err := c.userService.GetUserStats(queryOrgUnitID(ctx))
 	if err != nil {
 		log.Printf("获取用户统计信息失败: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
@@ -435,6 +481,7 @@ func (c *UserController) ExportUsers(ctx *gin.Context) {
 	// 获取所有用户数据（不分页）
 	params.Page = 0
 	params.Size = 0
+	params.Scope = utils.GetDataScope(ctx)
 	users, _, err := c.userService.GetUserList(params)
 	if err != nil {
 		log.Printf("导出用户数据失败: %v", err)
@@ -473,3 +520,277 @@ func (c *UserController) ExportUsers(ctx *gin.Context) {
 		})
 	}
 }
+
+// ResetUserTwoFactor 重置用户两步验证
+func (c *UserController) ResetUserTwoFactor(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		log.Printf("无效的用户ID: %s", idStr)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	if !c.ensureUserInScope(ctx, uint(id)) {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	if err := c.userService.ResetUserTwoFactor(uint(id), operatorID); err != nil {
+		log.Printf("重置两步验证失败 - 用户ID: %d, 错误: %v", id, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "重置两步验证失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已重置",
+		"data": gin.H{
+			"id": id,
+		},
+	})
+}
+
+// ensureUserInScope 检查目标用户是否在当前操作人的数据范围内，范围外按不存在处理
+func (c *UserController) ensureUserInScope(ctx *gin.Context, id uint) bool {
+	if c.userService.UserInScope(id, utils.GetDataScope(ctx)) {
+		return true
+	}
+	log.Printf("用户不在数据范围内 - 用户ID: %d, 操作人ID: %d", id, utils.GetCurrentUserID(ctx))
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"code":    404,
+		"message": "用户不存在",
+		"data":    nil,
+	})
+	return false
+}
+
+// resolveOrgUnit 选择了组织机构节点时按节点填写院系和专业
+func (c *UserController) resolveOrgUnit(ctx *gin.Context, orgUnitID *uint, department, major *string) bool {
+	if err := c.userService.ResolveOrgUnit(orgUnitID, department, major); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return false
+	}
+	return true
+}
+
+// ensureDepartmentInScope 院系管理员只能在本院系创建/调整用户，未填写院系时自动设为本院系
+func (c *UserController) ensureDepartmentInScope(ctx *gin.Context, department *string, roleKeys []string) bool {
+	scope := utils.GetDataScope(ctx)
+	if scope.IsGlobal() {
+		return true
+	}
+	for _, roleKey := range roleKeys {
+		if roleKey == "admin" {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "院系管理员不能分配系统管理员角色",
+				"data":    nil,
+			})
+			return false
+		}
+	}
+	if *department == "" {
+		*department = scope.Department
+	}
+	if *department == scope.Department && scope.Department != "" {
+		return true
+	}
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"code":    403,
+		"message": "只能管理本院系的用户",
+		"data":    nil,
+	})
+	return false
+}
+
+// GetUserSessions 获取用户当前有效的登录设备
+func (c *UserController) GetUserSessions(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+
+	if !c.ensureUserInScope(ctx, id) {
+		return
+	}
+
+	sessions, err := c.userService.GetUserSessions(id)
+	if err != nil {
+		log.Printf("获取用户登录设备失败 - 用户ID: %d, 错误: %v", id, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "获取登录设备失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取登录设备成功",
+		"data":    sessions,
+	})
+}
+
+// TerminateUserSessions 强制下线用户的全部登录设备，或通过 :sessionId 下线单个设备
+func (c *UserController) TerminateUserSessions(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+
+	var sessionID uint
+	if ctx.Param("sessionId") != "" {
+		if sessionID, ok = parseIDParam(ctx, "sessionId", "无效的会话ID"); !ok {
+			return
+		}
+	}
+
+	if !c.ensureUserInScope(ctx, id) {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	count, err := c.userService.TerminateUserSessions(id, sessionID, operatorID, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		log.Printf("强制下线失败 - 用户ID: %d, 会话ID: %d, 错误: %v", id, sessionID, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "强制下线失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已强制下线",
+		"data": gin.H{
+			"id":    id,
+			"count": count,
+		},
+	})
+}
+
+// GetUserIdentities 获取用户绑定的统一身份认证账号
+func (c *UserController) GetUserIdentities(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+
+	if !c.ensureUserInScope(ctx, id) {
+		return
+	}
+
+	identities, err := c.userService.GetUserIdentities(id)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "获取绑定账号失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取绑定账号成功",
+		"data":    identities,
+	})
+}
+
+// UnlinkUserIdentity 解除统一身份认证账号绑定（用于纠正错误关联）
+func (c *UserController) UnlinkUserIdentity(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+	identityID, ok := parseIDParam(ctx, "identityId", "无效的绑定ID")
+	if !ok {
+		return
+	}
+
+	if !c.ensureUserInScope(ctx, id) {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	if err := c.userService.UnlinkUserIdentity(id, identityID, operatorID, ctx.ClientIP(), ctx.Request.UserAgent()); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "解除绑定失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已解除绑定",
+		"data":    nil,
+	})
+}
+
+// ImpersonateUser 以该用户身份登录查看（模拟登录），返回限时令牌
+func (c *UserController) ImpersonateUser(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return
+	}
+
+	// 模拟登录会话或个人访问令牌不能再发起模拟
+	if utils.GetImpersonatorID(ctx) != 0 || currentSessionID(ctx) == 0 {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "当前登录方式不能发起模拟登录",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req models.ImpersonationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if !c.ensureUserInScope(ctx, id) {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	result, err := c.userService.ImpersonateUser(id, operatorID, req, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		log.Printf("模拟登录失败 - 管理员ID: %d, 用户ID: %d, 错误: %v", operatorID, id, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "模拟登录失败: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "模拟登录令牌已签发",
+		"data":    result,
+	})
+}
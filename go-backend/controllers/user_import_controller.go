@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userImportMaxFileSize 导入文件大小上限（10MB）
+const userImportMaxFileSize = 10 * 1024 * 1024
+
+type UserImportController struct {
+	importService *services.UserImportService
+}
+
+func NewUserImportController(db *gorm.DB) *UserImportController {
+	return &UserImportController{importService: services.NewUserImportService(db)}
+}
+
+// ImportUsers 从 CSV/XLSX 批量导入用户（multipart：file、dryRun、skipInvalid、notify、defaultRole）
+func (c *UserImportController) ImportUsers(ctx *gin.Context) {
+	var options models.UserImportOptions
+	if err := ctx.ShouldBind(&options); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请上传导入文件",
+			"data":    nil,
+		})
+		return
+	}
+	if fileHeader.Size > userImportMaxFileSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "文件大小不能超过10MB",
+			"data":    nil,
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "读取导入文件失败",
+			"data":    nil,
+		})
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "读取导入文件失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	options.Scope = utils.GetDataScope(ctx)
+	operatorID := utils.GetCurrentUserID(ctx)
+	log.Printf("批量导入用户 - 文件: %s, 试运行: %t, 操作人ID: %d", fileHeader.Filename, options.DryRun, operatorID)
+
+	report, err := c.importService.Import(fileHeader.Filename, content, options, operatorID)
+	if err != nil {
+		status, code := http.StatusBadRequest, 400
+		if errors.Is(err, services.ErrUserImportInvalid) {
+			status, code = http.StatusUnprocessableEntity, 422
+		}
+		ctx.JSON(status, gin.H{
+			"code":    code,
+			"message": err.Error(),
+			"data":    report,
+		})
+		return
+	}
+
+	message := "批量导入完成"
+	if options.DryRun {
+		message = "导入数据校验完成"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    report,
+	})
+}
+
+// GetImportTemplate 下载CSV导入模板
+func (c *UserImportController) GetImportTemplate(ctx *gin.Context) {
+	ctx.Header("Content-Disposition", `attachment; filename="user_import_template.csv"`)
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", services.UserImportTemplate())
+}
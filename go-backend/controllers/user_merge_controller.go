@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserMergeController struct {
+	db           *gorm.DB
+	mergeService *services.UserMergeService
+}
+
+func NewUserMergeController(db *gorm.DB) *UserMergeController {
+	return &UserMergeController{db: db, mergeService: services.NewUserMergeService(db)}
+}
+
+// GetDuplicates 查找疑似重复账号，reason 可选 email / student_id / real_name
+func (c *UserMergeController) GetDuplicates(ctx *gin.Context) {
+	reason := ctx.Query("reason")
+	if reason != "" && reason != "email" && reason != "student_id" && reason != "real_name" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的匹配条件",
+			"data":    nil,
+		})
+		return
+	}
+
+	groups, err := c.mergeService.FindDuplicates(reason, utils.GetDataScope(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查找重复账号失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查找重复账号成功",
+		"data":    groups,
+	})
+}
+
+// MergeUsers 把被合并账号的数据迁移到保留账号，dryRun 时只返回预计迁移的记录数
+func (c *UserMergeController) MergeUsers(ctx *gin.Context) {
+	var req models.UserMergeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	// 院系管理员只能合并本院系的账号
+	scope := utils.GetDataScope(ctx)
+	if !services.UserInScope(c.db, scope, req.SourceID) || !services.UserInScope(c.db, scope, req.TargetID) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "用户不存在",
+			"data":    nil,
+		})
+		return
+	}
+
+	result, err := c.mergeService.Merge(req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	message := "账号合并成功"
+	if req.DryRun {
+		message = "账号合并预览完成"
+	} else {
+		utils.SetAuditEntity(ctx, req.TargetID)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// GetMerges 获取账号合并记录
+func (c *UserMergeController) GetMerges(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(ctx.DefaultQuery("size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 20
+	}
+
+	records, total, err := c.mergeService.GetMerges(page, size)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取合并记录失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取合并记录成功",
+		"data": gin.H{
+			"list":  records,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserPrivacyController struct {
+	privacyService *services.UserPrivacyService
+	systemLogs     *services.SystemLogService
+}
+
+func NewUserPrivacyController(db *gorm.DB) *UserPrivacyController {
+	return &UserPrivacyController{
+		privacyService: services.NewUserPrivacyService(db),
+		systemLogs:     services.NewSystemLogService(db),
+	}
+}
+
+// ExportUserData 导出用户的全部关联数据，format=zip 时下载含附件的压缩包，默认返回JSON
+func (c *UserPrivacyController) ExportUserData(ctx *gin.Context) {
+	id, ok := c.scopedUserID(ctx)
+	if !ok {
+		return
+	}
+
+	export, err := c.privacyService.Export(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	utils.SetAuditEntity(ctx, id)
+
+	// 导出是查询请求，审计中间件不会记录，单独写入安全日志
+	format := "json"
+	if ctx.Query("format") == "zip" {
+		format = "zip"
+	}
+	operatorID := utils.GetCurrentUserID(ctx)
+	c.systemLogs.RecordSecurity("user_data_export", "导出个人数据", "success", &operatorID,
+		fmt.Sprintf("用户ID: %d, 格式: %s", id, format), ctx.ClientIP(), ctx.Request.UserAgent())
+
+	if format != "zip" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "导出个人数据成功",
+			"data":    export,
+		})
+		return
+	}
+
+	// 先写入缓冲区，打包失败时仍能返回错误信息
+	var buf bytes.Buffer
+	if err := c.privacyService.WriteArchive(&buf, export); err != nil {
+		log.Printf("打包个人数据失败 - 用户ID: %d, 错误: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "打包个人数据失败",
+			"data":    nil,
+		})
+		return
+	}
+	filename := fmt.Sprintf("user_%d_data_%s.zip", id, export.ExportedAt.Format("20060102150405"))
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// AnonymizeUser 清除用户的个人身份信息，保留统计和竞赛成绩数据（不可撤销）
+func (c *UserPrivacyController) AnonymizeUser(ctx *gin.Context) {
+	id, ok := c.scopedUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.UserAnonymizeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result, err := c.privacyService.Anonymize(id, req, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, id)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已匿名化",
+		"data":    result,
+	})
+}
+
+// scopedUserID 解析用户ID，院系管理员只能处理本院系的用户，范围外按不存在处理
+func (c *UserPrivacyController) scopedUserID(ctx *gin.Context) (uint, bool) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return 0, false
+	}
+	if !c.privacyService.InScope(id, utils.GetDataScope(ctx)) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "用户不存在",
+			"data":    nil,
+		})
+		return 0, false
+	}
+	return id, true
+}
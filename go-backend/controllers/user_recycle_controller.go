@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"yunmeng-backend/models"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserRecycleController struct {
+	recycleService *services.UserRecycleService
+}
+
+func NewUserRecycleController(db *gorm.DB) *UserRecycleController {
+	return &UserRecycleController{recycleService: services.NewUserRecycleService(db)}
+}
+
+// GetRecycleBin 获取回收站中的用户
+func (c *UserRecycleController) GetRecycleBin(ctx *gin.Context) {
+	var params models.UserQueryParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.Size <= 0 {
+		params.Size = 20
+	}
+	params.Scope = utils.GetDataScope(ctx)
+
+	users, total, err := c.recycleService.GetRecycleBin(params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取回收站用户失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取回收站用户成功",
+		"data": gin.H{
+			"list":  users,
+			"total": total,
+			"page":  params.Page,
+			"size":  params.Size,
+			"pages": (total + int64(params.Size) - 1) / int64(params.Size),
+		},
+	})
+}
+
+// RestoreUser 从回收站恢复用户（含删除前的角色）
+func (c *UserRecycleController) RestoreUser(ctx *gin.Context) {
+	id, ok := c.recycledID(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := c.recycleService.Restore(id, utils.GetCurrentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, id)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已恢复",
+		"data":    user,
+	})
+}
+
+// GetUserReferences 检查永久删除会影响的业务数据
+func (c *UserRecycleController) GetUserReferences(ctx *gin.Context) {
+	id, ok := c.recycledID(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.recycleService.References(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取引用数据成功",
+		"data":    report,
+	})
+}
+
+// PurgeUser 永久删除回收站中的用户，仍有引用数据时需传 force=true
+func (c *UserRecycleController) PurgeUser(ctx *gin.Context) {
+	id, ok := c.recycledID(ctx)
+	if !ok {
+		return
+	}
+
+	operatorID := utils.GetCurrentUserID(ctx)
+	report, err := c.recycleService.Purge(id, ctx.Query("force") == "true", &operatorID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserHasReferences) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": err.Error(),
+				"data":    report,
+			})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    report,
+		})
+		return
+	}
+
+	utils.SetAuditEntity(ctx, id)
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已永久删除",
+		"data":    report,
+	})
+}
+
+// recycledID 解析用户ID，院系管理员只能处理本院系的回收站用户，范围外按不存在处理
+func (c *UserRecycleController) recycledID(ctx *gin.Context) (uint, bool) {
+	id, ok := parseIDParam(ctx, "id", "无效的用户ID")
+	if !ok {
+		return 0, false
+	}
+	if !c.recycleService.RecycledInScope(id, utils.GetDataScope(ctx)) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "回收站中不存在该用户",
+			"data":    nil,
+		})
+		return 0, false
+	}
+	return id, true
+}
@@ -1,7 +0,0 @@
-module debug
-
-go 1.21
- 
-require (
-	github.com/golang-jwt/jwt/v4 v4.5.0
-) 
\ No newline at end of file
@@ -18,6 +18,8 @@ set DB_PASSWORD=123456
 set DB_DATABASE=cloud_dream_system
 set DB_CHARSET=utf8mb4
 set PORT=8080
+:: 本地开发未配置 JWT_SECRET 时使用内置开发密钥
+set JWT_ALLOW_DEV_SECRET=true
 
 echo [信息] 环境变量已设置
 echo [信息] 数据库: %DB_HOST%:%DB_PORT%/%DB_DATABASE%
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"yunmeng-backend/config"
 	"yunmeng-backend/routes"
+	"yunmeng-backend/services"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -20,19 +27,49 @@ func main() {
 	dbConfig := config.NewDatabaseConfig()
 	log.Printf("数据库配置: %s:%s/%s", dbConfig.Host, dbConfig.Port, dbConfig.Database)
 
+	// 加载JWT签名密钥
+	if err := config.LoadJWTKeys(config.NewJWTConfig()); err != nil {
+		log.Fatal("JWT密钥加载失败: ", err)
+	}
+
+	// 加载邮件发送配置
+	config.LoadMailSender(config.NewMailConfig())
+
+	// 加载统一身份认证配置
+	if err := config.LoadSSOProviders(config.NewSSOConfig()); err != nil {
+		log.Fatal("统一身份认证配置加载失败: ", err)
+	}
+
 	// 连接数据库
 	db, err := config.ConnectDatabase(dbConfig)
 	if err != nil {
 		log.Fatal("数据库连接失败: ", err)
 	}
 
-	// 注意：如果遇到数据库迁移错误，请先手动执行 sql/migrate_existing.sql 脚本
-	// 然后再取消下面的注释来启用自动迁移
-	/*
-		if err := config.AutoMigrate(db); err != nil {
-			log.Fatal("数据库迁移失败: ", err)
-		}
-	*/
+	// 自动迁移：sql/ 下的初始化脚本只包含基础表，会话、权限、项目工作流等表和新增字段
+	// 由模型定义在启动时补齐，说明见 sql/README.md
+	if err := config.AutoMigrate(db); err != nil {
+		log.Fatal("数据库迁移失败: ", err)
+	}
+
+	// 加载LDAP目录同步配置，配置了同步间隔时启动定时同步
+	ldapConfig := config.NewLDAPConfig()
+	if config.LoadLDAPDirectory(ldapConfig) && ldapConfig.SyncInterval > 0 {
+		services.StartLDAPSyncScheduler(db, time.Duration(ldapConfig.SyncInterval)*time.Minute)
+	}
+
+	// 启动学年升级定时检查（rollover_auto_date 为空时不执行）
+	services.StartAcademicRolloverScheduler(db)
+
+	// 启动回收站清理（user_recycle_retention_days 为0时不清理）
+	services.StartUserPurgeScheduler(db)
+
+	// 收到退出信号后停止接收请求，并等待进行中的审核期限检查结束
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 启动审核期限检查（到期提醒、超期升级或自动通过）
+	reviewScheduler := services.StartReviewDeadlineScheduler(ctx, db)
 
 	// 初始化默认数据
 	if err := config.InitDefaultData(db); err != nil {
@@ -59,12 +96,28 @@ func main() {
 	log.Printf("服务器启动中，监听端口: %s", port)
 	log.Println("前端地址: http://localhost:5173")
 	log.Printf("后端API地址: http://localhost:%s/api", port)
-	log.Println("注意：如果遇到数据库问题，请先执行 sql/migrate_existing.sql 脚本")
 
 	// 启动服务
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("服务器启动失败: ", err)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("服务器启动失败: ", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("服务器关闭中...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("服务器关闭失败: %v", err)
+	}
+	select {
+	case <-reviewScheduler:
+	case <-shutdownCtx.Done():
+		log.Println("等待审核期限检查结束超时")
 	}
+	log.Println("服务器已关闭")
 }
 
 // getEnv 获取环境变量，如果不存在则返回默认值
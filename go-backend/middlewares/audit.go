@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"yunmeng-backend/services"
+	"yunmeng-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditMiddleware 审计中间件，为每个写操作（POST/PUT/PATCH/DELETE）记录系统日志和实体字段变更，
+// 模拟登录期间的全部请求（包括查询）都会记录
+func AuditMiddleware(auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		impersonatorID := utils.GetImpersonatorID(c)
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			if impersonatorID == 0 {
+				c.Next()
+				return
+			}
+		}
+
+		route := c.FullPath()
+		entity := auditService.ResolveEntity(route)
+		entityID := c.Param("id")
+		snapshot := !auditService.SkipsSnapshot(route)
+		var before map[string]interface{}
+		if snapshot {
+			before = auditService.Snapshot(entity, entityID)
+		}
+
+		c.Next()
+
+		// 创建类接口在处理器中通过 utils.SetAuditEntity 回填新实体ID
+		if id, ok := utils.GetAuditEntityID(c); ok {
+			entityID = fmt.Sprintf("%d", id)
+		}
+
+		entry := services.AuditEntry{
+			UserID:     utils.GetCurrentUserID(c),
+			Role:       c.GetString("role"),
+			Method:     c.Request.Method,
+			Route:      route,
+			Path:       c.Request.URL.Path,
+			EntityID:   entityID,
+			StatusCode: c.Writer.Status(),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Before:     before,
+		}
+		entry.ImpersonatorID = impersonatorID
+		if tokenID, ok := c.Get("personalTokenID"); ok {
+			entry.PersonalTokenID, _ = tokenID.(uint)
+		}
+		if entity != nil {
+			entry.EntityType = entity.Type
+			if snapshot {
+				entry.After = auditService.Snapshot(entity, entityID)
+			}
+		}
+		auditService.Record(entry)
+	}
+}
@@ -1,16 +1,18 @@
 package middlewares
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"yunmeng-backend/services"
 	"yunmeng-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware JWT认证中间件（同时校验令牌所属会话未被撤销），也接受带作用域的个人访问令牌
+func AuthMiddleware(authService *services.AuthService, tokenService *services.PersonalTokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取Authorization头
 		authHeader := c.GetHeader("Authorization")
@@ -38,6 +40,12 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := tokenParts[1]
 
+		// 个人访问令牌：只能访问作用域内的路由分组
+		if services.IsPersonalToken(tokenString) {
+			authenticatePersonalToken(c, tokenService, tokenString)
+			return
+		}
+
 		// 解析JWT Token
 		claims, err := utils.ParseToken(tokenString)
 		if err != nil {
@@ -50,6 +58,62 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查会话是否已被撤销（登出、禁用账号、刷新令牌重放）
+		session, err := authService.ValidateSession(claims.SessionID, claims.UserID, claims.Role)
+		if err != nil {
+			log.Printf("AuthMiddleware失败 - 会话无效: 用户ID: %d, 会话ID: %d, 错误: %v", claims.UserID, claims.SessionID, err)
+			message := "登录会话已失效，请重新登录"
+			if errors.Is(err, services.ErrSessionRoleChanged) {
+				message = err.Error()
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": message,
+			})
+			c.Abort()
+			return
+		}
+
+		// 受限会话只能访问白名单接口
+		if session.Restriction != "" && !restrictedPathAllowed(session.Restriction, c.Request.URL.Path) {
+			log.Printf("AuthMiddleware拒绝 - 受限会话: 用户ID: %d, 限制: %s, 路径: %s",
+				claims.UserID, session.Restriction, c.Request.URL.Path)
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":        403,
+				"message":     restrictionMessages[session.Restriction],
+				"restriction": session.Restriction,
+			})
+			c.Abort()
+			return
+		}
+
+		// 模拟登录会话：令牌与会话记录的管理员必须一致，且默认只读
+		if session.ImpersonatorID != nil || claims.ImpersonatorID != 0 {
+			if session.ImpersonatorID == nil || *session.ImpersonatorID != claims.ImpersonatorID {
+				log.Printf("AuthMiddleware失败 - 模拟登录令牌与会话不匹配: 会话ID: %d", claims.SessionID)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": "登录会话已失效，请重新登录",
+				})
+				c.Abort()
+				return
+			}
+			if !impersonationAllowed(c.Request.Method, c.Request.URL.Path, session.ImpersonationWrite) {
+				log.Printf("AuthMiddleware拒绝 - 模拟登录禁止的操作: 管理员ID: %d, 用户ID: %d, %s %s",
+					claims.ImpersonatorID, claims.UserID, c.Request.Method, c.Request.URL.Path)
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":          403,
+					"message":       "模拟登录期间不允许该操作",
+					"impersonating": true,
+				})
+				c.Abort()
+				return
+			}
+			c.Set("impersonatorID", claims.ImpersonatorID)
+		}
+
+		authService.TouchSession(session, c.ClientIP())
+
 		log.Printf("AuthMiddleware成功 - 用户ID: %d, 用户名: %s, 角色: %s",
 			claims.UserID, claims.Username, claims.Role)
 
@@ -57,11 +121,91 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("sessionID", claims.SessionID)
 
 		c.Next()
 	}
 }
 
+// authenticatePersonalToken 校验个人访问令牌并写入上下文
+func authenticatePersonalToken(c *gin.Context, tokenService *services.PersonalTokenService, tokenString string) {
+	token, user, err := tokenService.Authenticate(tokenString, c.ClientIP())
+	if err != nil {
+		log.Printf("AuthMiddleware失败 - 个人访问令牌无效: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		c.Abort()
+		return
+	}
+
+	if err := tokenService.CheckScope(token, c.Request.Method, c.Request.URL.Path); err != nil {
+		log.Printf("AuthMiddleware拒绝 - 令牌作用域不足: 令牌ID: %d, %s %s", token.ID, c.Request.Method, c.Request.URL.Path)
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": err.Error(),
+			"scopes":  token.Scopes,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", token.Role)
+	c.Set("sessionID", uint(0))
+	c.Set("personalTokenID", token.ID)
+
+	c.Next()
+}
+
+// restrictedPaths 受限会话允许访问的接口前缀
+var restrictedPaths = map[string][]string{
+	services.SessionRestrictionTwoFactorSetup: {"/api/2fa/", "/api/logout"},
+	services.SessionRestrictionPasswordChange: {"/api/password/change", "/api/logout"},
+}
+
+// restrictionMessages 受限会话被拒绝时的提示
+var restrictionMessages = map[string]string{
+	services.SessionRestrictionTwoFactorSetup: "当前角色要求启用两步验证，请先完成绑定",
+	services.SessionRestrictionPasswordChange: "密码已被重置或已过期，请先修改密码",
+}
+
+// impersonationBlockedPaths 模拟登录期间始终禁止访问的接口（账号安全相关）
+var impersonationBlockedPaths = []string{"/api/2fa/", "/api/tokens", "/api/sessions", "/api/switch-role"}
+
+// impersonationAlwaysAllowedPaths 模拟登录期间始终允许的写接口（结束模拟）
+var impersonationAlwaysAllowedPaths = []string{"/api/logout", "/api/impersonation/stop"}
+
+// impersonationAllowed 模拟登录默认只读，发起时显式允许写操作后才放行写请求
+func impersonationAllowed(method, path string, allowWrite bool) bool {
+	for _, prefix := range impersonationAlwaysAllowedPaths {
+		if path == prefix {
+			return true
+		}
+	}
+	for _, prefix := range impersonationBlockedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return allowWrite
+}
+
+func restrictedPathAllowed(restriction, path string) bool {
+	for _, prefix := range restrictedPaths[restriction] {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // RoleMiddleware 角色权限中间件
 func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -146,3 +290,42 @@ func AdminOnly() gin.HandlerFunc {
 func TeacherOrAdmin() gin.HandlerFunc {
 	return RoleMiddleware("admin", "teacher")
 }
+
+// RequirePermission 权限中间件，当前角色需拥有全部指定权限（权限由 role_permissions 配置）
+func RequirePermission(permissionService *services.PermissionService, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "未找到用户角色信息",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !permissionService.HasPermission(role, permission) {
+				log.Printf("权限验证失败 - 用户角色: %s, 缺少权限: %s, 路径: %s", role, permission, c.Request.URL.Path)
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":       403,
+					"message":    "权限不足",
+					"permission": permission,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// DataScopeMiddleware 计算当前用户的数据范围并写入上下文（院系管理员仅能访问本院系数据）
+func DataScopeMiddleware(permissionService *services.PermissionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := permissionService.ResolveDataScope(utils.GetCurrentUserID(c), c.GetString("role"))
+		c.Set("dataScope", scope)
+		c.Next()
+	}
+}
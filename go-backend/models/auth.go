@@ -0,0 +1,320 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession 登录会话表（一个会话对应一个刷新令牌家族）
+type UserSession struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID       uint       `gorm:"not null;index;column:user_id" json:"userId"`
+	Role         string     `gorm:"size:30;not null;column:role" json:"role"`
+	IPAddress    string     `gorm:"size:50;column:ip_address" json:"ipAddress"`
+	UserAgent    string     `gorm:"type:text;column:user_agent" json:"userAgent"`
+	ExpiresAt    time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	RevokeReason string     `gorm:"size:50;column:revoke_reason" json:"revokeReason"`
+	// Restriction 受限会话只能访问指定接口，例如强制启用两步验证前的 two_factor_setup
+	Restriction string `gorm:"size:30;column:restriction" json:"restriction"`
+	// LoginMethod 登录方式：password、ldap 或 sso:<认证提供方标识>
+	LoginMethod string `gorm:"size:50;default:'password';column:login_method" json:"loginMethod"`
+	// LastActiveAt 最近一次携带该会话令牌访问接口的时间（按分钟节流更新）
+	LastActiveAt *time.Time `gorm:"column:last_active_at" json:"lastActiveAt"`
+	LastActiveIP string     `gorm:"size:50;column:last_active_ip" json:"lastActiveIp"`
+	// ImpersonatorID 模拟登录会话的发起管理员，ImpersonationWrite 表示是否允许写操作
+	ImpersonatorID     *uint     `gorm:"index;column:impersonator_id" json:"impersonatorId"`
+	ImpersonationWrite bool      `gorm:"column:impersonation_write;default:false" json:"impersonationWrite"`
+	CreatedAt          time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt          time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	// Current 是否为发起请求的当前会话（仅用于设备列表展示）
+	Current bool `gorm:"-" json:"current"`
+
+	// 关联关系
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (us *UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive 会话是否仍然有效
+func (us *UserSession) IsActive() bool {
+	return us.RevokedAt == nil && time.Now().Before(us.ExpiresAt)
+}
+
+// RefreshToken 刷新令牌表（仅保存令牌哈希）
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	SessionID  uint       `gorm:"not null;index;column:session_id" json:"sessionId"`
+	TokenHash  string     `gorm:"size:64;unique;not null;column:token_hash" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	UsedAt     *time.Time `gorm:"column:used_at" json:"usedAt"`
+	ReplacedBy *uint      `gorm:"column:replaced_by" json:"replacedBy"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+
+	// 关联关系
+	Session *UserSession `gorm:"foreignKey:SessionID" json:"session,omitempty"`
+}
+
+func (rt *RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SwitchRoleRequest 切换角色请求
+type SwitchRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	SessionID    uint   `json:"-"`
+}
+
+// ImpersonationRequest 管理员发起模拟登录请求
+type ImpersonationRequest struct {
+	Role            string `json:"role"`                                              // 以被模拟用户的哪个角色查看，默认其第一个角色
+	Reason          string `json:"reason" binding:"required,max=255"`                 // 模拟原因，写入审计日志
+	DurationMinutes int    `json:"durationMinutes" binding:"omitempty,min=5,max=120"` // 有效期，默认30分钟
+	AllowWrite      bool   `json:"allowWrite"`                                        // 是否允许写操作，默认只读
+}
+
+// ImpersonationResponse 模拟登录令牌
+type ImpersonationResponse struct {
+	Token      string    `json:"token"`
+	ExpiresIn  int64     `json:"expires_in"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	UserID     uint      `json:"userId"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+	AllowWrite bool      `json:"allowWrite"`
+}
+
+// PasswordResetToken 找回密码令牌记录（令牌一次性使用；账号不存在的请求也会记录，用于限流）
+type PasswordResetToken struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID     *uint      `gorm:"index;column:user_id" json:"userId"`
+	Identifier string     `gorm:"size:100;index;column:identifier" json:"identifier"` // 用户提交的用户名或邮箱
+	TokenHash  string     `gorm:"size:64;index;column:token_hash" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	UsedAt     *time.Time `gorm:"column:used_at" json:"usedAt"`
+	IPAddress  string     `gorm:"size:50;index;column:ip_address" json:"ipAddress"`
+	UserAgent  string     `gorm:"type:text;column:user_agent" json:"userAgent"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+func (prt *PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// ForgotPasswordRequest 找回密码请求
+type ForgotPasswordRequest struct {
+	Account string `json:"account" binding:"required,max=100"` // 用户名或邮箱
+}
+
+// ResetPasswordRequest 通过邮件令牌重置密码
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// LoginAttempt 登录尝试记录表（成功与失败均记录，用于暴力破解分析）
+type LoginAttempt struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Username   string    `gorm:"size:50;index;column:username" json:"username"`
+	UserID     *uint     `gorm:"column:user_id" json:"userId"`
+	IPAddress  string    `gorm:"size:50;index;column:ip_address" json:"ipAddress"`
+	UserAgent  string    `gorm:"type:text;column:user_agent" json:"userAgent"`
+	Success    bool      `gorm:"default:false;column:success" json:"success"`
+	FailReason string    `gorm:"size:50;column:fail_reason" json:"failReason"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+func (la *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// LoginLockout 登录锁定状态表（按用户名、按IP分别计数）
+type LoginLockout struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Scope        string     `gorm:"type:enum('username','ip');not null;uniqueIndex:idx_lockout_scope_key;column:scope" json:"scope"`
+	ScopeKey     string     `gorm:"size:100;not null;uniqueIndex:idx_lockout_scope_key;column:scope_key" json:"scopeKey"`
+	FailedCount  int        `gorm:"default:0;column:failed_count" json:"failedCount"`
+	LastFailedAt *time.Time `gorm:"column:last_failed_at" json:"lastFailedAt"`
+	LockedUntil  *time.Time `gorm:"column:locked_until" json:"lockedUntil"`
+	UnlockedBy   *uint      `gorm:"column:unlocked_by" json:"unlockedBy"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ll *LoginLockout) TableName() string {
+	return "login_lockouts"
+}
+
+// LoginUnlockRequest 管理员解除登录锁定请求
+type LoginUnlockRequest struct {
+	Username  string `json:"username"`
+	IPAddress string `json:"ipAddress"`
+}
+
+// UserTwoFactor 用户两步验证（TOTP）配置表
+type UserTwoFactor struct {
+	UserID       uint       `gorm:"primaryKey;column:user_id" json:"userId"`
+	Secret       string     `gorm:"size:64;not null;column:secret" json:"-"`
+	Enabled      bool       `gorm:"default:false;column:enabled" json:"enabled"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at" json:"enabledAt"`
+	LastUsedStep int64      `gorm:"default:0;column:last_used_step" json:"-"` // 最近一次使用的时间步，防止口令重放
+	FailedCount  int        `gorm:"default:0;column:failed_count" json:"-"`   // 关闭两步验证、重新生成恢复码时连续输错验证码的次数
+	LockedUntil  *time.Time `gorm:"column:locked_until" json:"-"`             // 连续输错达到上限后，在此之前不再校验验证码
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (utf *UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// TwoFactorRecoveryCode 两步验证恢复码表（仅保存哈希，每个恢复码只能使用一次）
+type TwoFactorRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID    uint       `gorm:"not null;index;column:user_id" json:"userId"`
+	CodeHash  string     `gorm:"size:64;not null;column:code_hash" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (trc *TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
+
+// TwoFactorChallenge 登录第二步验证挑战表（密码校验通过后签发，验证通过才签发JWT）
+type TwoFactorChallenge struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenHash string     `gorm:"size:64;unique;not null;column:token_hash" json:"-"`
+	UserID    uint       `gorm:"not null;column:user_id" json:"userId"`
+	Role      string     `gorm:"size:30;not null;column:role" json:"role"`
+	IPAddress string     `gorm:"size:50;column:ip_address" json:"ipAddress"`
+	UserAgent string     `gorm:"type:text;column:user_agent" json:"userAgent"`
+	Attempts  int        `gorm:"default:0;column:attempts" json:"attempts"`
+	ExpiresAt time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	// LoginMethod 第一步的登录方式，验证通过后写入会话
+	LoginMethod string `gorm:"size:50;default:'password';column:login_method" json:"loginMethod"`
+}
+
+func (tfc *TwoFactorChallenge) TableName() string {
+	return "two_factor_challenges"
+}
+
+// TwoFactorCodeRequest 两步验证口令请求（TOTP口令或恢复码）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest 登录第二步验证请求
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorSetupResponse 两步验证绑定信息
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// TwoFactorStatusResponse 两步验证状态
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Enforced               bool       `json:"enforced"`
+	EnabledAt              *time.Time `json:"enabledAt"`
+	RemainingRecoveryCodes int64      `json:"remainingRecoveryCodes"`
+}
+
+// PersonalAccessToken 个人访问令牌表（供脚本调用API，仅保存令牌哈希）
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID      uint       `gorm:"not null;index;column:user_id" json:"userId"`
+	Name        string     `gorm:"size:100;not null;column:name" json:"name"`
+	TokenPrefix string     `gorm:"size:16;column:token_prefix" json:"tokenPrefix"` // 令牌前几位，便于用户识别
+	TokenHash   string     `gorm:"size:64;unique;not null;column:token_hash" json:"-"`
+	Role        string     `gorm:"size:30;not null;column:role" json:"role"`
+	Scopes      JSONArray  `gorm:"type:json;column:scopes" json:"scopes"`
+	ExpiresAt   time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at" json:"lastUsedAt"`
+	LastUsedIP  string     `gorm:"size:50;column:last_used_ip" json:"lastUsedIp"`
+	RevokedAt   *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (pat *PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// IsActive 令牌是否仍然有效
+func (pat *PersonalAccessToken) IsActive() bool {
+	return pat.RevokedAt == nil && time.Now().Before(pat.ExpiresAt)
+}
+
+// PersonalAccessTokenRequest 创建个人访问令牌请求
+type PersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"required,min=1,max=365"`
+	Role          string   `json:"role"` // 令牌使用的角色，默认当前登录角色
+}
+
+// UserIdentity 本地账号与统一身份认证平台身份的绑定关系
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID      uint       `gorm:"not null;index;column:user_id" json:"userId"`
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_user_identities_subject;column:provider" json:"provider"`
+	Subject     string     `gorm:"size:191;not null;uniqueIndex:idx_user_identities_subject;column:subject" json:"subject"`
+	Attributes  string     `gorm:"type:text;column:attributes" json:"attributes"` // 最近一次登录时身份平台返回的属性（JSON）
+	LastLoginAt *time.Time `gorm:"column:last_login_at" json:"lastLoginAt"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (ui *UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// SSOLinkConfirmRequest 统一身份认证关联已有账号时的本人确认请求
+type SSOLinkConfirmRequest struct {
+	LinkToken string `json:"linkToken" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	Role      string `json:"role"`
+}
+
+// SSOLoginState 统一身份认证发起记录，回调时一次性消费，防止状态令牌被重放
+type SSOLoginState struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	NonceHash string     `gorm:"size:64;unique;not null;column:nonce_hash" json:"-"`
+	Provider  string     `gorm:"size:50;not null;column:provider" json:"provider"`
+	IPAddress string     `gorm:"size:50;column:ip_address" json:"ipAddress"`
+	UserAgent string     `gorm:"type:text;column:user_agent" json:"userAgent"`
+	ExpiresAt time.Time  `gorm:"not null;index;column:expires_at" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"usedAt"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (sls *SSOLoginState) TableName() string {
+	return "sso_login_states"
+}
+
+// SSOLoginRequest 统一身份认证回调登录请求：OIDC 提交 code，CAS 提交 ticket；
+// Role 为空时使用账号的第一个角色
+type SSOLoginRequest struct {
+	State  string `json:"state" binding:"required"`
+	Code   string `json:"code"`
+	Ticket string `json:"ticket"`
+	Role   string `json:"role"`
+}
@@ -109,6 +109,7 @@ type Competition struct {
 	Status              string     `json:"status" gorm:"type:enum('draft','registration','submission','review','completed');default:draft;comment:竞赛状态"`
 	AwardConfig         JSONMap    `json:"award_config" gorm:"column:award_config;type:json;comment:获奖配置"`
 	DepartmentLimit     string     `json:"department_limit" gorm:"column:department_limit;type:varchar(255);comment:院系限制（可选）"`
+	OrgUnitID           *uint      `json:"org_unit_id" gorm:"column:org_unit_id;index;comment:限定的组织机构节点（含下级节点，可选）"`
 	TeacherLimit        bool       `json:"teacher_limit" gorm:"column:teacher_limit;default:false;comment:是否需要绑定教师才能报名"`
 	CreatedBy           uint       `json:"created_by" gorm:"not null;comment:创建者ID"`
 	CreatedAt           time.Time  `json:"created_at" gorm:"autoCreateTime"`
@@ -324,6 +325,7 @@ type CompetitionCreateRequest struct {
 	MaxParticipants   *int        `json:"max_participants"`
 	Status            string      `json:"status" binding:"omitempty,oneof=draft registration submission review completed"`
 	AwardConfig       JSONMap     `json:"award_config"`
+	OrgUnitID         *uint       `json:"org_unit_id"` // 限定组织机构节点（含下级），院系限制按节点填写
 }
 
 // CompetitionUpdateRequest 更新竞赛请求
@@ -341,6 +343,7 @@ type CompetitionUpdateRequest struct {
 	MaxParticipants   *int        `json:"max_participants"`
 	Status            string      `json:"status" binding:"omitempty,oneof=draft registration submission review completed"`
 	AwardConfig       JSONMap     `json:"award_config"`
+	OrgUnitID         *uint       `json:"org_unit_id"` // 限定组织机构节点（含下级），院系限制按节点填写
 }
 
 // CompetitionRegistrationRequest 竞赛报名请求
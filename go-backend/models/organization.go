@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// OrgUnitTypes 组织机构节点类型，按层级从高到低排列
+var OrgUnitTypes = []string{"university", "college", "department", "major", "class"}
+
+// OrgUnit 组织机构节点（学校 → 学院 → 系 → 专业 → 班级），可跳过中间层级（如学院下直接设专业）
+// Path 为从根到本节点的ID路径（如 /1/3/7/），按前缀匹配即可查询全部子孙节点
+type OrgUnit struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ParentID  *uint     `gorm:"index;column:parent_id" json:"parentId"`
+	Type      string    `gorm:"type:enum('university','college','department','major','class');not null;column:type" json:"type"`
+	Name      string    `gorm:"size:100;not null;column:name" json:"name"`
+	Code      string    `gorm:"size:50;column:code" json:"code"`
+	Aliases   string    `gorm:"type:text;column:aliases" json:"aliases"` // 其他写法（逗号分隔），迁移旧文本字段时用于匹配
+	Path      string    `gorm:"size:255;index;column:path" json:"path"`
+	SortOrder int       `gorm:"default:0;column:sort_order" json:"sortOrder"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	Children []*OrgUnit `gorm:"-" json:"children,omitempty"`
+}
+
+func (o *OrgUnit) TableName() string {
+	return "org_units"
+}
+
+// OrgUnitRequest 创建/更新组织机构节点请求
+type OrgUnitRequest struct {
+	ParentID  *uint  `json:"parentId"`
+	Type      string `json:"type" binding:"required,oneof=university college department major class"`
+	Name      string `json:"name" binding:"required,max=100"`
+	Code      string `json:"code" binding:"max=50"`
+	Aliases   string `json:"aliases"`
+	SortOrder int    `json:"sortOrder"`
+}
+
+// OrgMappingRequest 旧文本字段映射请求，Mappings 为手工指定的 文本 → 节点ID，应用后写入节点别名
+type OrgMappingRequest struct {
+	DryRun   bool            `json:"dryRun"`
+	Mappings map[string]uint `json:"mappings"`
+}
+
+// OrgMappingItem 一个旧文本值的映射结果
+type OrgMappingItem struct {
+	Source      string `json:"source"`            // user_department / user_major / competition / review_flow
+	Context     string `json:"context,omitempty"` // 专业所属的院系文本
+	Value       string `json:"value"`
+	Count       int64  `json:"count"`  // 使用该文本的记录数
+	Status      string `json:"status"` // matched / unmatched / ambiguous（多个同名节点）/ multiple（限定多个院系）
+	OrgUnitID   uint   `json:"orgUnitId,omitempty"`
+	OrgUnitName string `json:"orgUnitName,omitempty"`
+	Candidates  []uint `json:"candidates,omitempty"` // 同名的多个节点，需手工指定
+}
+
+// OrgMappingReport 旧文本字段映射报告
+type OrgMappingReport struct {
+	DryRun    bool             `json:"dryRun"`
+	Matched   int              `json:"matched"`
+	Unmatched int              `json:"unmatched"`
+	Ambiguous int              `json:"ambiguous"` // 需手工处理（ambiguous 和 multiple）
+	Updated   map[string]int64 `json:"updated"`   // 各来源实际写入节点ID的记录数
+	Items     []OrgMappingItem `json:"items"`
+}
+
+// OrgUnitStat 组织机构节点（含子孙节点）的用户统计
+type OrgUnitStat struct {
+	OrgUnitID uint   `json:"orgUnitId"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Count     int64  `json:"count"`
+}
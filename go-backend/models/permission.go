@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// PermissionAll 通配权限，拥有该权限的角色可访问全部接口
+const PermissionAll = "*"
+
+// 角色数据范围
+const (
+	DataScopeAll        = "all"        // 全部数据
+	DataScopeDepartment = "department" // 仅本院系数据
+)
+
+// DataScope 当前登录用户的数据可见范围
+type DataScope struct {
+	Global     bool   `json:"global"`
+	Department string `json:"department"`
+}
+
+// IsGlobal 是否为全局范围（nil 按最小范围处理，需要全局访问的内部调用须显式传入 &DataScope{Global: true}）
+func (ds *DataScope) IsGlobal() bool {
+	return ds != nil && ds.Global
+}
+
+// Permission 权限定义表（权限键形如 competition.finalize，按模块分组）
+type Permission struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	PermKey     string    `gorm:"unique;not null;size:100;column:perm_key" json:"permKey"`
+	Name        string    `gorm:"not null;size:100;column:name" json:"name"`
+	Module      string    `gorm:"size:50;index;column:module" json:"module"`
+	Description string    `gorm:"type:text" json:"description"`
+	IsSystem    bool      `gorm:"default:false;column:is_system" json:"isSystem"` // 内置权限由路由使用，不可删除
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	// 关联关系
+	Roles []Role `gorm:"many2many:role_permissions;" json:"roles,omitempty"`
+}
+
+func (p *Permission) TableName() string {
+	return "permissions"
+}
+
+// RolePermission 角色权限关联表（中间表）
+type RolePermission struct {
+	RoleID       uint `gorm:"primaryKey;column:role_id" json:"roleId"`
+	PermissionID uint `gorm:"primaryKey;column:permission_id" json:"permissionId"`
+}
+
+func (rp *RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// RoleRequest 创建/更新角色请求
+type RoleRequest struct {
+	RoleKey     string   `json:"roleKey" binding:"required,max=30"`
+	RoleName    string   `json:"roleName" binding:"required,max=50"`
+	Description string   `json:"description"`
+	DataScope   string   `json:"dataScope" binding:"omitempty,oneof=all department"`
+	Permissions []string `json:"permissions"` // 权限键列表，为nil时不修改角色权限
+}
+
+// RolePermissionsRequest 设置角色权限请求
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionRequest 创建/更新权限请求
+type PermissionRequest struct {
+	PermKey     string `json:"permKey" binding:"required,max=100"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Module      string `json:"module"`
+	Description string `json:"description"`
+}
+
+// RoleWithStats 角色信息（包含权限和用户数量）
+type RoleWithStats struct {
+	Role
+	UserCount int64 `json:"userCount"`
+}
@@ -9,11 +9,12 @@ type Project struct {
 	ID          uint   `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	Title       string `gorm:"column:title;type:varchar(255);not null" json:"title"`
 	Description string `gorm:"column:description;varchar(255)" json:"description"`
-	Type        string `gorm:"column:type;type:varchar(255)" json:"type"` // 冗余字段，便于展示
-	StudentID   uint   `gorm:"column:student_id;not null" json:"studentId"`
+	Type        string `gorm:"column:type;type:varchar(255)" json:"type"`   // 冗余字段，便于展示
+	TypeID      *uint  `gorm:"column:type_id;index" json:"typeId"`          // 项目分类（project_types.id）
+	StudentID   uint   `gorm:"column:student_id;not null" json:"studentId"` // 项目负责人（团队队长）
 	TeacherID   uint   `gorm:"column:teacher_id" json:"teacherId,omitempty"`
 
-	Status          string     `gorm:"column:status;type:enum('draft','submitted','reviewing','approved','rejected','completed');default:'draft'" json:"status"`
+	Status          string     `gorm:"column:status;type:varchar(50);default:'draft'" json:"status"` // 取值由项目分类的状态流转配置决定
 	SubmittedAt     *time.Time `gorm:"column:submitted_at" json:"submittedAt"`
 	ApprovedAt      *time.Time `gorm:"column:approved_at" json:"approvedAt"`
 	ApprovedBy      *uint      `gorm:"column:approved_by" json:"approvedBy,omitempty"`
@@ -38,19 +39,51 @@ func (Project) TableName() string {
 	return "projects"
 }
 
-// ProjectMember 项目成员表
+// ProjectMember 项目成员表，UserID 为空的是早期只登记姓名和学号的成员
+// 队长（Role 为 leader）与 Project.StudentID 保持一致；邀请需被邀请人接受后才成为正式成员
 type ProjectMember struct {
-	ID            uint   `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	ProjectID     uint   `gorm:"not null;column:project_id" json:"projectId"`
-	Name          string `gorm:"not null;size:50" json:"name"`
-	StudentNumber string `gorm:"size:30;column:student_number" json:"studentNumber"`
-	Role          string `gorm:"size:30" json:"role"`
+	ID            uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ProjectID     uint       `gorm:"not null;index;column:project_id" json:"projectId"`
+	UserID        *uint      `gorm:"index;column:user_id" json:"userId"`
+	Name          string     `gorm:"not null;size:50" json:"name"`
+	StudentNumber string     `gorm:"size:30;column:student_number" json:"studentNumber"`
+	Role          string     `gorm:"size:30" json:"role"`                                  // leader / member
+	Status        string     `gorm:"size:20;default:'active';column:status" json:"status"` // invited / active / declined
+	InvitedBy     *uint      `gorm:"column:invited_by" json:"invitedBy"`
+	JoinedAt      *time.Time `gorm:"column:joined_at" json:"joinedAt"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	// 关联关系
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Project *Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
 }
 
 func (pm *ProjectMember) TableName() string {
 	return "project_members"
 }
 
+// ProjectMemberInviteRequest 邀请团队成员请求，按用户ID或学号指定被邀请人
+type ProjectMemberInviteRequest struct {
+	UserID    uint   `json:"userId"`
+	StudentID string `json:"studentId" binding:"max=50"`
+}
+
+// ProjectLeaderTransferRequest 转让队长请求，MemberID 为接任成员的成员记录ID
+type ProjectLeaderTransferRequest struct {
+	MemberID uint `json:"memberId" binding:"required"`
+}
+
+// ProjectInvitationResponse 我收到的团队邀请
+type ProjectInvitationResponse struct {
+	ID           uint      `json:"id"` // 成员记录ID，接受/拒绝时使用
+	ProjectID    uint      `json:"projectId"`
+	ProjectTitle string    `json:"projectTitle"`
+	InvitedBy    *uint     `json:"invitedBy"`
+	InviterName  string    `json:"inviterName"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
 // ProjectFile 项目附件表
 type ProjectFile struct {
 	ID             uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
@@ -73,19 +106,29 @@ func (pf *ProjectFile) TableName() string {
 	return "project_files"
 }
 
-// ProjectReview 项目审核记录表
+// ProjectReview 项目审核记录表，同时是多级审核的审核任务
+// 多级审核：提交时按审核流程配置为每个审核人生成任务，未轮到的级别为 waiting，当前级别为 pending；
+// 同一流程配置（FlowID）的多个审核人任一处理即可，其余任务记为 cancelled。Round 为 0 的是单级审核记录
 type ProjectReview struct {
 	ID          uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	ProjectID   uint       `gorm:"not null;column:project_id" json:"projectId"`
-	ReviewerID  uint       `gorm:"not null;column:reviewer_id" json:"reviewerId"`
-	Status      string     `gorm:"type:enum('approved','rejected','archived');not null" json:"status"`
+	ProjectID   uint       `gorm:"not null;index;column:project_id" json:"projectId"`
+	ReviewerID  uint       `gorm:"not null;index;column:reviewer_id" json:"reviewerId"`
+	Status      string     `gorm:"type:enum('waiting','pending','approved','rejected','skipped','cancelled','archived');not null" json:"status"`
 	Comments    string     `gorm:"type:text" json:"comments"`
 	ReviewTime  time.Time  `gorm:"column:review_time;autoCreateTime" json:"reviewTime"`
 	IsForce     bool       `gorm:"default:false;column:is_force" json:"isForce"`
+	FlowID      *uint      `gorm:"column:review_flow_id" json:"flowId"`
+	Round       int        `gorm:"default:0;column:round" json:"round"` // 第几次提交
 	ReviewLevel int        `gorm:"column:review_level" json:"reviewLevel"`
 	ReviewOrder int        `gorm:"column:review_order" json:"reviewOrder"`
+	IsRequired  bool       `gorm:"column:is_required" json:"isRequired"`
+	CanDelegate bool       `gorm:"column:can_delegate" json:"canDelegate"`
 	Deadline    *time.Time `gorm:"column:deadline" json:"deadline"`
-	IsUrgent    bool       `gorm:"default:false;column:is_urgent" json:"isUrgent"`
+	AssignedAt  *time.Time `gorm:"column:assigned_at" json:"assignedAt"` // 轮到该任务（pending）的时间，用于统计审核时长
+	RemindedAt  *time.Time `gorm:"column:reminded_at" json:"remindedAt"`
+	EscalatedAt *time.Time `gorm:"column:escalated_at" json:"escalatedAt"`
+	ReviewedAt  *time.Time `gorm:"column:reviewed_at" json:"reviewedAt"`
+	IsUrgent    bool       `gorm:"default:false;column:is_urgent" json:"isUrgent"` // 已超期升级
 	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	UpdatedAt   time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 
@@ -121,6 +164,7 @@ type ProjectCreateRequest struct {
 	Type        string    `json:"type" `
 	Status      string    `json:"status" `
 	TeacherID   uint      `json:"teacherId" binding:"required"`
+	TypeID      *uint     `json:"typeId"`
 	Plan        string    `json:"plan"`
 	FinishedAt  time.Time `json:"finishedAt"`
 }
@@ -178,6 +222,7 @@ type ProjectDetailResponse struct {
 	Description string    `json:"description"`
 	IsApproved  bool      `json:"isApproved"`
 	Type        string    `json:"type"`
+	TypeID      *uint     `json:"typeId"`
 	Status      string    `json:"status"`
 	Plan        string    `json:"plan"`
 	CreatedAt   time.Time `json:"createdAt"`
@@ -205,6 +250,7 @@ type ProjectDetailResponse struct {
 		FileURL    string    `json:"fileUrl"`
 		UploadTime time.Time `json:"uploadTime"`
 	} `json:"files"`
+	Members []ProjectMember `json:"members"` // 团队正式成员（含队长）
 }
 
 // ExtensionApprovalRequest 教师审批延期
@@ -306,6 +352,8 @@ type ProjectQueryParams struct {
 	StudentID  uint   `form:"studentId"`
 	SortBy     string `form:"sortBy"`
 	SortOrder  string `form:"sortOrder"`
+
+	Scope *DataScope `form:"-" json:"-"` // 当前操作人的数据范围，由控制器设置
 }
 
 // ProjectExportRequest 项目导出请求
@@ -330,6 +378,7 @@ type ProjectMyListResponse struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"createdAt"`
 	Deadline    time.Time `json:"updated_at"`
+	MemberRole  string    `json:"memberRole"` // 当前用户在团队中的角色：leader / member
 }
 
 // ProjectListForTeacherResponse 教师查看的项目列表响应
@@ -350,12 +399,24 @@ type ProjectReviewResponse struct {
 	ReviewTime time.Time `json:"reviewTime"`
 }
 
-// ProjectReviewRecordResponse 审核记录响应
+// ProjectReviewRecordResponse 审核记录响应（含多级审核各级任务）
 type ProjectReviewRecordResponse struct {
-	Reviewer   string    `json:"reviewer"`
-	Status     string    `json:"status"`
-	Comments   string    `json:"comments"`
-	ReviewTime time.Time `json:"reviewTime"`
+	ID          uint       `json:"id"`
+	Reviewer    string     `json:"reviewer"`
+	Status      string     `json:"status"`
+	Comments    string     `json:"comments"`
+	ReviewTime  time.Time  `json:"reviewTime"`
+	Round       int        `json:"round"`
+	ReviewLevel int        `json:"reviewLevel"`
+	IsRequired  bool       `json:"isRequired"`
+	Deadline    *time.Time `json:"deadline"`
+	ReviewedAt  *time.Time `json:"reviewedAt"`
+}
+
+// ReviewTaskDecisionRequest 处理审核任务，skip 只适用于非必审的任务
+type ReviewTaskDecisionRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject skip"`
+	Comments string `json:"comments"`
 }
 
 // FileUploadResponse 文件上传响应
@@ -393,6 +454,7 @@ type TeacherQueryParams struct {
 	Page       int    `form:"page" binding:"omitempty,min=1"`
 	Size       int    `form:"size" binding:"omitempty,min=1,max=100"`
 	Department string `form:"department"`
+	OrgUnitID  uint   `form:"orgUnitId"` // 按组织机构节点筛选（含子孙节点）
 	Title      string `form:"title"`
 	Status     string `form:"status"`
 	SortBy     string `form:"sortBy"`
@@ -508,12 +570,13 @@ type ProjectType struct {
 	IsActive     bool      `gorm:"default:true;column:is_active" json:"isActive"`
 	Icon         string    `gorm:"size:100" json:"icon"`
 	Color        string    `gorm:"size:20" json:"color"`
+	MaxMembers   int       `gorm:"default:0;column:max_members" json:"maxMembers"` // 团队人数上限（含队长），0 表示不限
 	ProjectCount int64     `gorm:"-" json:"projectCount"`
 	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 
 	// 关联关系
-	Projects []Project     `gorm:"foreignKey:CategoryID" json:"projects,omitempty"`
+	Projects []Project     `gorm:"foreignKey:TypeID" json:"projects,omitempty"`
 	Parent   *ProjectType  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
 	Children []ProjectType `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 }
@@ -532,6 +595,7 @@ type ProjectTypeCreateRequest struct {
 	IsActive    bool   `json:"isActive"`
 	Icon        string `json:"icon" binding:"omitempty,max=100"`
 	Color       string `json:"color" binding:"omitempty,max=20"`
+	MaxMembers  int    `json:"maxMembers" binding:"omitempty,min=0"`
 }
 
 // ProjectTypeUpdateRequest 更新项目分类请求
@@ -544,6 +608,7 @@ type ProjectTypeUpdateRequest struct {
 	IsActive    *bool  `json:"isActive"`
 	Icon        string `json:"icon" binding:"omitempty,max=100"`
 	Color       string `json:"color" binding:"omitempty,max=20"`
+	MaxMembers  *int   `json:"maxMembers" binding:"omitempty,min=0"`
 }
 
 // ProjectTypeResponse 项目分类响应
@@ -560,10 +625,11 @@ type ProjectTypeResponse struct {
 // 1. 项目状态管理增强 - 新增模型
 // =============================================
 
-// ProjectStatusUpdateRequest 项目状态更新请求
+// ProjectStatusUpdateRequest 项目状态更新请求，按动作或目标状态匹配流转规则（至少填写一项）
 type ProjectStatusUpdateRequest struct {
-	Status             string `json:"status" binding:"required,oneof=draft submitted approved rejected archived in_progress completed suspended need_revision"`
-	StatusChangeReason string `json:"statusChangeReason" binding:"required"`
+	Action             string `json:"action" binding:"max=50"`
+	Status             string `json:"status" binding:"max=50"`
+	StatusChangeReason string `json:"statusChangeReason"`
 }
 
 // ProjectStatusHistoryResponse 项目状态变更历史响应
@@ -573,7 +639,9 @@ type ProjectStatusHistoryResponse struct {
 	OldStatus    string    `json:"oldStatus"`
 	NewStatus    string    `json:"newStatus"`
 	ChangeReason string    `json:"changeReason"`
-	ChangedBy    uint      `json:"changedBy"`
+	Action       string    `json:"action"`
+	IsForced     bool      `json:"isForced"`
+	ChangedBy    *uint     `json:"changedBy"`
 	ChangedAt    time.Time `json:"changedAt"`
 	OperatorName string    `json:"operatorName"`
 }
@@ -708,6 +776,7 @@ type ProjectTypeEnhancedResponse struct {
 	IsActive     bool                          `json:"isActive"`
 	Icon         string                        `json:"icon"`
 	Color        string                        `json:"color"`
+	MaxMembers   int                           `json:"maxMembers"`
 	ProjectCount int                           `json:"projectCount"`
 	Children     []ProjectTypeEnhancedResponse `json:"children,omitempty"`
 	CreatedAt    time.Time                     `json:"createdAt"`
@@ -751,10 +820,12 @@ type ReviewFlowCreateRequest struct {
 	ReviewLevel        int    `json:"reviewLevel" binding:"required,min=1,max=5"`
 	ReviewerRole       string `json:"reviewerRole" binding:"required"`
 	ReviewerDepartment string `json:"reviewerDepartment"`
+	ReviewerOrgUnitID  *uint  `json:"reviewerOrgUnitId"`
 	ReviewOrder        int    `json:"reviewOrder" binding:"required,min=1"`
-	IsRequired         bool   `json:"isRequired"`
+	IsRequired         *bool  `json:"isRequired"` // 不传时默认必审
 	DeadlineHours      int    `json:"deadlineHours" binding:"required,min=1"`
 	AutoApprove        bool   `json:"autoApprove"`
+	TimeoutApprove     bool   `json:"timeoutApprove"`
 	CanDelegate        bool   `json:"canDelegate"`
 }
 
@@ -765,10 +836,12 @@ type ReviewFlowResponse struct {
 	ReviewLevel        int       `json:"reviewLevel"`
 	ReviewerRole       string    `json:"reviewerRole"`
 	ReviewerDepartment string    `json:"reviewerDepartment"`
+	ReviewerOrgUnitID  *uint     `json:"reviewerOrgUnitId"`
 	ReviewOrder        int       `json:"reviewOrder"`
 	IsRequired         bool      `json:"isRequired"`
 	DeadlineHours      int       `json:"deadlineHours"`
 	AutoApprove        bool      `json:"autoApprove"`
+	TimeoutApprove     bool      `json:"timeoutApprove"`
 	CanDelegate        bool      `json:"canDelegate"`
 	CreatedAt          time.Time `json:"createdAt"`
 	UpdatedAt          time.Time `json:"updatedAt"`
@@ -804,13 +877,45 @@ type ReviewTaskQueryParams struct {
 	SortOrder string `form:"sortOrder"`
 }
 
+// ReviewSLAQueryParams 审核时效统计查询参数，日期按任务分配时间（yyyy-MM-dd）筛选
+type ReviewSLAQueryParams struct {
+	StartDate     string `form:"startDate"`
+	EndDate       string `form:"endDate"`
+	ProjectTypeID *uint  `form:"projectTypeId"`
+}
+
+// ReviewSLAStats 审核时效统计，平均时长为已处理任务从分配到处理的小时数
+type ReviewSLAStats struct {
+	ReviewerID   uint    `json:"reviewerId,omitempty"`
+	ReviewerName string  `json:"reviewerName,omitempty"`
+	ReviewLevel  int     `json:"reviewLevel,omitempty"`
+	Assigned     int64   `json:"assigned"`
+	Decided      int64   `json:"decided"`
+	Pending      int64   `json:"pending"`
+	Overdue      int64   `json:"overdue"` // 超过截止时间才处理或仍未处理
+	Escalated    int64   `json:"escalated"`
+	AvgHours     float64 `json:"avgHours"`
+	MaxHours     float64 `json:"maxHours"`
+	OnTimeRate   float64 `json:"onTimeRate"` // 按时处理的比例（%）
+}
+
+// ReviewSLAReport 审核时效报表
+type ReviewSLAReport struct {
+	Overall   ReviewSLAStats   `json:"overall"`
+	Reviewers []ReviewSLAStats `json:"reviewers"`
+	Levels    []ReviewSLAStats `json:"levels"`
+}
+
 // ReviewTaskResponse 审核任务响应
 type ReviewTaskResponse struct {
 	ID           uint       `json:"id"`
 	ProjectID    uint       `json:"projectId"`
 	ProjectTitle string     `json:"projectTitle"`
+	Round        int        `json:"round"`
 	ReviewLevel  int        `json:"reviewLevel"`
 	ReviewOrder  int        `json:"reviewOrder"`
+	IsRequired   bool       `json:"isRequired"`
+	CanDelegate  bool       `json:"canDelegate"`
 	Deadline     *time.Time `json:"deadline"`
 	IsUrgent     bool       `json:"isUrgent"`
 	Status       string     `json:"status"`
@@ -932,7 +1037,9 @@ type ProjectStatusHistory struct {
 	OldStatus    string    `gorm:"size:50;not null;column:old_status" json:"oldStatus"`
 	NewStatus    string    `gorm:"size:50;not null;column:new_status" json:"newStatus"`
 	ChangeReason string    `gorm:"type:text;not null;column:change_reason" json:"changeReason"`
-	ChangedBy    uint      `gorm:"not null;column:changed_by" json:"changedBy"`
+	Action       string    `gorm:"size:50;column:action" json:"action"`            // 触发的流转动作，强制修改为 force，学年升级为 rollover / rollover_undo
+	IsForced     bool      `gorm:"default:false;column:is_forced" json:"isForced"` // 未经流转规则校验的修改（管理员强制修改、学年升级）
+	ChangedBy    *uint     `gorm:"column:changed_by" json:"changedBy"`             // 系统自动执行时为空
 	ChangedAt    time.Time `gorm:"column:changed_at;autoCreateTime" json:"changedAt"`
 
 	// 关联关系
@@ -1038,17 +1145,23 @@ func (pts *ProjectTypeStats) TableName() string {
 // 审核流程增强模型
 // =============================================
 
-// ProjectReviewFlow 项目审核流程配置表
+// ProjectReviewFlow 项目审核流程配置表，项目提交后按 ReviewLevel 逐级审核，同一级的多条配置须全部通过
+// ReviewerRole 为 advisor 时由项目指导教师审核；未指定院系和组织机构时，优先由项目负责人所在院系的该角色用户审核
+// AutoApprove 的配置轮到时直接通过；审核人超过 DeadlineHours 未处理时，TimeoutApprove 的配置自动通过，
+// 否则升级给同级下一个配置的审核人或院系管理员
+// IsRequired 为 false 的配置可由审核人跳过，且不阻塞同级必审配置
 type ProjectReviewFlow struct {
 	ID                 uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
 	ProjectTypeID      uint      `gorm:"not null;column:project_type_id" json:"projectTypeId"`
 	ReviewLevel        int       `gorm:"not null;column:review_level" json:"reviewLevel"`
 	ReviewerRole       string    `gorm:"size:50;not null;column:reviewer_role" json:"reviewerRole"`
 	ReviewerDepartment string    `gorm:"size:100;column:reviewer_department" json:"reviewerDepartment"`
+	ReviewerOrgUnitID  *uint     `gorm:"column:reviewer_org_unit_id" json:"reviewerOrgUnitId"` // 审核人所属组织机构节点（含下级节点）
 	ReviewOrder        int       `gorm:"not null;column:review_order" json:"reviewOrder"`
-	IsRequired         bool      `gorm:"default:true;column:is_required" json:"isRequired"`
+	IsRequired         bool      `gorm:"column:is_required" json:"isRequired"` // 不设默认值，创建时的 false 才能写入
 	DeadlineHours      int       `gorm:"default:72;column:deadline_hours" json:"deadlineHours"`
 	AutoApprove        bool      `gorm:"default:false;column:auto_approve" json:"autoApprove"`
+	TimeoutApprove     bool      `gorm:"default:false;column:timeout_approve" json:"timeoutApprove"`
 	CanDelegate        bool      `gorm:"default:false;column:can_delegate" json:"canDelegate"`
 	CreatedAt          time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	UpdatedAt          time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
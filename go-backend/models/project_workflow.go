@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// ProjectWorkflowRoles 流转规则中表示项目内身份的角色，其余角色按用户的系统角色（如 admin）匹配
+var ProjectWorkflowRoles = []string{"leader", "member", "advisor"}
+
+// ProjectWorkflowFields 流转前可要求填写的项目字段，progress 表示进度达到 100%
+var ProjectWorkflowFields = []string{"title", "description", "plan", "teacher_id", "type_id", "finish_time", "progress"}
+
+// ProjectWorkflowSideEffects 流转成功后执行的附加动作
+//   - mark_submitted：记录提交时间
+//   - mark_approved：记录审批人和审批时间并标记为已立项
+//   - mark_rejected：把流转原因写入驳回原因
+//   - notify_team / notify_teacher：给团队成员 / 指导教师发送项目通知
+//   - create_milestone：按 MilestoneTitle、MilestoneDueDays 创建里程碑
+var ProjectWorkflowSideEffects = []string{"mark_submitted", "mark_approved", "mark_rejected", "notify_team", "notify_teacher", "create_milestone"}
+
+// ProjectWorkflowState 项目状态定义，ProjectTypeID 为空的是默认流程
+// 项目分类未单独配置流程时沿用上级分类的流程，都未配置则使用默认流程
+type ProjectWorkflowState struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ProjectTypeID *uint     `gorm:"index;column:project_type_id" json:"projectTypeId"`
+	StateKey      string    `gorm:"size:50;not null;column:state_key" json:"stateKey"`
+	Name          string    `gorm:"size:50;not null;column:name" json:"name"`
+	IsInitial     bool      `gorm:"default:false;column:is_initial" json:"isInitial"` // 新建项目的状态，每个流程有且只有一个
+	IsFinal       bool      `gorm:"default:false;column:is_final" json:"isFinal"`     // 终态，不能再发起流转
+	SortOrder     int       `gorm:"default:0;column:sort_order" json:"sortOrder"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (s *ProjectWorkflowState) TableName() string {
+	return "project_workflow_states"
+}
+
+// ProjectWorkflowTransition 项目状态流转规则，同一动作可从多个状态发起（每个起始状态一条记录）
+type ProjectWorkflowTransition struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ProjectTypeID     *uint     `gorm:"index;column:project_type_id" json:"projectTypeId"`
+	Action            string    `gorm:"size:50;not null;column:action" json:"action"` // 动作标识，如 submit / approve
+	Name              string    `gorm:"size:50;not null;column:name" json:"name"`
+	FromStatus        string    `gorm:"size:50;not null;column:from_status" json:"fromStatus"`
+	ToStatus          string    `gorm:"size:50;not null;column:to_status" json:"toStatus"`
+	AllowedRoles      JSONArray `gorm:"type:json;column:allowed_roles" json:"allowedRoles"`
+	RequiredFields    JSONArray `gorm:"type:json;column:required_fields" json:"requiredFields"`
+	RequiredFileTypes JSONArray `gorm:"type:json;column:required_file_types" json:"requiredFileTypes"` // 须已上传且未被驳回的文件类型
+	RequireReason     bool      `gorm:"default:false;column:require_reason" json:"requireReason"`
+	SideEffects       JSONArray `gorm:"type:json;column:side_effects" json:"sideEffects"`
+	MilestoneTitle    string    `gorm:"size:200;column:milestone_title" json:"milestoneTitle"`
+	MilestoneDueDays  int       `gorm:"default:0;column:milestone_due_days" json:"milestoneDueDays"`
+	SortOrder         int       `gorm:"default:0;column:sort_order" json:"sortOrder"`
+	CreatedAt         time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt         time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (t *ProjectWorkflowTransition) TableName() string {
+	return "project_workflow_transitions"
+}
+
+// ProjectWorkflowResponse 项目分类实际生效的状态流转配置
+type ProjectWorkflowResponse struct {
+	ProjectTypeID *uint                       `json:"projectTypeId"` // 请求的分类，为空表示默认流程
+	SourceTypeID  *uint                       `json:"sourceTypeId"`  // 配置实际所属的分类，为空表示使用默认流程
+	Inherited     bool                        `json:"inherited"`     // 是否沿用上级分类或默认流程
+	States        []ProjectWorkflowState      `json:"states"`
+	Transitions   []ProjectWorkflowTransition `json:"transitions"`
+}
+
+// ProjectWorkflowStateRequest 状态定义
+type ProjectWorkflowStateRequest struct {
+	StateKey  string `json:"stateKey" binding:"required,max=50"`
+	Name      string `json:"name" binding:"required,max=50"`
+	IsInitial bool   `json:"isInitial"`
+	IsFinal   bool   `json:"isFinal"`
+	SortOrder int    `json:"sortOrder"`
+}
+
+// ProjectWorkflowTransitionRequest 流转规则定义
+type ProjectWorkflowTransitionRequest struct {
+	Action            string   `json:"action" binding:"required,max=50"`
+	Name              string   `json:"name" binding:"required,max=50"`
+	FromStatus        string   `json:"fromStatus" binding:"required,max=50"`
+	ToStatus          string   `json:"toStatus" binding:"required,max=50"`
+	AllowedRoles      []string `json:"allowedRoles" binding:"required,min=1"`
+	RequiredFields    []string `json:"requiredFields"`
+	RequiredFileTypes []string `json:"requiredFileTypes"`
+	RequireReason     bool     `json:"requireReason"`
+	SideEffects       []string `json:"sideEffects"`
+	MilestoneTitle    string   `json:"milestoneTitle" binding:"max=200"`
+	MilestoneDueDays  int      `json:"milestoneDueDays" binding:"min=0"`
+	SortOrder         int      `json:"sortOrder"`
+}
+
+// ProjectWorkflowSaveRequest 保存项目分类的状态流转配置，整体替换该分类原有配置
+type ProjectWorkflowSaveRequest struct {
+	States      []ProjectWorkflowStateRequest      `json:"states" binding:"required,min=1,dive"`
+	Transitions []ProjectWorkflowTransitionRequest `json:"transitions" binding:"required,min=1,dive"`
+}
+
+// ProjectAvailableTransition 当前用户可对项目发起的流转
+type ProjectAvailableTransition struct {
+	Action        string   `json:"action"`
+	Name          string   `json:"name"`
+	ToStatus      string   `json:"toStatus"`
+	ToStatusName  string   `json:"toStatusName"`
+	RequireReason bool     `json:"requireReason"`
+	Missing       []string `json:"missing,omitempty"` // 尚未满足的字段/文件要求，为空才能发起
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// AcademicRollover 学年升级记录，Snapshot 保存撤销所需的变更明细（RolloverReport 的JSON）
+type AcademicRollover struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	AcademicYear    string     `gorm:"size:20;not null;index;column:academic_year" json:"academicYear"`
+	Department      string     `gorm:"size:100;column:department" json:"department"` // 为空表示全校
+	TriggerType     string     `gorm:"type:enum('schedule','manual');default:'manual';column:trigger_type" json:"triggerType"`
+	Status          string     `gorm:"type:enum('applied','undone');default:'applied';column:status" json:"status"`
+	Rules           string     `gorm:"type:text;column:rules" json:"rules"` // 本次使用的规则（JSON）
+	Promoted        int        `gorm:"default:0;column:promoted" json:"promoted"`
+	Graduated       int        `gorm:"default:0;column:graduated" json:"graduated"`
+	Skipped         int        `gorm:"default:0;column:skipped" json:"skipped"`
+	ProjectsClosed  int        `gorm:"default:0;column:projects_closed" json:"projectsClosed"`
+	BindingsRemoved int        `gorm:"default:0;column:bindings_removed" json:"bindingsRemoved"`
+	Snapshot        string     `gorm:"type:longtext;column:snapshot" json:"-"`
+	OperatorID      *uint      `gorm:"column:operator_id" json:"operatorId"`
+	AppliedAt       time.Time  `gorm:"column:applied_at" json:"appliedAt"`
+	UndoDeadline    time.Time  `gorm:"column:undo_deadline" json:"undoDeadline"`
+	UndoneAt        *time.Time `gorm:"column:undone_at" json:"undoneAt"`
+	UndoneBy        *uint      `gorm:"column:undone_by" json:"undoneBy"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (r *AcademicRollover) TableName() string {
+	return "academic_rollovers"
+}
+
+// RolloverRules 学年升级规则，未填写的项使用系统设置中的默认值
+type RolloverRules struct {
+	AcademicYear   string   `json:"academicYear"`                                              // 如 2026-2027，默认按当前日期计算
+	Department     string   `json:"department"`                                                // 只处理该院系的学生，为空表示全校
+	GraduateGrades []string `json:"graduateGrades"`                                            // 处于这些年级的学生毕业
+	GraduateStatus string   `json:"graduateStatus" binding:"omitempty,oneof=inactive alumni"`  // 毕业生账号状态
+	ProjectAction  string   `json:"projectAction" binding:"omitempty,oneof=close reject keep"` // 毕业生未结束项目的处理方式
+	BindingAction  string   `json:"bindingAction" binding:"omitempty,oneof=remove keep"`       // 毕业生师生绑定的处理方式
+}
+
+// RolloverStudentItem 学生年级/状态变更
+type RolloverStudentItem struct {
+	UserID    uint   `json:"userId"`
+	Username  string `json:"username"`
+	OldGrade  string `json:"oldGrade"`
+	NewGrade  string `json:"newGrade"`
+	OldStatus string `json:"oldStatus"`
+	NewStatus string `json:"newStatus"`
+	Graduate  bool   `json:"graduate"`
+}
+
+// RolloverProjectItem 毕业生项目状态变更
+type RolloverProjectItem struct {
+	ProjectID          uint   `json:"projectId"`
+	Title              string `json:"title"`
+	StudentID          uint   `json:"studentId"`
+	OldStatus          string `json:"oldStatus"`
+	NewStatus          string `json:"newStatus"`
+	OldRejectionReason string `json:"oldRejectionReason,omitempty"`
+}
+
+// RolloverSkippedItem 未处理的学生（年级为空或不在年级设置中）
+type RolloverSkippedItem struct {
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+	Grade    string `json:"grade"`
+	Reason   string `json:"reason"`
+}
+
+// RolloverReport 学年升级预览/执行报告
+type RolloverReport struct {
+	RolloverID   uint                  `json:"rolloverId,omitempty"`
+	AcademicYear string                `json:"academicYear"`
+	Rules        RolloverRules         `json:"rules"`
+	GradeChanges map[string]int        `json:"gradeChanges"` // "大一 -> 大二": 人数
+	Students     []RolloverStudentItem `json:"students"`
+	Projects     []RolloverProjectItem `json:"projects"`
+	Bindings     []StudentTeacher      `json:"bindings"` // 将解除的师生绑定
+	Skipped      []RolloverSkippedItem `json:"skipped"`
+	UndoDeadline *time.Time            `json:"undoDeadline,omitempty"`
+}
+
+// RolloverUndoResult 撤销结果，撤销后又被修改过的记录保持现状并计入 Conflicts
+type RolloverUndoResult struct {
+	Students  int      `json:"students"`
+	Projects  int      `json:"projects"`
+	Bindings  int      `json:"bindings"`
+	Conflicts []string `json:"conflicts"`
+}
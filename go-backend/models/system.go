@@ -8,17 +8,26 @@ import (
 
 // SystemLog 系统日志表
 type SystemLog struct {
-	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	LogType    string     `json:"log_type" gorm:"type:enum('info','warning','error','debug','security');default:info;comment:日志类型"`
-	Operation  string     `json:"operation" gorm:"type:varchar(100);comment:操作名称"`
-	Status     string     `json:"status" gorm:"type:enum('success','failed','pending');default:success;comment:执行状态"`
-	UserID     *uint      `json:"user_id" gorm:"comment:操作用户ID"`
-	Action     string     `json:"action" gorm:"type:varchar(255);not null;comment:执行动作"`
-	Details    string     `json:"details" gorm:"type:text;comment:详细内容"`
-	IPAddress  string     `json:"ip_address" gorm:"type:varchar(50);comment:IP地址"`
-	UserAgent  string     `json:"user_agent" gorm:"type:text;comment:用户代理"`
-	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
-	ExpireTime *time.Time `json:"expire_time" gorm:"comment:日志过期时间"`
+	ID         uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	LogType    string `json:"log_type" gorm:"type:enum('info','warning','error','debug','security','audit');default:info;comment:日志类型"`
+	Operation  string `json:"operation" gorm:"type:varchar(100);comment:操作名称"`
+	Status     string `json:"status" gorm:"type:enum('success','failed','pending');default:success;comment:执行状态"`
+	UserID     *uint  `json:"user_id" gorm:"comment:操作用户ID"`
+	Action     string `json:"action" gorm:"type:varchar(255);not null;comment:执行动作"`
+	Details    string `json:"details" gorm:"type:text;comment:详细内容"`
+	IPAddress  string `json:"ip_address" gorm:"type:varchar(50);comment:IP地址"`
+	UserAgent  string `json:"user_agent" gorm:"type:text;comment:用户代理"`
+	Role       string `json:"role" gorm:"type:varchar(30);comment:操作时的角色"`
+	Method     string `json:"method" gorm:"type:varchar(10);comment:请求方法"`
+	Route      string `json:"route" gorm:"type:varchar(255);comment:路由模板"`
+	EntityType string `json:"entity_type" gorm:"type:varchar(50);index:idx_system_logs_entity;comment:实体类型"`
+	EntityID   string `json:"entity_id" gorm:"type:varchar(64);index:idx_system_logs_entity;comment:实体ID"`
+	StatusCode int    `json:"status_code" gorm:"comment:HTTP状态码"`
+	Changes    string `json:"changes" gorm:"type:text;comment:字段变更(JSON)"`
+	// ImpersonatorID 模拟登录期间的请求记录发起模拟的管理员，UserID 为被模拟用户
+	ImpersonatorID *uint      `json:"impersonator_id" gorm:"index;comment:模拟登录管理员ID"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	ExpireTime     *time.Time `json:"expire_time" gorm:"comment:日志过期时间"`
 
 	// 关联关系
 	User *User `json:"user" gorm:"foreignKey:UserID"`
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User 用户基础信息表
@@ -13,16 +15,26 @@ type User struct {
 	Username   string    `gorm:"unique;not null;size:50" json:"username"`
 	Password   string    `gorm:"not null;size:100" json:"-"` // 密码不返回前端
 	Email      string    `gorm:"unique;not null;size:100" json:"email"`
-	Status     string    `gorm:"type:enum('active','inactive');default:'active'" json:"status"`
+	Status     string    `gorm:"type:enum('active','inactive','alumni');default:'active'" json:"status"` // alumni 为毕业校友，不能登录
 	Department string    `gorm:"size:100;column:department" json:"department"`
 	Title      string    `gorm:"size:50;column:title" json:"title"`
 	Grade      string    `gorm:"size:20;column:grade" json:"grade"`
 	Major      string    `gorm:"size:100;column:major" json:"major"`
+	OrgUnitID  *uint     `gorm:"index;column:org_unit_id" json:"orgUnitId"` // 所属组织机构节点（最具体的一级），设置后 Department/Major 按节点同步
 	CreateTime time.Time `gorm:"column:create_time;autoCreateTime" json:"createTime"`
 	UpdateTime time.Time `gorm:"column:update_time;autoUpdateTime" json:"updateTime"`
 	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 	RoleName   string    `gorm:"not null;size:50;column:role_name" json:"roleName"`
+	// MustChangePassword 管理员重置或新建账号后必须先修改密码；PasswordChangedAt 用于计算密码有效期
+	MustChangePassword bool       `gorm:"column:must_change_password;default:false" json:"mustChangePassword"`
+	PasswordChangedAt  *time.Time `gorm:"column:password_changed_at" json:"passwordChangedAt"`
+	// 软删除：删除后进入回收站，DeletedRoleIDs 保存删除前的角色ID（JSON）用于恢复
+	DeletedAt      gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deletedAt,omitempty"`
+	DeletedBy      *uint          `gorm:"column:deleted_by" json:"deletedBy,omitempty"`
+	DeletedRoleIDs string         `gorm:"type:text;column:deleted_role_ids" json:"-"`
+	// AnonymizedAt 个人信息已清除的时间，账号仅保留院系等统计字段
+	AnonymizedAt *time.Time `gorm:"column:anonymized_at" json:"anonymizedAt,omitempty"`
 	// 关联关系
 	Profile   *UserProfile `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"profile,omitempty"`
 	Roles     []Role       `gorm:"many2many:user_roles;" json:"roles,omitempty"`
@@ -33,6 +45,24 @@ func (u *User) TableName() string {
 	return "users"
 }
 
+// PasswordHistory 历史密码（用于禁止重复使用最近的密码）
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	UserID       uint      `gorm:"not null;index;column:user_id" json:"userId"`
+	PasswordHash string    `gorm:"size:100;not null;column:password_hash" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (ph *PasswordHistory) TableName() string {
+	return "password_histories"
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
 // UserProfile 用户扩展信息表
 type UserProfile struct {
 	UserID     uint       `gorm:"primaryKey;column:user_id" json:"userId"`
@@ -56,9 +86,12 @@ type Role struct {
 	RoleKey     string `gorm:"unique;not null;size:30;column:role_key" json:"roleKey"`
 	RoleName    string `gorm:"not null;size:50;column:role_name" json:"roleName"`
 	Description string `gorm:"type:text" json:"description"`
+	IsSystem    bool   `gorm:"default:false;column:is_system" json:"isSystem"`                                        // 内置角色不可删除
+	DataScope   string `gorm:"type:enum('all','department');default:'department';column:data_scope" json:"dataScope"` // 默认仅本院系，全局范围须显式设置
 
 	// 关联关系
-	Users []User `gorm:"many2many:user_roles;" json:"users,omitempty"`
+	Users       []User       `gorm:"many2many:user_roles;" json:"users,omitempty"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
 }
 
 func (r *Role) TableName() string {
@@ -79,6 +112,7 @@ func (ur *UserRole) TableName() string {
 type LoginLog struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
 	UserID    uint      `gorm:"not null;column:user_id" json:"userId"`
+	SessionID *uint     `gorm:"index;column:session_id" json:"sessionId"`
 	LoginTime time.Time `gorm:"column:login_time;autoCreateTime" json:"loginTime"`
 	IPAddress string    `gorm:"size:50;column:ip_address" json:"ipAddress"`
 	UserAgent string    `gorm:"type:text;column:user_agent" json:"userAgent"`
@@ -125,7 +159,7 @@ type UserWithRoles struct {
 // UserCreateRequest 创建用户请求
 type UserCreateRequest struct {
 	Username   string   `json:"username" binding:"required,min=3,max=20"`
-	Password   string   `json:"password" binding:"required,min=6,max=20"`
+	Password   string   `json:"password" binding:"required,min=6,max=72"` // 复杂度按密码规则校验
 	Email      string   `json:"email" binding:"required,email"`
 	RealName   string   `json:"realName" binding:"required"`
 	Phone      string   `json:"phone"`
@@ -134,6 +168,7 @@ type UserCreateRequest struct {
 	Grade      string   `json:"grade"`
 	Major      string   `json:"major"`
 	StudentID  string   `json:"studentId"`
+	OrgUnitID  *uint    `json:"orgUnitId"` // 设置后按节点填写院系和专业
 	RoleKeys   []string `json:"roleKeys" binding:"required"`
 }
 
@@ -147,6 +182,7 @@ type UserUpdateRequest struct {
 	Grade      string   `json:"grade"`
 	Major      string   `json:"major"`
 	StudentID  string   `json:"studentId"`
+	OrgUnitID  *uint    `json:"orgUnitId"` // 设置后按节点填写院系和专业
 	RoleKeys   []string `json:"roleKeys"`
 }
 
@@ -163,6 +199,7 @@ type UserListResponse struct {
 	Grade      string     `json:"grade"`
 	Major      string     `json:"major"`
 	StudentID  string     `json:"studentId"`
+	OrgUnitID  *uint      `json:"orgUnitId"`
 	RoleNames  []string   `json:"roleNames"`
 	CreateTime time.Time  `json:"createTime"`
 	CreatedAt  time.Time  `json:"createdAt"`
@@ -193,6 +230,7 @@ type UserDetailResponse struct {
 		RoleName    string `json:"roleName"`
 		Description string `json:"description"`
 	} `json:"roles"`
+	ActiveSessions int64 `json:"activeSessions"` // 当前有效登录会话数
 }
 
 // UserStats 用户统计信息
@@ -202,6 +240,7 @@ type UserStats struct {
 	InactiveUsers   int64            `json:"inactiveUsers"`
 	RoleStats       map[string]int64 `json:"roleStats"`
 	DepartmentStats map[string]int64 `json:"departmentStats"`
+	OrgUnitStats    []OrgUnitStat    `json:"orgUnitStats"` // 所选节点（默认顶级节点）下一级各节点的用户数（含子孙节点）
 }
 
 // UserQueryParams 用户查询参数
@@ -212,6 +251,125 @@ type UserQueryParams struct {
 	Role       string `form:"role"`
 	Status     string `form:"status"`
 	Department string `form:"department"`
+	OrgUnitID  uint   `form:"orgUnitId"` // 按组织机构节点筛选（含子孙节点）
 	SortBy     string `form:"sortBy"`
 	SortOrder  string `form:"sortOrder"`
+
+	Scope *DataScope `form:"-" json:"-"` // 当前操作人的数据范围，由控制器设置
+}
+
+// LDAPSyncRun LDAP目录同步记录（含试运行），Report 为差异明细 []LDAPSyncItem 的JSON
+type LDAPSyncRun struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TriggerType  string     `gorm:"type:enum('schedule','manual');default:'manual';column:trigger_type" json:"triggerType"`
+	DryRun       bool       `gorm:"default:false;column:dry_run" json:"dryRun"`
+	Status       string     `gorm:"type:enum('running','success','partial','failed');default:'running';column:status" json:"status"`
+	Total        int        `gorm:"default:0;column:total" json:"total"`
+	Created      int        `gorm:"default:0;column:created" json:"created"`
+	Updated      int        `gorm:"default:0;column:updated" json:"updated"`
+	Linked       int        `gorm:"default:0;column:linked" json:"linked"`
+	Deactivated  int        `gorm:"default:0;column:deactivated" json:"deactivated"`
+	Unchanged    int        `gorm:"default:0;column:unchanged" json:"unchanged"`
+	Conflicts    int        `gorm:"default:0;column:conflicts" json:"conflicts"`
+	Skipped      int        `gorm:"default:0;column:skipped" json:"skipped"`
+	Failed       int        `gorm:"default:0;column:failed" json:"failed"`
+	Report       string     `gorm:"type:longtext;column:report" json:"-"`
+	ErrorMessage string     `gorm:"type:text;column:error_message" json:"errorMessage"`
+	OperatorID   *uint      `gorm:"column:operator_id" json:"operatorId"`
+	StartedAt    time.Time  `gorm:"column:started_at" json:"startedAt"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finishedAt"`
+
+	// Items 差异明细（仅详情接口返回）
+	Items []LDAPSyncItem `gorm:"-" json:"items,omitempty"`
+}
+
+func (r *LDAPSyncRun) TableName() string {
+	return "ldap_sync_runs"
+}
+
+// LDAPSyncItem 单个目录账号的同步差异
+type LDAPSyncItem struct {
+	Action   string               `json:"action"` // create、update、link、deactivate、conflict、skip
+	DN       string               `json:"dn,omitempty"`
+	Subject  string               `json:"subject,omitempty"`
+	Username string               `json:"username"`
+	UserID   uint                 `json:"userId,omitempty"`
+	Changes  map[string][2]string `json:"changes,omitempty"` // 字段 -> [原值, 新值]
+	Message  string               `json:"message,omitempty"`
+	Error    string               `json:"error,omitempty"` // 执行失败原因
+}
+
+// LDAPSyncRequest 手动触发目录同步
+type LDAPSyncRequest struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// UserImportOptions 批量导入选项（multipart 表单字段）
+type UserImportOptions struct {
+	DryRun      bool   `form:"dryRun"`      // 只校验不写入
+	SkipInvalid bool   `form:"skipInvalid"` // 跳过校验失败的行，否则存在错误时整批不导入
+	Notify      bool   `form:"notify"`      // 导入成功后发送开通邮件（含设置密码链接）
+	DefaultRole string `form:"defaultRole"` // 角色列为空时使用的角色
+
+	Scope *DataScope `form:"-" json:"-"` // 当前操作人的数据范围，由控制器设置
+}
+
+// UserImportRowError 导入行校验或写入错误，Row 为表格中的行号（表头为第1行）
+type UserImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// UserImportCreated 导入成功的用户
+type UserImportCreated struct {
+	Row      int    `json:"row"`
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+}
+
+// UserImportReport 批量导入报告
+type UserImportReport struct {
+	DryRun   bool                 `json:"dryRun"`
+	Total    int                  `json:"total"`    // 数据行数
+	Valid    int                  `json:"valid"`    // 校验通过的行数
+	Invalid  int                  `json:"invalid"`  // 校验失败的行数
+	Created  int                  `json:"created"`  // 实际创建的用户数
+	Failed   int                  `json:"failed"`   // 写入失败的行数（所在批次整体回滚）
+	Chunks   int                  `json:"chunks"`   // 提交批次数
+	Notified int                  `json:"notified"` // 已加入发送队列的开通邮件数
+	Errors   []UserImportRowError `json:"errors"`
+	Users    []UserImportCreated  `json:"users,omitempty"`
+}
+
+// RecycledUserResponse 回收站中的用户
+type RecycledUserResponse struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Status        string    `json:"status"`
+	RealName      string    `json:"realName"`
+	Department    string    `json:"department"`
+	RoleNames     []string  `json:"roleNames"` // 删除前的角色，恢复时重新分配
+	DeletedAt     time.Time `json:"deletedAt"`
+	DeletedBy     *uint     `json:"deletedBy"`
+	DeletedByName string    `json:"deletedByName"`
+	PurgeAt       time.Time `json:"purgeAt"` // 超过保留期后由定时任务永久删除
+}
+
+// UserReference 引用该用户的业务数据
+type UserReference struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Label  string `json:"label"`
+	Count  int64  `json:"count"`
+}
+
+// UserReferenceReport 永久删除前的引用检查结果，Total 大于0时永久删除会使这些数据失去关联或被级联删除
+type UserReferenceReport struct {
+	UserID     uint            `json:"userId"`
+	Username   string          `json:"username"`
+	Total      int64           `json:"total"`
+	References []UserReference `json:"references"`
 }
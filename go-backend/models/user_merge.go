@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// UserMerge 账号合并审计记录，Details 为各数据表迁移的记录数（map[表.列]数量 的JSON）
+type UserMerge struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	SourceUserID   uint      `gorm:"not null;index;column:source_user_id" json:"sourceUserId"` // 被合并（移入回收站）的账号
+	SourceUsername string    `gorm:"size:50;column:source_username" json:"sourceUsername"`
+	TargetUserID   uint      `gorm:"not null;index;column:target_user_id" json:"targetUserId"` // 保留的账号
+	TargetUsername string    `gorm:"size:50;column:target_username" json:"targetUsername"`
+	Reason         string    `gorm:"size:255;column:reason" json:"reason"`
+	Details        string    `gorm:"type:text;column:details" json:"details"`
+	OperatorID     uint      `gorm:"column:operator_id" json:"operatorId"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (m *UserMerge) TableName() string {
+	return "user_merges"
+}
+
+// DuplicateUser 疑似重复账号中的一个用户
+type DuplicateUser struct {
+	ID         uint       `json:"id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email"`
+	Status     string     `json:"status"`
+	RealName   string     `json:"realName"`
+	StudentID  string     `json:"studentId"`
+	Department string     `json:"department"`
+	RoleNames  []string   `json:"roleNames"`
+	Projects   int64      `json:"projects"` // 作为学生的项目数，帮助判断保留哪个账号
+	LastLogin  *time.Time `json:"lastLogin"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// DuplicateGroup 一组疑似重复账号
+type DuplicateGroup struct {
+	Reason     string          `json:"reason"`     // email / student_id / real_name
+	Value      string          `json:"value"`      // 规范化后的匹配值
+	Confidence string          `json:"confidence"` // high / medium / low
+	Users      []DuplicateUser `json:"users"`
+}
+
+// UserMergeRequest 合并账号请求
+type UserMergeRequest struct {
+	SourceID             uint   `json:"sourceId" binding:"required"`
+	TargetID             uint   `json:"targetId" binding:"required"`
+	Reason               string `json:"reason" binding:"max=255"`
+	DryRun               bool   `json:"dryRun"`
+	GrantPrivilegedRoles bool   `json:"grantPrivilegedRoles"` // 是否把被合并账号的管理类角色（学生、教师以外）授予保留账号，默认不授予
+}
+
+// UserMergeResult 合并结果，DryRun 时为预计迁移的记录数
+type UserMergeResult struct {
+	DryRun       bool             `json:"dryRun"`
+	MergeID      uint             `json:"mergeId,omitempty"`
+	Moved        map[string]int64 `json:"moved"`        // 表.列 → 迁移记录数
+	Removed      map[string]int64 `json:"removed"`      // 合并后重复的记录（如相同的师生绑定）被删除的数量
+	Roles        []string         `json:"roles"`        // 补充给保留账号的角色
+	DroppedRoles []string         `json:"droppedRoles"` // 未设置 grantPrivilegedRoles 时没有授予保留账号的管理类角色
+	Profile      []string         `json:"profile"`      // 从被合并账号补全的档案字段
+}
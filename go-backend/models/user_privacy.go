@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// UserDataExport 个人数据导出内容（离校学生申请查阅本人数据时使用），Files 为附件清单
+type UserDataExport struct {
+	ExportedAt    time.Time                 `json:"exportedAt"`
+	User          User                      `json:"user"`
+	LoginLogs     []LoginLog                `json:"loginLogs"`
+	LoginAttempts []LoginAttempt            `json:"loginAttempts"`
+	Sessions      []UserSession             `json:"sessions"`
+	Identities    []UserIdentity            `json:"identities"`
+	Projects      []Project                 `json:"projects"`
+	ProjectFiles  []ProjectFile             `json:"projectFiles"`
+	Registrations []CompetitionRegistration `json:"registrations"`
+	Submissions   []CompetitionSubmission   `json:"submissions"`
+	Feedback      []CompetitionFeedback     `json:"feedback"`
+	Scores        []CompetitionScore        `json:"scores"` // 本人作品获得的评分
+	Results       []CompetitionResult       `json:"results"`
+	Notifications []ProjectNotification     `json:"notifications"`
+	Files         []UserDataFile            `json:"files"`
+}
+
+// UserDataFile 导出的附件，ZIP 格式时 ArchivePath 为压缩包内的路径，文件缺失时为空
+type UserDataFile struct {
+	Source      string `json:"source"` // project_file / competition_submission
+	RecordID    uint   `json:"recordId"`
+	FileName    string `json:"fileName"`
+	FileURL     string `json:"fileUrl"`
+	ArchivePath string `json:"archivePath,omitempty"`
+}
+
+// UserAnonymizeRequest 匿名化请求，Confirm 须填写该用户的用户名
+type UserAnonymizeRequest struct {
+	Confirm string `json:"confirm" binding:"required"`
+	Reason  string `json:"reason" binding:"max=255"`
+}
+
+// UserAnonymizeResult 匿名化结果
+type UserAnonymizeResult struct {
+	UserID   uint             `json:"userId"`
+	Username string           `json:"username"` // 匿名化后的用户名
+	Cleared  map[string]int64 `json:"cleared"`  // 各数据表清除或删除的记录数
+}
@@ -10,6 +10,17 @@ import (
 )
 
 func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
+	authService := services.NewAuthService(db)
+	authController := controllers.NewAuthController(db, authService)
+	permissionService := services.NewPermissionService(db)
+	personalTokenService := services.NewPersonalTokenService(db)
+	requirePermission := func(permissions ...string) gin.HandlerFunc {
+		return middlewares.RequirePermission(permissionService, permissions...)
+	}
+
+	// JWKS公钥发布（无需认证，标准路径）
+	r.GET("/.well-known/jwks.json", authController.GetJWKS)
+
 	api := r.Group("/api")
 	{
 		// 健康检查路由（无需认证）
@@ -23,33 +34,123 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 		// 登录路由（无需认证）
 		api.POST("/login", controllers.LoginHandler(db))
+		api.POST("/login/2fa", controllers.LoginTwoFactorHandler(db)) // 登录第二步：两步验证
+
+		// 统一身份认证登录（OIDC / CAS，无需认证）
+		api.GET("/sso/providers", controllers.SSOProvidersHandler())             // 获取已启用的认证方式
+		api.GET("/sso/:provider/authorize", controllers.SSOAuthorizeHandler(db)) // 获取身份平台登录地址
+		api.POST("/sso/:provider/login", controllers.SSOLoginHandler(db))        // 身份平台回调后登录
+		api.POST("/sso/:provider/link", controllers.SSOLinkConfirmHandler(db))   // 关联已有账号前输入本地密码确认
+
+		// 自助找回密码（无需认证）
+		passwordController := controllers.NewPasswordController(db)
+		api.GET("/password/policy", passwordController.GetPolicy)              // 获取密码规则
+		api.POST("/password/forgot", passwordController.ForgotPassword)        // 发送重置密码邮件
+		api.GET("/password/reset/verify", passwordController.VerifyResetToken) // 校验重置链接
+		api.POST("/password/reset", passwordController.ResetPassword)          // 通过重置链接设置新密码
 
 		// 认证相关路由
-		authController := controllers.NewAuthController(db)
-		api.POST("/refresh-token", authController.RefreshToken)  // Token刷新
+		api.GET("/jwks", authController.GetJWKS)                 // JWT验签公钥
+		api.POST("/refresh-token", authController.RefreshToken)  // Token刷新（刷新令牌轮换）
 		api.GET("/validate-token", authController.ValidateToken) // Token验证
 
 		// 需要认证的路由组
 		auth := api.Group("")
-		auth.Use(middlewares.AuthMiddleware())
+		auth.Use(middlewares.AuthMiddleware(authService, personalTokenService), middlewares.DataScopeMiddleware(permissionService),
+			middlewares.AuditMiddleware(services.NewAuditService(db)))
 		{
+			auth.POST("/logout", authController.Logout)                        // 退出登录（撤销当前会话）
+			auth.GET("/user-info", authController.GetUserInfo)                 // 获取当前用户信息（含可用角色）
+			auth.GET("/my-roles", authController.GetMyRoles)                   // 获取可切换的角色
+			auth.POST("/switch-role", authController.SwitchRole)               // 切换角色并重新签发令牌
+			auth.POST("/impersonation/stop", authController.StopImpersonation) // 结束模拟登录
+			auth.POST("/password/change", passwordController.ChangePassword)   // 修改密码
+
+			// 个人访问令牌管理（令牌本身不能访问这些接口）
+			personalTokenController := controllers.NewPersonalTokenController(personalTokenService)
+			tokens := auth.Group("/tokens")
+			{
+				tokens.GET("", personalTokenController.GetMyTokens)        // 获取我的访问令牌
+				tokens.GET("/scopes", personalTokenController.GetScopes)   // 获取可选作用域
+				tokens.POST("", personalTokenController.CreateToken)       // 创建访问令牌
+				tokens.DELETE("/:id", personalTokenController.RevokeToken) // 撤销访问令牌
+			}
+
+			// 我的登录设备（会话）管理
+			sessionController := controllers.NewSessionController(authService, services.NewSystemLogService(db))
+			sessions := auth.Group("/sessions")
+			{
+				sessions.GET("", sessionController.GetMySessions)                      // 获取我的登录设备
+				sessions.DELETE("/:id", sessionController.RevokeMySession)             // 下线指定设备
+				sessions.POST("/revoke-others", sessionController.RevokeOtherSessions) // 下线其他全部设备
+			}
+
+			roleController := controllers.NewRoleController(permissionService)
+			auth.GET("/permissions", roleController.GetMyPermissions) // 获取当前角色的权限
+
+			// 两步验证（TOTP）路由
+			twoFactorController := controllers.NewTwoFactorController(services.NewTwoFactorService(db))
+			twoFactor := auth.Group("/2fa")
+			{
+				twoFactor.GET("/status", twoFactorController.GetStatus)                        // 获取两步验证状态
+				twoFactor.POST("/setup", twoFactorController.Setup)                            // 生成密钥
+				twoFactor.POST("/enable", twoFactorController.Enable)                          // 验证并启用
+				twoFactor.POST("/disable", twoFactorController.Disable)                        // 关闭
+				twoFactor.POST("/recovery-codes", twoFactorController.RegenerateRecoveryCodes) // 重新生成恢复码
+			}
+
+			// 组织机构树（登录用户均可查看，维护见 /admin/org-units）
+			orgUnitController := controllers.NewOrgUnitController(db)
+			auth.GET("/org-units", orgUnitController.GetOrgUnitTree) // 获取组织机构树
+			auth.GET("/org-units/:id", orgUnitController.GetOrgUnit) // 获取节点及直接下级
+
 			// 用户管理路由（仅管理员）
 			userService := services.NewUserService(db)
 			userController := controllers.NewUserController(userService)
+			userImportController := controllers.NewUserImportController(db)
+			userRecycleController := controllers.NewUserRecycleController(db)
+			userMergeController := controllers.NewUserMergeController(db)
+			userPrivacyController := controllers.NewUserPrivacyController(db)
 
 			users := auth.Group("/users")
-			users.Use(middlewares.AdminOnly())
+			users.Use(requirePermission("user.manage"))
 			{
-				users.GET("", userController.GetUserList)                           // 获取用户列表
-				users.GET("/:id", userController.GetUserByID)                       // 获取用户详情
-				users.POST("", userController.CreateUser)                           // 创建用户
-				users.PUT("/:id", userController.UpdateUser)                        // 更新用户
-				users.DELETE("/:id", userController.DeleteUser)                     // 删除用户
-				users.PATCH("/:id/status", userController.ToggleUserStatus)         // 切换用户状态
-				users.POST("/:id/reset-password", userController.ResetUserPassword) // 重置密码
-				users.POST("/batch-delete", userController.BatchDeleteUsers)        // 批量删除
-				users.GET("/stats", userController.GetUserStats)                    // 获取统计信息
-				users.GET("/export", userController.ExportUsers)                    // 导出用户数据
+				users.GET("", userController.GetUserList)                                      // 获取用户列表
+				users.GET("/:id", userController.GetUserByID)                                  // 获取用户详情
+				users.POST("", userController.CreateUser)                                      // 创建用户
+				users.PUT("/:id", userController.UpdateUser)                                   // 更新用户
+				users.DELETE("/:id", userController.DeleteUser)                                // 删除用户
+				users.PATCH("/:id/status", userController.ToggleUserStatus)                    // 切换用户状态
+				users.POST("/:id/reset-password", userController.ResetUserPassword)            // 重置密码
+				users.POST("/:id/reset-2fa", userController.ResetUserTwoFactor)                // 重置两步验证
+				users.GET("/:id/sessions", userController.GetUserSessions)                     // 获取用户登录设备
+				users.DELETE("/:id/sessions", userController.TerminateUserSessions)            // 强制下线全部设备
+				users.DELETE("/:id/sessions/:sessionId", userController.TerminateUserSessions) // 强制下线单个设备
+				users.GET("/:id/identities", userController.GetUserIdentities)                 // 获取绑定的统一身份认证账号
+				users.DELETE("/:id/identities/:identityId", userController.UnlinkUserIdentity) // 解除统一身份认证账号绑定
+				users.POST("/batch-delete", userController.BatchDeleteUsers)                   // 批量删除
+				users.GET("/stats", userController.GetUserStats)                               // 获取统计信息
+				users.GET("/export", userController.ExportUsers)                               // 导出用户数据
+				users.POST("/import", userImportController.ImportUsers)                        // 批量导入（CSV/XLSX，支持试运行）
+				users.GET("/import/template", userImportController.GetImportTemplate)          // 下载导入模板
+
+				// 回收站（删除的用户保留期内可恢复，永久删除前检查引用数据）
+				users.GET("/recycle-bin", userRecycleController.GetRecycleBin)
+				users.GET("/recycle-bin/:id/references", userRecycleController.GetUserReferences)
+				users.POST("/recycle-bin/:id/restore", userRecycleController.RestoreUser)
+				users.DELETE("/recycle-bin/:id", requirePermission("user.purge"), userRecycleController.PurgeUser)
+
+				// 重复账号检测与合并（数据迁移到保留账号，被合并账号移入回收站）
+				users.GET("/duplicates", userMergeController.GetDuplicates)
+				users.GET("/merges", userMergeController.GetMerges)
+				users.POST("/merge", requirePermission("user.merge"), userMergeController.MergeUsers)
+
+				// 个人数据导出（JSON/ZIP）与匿名化（清除个人信息，保留统计和竞赛成绩）
+				users.GET("/:id/data-export", userPrivacyController.ExportUserData)
+				users.POST("/:id/anonymize", requirePermission("user.anonymize"), userPrivacyController.AnonymizeUser)
+
+				// 模拟登录（以用户身份查看，限时、默认只读、全程审计）
+				users.POST("/:id/impersonate", requirePermission("user.impersonate"), userController.ImpersonateUser)
 			}
 
 			// 项目模块路由
@@ -60,7 +161,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 			// 项目分类管理路由（仅管理员）
 			projectTypeController := controllers.NewProjectTypeController(db)
 			projectTypes := auth.Group("/project-types")
-			projectTypes.Use(middlewares.AdminOnly())
+			projectTypes.Use(requirePermission("project_type.manage"))
 			{
 				projectTypes.GET("", projectTypeController.GetProjectTypeList)        // 获取项目分类列表
 				projectTypes.GET("/stats", projectTypeController.GetProjectTypeStats) // 获取项目分类统计
@@ -72,7 +173,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 教师管理路由
 			teachers := auth.Group("/teachers")
-			teachers.Use(middlewares.RoleMiddleware("teacher", "admin"))
+			teachers.Use(requirePermission("teacher.access"))
 			{
 				teachers.GET("", projectController.GetTeacherList)                                               // 获取教师列表
 				teachers.POST("/ApproveExtensionApplication", projectController.ApproveExtensionApplication)     // 审批延期申请
@@ -88,7 +189,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 教师/管理员项目路由
 			teacherProjects := auth.Group("/teacher-projects")
-			teacherProjects.Use(middlewares.RoleMiddleware("teacher", "admin"))
+			teacherProjects.Use(requirePermission("project.review"))
 			{
 				teacherProjects.GET("", projectController.GetProjectList)                // 获取所有项目列表
 				teacherProjects.PUT("/:id/review", projectController.ReviewProject)      // 审核项目
@@ -102,6 +203,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 				// =============================================
 				// 5. 审核流程增强路由（教师/管理员）
 				// =============================================
+				teacherProjects.PUT("/reviews/:reviewId", projectController.DecideReviewTask)         // 处理多级审核任务
 				teacherProjects.POST("/reviews/:reviewId/delegate", projectController.DelegateReview) // 委托审核
 				teacherProjects.GET("/my-review-tasks", projectController.GetMyReviewTasks)           // 获取我的审核任务
 				teacherProjects.GET("/review-flow-config", projectController.GetReviewFlowConfig)     // 获取审核流程配置
@@ -109,8 +211,9 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 			}
 
 			// 管理员项目管理路由
+			projectWorkflowController := controllers.NewProjectWorkflowController(db)
 			adminProjects := auth.Group("/admin/projects")
-			//adminProjects.Use(middlewares.AdminOnly())
+			adminProjects.Use(requirePermission("project.admin"))
 			{
 				adminProjects.PUT("/:id/force-status", projectController.ForceUpdateProjectStatus) // 强制更新项目状态
 
@@ -125,10 +228,18 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 				adminProjects.GET("/types/tree", projectController.GetProjectTypeTree)   // 获取项目分类树
 				adminProjects.GET("/types/stats", projectController.GetProjectTypeStats) // 获取项目分类统计
 
+				// 项目状态流转配置（分类未单独配置时沿用上级分类或默认流程）
+				adminProjects.GET("/workflow", projectWorkflowController.GetWorkflow)                // 获取默认流程
+				adminProjects.PUT("/workflow", projectWorkflowController.SaveWorkflow)               // 保存默认流程
+				adminProjects.GET("/types/:id/workflow", projectWorkflowController.GetWorkflow)      // 获取分类生效的流程
+				adminProjects.PUT("/types/:id/workflow", projectWorkflowController.SaveWorkflow)     // 保存分类流程
+				adminProjects.DELETE("/types/:id/workflow", projectWorkflowController.ResetWorkflow) // 删除分类流程，改为沿用
+
 				// =============================================
 				// 5. 审核流程增强路由（管理员）
 				// =============================================
 				adminProjects.POST("/review-flows", projectController.CreateReviewFlow) // 创建审核流程配置
+				adminProjects.GET("/review-sla", projectController.GetReviewSLAReport)  // 审核时效统计
 
 				// =============================================
 				// 6. 新增的管理员项目统计路由（兼容前端调用）
@@ -141,7 +252,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 学生专用路由
 			students := auth.Group("/students")
-			students.Use(middlewares.RoleMiddleware("student"))
+			students.Use(requirePermission("student.bind_teacher"))
 			{
 				students.POST("/bind-teacher", projectController.BindStudentToTeacher) // 学生绑定教师
 			}
@@ -154,6 +265,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 			}
 
 			// 通用项目路由（所有认证用户）
+			projectTeamController := controllers.NewProjectTeamController(db)
 			projects := auth.Group("/projects")
 			{
 				projects.GET("/my", projectController.GetMyProjects)                                   // 学生获取我的项目
@@ -175,6 +287,9 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 				projects.PUT("/milestones/:milestoneId", projectController.UpdateProjectMilestone) // 更新项目里程碑
 				projects.GET("/:id/milestones", projectController.GetProjectMilestones)            // 获取项目里程碑列表
 				projects.PUT("/:id/progress", projectController.UpdateProjectProgress)             // 更新项目进度
+				projects.GET("/:id/transitions", projectController.GetAvailableTransitions)        // 获取可执行的状态流转
+				projects.PUT("/:id/status", projectController.UpdateProjectStatus)                 // 按流转规则变更项目状态
+				projects.GET("/:id/status-history", projectController.GetProjectStatusHistory)     // 获取项目状态变更历史
 
 				// =============================================
 				// 3. 成果文件管理增强路由
@@ -182,6 +297,15 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 				projects.POST("/:id/files", projectController.UploadProjectFile)         // 上传项目文件（增强版）
 				projects.GET("/:id/files", projectController.GetProjectFilesByType)      // 按类型获取项目文件
 				projects.GET("/file-type-configs", projectController.GetFileTypeConfigs) // 获取文件类型配置
+
+				// 项目团队（队长邀请成员，被邀请人接受后加入；人数上限按项目分类设置）
+				projects.GET("/invitations", projectTeamController.GetMyInvitations)                     // 我收到的团队邀请
+				projects.POST("/invitations/:memberId/accept", projectTeamController.AcceptInvitation)   // 接受邀请
+				projects.POST("/invitations/:memberId/decline", projectTeamController.DeclineInvitation) // 拒绝邀请
+				projects.GET("/:id/members", projectTeamController.GetProjectMembers)                    // 获取团队成员
+				projects.POST("/:id/members", projectTeamController.InviteProjectMember)                 // 邀请成员
+				projects.DELETE("/:id/members/:memberId", projectTeamController.RemoveProjectMember)     // 移除成员/退出团队
+				projects.POST("/:id/transfer-leader", projectTeamController.TransferProjectLeader)       // 转让队长
 			}
 
 			// 文件上传路由（所有认证用户）
@@ -211,7 +335,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 教师竞赛路由
 			teacherCompetitions := auth.Group("/teacher-competitions")
-			teacherCompetitions.Use(middlewares.RoleMiddleware("teacher", "admin"))
+			teacherCompetitions.Use(requirePermission("competition.judge"))
 			{
 				teacherCompetitions.GET("/:id/submissions", competitionController.GetCompetitionSubmissions) // 查看竞赛提交作品
 				teacherCompetitions.POST("/:id/feedback", competitionController.SubmitFeedback)              // 提交评语
@@ -223,7 +347,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 管理员竞赛管理路由
 			adminCompetitions := auth.Group("/admin/competitions")
-			adminCompetitions.Use(middlewares.AdminOnly())
+			adminCompetitions.Use(requirePermission("competition.manage"))
 			{
 				adminCompetitions.POST("", competitionController.CreateCompetition)                            // 创建竞赛
 				adminCompetitions.PUT("/:id", competitionController.UpdateCompetition)                         // 更新竞赛
@@ -233,10 +357,10 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 				adminCompetitions.GET("/:id/export", competitionController.ExportCompetitionData)              // 导出竞赛数据
 
 				// 竞赛评审管理
-				adminCompetitions.POST("/:id/judges", competitionController.AssignJudge)                 // 分配评审教师
-				adminCompetitions.GET("/:id/judges", competitionController.GetCompetitionJudges)         // 获取评审教师列表
-				adminCompetitions.GET("/:id/judging-progress", competitionController.GetJudgingProgress) // 获取评审进度
-				adminCompetitions.POST("/:id/finalize", competitionController.FinalizeResults)           // 最终确认成绩
+				adminCompetitions.POST("/:id/judges", competitionController.AssignJudge)                                                  // 分配评审教师
+				adminCompetitions.GET("/:id/judges", competitionController.GetCompetitionJudges)                                          // 获取评审教师列表
+				adminCompetitions.GET("/:id/judging-progress", competitionController.GetJudgingProgress)                                  // 获取评审进度
+				adminCompetitions.POST("/:id/finalize", requirePermission("competition.finalize"), competitionController.FinalizeResults) // 最终确认成绩
 			}
 
 			// 通知系统路由
@@ -253,7 +377,7 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 
 			// 管理员通知管理路由
 			adminNotifications := auth.Group("/admin/notifications")
-			adminNotifications.Use(middlewares.AdminOnly())
+			adminNotifications.Use(requirePermission("notification.manage"))
 			{
 				adminNotifications.GET("/templates", notificationController.GetNotificationTemplates)       // 获取通知模板列表
 				adminNotifications.PUT("/templates/:id", notificationController.UpdateNotificationTemplate) // 更新通知模板
@@ -265,46 +389,90 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 			systemController := controllers.NewSystemController(db)
 
 			admin := auth.Group("/admin")
-			admin.Use(middlewares.AdminOnly())
 			{
 				// 仪表板
-				admin.GET("/dashboard", adminController.GetDashboardStats)       // 获取仪表板数据
-				admin.GET("/dashboard/stats", adminController.GetDashboardStats) // 获取仪表板统计数据 (兼容前端调用)
-				admin.GET("/overview", adminController.GetUserOverview)          // 获取用户概览
-
-				// 系统管理
-				admin.GET("/logs", systemController.GetSystemLogs)                         // 获取系统日志
-				admin.GET("/logs/summary", systemController.GetSystemLogsSummary)          // 获取系统日志统计
-				admin.GET("/logs/health", systemController.GetSystemHealthLogs)            // 获取系统健康日志
-				admin.GET("/logs/health/summary", systemController.GetSystemHealthSummary) // 获取系统健康统计
-				admin.POST("/logs/health", systemController.RecordSystemHealth)            // 记录系统健康状态
-				admin.GET("/settings", systemController.GetSystemSettings)                 // 获取系统设置
-				admin.GET("/settings/:key", systemController.GetSystemSettingByKey)        // 根据键获取系统设置
-				admin.PUT("/settings/:key", systemController.UpdateSystemSetting)          // 更新系统设置
-				admin.GET("/config", systemController.GetSystemConfig)                     // 获取系统配置结构
-				admin.GET("/health", systemController.GetSystemHealth)                     // 获取系统健康状态
-				admin.GET("/system/health", systemController.GetSystemHealth)              // 获取系统健康状态 (兼容前端调用)
-				admin.GET("/stats", systemController.GetSystemStats)                       // 获取系统统计
-				admin.PUT("/maintenance", systemController.UpdateMaintenanceMode)          // 更新维护模式
-				admin.POST("/logs/cleanup", systemController.CleanupOldLogs)               // 清理过期日志
-
-				// 系统性能监控
-				admin.GET("/performance", systemController.GetSystemPerformance)            // 获取系统性能数据
-				admin.POST("/performance/record", systemController.RecordSystemPerformance) // 记录系统性能数据
-
-				// 系统告警管理
-				admin.GET("/alerts", systemController.GetSystemAlerts)                   // 获取系统告警列表
-				admin.POST("/alerts/:id/acknowledge", systemController.AcknowledgeAlert) // 确认告警
-				admin.POST("/alerts/:id/resolve", systemController.ResolveAlert)         // 解决告警
-
-				// 系统诊断
-				admin.GET("/diagnostics", systemController.GetSystemDiagnostics)      // 获取系统诊断记录
-				admin.POST("/diagnostics/run", systemController.RunSystemDiagnostics) // 运行系统诊断
+				dashboard := admin.Group("", requirePermission("system.dashboard"))
+				dashboard.GET("/dashboard", adminController.GetDashboardStats)       // 获取仪表板数据
+				dashboard.GET("/dashboard/stats", adminController.GetDashboardStats) // 获取仪表板统计数据 (兼容前端调用)
+				dashboard.GET("/overview", adminController.GetUserOverview)          // 获取用户概览
+
+				// 系统日志
+				logs := admin.Group("", requirePermission("system.logs"))
+				logs.GET("/logs", systemController.GetSystemLogs)                         // 获取系统日志
+				logs.GET("/logs/summary", systemController.GetSystemLogsSummary)          // 获取系统日志统计
+				logs.GET("/logs/health", systemController.GetSystemHealthLogs)            // 获取系统健康日志
+				logs.GET("/logs/health/summary", systemController.GetSystemHealthSummary) // 获取系统健康统计
+				logs.POST("/logs/health", systemController.RecordSystemHealth)            // 记录系统健康状态
+				logs.POST("/logs/cleanup", systemController.CleanupOldLogs)               // 清理过期日志
+
+				// 系统设置
+				settings := admin.Group("", requirePermission("system.settings"))
+				settings.GET("/settings", systemController.GetSystemSettings)          // 获取系统设置
+				settings.GET("/settings/:key", systemController.GetSystemSettingByKey) // 根据键获取系统设置
+				settings.PUT("/settings/:key", systemController.UpdateSystemSetting)   // 更新系统设置
+				settings.GET("/config", systemController.GetSystemConfig)              // 获取系统配置结构
+				settings.PUT("/maintenance", systemController.UpdateMaintenanceMode)   // 更新维护模式
+
+				// 系统监控（健康、性能、告警、诊断）
+				monitor := admin.Group("", requirePermission("system.monitor"))
+				monitor.GET("/health", systemController.GetSystemHealth)                      // 获取系统健康状态
+				monitor.GET("/system/health", systemController.GetSystemHealth)               // 获取系统健康状态 (兼容前端调用)
+				monitor.GET("/stats", systemController.GetSystemStats)                        // 获取系统统计
+				monitor.GET("/performance", systemController.GetSystemPerformance)            // 获取系统性能数据
+				monitor.POST("/performance/record", systemController.RecordSystemPerformance) // 记录系统性能数据
+				monitor.GET("/alerts", systemController.GetSystemAlerts)                      // 获取系统告警列表
+				monitor.POST("/alerts/:id/acknowledge", systemController.AcknowledgeAlert)    // 确认告警
+				monitor.POST("/alerts/:id/resolve", systemController.ResolveAlert)            // 解决告警
+				monitor.GET("/diagnostics", systemController.GetSystemDiagnostics)            // 获取系统诊断记录
+				monitor.POST("/diagnostics/run", systemController.RunSystemDiagnostics)       // 运行系统诊断
+
+				// 登录安全管理
+				securityController := controllers.NewSecurityController(services.NewLoginGuardService(db))
+				security := admin.Group("/security", requirePermission("security.manage"))
+				security.GET("/lockouts", securityController.GetLoginLockouts) // 获取登录锁定列表
+				security.POST("/unlock", securityController.UnlockLogin)       // 解除登录锁定
+
+				// LDAP目录同步
+				ldapSyncController := controllers.NewLDAPSyncController(db)
+				ldap := admin.Group("/ldap", requirePermission("user.sync"))
+				ldap.POST("/sync", ldapSyncController.Sync)      // 手动同步（dryRun 时只返回差异）
+				ldap.GET("/runs", ldapSyncController.GetRuns)    // 获取同步记录
+				ldap.GET("/runs/:id", ldapSyncController.GetRun) // 获取同步记录详情
+
+				// 学年升级
+				rolloverController := controllers.NewAcademicRolloverController(db)
+				rollover := admin.Group("/rollover", requirePermission("user.rollover"))
+				rollover.GET("", rolloverController.GetRollovers)             // 获取学年升级记录
+				rollover.GET("/:id", rolloverController.GetRollover)          // 获取学年升级记录详情
+				rollover.POST("/preview", rolloverController.PreviewRollover) // 预览学年升级
+				rollover.POST("", rolloverController.ApplyRollover)           // 执行学年升级
+				rollover.POST("/:id/undo", rolloverController.UndoRollover)   // 撤销学年升级
+
+				// 组织机构维护
+				orgUnits := admin.Group("/org-units", requirePermission("org.manage"))
+				orgUnits.POST("", orgUnitController.CreateOrgUnit)           // 创建节点
+				orgUnits.PUT("/:id", orgUnitController.UpdateOrgUnit)        // 更新节点（可调整上级）
+				orgUnits.DELETE("/:id", orgUnitController.DeleteOrgUnit)     // 删除节点
+				orgUnits.POST("/mapping", orgUnitController.MapLegacyValues) // 映射旧院系/专业文本（支持试运行）
 
 				// 备份管理
-				admin.GET("/backups", systemController.GetBackupRecords)               // 获取备份记录
-				admin.POST("/backups", systemController.CreateBackup)                  // 创建备份
-				admin.GET("/backups/statistics", systemController.GetBackupStatistics) // 获取备份统计
+				backups := admin.Group("/backups", requirePermission("system.backup"))
+				backups.GET("", systemController.GetBackupRecords)               // 获取备份记录
+				backups.POST("", systemController.CreateBackup)                  // 创建备份
+				backups.GET("/statistics", systemController.GetBackupStatistics) // 获取备份统计
+
+				// 角色与权限管理
+				rbac := admin.Group("", requirePermission("rbac.manage"))
+				rbac.GET("/roles", roleController.GetRoleList)                        // 获取角色列表
+				rbac.GET("/roles/:id", roleController.GetRoleByID)                    // 获取角色详情
+				rbac.POST("/roles", roleController.CreateRole)                        // 创建角色
+				rbac.PUT("/roles/:id", roleController.UpdateRole)                     // 更新角色
+				rbac.PUT("/roles/:id/permissions", roleController.SetRolePermissions) // 设置角色权限
+				rbac.DELETE("/roles/:id", roleController.DeleteRole)                  // 删除角色
+				rbac.GET("/permissions", roleController.GetPermissionList)            // 获取权限列表
+				rbac.POST("/permissions", roleController.CreatePermission)            // 创建权限
+				rbac.PUT("/permissions/:id", roleController.UpdatePermission)         // 更新权限
+				rbac.DELETE("/permissions/:id", roleController.DeletePermission)      // 删除权限
 			}
 		}
 	}
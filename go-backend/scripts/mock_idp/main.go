@@ -0,0 +1,336 @@
+// mock_idp 本地模拟统一身份认证平台，同时提供 OIDC 和 CAS 3.0 接口，用于联调统一身份认证登录。
+//
+//	go run ./scripts/mock_idp
+//	SSO_CONFIG_FILE=scripts/mock_idp/sso.example.json go run .
+//
+// 登录页列出内置测试用户，点击即以该用户登录；也可以在授权地址上附加 login_hint=<用户标识> 跳过选择。
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const mockKID = "mock-idp"
+
+// mockUser 内置测试用户
+type mockUser struct {
+	Subject    string
+	Name       string
+	Email      string
+	StudentNo  string
+	Department string
+	Major      string
+	Grade      string
+	Groups     []string
+}
+
+var mockUsers = []mockUser{
+	{Subject: "2024001001", Name: "张三", Email: "zhangsan@stu.example.edu.cn", StudentNo: "2024001001", Department: "计算机学院", Major: "软件工程", Grade: "2024", Groups: []string{"students"}},
+	{Subject: "2024001002", Name: "李四", Email: "lisi@stu.example.edu.cn", StudentNo: "2024001002", Department: "电子信息学院", Major: "通信工程", Grade: "2024"},
+	{Subject: "T2019001", Name: "王老师", Email: "wang@example.edu.cn", Department: "计算机学院", Groups: []string{"teachers"}},
+}
+
+// mockGrant 授权码/票据/访问令牌对应的登录信息
+type mockGrant struct {
+	User        mockUser
+	ClientID    string
+	RedirectURI string
+	Nonce       string
+	ExpiresAt   time.Time
+}
+
+type mockIdP struct {
+	issuer string
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]*mockGrant
+}
+
+func main() {
+	addr := getEnv("MOCK_IDP_ADDR", ":9000")
+	issuer := getEnv("MOCK_IDP_ISSUER", "http://localhost:9000")
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("生成签名密钥失败: ", err)
+	}
+	idp := &mockIdP{issuer: strings.TrimSuffix(issuer, "/"), key: key, grants: map[string]*mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/userinfo", idp.userInfo)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/cas/login", idp.casLogin)
+	mux.HandleFunc("/cas/p3/serviceValidate", idp.casValidate)
+
+	log.Printf("模拟身份平台已启动 - OIDC Issuer: %s, CAS: %s/cas", idp.issuer, idp.issuer)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                idp.issuer,
+		"authorization_endpoint":                idp.issuer + "/authorize",
+		"token_endpoint":                        idp.issuer + "/token",
+		"userinfo_endpoint":                     idp.issuer + "/userinfo",
+		"jwks_uri":                              idp.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// authorize OIDC 授权端点：选择用户后携带授权码跳回 redirect_uri
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("response_type") != "code" || redirectURI == "" || query.Get("client_id") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findUser(query.Get("login_hint"))
+	if !ok {
+		renderUserPicker(w, r.URL, "login_hint")
+		return
+	}
+
+	code := idp.issue(&mockGrant{User: user, ClientID: query.Get("client_id"), RedirectURI: redirectURI, Nonce: query.Get("nonce")})
+	callback := url.Values{}
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	http.Redirect(w, r, appendQuery(redirectURI, callback), http.StatusFound)
+}
+
+// token OIDC 令牌端点：授权码只能使用一次
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	grant := idp.consume(r.PostForm.Get("code"))
+	if grant == nil || grant.ClientID != r.PostForm.Get("client_id") || grant.RedirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := userClaims(grant.User)
+	claims["iss"] = idp.issuer
+	claims["aud"] = grant.ClientID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
+	if grant.Nonce != "" {
+		claims["nonce"] = grant.Nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKID
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken := idp.issue(&mockGrant{User: grant.User})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *mockIdP) userInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	idp.mu.Lock()
+	grant := idp.grants[accessToken]
+	idp.mu.Unlock()
+	if grant == nil || time.Now().After(grant.ExpiresAt) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, userClaims(grant.User))
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := idp.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": mockKID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// casLogin CAS 登录页：选择用户后携带 ST 票据跳回 service
+func (idp *mockIdP) casLogin(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	if service == "" {
+		http.Error(w, "missing service", http.StatusBadRequest)
+		return
+	}
+	user, ok := findUser(r.URL.Query().Get("user"))
+	if !ok {
+		renderUserPicker(w, r.URL, "user")
+		return
+	}
+
+	ticket := "ST-" + idp.issue(&mockGrant{User: user, RedirectURI: service})
+	callback := url.Values{}
+	callback.Set("ticket", ticket)
+	http.Redirect(w, r, appendQuery(service, callback), http.StatusFound)
+}
+
+// casValidate CAS 3.0 票据校验，属性放在 cas:attributes 中，组为多值属性 groups
+func (idp *mockIdP) casValidate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	grant := idp.consume(strings.TrimPrefix(query.Get("ticket"), "ST-"))
+	if grant == nil || grant.RedirectURI != query.Get("service") {
+		fmt.Fprintf(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas"><cas:authenticationFailure code="INVALID_TICKET">票据无效: %s</cas:authenticationFailure></cas:serviceResponse>`,
+			xmlEscape(query.Get("ticket")))
+		return
+	}
+
+	user := grant.User
+	var attrs strings.Builder
+	writeAttr := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&attrs, "<cas:%s>%s</cas:%s>", name, xmlEscape(value), name)
+		}
+	}
+	writeAttr("name", user.Name)
+	writeAttr("email", user.Email)
+	writeAttr("student_number", user.StudentNo)
+	writeAttr("department", user.Department)
+	writeAttr("major", user.Major)
+	writeAttr("grade", user.Grade)
+	for _, group := range user.Groups {
+		writeAttr("groups", group)
+	}
+	fmt.Fprintf(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas"><cas:authenticationSuccess><cas:user>%s</cas:user><cas:attributes>%s</cas:attributes></cas:authenticationSuccess></cas:serviceResponse>`,
+		xmlEscape(user.Subject), attrs.String())
+}
+
+// issue 生成一次性凭据（5分钟有效）
+func (idp *mockIdP) issue(grant *mockGrant) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	value := hex.EncodeToString(buf)
+	grant.ExpiresAt = time.Now().Add(5 * time.Minute)
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.grants[value] = grant
+	return value
+}
+
+// consume 取出并作废一次性凭据
+func (idp *mockIdP) consume(value string) *mockGrant {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	grant := idp.grants[value]
+	delete(idp.grants, value)
+	if grant == nil || time.Now().After(grant.ExpiresAt) {
+		return nil
+	}
+	return grant
+}
+
+func userClaims(user mockUser) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":                user.Subject,
+		"preferred_username": user.Subject,
+		"name":               user.Name,
+		"email":              user.Email,
+		"department":         user.Department,
+		"major":              user.Major,
+		"grade":              user.Grade,
+	}
+	if user.StudentNo != "" {
+		claims["student_number"] = user.StudentNo
+	}
+	if len(user.Groups) > 0 {
+		claims["groups"] = user.Groups
+	}
+	return claims
+}
+
+func findUser(subject string) (mockUser, bool) {
+	for _, user := range mockUsers {
+		if user.Subject == subject {
+			return user, true
+		}
+	}
+	return mockUser{}, false
+}
+
+// renderUserPicker 输出测试用户选择页，选择后带上 param=<用户标识> 重新请求当前地址
+func renderUserPicker(w http.ResponseWriter, current *url.URL, param string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, "<!DOCTYPE html><html><head><meta charset=\"utf-8\"><title>模拟统一身份认证</title></head><body><h3>选择登录用户</h3><ul>")
+	for _, user := range mockUsers {
+		query := current.Query()
+		query.Set(param, user.Subject)
+		link := current.Path + "?" + query.Encode()
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s（%s，%s，组: %s）</a></li>",
+			html.EscapeString(link), html.EscapeString(user.Name), html.EscapeString(user.Subject),
+			html.EscapeString(user.Department), html.EscapeString(strings.Join(user.Groups, ",")))
+	}
+	fmt.Fprint(w, "</ul></body></html>")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func xmlEscape(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+func appendQuery(baseURL string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return baseURL + separator + query.Encode()
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
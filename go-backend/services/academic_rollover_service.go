@@ -0,0 +1,430 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// rolloverClosedReason 毕业关闭项目时写入的原因
+const rolloverClosedReason = "学生已毕业，学年升级时自动关闭"
+
+var (
+	ErrRolloverExists      = errors.New("该学年已执行过升级，如需重新执行请先撤销")
+	ErrRolloverUndoExpired = errors.New("已超过撤销期限")
+	ErrRolloverNotLatest   = errors.New("只能撤销最近一次学年升级")
+)
+
+// rolloverUnfinishedStatuses 视为未结束的项目状态
+var rolloverUnfinishedStatuses = []string{"draft", "submitted", "reviewing", "approved"}
+
+// AcademicRolloverService 学年升级：学生年级升一级，毕业年级的学生转为校友或停用，
+// 并按规则关闭其未结束的项目、解除师生绑定。执行结果保存快照，可在撤销期限内撤销。
+type AcademicRolloverService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	systemLogs *SystemLogService
+}
+
+func NewAcademicRolloverService(db *gorm.DB) *AcademicRolloverService {
+	return &AcademicRolloverService{
+		db:         db,
+		settings:   NewSettingService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// CurrentAcademicYear 按日期计算学年（8月起为新学年），如 2026-2027
+func CurrentAcademicYear(now time.Time) string {
+	year := now.Year()
+	if now.Month() < time.August {
+		year--
+	}
+	return fmt.Sprintf("%d-%d", year, year+1)
+}
+
+// resolveRules 用系统设置补全未填写的规则
+func (s *AcademicRolloverService) resolveRules(rules models.RolloverRules) models.RolloverRules {
+	if rules.AcademicYear == "" {
+		rules.AcademicYear = CurrentAcademicYear(time.Now())
+	}
+	if len(rules.GraduateGrades) == 0 {
+		rules.GraduateGrades = s.settings.GetList("rollover_graduate_grades", "大四,研三")
+	}
+	if rules.GraduateStatus == "" {
+		rules.GraduateStatus = s.settings.GetString("rollover_graduate_status", "alumni")
+	}
+	if rules.ProjectAction == "" {
+		rules.ProjectAction = s.settings.GetString("rollover_project_action", "close")
+	}
+	if rules.BindingAction == "" {
+		rules.BindingAction = s.settings.GetString("rollover_binding_action", "remove")
+	}
+	return rules
+}
+
+// Preview 计算学年升级的变更，不修改数据
+func (s *AcademicRolloverService) Preview(rules models.RolloverRules) (*models.RolloverReport, error) {
+	return s.plan(s.db, s.resolveRules(rules))
+}
+
+// plan 生成变更明细
+func (s *AcademicRolloverService) plan(db *gorm.DB, rules models.RolloverRules) (*models.RolloverReport, error) {
+	grades := s.settings.GetList("user_grades", DefaultUserGrades)
+	nextGrade := make(map[string]string, len(grades))
+	for i, grade := range grades {
+		nextGrade[grade] = ""
+		if i+1 < len(grades) {
+			nextGrade[grade] = grades[i+1]
+		}
+	}
+	graduate := make(map[string]bool, len(rules.GraduateGrades))
+	for _, grade := range rules.GraduateGrades {
+		graduate[grade] = true
+	}
+
+	query := db.Model(&models.User{}).
+		Joins("JOIN user_roles ON users.id = user_roles.user_id").
+		Joins("JOIN roles ON user_roles.role_id = roles.id").
+		Where("roles.role_key = ? AND users.status = ?", "student", "active")
+	if rules.Department != "" {
+		query = query.Where("users.department = ?", rules.Department)
+	}
+	var students []models.User
+	if err := query.Select("users.id", "users.username", "users.grade", "users.status").Order("users.id ASC").Find(&students).Error; err != nil {
+		return nil, err
+	}
+
+	report := &models.RolloverReport{
+		AcademicYear: rules.AcademicYear,
+		Rules:        rules,
+		GradeChanges: map[string]int{},
+		Students:     []models.RolloverStudentItem{},
+		Projects:     []models.RolloverProjectItem{},
+		Bindings:     []models.StudentTeacher{},
+		Skipped:      []models.RolloverSkippedItem{},
+	}
+	var graduateIDs []uint
+	for _, student := range students {
+		next, known := nextGrade[student.Grade]
+		switch {
+		case student.Grade == "":
+			report.Skipped = append(report.Skipped, models.RolloverSkippedItem{UserID: student.ID, Username: student.Username, Reason: "未设置年级"})
+		case !known:
+			report.Skipped = append(report.Skipped, models.RolloverSkippedItem{UserID: student.ID, Username: student.Username, Grade: student.Grade, Reason: "年级不在年级设置中"})
+		case graduate[student.Grade]:
+			report.Students = append(report.Students, models.RolloverStudentItem{
+				UserID: student.ID, Username: student.Username, OldGrade: student.Grade, NewGrade: student.Grade,
+				OldStatus: student.Status, NewStatus: rules.GraduateStatus, Graduate: true,
+			})
+			report.GradeChanges[student.Grade+" -> 毕业"]++
+			graduateIDs = append(graduateIDs, student.ID)
+		case next == "":
+			report.Skipped = append(report.Skipped, models.RolloverSkippedItem{UserID: student.ID, Username: student.Username, Grade: student.Grade, Reason: "已是最高年级且不在毕业年级中"})
+		default:
+			report.Students = append(report.Students, models.RolloverStudentItem{
+				UserID: student.ID, Username: student.Username, OldGrade: student.Grade, NewGrade: next,
+				OldStatus: student.Status, NewStatus: student.Status,
+			})
+			report.GradeChanges[student.Grade+" -> "+next]++
+		}
+	}
+	if len(graduateIDs) == 0 {
+		return report, nil
+	}
+
+	if rules.ProjectAction != "keep" {
+		var projects []models.Project
+		if err := db.Select("id", "title", "student_id", "status", "rejection_reason").
+			Where("student_id IN ? AND status IN ? AND deleted = ?", graduateIDs, rolloverUnfinishedStatuses, false).
+			Order("id ASC").Find(&projects).Error; err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			newStatus := "rejected"
+			if rules.ProjectAction == "close" && project.Status == "approved" {
+				// 已立项进行中的项目按结题处理，尚未通过审核的项目按驳回处理
+				newStatus = "completed"
+			}
+			report.Projects = append(report.Projects, models.RolloverProjectItem{
+				ProjectID: project.ID, Title: project.Title, StudentID: project.StudentID,
+				OldStatus: project.Status, NewStatus: newStatus, OldRejectionReason: project.RejectionReason,
+			})
+		}
+	}
+
+	if rules.BindingAction == "remove" {
+		if err := db.Where("student_id IN ?", graduateIDs).Order("id ASC").Find(&report.Bindings).Error; err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Apply 执行学年升级，整个过程在一个事务中完成；同一学年（及院系范围）只能执行一次，撤销后可重新执行
+func (s *AcademicRolloverService) Apply(rules models.RolloverRules, triggerType string, operatorID *uint) (*models.RolloverReport, error) {
+	rules = s.resolveRules(rules)
+	undoDays := s.settings.GetInt("rollover_undo_days", 7)
+
+	var report *models.RolloverReport
+	var record models.AcademicRollover
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		existing := tx.Model(&models.AcademicRollover{}).Where("academic_year = ? AND status = ?", rules.AcademicYear, "applied")
+		if rules.Department != "" {
+			existing = existing.Where("department = ? OR department = ''", rules.Department)
+		}
+		existing.Count(&count)
+		if count > 0 {
+			return ErrRolloverExists
+		}
+
+		var err error
+		report, err = s.plan(tx, rules)
+		if err != nil {
+			return err
+		}
+		if err := applyRolloverStudents(tx, report.Students); err != nil {
+			return err
+		}
+
+		for _, item := range report.Projects {
+			updates := map[string]interface{}{"status": item.NewStatus}
+			if item.NewStatus == "rejected" {
+				updates["rejection_reason"] = rolloverClosedReason
+			}
+			if err := tx.Model(&models.Project{}).Where("id = ?", item.ProjectID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.ProjectStatusHistory{
+				ProjectID: item.ProjectID, OldStatus: item.OldStatus, NewStatus: item.NewStatus,
+				ChangeReason: rolloverClosedReason, Action: "rollover", IsForced: true, ChangedBy: operatorID, ChangedAt: time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(report.Bindings) > 0 {
+			ids := make([]uint, len(report.Bindings))
+			for i, binding := range report.Bindings {
+				ids[i] = binding.ID
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&models.StudentTeacher{}).Error; err != nil {
+				return err
+			}
+		}
+
+		graduated := 0
+		for _, item := range report.Students {
+			if item.Graduate {
+				graduated++
+			}
+		}
+		ruleData, _ := json.Marshal(rules)
+		snapshot, _ := json.Marshal(report)
+		now := time.Now()
+		record = models.AcademicRollover{
+			AcademicYear:    rules.AcademicYear,
+			Department:      rules.Department,
+			TriggerType:     triggerType,
+			Status:          "applied",
+			Rules:           string(ruleData),
+			Promoted:        len(report.Students) - graduated,
+			Graduated:       graduated,
+			Skipped:         len(report.Skipped),
+			ProjectsClosed:  len(report.Projects),
+			BindingsRemoved: len(report.Bindings),
+			Snapshot:        string(snapshot),
+			OperatorID:      operatorID,
+			AppliedAt:       now,
+			UndoDeadline:    now.AddDate(0, 0, undoDays),
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrRolloverExists) {
+			log.Printf("学年升级失败 - 学年: %s, 错误: %v", rules.AcademicYear, err)
+		}
+		return nil, err
+	}
+
+	// 毕业生账号不再可用，撤销其登录会话
+	authService := NewAuthService(s.db)
+	for _, item := range report.Students {
+		if item.Graduate && item.NewStatus != "active" {
+			authService.RevokeUserSessions(item.UserID, "academic_rollover")
+		}
+	}
+
+	report.RolloverID = record.ID
+	report.UndoDeadline = &record.UndoDeadline
+	s.systemLogs.RecordSecurity("academic_rollover", "执行学年升级", "success", operatorID,
+		fmt.Sprintf("记录ID: %d, 学年: %s, 院系: %s, 升级: %d, 毕业: %d, 关闭项目: %d, 解除绑定: %d",
+			record.ID, record.AcademicYear, record.Department, record.Promoted, record.Graduated, record.ProjectsClosed, record.BindingsRemoved), "", "")
+	log.Printf("学年升级完成 - 记录ID: %d, 学年: %s, 升级: %d, 毕业: %d", record.ID, record.AcademicYear, record.Promoted, record.Graduated)
+	return report, nil
+}
+
+// applyRolloverStudents 按 (新年级, 新状态) 分组批量更新学生
+func applyRolloverStudents(tx *gorm.DB, items []models.RolloverStudentItem) error {
+	type change struct{ grade, status string }
+	groups := make(map[change][]uint)
+	for _, item := range items {
+		key := change{item.NewGrade, item.NewStatus}
+		groups[key] = append(groups[key], item.UserID)
+	}
+	for key, ids := range groups {
+		for start := 0; start < len(ids); start += 1000 {
+			end := start + 1000
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if err := tx.Model(&models.User{}).Where("id IN ?", ids[start:end]).
+				Updates(map[string]interface{}{"grade": key.grade, "status": key.status}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Undo 撤销学年升级：只恢复仍保持升级后状态的记录，之后被手动修改过的记录保持现状并在结果中列出
+func (s *AcademicRolloverService) Undo(id uint, operatorID uint) (*models.RolloverUndoResult, error) {
+	var record models.AcademicRollover
+	if err := s.db.First(&record, id).Error; err != nil {
+		return nil, errors.New("学年升级记录不存在")
+	}
+	if record.Status != "applied" {
+		return nil, errors.New("该学年升级已撤销")
+	}
+	if time.Now().After(record.UndoDeadline) {
+		return nil, ErrRolloverUndoExpired
+	}
+	var later int64
+	s.db.Model(&models.AcademicRollover{}).Where("id > ? AND status = ?", record.ID, "applied").Count(&later)
+	if later > 0 {
+		return nil, ErrRolloverNotLatest
+	}
+
+	var report models.RolloverReport
+	if err := json.Unmarshal([]byte(record.Snapshot), &report); err != nil {
+		return nil, errors.New("学年升级快照损坏，无法撤销")
+	}
+
+	result := &models.RolloverUndoResult{Conflicts: []string{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range report.Students {
+			res := tx.Model(&models.User{}).
+				Where("id = ? AND grade = ? AND status = ?", item.UserID, item.NewGrade, item.NewStatus).
+				Updates(map[string]interface{}{"grade": item.OldGrade, "status": item.OldStatus})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				result.Conflicts = append(result.Conflicts, fmt.Sprintf("用户 %s 的年级或状态已被修改", item.Username))
+				continue
+			}
+			result.Students++
+		}
+
+		for _, item := range report.Projects {
+			res := tx.Model(&models.Project{}).Where("id = ? AND status = ?", item.ProjectID, item.NewStatus).
+				Updates(map[string]interface{}{"status": item.OldStatus, "rejection_reason": item.OldRejectionReason})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				result.Conflicts = append(result.Conflicts, fmt.Sprintf("项目「%s」的状态已被修改", item.Title))
+				continue
+			}
+			if err := tx.Create(&models.ProjectStatusHistory{
+				ProjectID: item.ProjectID, OldStatus: item.NewStatus, NewStatus: item.OldStatus,
+				ChangeReason: "撤销学年升级", Action: "rollover_undo", IsForced: true, ChangedBy: &operatorID, ChangedAt: time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+			result.Projects++
+		}
+
+		for _, binding := range report.Bindings {
+			var count int64
+			tx.Model(&models.StudentTeacher{}).Where("student_id = ? AND teacher_id = ?", binding.StudentID, binding.TeacherID).Count(&count)
+			if count > 0 {
+				continue
+			}
+			restored := models.StudentTeacher{StudentID: binding.StudentID, TeacherID: binding.TeacherID, BindTime: binding.BindTime}
+			if err := tx.Create(&restored).Error; err != nil {
+				return err
+			}
+			result.Bindings++
+		}
+
+		now := time.Now()
+		return tx.Model(&record).Updates(map[string]interface{}{
+			"status":    "undone",
+			"undone_at": now,
+			"undone_by": operatorID,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("撤销学年升级失败 - 记录ID: %d, 错误: %v", id, err)
+		return nil, errors.New("撤销学年升级失败")
+	}
+
+	s.systemLogs.RecordSecurity("academic_rollover_undo", "撤销学年升级", "success", &operatorID,
+		fmt.Sprintf("记录ID: %d, 学年: %s, 恢复学生: %d, 恢复项目: %d, 恢复绑定: %d, 冲突: %d",
+			record.ID, record.AcademicYear, result.Students, result.Projects, result.Bindings, len(result.Conflicts)), "", "")
+	return result, nil
+}
+
+// GetRollovers 获取学年升级记录；院系管理员只能看到本院系的记录（与查看详情、撤销的范围一致）
+func (s *AcademicRolloverService) GetRollovers(scope *models.DataScope) ([]models.AcademicRollover, error) {
+	records := []models.AcademicRollover{}
+	query := s.db.Omit("snapshot")
+	if !scope.IsGlobal() {
+		if scope.Department == "" {
+			return records, nil
+		}
+		query = query.Where("department = ?", scope.Department)
+	}
+	err := query.Order("id DESC").Find(&records).Error
+	return records, err
+}
+
+// GetRollover 获取学年升级记录及变更明细
+func (s *AcademicRolloverService) GetRollover(id uint) (*models.AcademicRollover, *models.RolloverReport, error) {
+	var record models.AcademicRollover
+	if err := s.db.First(&record, id).Error; err != nil {
+		return nil, nil, errors.New("学年升级记录不存在")
+	}
+	var report models.RolloverReport
+	json.Unmarshal([]byte(record.Snapshot), &report)
+	report.RolloverID = record.ID
+	report.UndoDeadline = &record.UndoDeadline
+	return &record, &report, nil
+}
+
+// StartAcademicRolloverScheduler 每小时检查一次，到达 rollover_auto_date（MM-DD）当天且本学年尚未升级时自动执行
+func StartAcademicRolloverScheduler(db *gorm.DB) {
+	service := NewAcademicRolloverService(db)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			date := service.settings.GetString("rollover_auto_date", "")
+			now := time.Now()
+			if date == "" || now.Format("01-02") != date {
+				continue
+			}
+			_, err := service.Apply(models.RolloverRules{}, "schedule", nil)
+			if err != nil && !errors.Is(err, ErrRolloverExists) {
+				log.Printf("自动学年升级失败: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+)
+
+func TestGetRolloversFollowsDataScope(t *testing.T) {
+	db := newTestDB(t, &models.AcademicRollover{})
+	for _, department := range []string{"", "计算机学院", "软件学院"} {
+		db.Create(&models.AcademicRollover{AcademicYear: "2026-2027", Department: department, TriggerType: "manual",
+			Status: "applied", AppliedAt: time.Now(), UndoDeadline: time.Now()})
+	}
+	service := NewAcademicRolloverService(db)
+
+	if records, _ := service.GetRollovers(&models.DataScope{Global: true}); len(records) != 3 {
+		t.Errorf("全局范围应看到全部记录，实际 %d", len(records))
+	}
+	records, _ := service.GetRollovers(&models.DataScope{Department: "计算机学院"})
+	if len(records) != 1 || records[0].Department != "计算机学院" {
+		t.Errorf("院系管理员只能看到本院系记录: %+v", records)
+	}
+	if records, _ := service.GetRollovers(&models.DataScope{}); len(records) != 0 {
+		t.Errorf("未设置院系的非全局范围不应看到记录，实际 %d", len(records))
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditEntity 审计实体定义：路由分组对应的实体类型和用于加载快照的模型
+type AuditEntity struct {
+	Type      string
+	BasePaths []string           // 路由模板前缀，路径中的 :id 参数视为该实体ID
+	NewModel  func() interface{} // 返回用于加载快照的模型指针
+}
+
+// auditEntities 需要记录字段变更的实体，匹配时取最长前缀
+var auditEntities = []AuditEntity{
+	{Type: "user", BasePaths: []string{"/api/users"}, NewModel: func() interface{} { return &models.User{} }},
+	{Type: "project", BasePaths: []string{"/api/projects", "/api/admin/projects", "/api/teacher-projects"}, NewModel: func() interface{} { return &models.Project{} }},
+	{Type: "project_type", BasePaths: []string{"/api/project-types", "/api/admin/projects/types"}, NewModel: func() interface{} { return &models.ProjectType{} }},
+	{Type: "competition", BasePaths: []string{"/api/competitions", "/api/admin/competitions", "/api/student-competitions", "/api/teacher-competitions"}, NewModel: func() interface{} { return &models.Competition{} }},
+	{Type: "role", BasePaths: []string{"/api/admin/roles"}, NewModel: func() interface{} { return &models.Role{} }},
+	{Type: "permission", BasePaths: []string{"/api/admin/permissions"}, NewModel: func() interface{} { return &models.Permission{} }},
+}
+
+// auditNoSnapshotRoutes 只记录请求、不记录字段快照的路由：匿名化会清除日志中的个人信息，本次变更前的取值也不能写入日志
+var auditNoSnapshotRoutes = map[string]bool{
+	"/api/users/:id/anonymize": true,
+}
+
+// auditIgnoredFields 不参与变更比较的字段（自动维护的时间戳）
+var auditIgnoredFields = map[string]bool{
+	"updatedAt":   true,
+	"updated_at":  true,
+	"updateTime":  true,
+	"update_time": true,
+}
+
+// auditSensitiveMarkers 字段名包含这些关键字时只记录“已修改”，不记录取值
+var auditSensitiveMarkers = []string{"password", "secret", "token", "hash"}
+
+// AuditFieldChange 单个字段的变更前后取值
+type AuditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry 一次写操作的审计记录
+type AuditEntry struct {
+	UserID          uint
+	Role            string
+	Method          string
+	Route           string
+	Path            string
+	EntityType      string
+	EntityID        string
+	StatusCode      int
+	IPAddress       string
+	UserAgent       string
+	PersonalTokenID uint
+	ImpersonatorID  uint
+	Before          map[string]interface{}
+	After           map[string]interface{}
+}
+
+type AuditService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// ResolveEntity 根据路由模板匹配审计实体，未登记的路由返回nil
+func (s *AuditService) ResolveEntity(route string) *AuditEntity {
+	var matched *AuditEntity
+	matchedLen := 0
+	for i := range auditEntities {
+		for _, base := range auditEntities[i].BasePaths {
+			if (route == base || strings.HasPrefix(route, base+"/")) && len(base) > matchedLen {
+				matched = &auditEntities[i]
+				matchedLen = len(base)
+			}
+		}
+	}
+	return matched
+}
+
+// SkipsSnapshot 路由是否不记录字段快照
+func (s *AuditService) SkipsSnapshot(route string) bool {
+	return auditNoSnapshotRoutes[route]
+}
+
+// Snapshot 加载实体当前数据并转换为字段表，记录不存在时返回nil
+func (s *AuditService) Snapshot(entity *AuditEntity, id string) map[string]interface{} {
+	if entity == nil || id == "" {
+		return nil
+	}
+	model := entity.NewModel()
+	if err := s.db.First(model, "id = ?", id).Error; err != nil {
+		return nil
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	// 关联对象未预加载，不参与比较
+	for key, value := range fields {
+		if _, isObject := value.(map[string]interface{}); isObject || value == nil {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+// Diff 比较前后快照，返回发生变化的字段
+func (s *AuditService) Diff(before, after map[string]interface{}) map[string]AuditFieldChange {
+	changes := make(map[string]AuditFieldChange)
+	keys := make(map[string]bool, len(before)+len(after))
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		oldValue, newValue := before[key], after[key]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if isSensitiveField(key) {
+			changes[key] = AuditFieldChange{Before: "******", After: "******"}
+			continue
+		}
+		changes[key] = AuditFieldChange{Before: oldValue, After: newValue}
+	}
+	return changes
+}
+
+// Record 写入审计日志
+func (s *AuditService) Record(entry AuditEntry) {
+	status := "success"
+	if entry.StatusCode >= http.StatusBadRequest {
+		status = "failed"
+	}
+
+	var changes string
+	if diff := s.Diff(entry.Before, entry.After); len(diff) > 0 {
+		if data, err := json.Marshal(diff); err == nil {
+			changes = string(data)
+		} else {
+			log.Printf("序列化审计变更失败 - 路由: %s, 错误: %v", entry.Route, err)
+		}
+	}
+
+	details := fmt.Sprintf("路径: %s, 状态码: %d", entry.Path, entry.StatusCode)
+	if entry.PersonalTokenID != 0 {
+		details += fmt.Sprintf(", 个人访问令牌ID: %d", entry.PersonalTokenID)
+	}
+	var impersonatorID *uint
+	if entry.ImpersonatorID != 0 {
+		impersonatorID = &entry.ImpersonatorID
+		details += fmt.Sprintf(", 模拟登录管理员ID: %d", entry.ImpersonatorID)
+	}
+
+	var userID *uint
+	if entry.UserID != 0 {
+		userID = &entry.UserID
+	}
+
+	s.systemLogs.Record(models.SystemLog{
+		LogType:    "audit",
+		Operation:  auditOperation(entry.EntityType, entry.Method),
+		Status:     status,
+		UserID:     userID,
+		Action:     entry.Method + " " + entry.Route,
+		Details:    details,
+		IPAddress:  entry.IPAddress,
+		UserAgent:  entry.UserAgent,
+		Role:       entry.Role,
+		Method:     entry.Method,
+		Route:      entry.Route,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		StatusCode: entry.StatusCode,
+		Changes:    changes,
+		// 模拟登录期间的请求同时记录管理员
+		ImpersonatorID: impersonatorID,
+	})
+}
+
+// auditOperation 由实体类型和请求方法生成操作名，例如 project.update
+func auditOperation(entityType, method string) string {
+	verb := "update"
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		verb = "read"
+	case http.MethodPost:
+		verb = "create"
+	case http.MethodDelete:
+		verb = "delete"
+	}
+	if entityType == "" {
+		return "request." + verb
+	}
+	return entityType + "." + verb
+}
+
+func isSensitiveField(key string) bool {
+	lower := strings.ToLower(key)
+	for _, marker := range auditSensitiveMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
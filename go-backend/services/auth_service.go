@@ -0,0 +1,441 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenTTL 刷新令牌有效期，每次轮换后重新计算
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+// sessionTouchInterval 会话最近活动时间的最小更新间隔，避免每个请求都写库
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，会话已撤销")
+	ErrSessionRevoked      = errors.New("会话已失效，请重新登录")
+	ErrSessionRoleChanged  = errors.New("当前会话已切换角色，请使用新令牌")
+	ErrRoleNotHeld         = errors.New("用户不具备该角色")
+	ErrSessionNotFound     = errors.New("会话不存在或已失效")
+)
+
+// 受限会话类型
+const (
+	SessionRestrictionTwoFactorSetup = "two_factor_setup"
+	SessionRestrictionPasswordChange = "password_change"
+)
+
+// 登录方式，统一身份认证登录为 sso:<认证提供方标识>，目录密码登录为 ldap
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodLDAP      = "ldap"
+	LoginMethodSSOPrefix = "sso:"
+)
+
+// SessionOptions 创建会话的附加选项
+type SessionOptions struct {
+	Restriction string // 受限会话类型，空表示不受限
+	LoginMethod string // 登录方式，空表示账号密码登录
+}
+
+type AuthService struct {
+	db *gorm.DB
+}
+
+func NewAuthService(db *gorm.DB) *AuthService {
+	return &AuthService{db: db}
+}
+
+// CreateSession 创建登录会话并签发访问令牌和刷新令牌
+func (s *AuthService) CreateSession(user *models.User, role, ipAddress, userAgent string, opts SessionOptions) (*models.TokenPair, error) {
+	var pair *models.TokenPair
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		loginMethod := opts.LoginMethod
+		if loginMethod == "" {
+			loginMethod = LoginMethodPassword
+		}
+		session := models.UserSession{
+			UserID:       user.ID,
+			Role:         role,
+			IPAddress:    ipAddress,
+			UserAgent:    userAgent,
+			ExpiresAt:    now.Add(RefreshTokenTTL),
+			Restriction:  opts.Restriction,
+			LoginMethod:  loginMethod,
+			LastActiveAt: &now,
+			LastActiveIP: ipAddress,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		refreshToken, _, err := s.issueRefreshToken(tx, session.ID)
+		if err != nil {
+			return err
+		}
+
+		accessToken, err := utils.GenerateToken(user.ID, user.Username, role, session.ID)
+		if err != nil {
+			return err
+		}
+
+		pair = &models.TokenPair{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+			SessionID:    session.ID,
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("创建登录会话失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return nil, errors.New("创建登录会话失败")
+	}
+
+	log.Printf("登录会话创建成功 - 用户ID: %d, 会话ID: %d", user.ID, pair.SessionID)
+	return pair, nil
+}
+
+// RefreshSession 使用刷新令牌换取新的令牌对（刷新令牌一次性使用并轮换）
+func (s *AuthService) RefreshSession(rawToken string) (*models.TokenPair, error) {
+	var token models.RefreshToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	// 已使用过的刷新令牌再次出现，视为令牌泄露，撤销整个令牌家族
+	if token.UsedAt != nil {
+		log.Printf("检测到刷新令牌重放 - 会话ID: %d, 令牌ID: %d", token.SessionID, token.ID)
+		if err := s.RevokeSession(token.SessionID, "refresh_token_reuse"); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	var session models.UserSession
+	if err := s.db.First(&session, token.SessionID).Error; err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if !session.IsActive() {
+		return nil, ErrSessionRevoked
+	}
+
+	var user models.User
+	if err := s.db.First(&user, session.UserID).Error; err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if user.Status != "active" {
+		s.RevokeSession(session.ID, "user_disabled")
+		return nil, ErrSessionRevoked
+	}
+
+	var pair *models.TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 条件更新保证并发请求中只有一个能完成轮换
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		refreshToken, newTokenID, err := s.issueRefreshToken(tx, session.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).Where("id = ?", token.ID).Update("replaced_by", newTokenID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"expires_at":     now.Add(RefreshTokenTTL),
+			"last_active_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		accessToken, err := utils.GenerateToken(user.ID, user.Username, session.Role, session.ID)
+		if err != nil {
+			return err
+		}
+
+		pair = &models.TokenPair{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+			SessionID:    session.ID,
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			s.RevokeSession(session.ID, "refresh_token_reuse")
+			return nil, err
+		}
+		log.Printf("刷新令牌轮换失败 - 会话ID: %d, 错误: %v", session.ID, err)
+		return nil, errors.New("刷新令牌失败")
+	}
+
+	log.Printf("刷新令牌轮换成功 - 用户ID: %d, 会话ID: %d", user.ID, session.ID)
+	return pair, nil
+}
+
+// ValidateSession 校验访问令牌所属会话是否仍然有效且角色与会话一致，返回会话记录
+func (s *AuthService) ValidateSession(sessionID, userID uint, role string) (*models.UserSession, error) {
+	if sessionID == 0 {
+		return nil, ErrSessionRevoked
+	}
+
+	var session models.UserSession
+	if err := s.db.Select("id", "user_id", "role", "expires_at", "revoked_at", "restriction", "last_active_at", "last_active_ip", "impersonator_id", "impersonation_write").First(&session, sessionID).Error; err != nil {
+		return nil, ErrSessionRevoked
+	}
+	if session.UserID != userID || !session.IsActive() {
+		return nil, ErrSessionRevoked
+	}
+	// 切换角色后，旧角色签发的访问令牌立即失效
+	if session.Role != role {
+		return nil, ErrSessionRoleChanged
+	}
+	return &session, nil
+}
+
+// TouchSession 记录会话最近活动时间和IP（同一会话每分钟最多写一次，IP变化时立即更新）
+func (s *AuthService) TouchSession(session *models.UserSession, ipAddress string) {
+	now := time.Now()
+	if session.LastActiveAt != nil && now.Sub(*session.LastActiveAt) < sessionTouchInterval && session.LastActiveIP == ipAddress {
+		return
+	}
+	if err := s.db.Model(&models.UserSession{}).Where("id = ?", session.ID).UpdateColumns(map[string]interface{}{
+		"last_active_at": now,
+		"last_active_ip": ipAddress,
+	}).Error; err != nil {
+		log.Printf("更新会话活动时间失败 - 会话ID: %d, 错误: %v", session.ID, err)
+	}
+}
+
+// GetActiveSessions 获取用户当前有效的登录会话（按最近活动时间倒序），currentSessionID 对应的会话标记为当前设备
+func (s *AuthService) GetActiveSessions(userID, currentSessionID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_active_at DESC").Order("id DESC").
+		Find(&sessions).Error; err != nil {
+		log.Printf("获取用户会话失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, errors.New("获取登录设备失败")
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// CountActiveSessions 统计用户当前有效的登录会话数
+func (s *AuthService) CountActiveSessions(userID uint) int64 {
+	var count int64
+	s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Count(&count)
+	return count
+}
+
+// RevokeUserSession 撤销属于指定用户的单个会话
+func (s *AuthService) RevokeUserSession(userID, sessionID uint, reason string) error {
+	result := s.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]interface{}{
+			"revoked_at":    time.Now(),
+			"revoke_reason": reason,
+		})
+	if result.Error != nil {
+		log.Printf("撤销会话失败 - 用户ID: %d, 会话ID: %d, 错误: %v", userID, sessionID, result.Error)
+		return errors.New("撤销会话失败")
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	log.Printf("会话已撤销 - 用户ID: %d, 会话ID: %d, 原因: %s", userID, sessionID, reason)
+	return nil
+}
+
+// RevokeOtherSessions 撤销用户除当前会话外的全部会话
+func (s *AuthService) RevokeOtherSessions(userID, currentSessionID uint, reason string) (int64, error) {
+	result := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentSessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":    time.Now(),
+			"revoke_reason": reason,
+		})
+	if result.Error != nil {
+		log.Printf("撤销其他会话失败 - 用户ID: %d, 错误: %v", userID, result.Error)
+		return 0, errors.New("撤销其他会话失败")
+	}
+
+	log.Printf("其他会话已撤销 - 用户ID: %d, 保留会话ID: %d, 数量: %d", userID, currentSessionID, result.RowsAffected)
+	return result.RowsAffected, nil
+}
+
+// GetUserRoles 获取用户拥有的全部角色
+func (s *AuthService) GetUserRoles(userID uint) ([]models.Role, error) {
+	var roles []models.Role
+	if err := s.db.Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.id ASC").
+		Find(&roles).Error; err != nil {
+		log.Printf("获取用户角色失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SwitchRole 将当前会话切换到用户拥有的另一个角色，重新签发令牌对
+func (s *AuthService) SwitchRole(sessionID, userID uint, role string) (*models.TokenPair, string, error) {
+	var session models.UserSession
+	if err := s.db.First(&session, sessionID).Error; err != nil || session.UserID != userID || !session.IsActive() {
+		return nil, "", ErrSessionRevoked
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, "", ErrSessionRevoked
+	}
+
+	var count int64
+	s.db.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.role_key = ?", userID, role).
+		Count(&count)
+	if count == 0 {
+		return nil, "", ErrRoleNotHeld
+	}
+
+	previousRole := session.Role
+	var pair *models.TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&session).Update("role", role).Error; err != nil {
+			return err
+		}
+		// 旧刷新令牌直接过期（而不是标记为已使用，避免被误判为重放而撤销整个会话）
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("session_id = ? AND used_at IS NULL AND expires_at > ?", session.ID, now).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+
+		refreshToken, _, err := s.issueRefreshToken(tx, session.ID)
+		if err != nil {
+			return err
+		}
+		accessToken, err := utils.GenerateToken(user.ID, user.Username, role, session.ID)
+		if err != nil {
+			return err
+		}
+
+		pair = &models.TokenPair{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(utils.AccessTokenTTL.Seconds()),
+			SessionID:    session.ID,
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("切换角色失败 - 用户ID: %d, 会话ID: %d, 错误: %v", userID, sessionID, err)
+		return nil, "", errors.New("切换角色失败")
+	}
+
+	log.Printf("角色切换成功 - 用户ID: %d, 会话ID: %d, %s -> %s", userID, sessionID, previousRole, role)
+	return pair, previousRole, nil
+}
+
+// ClearSessionRestriction 解除用户会话上的指定限制（例如完成两步验证绑定后）
+func (s *AuthService) ClearSessionRestriction(userID uint, restriction string) error {
+	if err := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND restriction = ? AND revoked_at IS NULL", userID, restriction).
+		Update("restriction", "").Error; err != nil {
+		log.Printf("解除会话限制失败 - 用户ID: %d, 错误: %v", userID, err)
+		return errors.New("解除会话限制失败")
+	}
+	return nil
+}
+
+// SetSessionRestriction 设置单个会话的限制（空字符串表示解除限制）
+func (s *AuthService) SetSessionRestriction(sessionID uint, restriction string) error {
+	if err := s.db.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("restriction", restriction).Error; err != nil {
+		log.Printf("更新会话限制失败 - 会话ID: %d, 错误: %v", sessionID, err)
+		return errors.New("更新会话限制失败")
+	}
+	return nil
+}
+
+// RevokeSession 撤销单个会话及其全部刷新令牌
+func (s *AuthService) RevokeSession(sessionID uint, reason string) error {
+	now := time.Now()
+	if err := s.db.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoke_reason": reason,
+		}).Error; err != nil {
+		log.Printf("撤销会话失败 - 会话ID: %d, 错误: %v", sessionID, err)
+		return errors.New("撤销会话失败")
+	}
+
+	log.Printf("会话已撤销 - 会话ID: %d, 原因: %s", sessionID, reason)
+	return nil
+}
+
+// RevokeUserSessions 撤销用户的全部会话
+func (s *AuthService) RevokeUserSessions(userID uint, reason string) (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoke_reason": reason,
+		})
+	if result.Error != nil {
+		log.Printf("撤销用户会话失败 - 用户ID: %d, 错误: %v", userID, result.Error)
+		return 0, errors.New("撤销用户会话失败")
+	}
+
+	log.Printf("用户会话已全部撤销 - 用户ID: %d, 数量: %d, 原因: %s", userID, result.RowsAffected, reason)
+	return result.RowsAffected, nil
+}
+
+// issueRefreshToken 为会话签发新的刷新令牌，返回明文令牌和记录ID
+func (s *AuthService) issueRefreshToken(tx *gorm.DB, sessionID uint) (string, uint, error) {
+	rawToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	token := models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", 0, err
+	}
+	return rawToken, token.ID, nil
+}
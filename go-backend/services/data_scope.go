@@ -0,0 +1,34 @@
+package services
+
+import (
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// DepartmentUserIDs 返回指定院系用户ID的子查询（院系以用户档案为准）
+func DepartmentUserIDs(db *gorm.DB, department string) *gorm.DB {
+	return db.Model(&models.UserProfile{}).Select("user_id").Where("department = ?", department)
+}
+
+// ApplyUserScope 按数据范围限制查询，userColumn 为查询中代表数据归属用户的列
+func ApplyUserScope(db, query *gorm.DB, scope *models.DataScope, userColumn string) *gorm.DB {
+	if scope.IsGlobal() {
+		return query
+	}
+	// 未设置院系的受限账号看不到任何数据
+	if scope.Department == "" {
+		return query.Where("1 = 0")
+	}
+	return query.Where(userColumn+" IN (?)", DepartmentUserIDs(db, scope.Department))
+}
+
+// UserInScope 判断用户是否在数据范围内
+func UserInScope(db *gorm.DB, scope *models.DataScope, userID uint) bool {
+	if scope.IsGlobal() {
+		return true
+	}
+	var count int64
+	ApplyUserScope(db, db.Model(&models.User{}), scope, "users.id").Where("users.id = ?", userID).Count(&count)
+	return count > 0
+}
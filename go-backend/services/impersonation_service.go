@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// defaultImpersonationMinutes 模拟登录默认有效期（分钟）
+const defaultImpersonationMinutes = 30
+
+var (
+	ErrImpersonateSelf   = errors.New("不能模拟自己的账号")
+	ErrImpersonateAdmin  = errors.New("不能模拟管理员账号")
+	ErrNotImpersonating  = errors.New("当前会话不是模拟登录会话")
+	ErrImpersonateTarget = errors.New("目标用户不存在或已被禁用")
+)
+
+// ImpersonationService 管理员模拟登录（以用户身份查看页面，用于排查问题）
+type ImpersonationService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewImpersonationService(db *gorm.DB) *ImpersonationService {
+	return &ImpersonationService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// Start 创建模拟登录会话并签发限时令牌，令牌同时携带管理员ID和被模拟用户ID
+func (s *ImpersonationService) Start(operatorID, targetID uint, req models.ImpersonationRequest, ipAddress, userAgent string) (*models.ImpersonationResponse, error) {
+	if operatorID == targetID {
+		return nil, ErrImpersonateSelf
+	}
+
+	var target models.User
+	if err := s.db.Preload("Roles").First(&target, targetID).Error; err != nil || target.Status != "active" {
+		return nil, ErrImpersonateTarget
+	}
+
+	role := req.Role
+	held := false
+	for _, r := range target.Roles {
+		if r.RoleKey == "admin" {
+			return nil, ErrImpersonateAdmin
+		}
+		if role == "" {
+			role = r.RoleKey
+		}
+		if r.RoleKey == role {
+			held = true
+		}
+	}
+	if !held {
+		return nil, ErrRoleNotHeld
+	}
+
+	minutes := req.DurationMinutes
+	if minutes <= 0 {
+		minutes = defaultImpersonationMinutes
+	}
+	ttl := time.Duration(minutes) * time.Minute
+	now := time.Now()
+
+	session := models.UserSession{
+		UserID:             target.ID,
+		Role:               role,
+		IPAddress:          ipAddress,
+		UserAgent:          userAgent,
+		ExpiresAt:          now.Add(ttl),
+		LastActiveAt:       &now,
+		LastActiveIP:       ipAddress,
+		ImpersonatorID:     &operatorID,
+		ImpersonationWrite: req.AllowWrite,
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		log.Printf("创建模拟登录会话失败 - 管理员ID: %d, 目标用户ID: %d, 错误: %v", operatorID, targetID, err)
+		return nil, errors.New("创建模拟登录会话失败")
+	}
+
+	token, err := utils.GenerateImpersonationToken(target.ID, target.Username, role, session.ID, operatorID, ttl)
+	if err != nil {
+		s.db.Model(&session).Updates(map[string]interface{}{"revoked_at": now, "revoke_reason": "impersonation_error"})
+		return nil, errors.New("生成模拟登录令牌失败")
+	}
+
+	s.systemLogs.RecordSecurity("impersonation_start", "开始模拟登录", "success", &operatorID,
+		fmt.Sprintf("目标用户ID: %d, 用户名: %s, 角色: %s, 会话ID: %d, 有效期: %d分钟, 允许写操作: %t, 原因: %s",
+			target.ID, target.Username, role, session.ID, minutes, req.AllowWrite, req.Reason),
+		ipAddress, userAgent)
+	log.Printf("模拟登录开始 - 管理员ID: %d, 目标用户ID: %d, 会话ID: %d", operatorID, target.ID, session.ID)
+
+	return &models.ImpersonationResponse{
+		Token:      token,
+		ExpiresIn:  int64(ttl.Seconds()),
+		ExpiresAt:  session.ExpiresAt,
+		UserID:     target.ID,
+		Username:   target.Username,
+		Role:       role,
+		AllowWrite: req.AllowWrite,
+	}, nil
+}
+
+// Stop 结束模拟登录会话
+func (s *ImpersonationService) Stop(sessionID, impersonatorID, userID uint, ipAddress, userAgent string) error {
+	if sessionID == 0 || impersonatorID == 0 {
+		return ErrNotImpersonating
+	}
+
+	if err := NewAuthService(s.db).RevokeSession(sessionID, "impersonation_end"); err != nil {
+		return err
+	}
+
+	s.systemLogs.RecordSecurity("impersonation_stop", "结束模拟登录", "success", &impersonatorID,
+		fmt.Sprintf("目标用户ID: %d, 会话ID: %d", userID, sessionID), ipAddress, userAgent)
+	log.Printf("模拟登录结束 - 管理员ID: %d, 目标用户ID: %d, 会话ID: %d", impersonatorID, userID, sessionID)
+	return nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// LDAPAuthService 目录账号登录：同步创建的账号本地只有随机密码，登录时使用目录密码绑定认证
+type LDAPAuthService struct {
+	db *gorm.DB
+}
+
+func NewLDAPAuthService(db *gorm.DB) *LDAPAuthService {
+	return &LDAPAuthService{db: db}
+}
+
+// Authenticate 用户是否为已关联的目录账号且目录密码正确。
+// 未配置目录、用户未关联目录或目录条目与关联记录不一致（用户名被重新分配）时返回 false
+func (s *LDAPAuthService) Authenticate(user *models.User, password string) bool {
+	directory, err := utils.GetLDAPDirectory()
+	if err != nil {
+		return false
+	}
+	var identity models.UserIdentity
+	if err := s.db.Where("provider = ? AND user_id = ?", ldapIdentityProvider, user.ID).First(&identity).Error; err != nil {
+		return false
+	}
+
+	entry, err := directory.Authenticate(user.Username, password)
+	if err != nil {
+		if !errors.Is(err, utils.ErrLDAPInvalidCredentials) {
+			log.Printf("目录认证失败 - 用户名: %s, 错误: %v", user.Username, err)
+		}
+		return false
+	}
+	if mapLDAPEntry(directory, entry).Subject != identity.Subject {
+		log.Printf("目录认证拒绝 - 用户名: %s, 目录条目与关联记录不一致", user.Username)
+		return false
+	}
+
+	now := time.Now()
+	s.db.Model(&identity).Update("last_login_at", &now)
+	return true
+}
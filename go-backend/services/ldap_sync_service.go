@@ -0,0 +1,578 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// ldapIdentityProvider 目录账号在 user_identities 中的认证方式标识
+const ldapIdentityProvider = "ldap"
+
+var ErrLDAPSyncRunning = errors.New("目录同步正在进行中，请稍后再试")
+
+// ldapSyncLock 同一时间只允许一个同步任务（定时任务与手动触发共用）
+var ldapSyncLock sync.Mutex
+
+// ldapPerson 目录账号映射后的本地字段
+type ldapPerson struct {
+	Subject    string
+	DN         string
+	Username   string
+	Email      string
+	RealName   string
+	Phone      string
+	StudentID  string
+	Department string
+	Title      string
+	Grade      string
+	Major      string
+	Role       string
+}
+
+// ldapSyncPlan 一条同步差异及执行所需的数据
+type ldapSyncPlan struct {
+	item   models.LDAPSyncItem
+	person ldapPerson
+	update models.UserUpdateRequest
+}
+
+// LDAPSyncService LDAP目录同步：从目录拉取教职工和学生，通过 UserService 创建、更新或停用本地账号
+type LDAPSyncService struct {
+	db          *gorm.DB
+	userService *UserService
+	settings    *SettingService
+	systemLogs  *SystemLogService
+}
+
+func NewLDAPSyncService(db *gorm.DB) *LDAPSyncService {
+	return &LDAPSyncService{
+		db:          db,
+		userService: NewUserService(db),
+		settings:    NewSettingService(db),
+		systemLogs:  NewSystemLogService(db),
+	}
+}
+
+// Run 执行一次目录同步；dryRun 时只计算差异不修改账号。每次运行（含试运行）都会记录到 ldap_sync_runs
+func (s *LDAPSyncService) Run(triggerType string, dryRun bool, operatorID *uint) (*models.LDAPSyncRun, error) {
+	directory, err := utils.GetLDAPDirectory()
+	if err != nil {
+		return nil, err
+	}
+	if !ldapSyncLock.TryLock() {
+		return nil, ErrLDAPSyncRunning
+	}
+	defer ldapSyncLock.Unlock()
+
+	run := models.LDAPSyncRun{
+		TriggerType: triggerType,
+		DryRun:      dryRun,
+		Status:      "running",
+		OperatorID:  operatorID,
+		StartedAt:   time.Now(),
+	}
+	if err := s.db.Create(&run).Error; err != nil {
+		log.Printf("创建目录同步记录失败: %v", err)
+		return nil, errors.New("创建目录同步记录失败")
+	}
+
+	plans, err := s.plan(directory)
+	if err != nil {
+		s.finish(&run, nil, err)
+		return &run, err
+	}
+	if !dryRun {
+		s.apply(plans)
+	}
+	s.finish(&run, plans, nil)
+	return &run, nil
+}
+
+// plan 读取目录并与本地账号比较，生成差异列表
+func (s *LDAPSyncService) plan(directory *utils.LDAPDirectory) ([]*ldapSyncPlan, error) {
+	entries, err := directory.Search()
+	if err != nil {
+		return nil, err
+	}
+	// 目录返回空结果通常是配置错误，继续执行会停用全部目录账号
+	if len(entries) == 0 {
+		return nil, errors.New("目录未返回任何账号，已中止同步，请检查 LDAP_BASE_DN 和 LDAP_USER_FILTER")
+	}
+	return s.planEntries(directory, entries), nil
+}
+
+// planEntries 比较目录条目与本地账号。本地账号按批预先加载，查询次数不随目录人数增长
+func (s *LDAPSyncService) planEntries(directory *utils.LDAPDirectory, entries []utils.LDAPEntry) []*ldapSyncPlan {
+	var identities []models.UserIdentity
+	s.db.Where("provider = ?", ldapIdentityProvider).Find(&identities)
+	linked := make(map[string]uint, len(identities))
+	linkedUsers := make(map[uint]string, len(identities))
+	for _, identity := range identities {
+		linked[identity.Subject] = identity.UserID
+		linkedUsers[identity.UserID] = identity.Subject
+	}
+
+	persons := make([]ldapPerson, 0, len(entries))
+	var usernames, emails []string
+	for _, entry := range entries {
+		person := mapLDAPEntry(directory, entry)
+		persons = append(persons, person)
+		if person.Username != "" {
+			usernames = append(usernames, person.Username)
+		}
+		if person.Email != "" {
+			emails = append(emails, person.Email)
+		}
+	}
+	users := s.loadUsers(linkedUsers, usernames, emails)
+	recycled := s.recycledNames(usernames, emails)
+
+	conflictPolicy := s.settings.GetString("ldap_sync_conflict_policy", "skip")
+	seen := make(map[string]bool, len(entries))
+	var plans []*ldapSyncPlan
+
+	for _, person := range persons {
+		plan := &ldapSyncPlan{person: person}
+		plan.item = models.LDAPSyncItem{DN: person.DN, Subject: person.Subject, Username: person.Username}
+		plans = append(plans, plan)
+
+		if seen[person.Subject] {
+			plan.item.Action = "skip"
+			plan.item.Message = "目录中存在重复的唯一标识"
+			continue
+		}
+		seen[person.Subject] = true
+
+		if person.Username == "" || person.Email == "" {
+			plan.item.Action = "skip"
+			plan.item.Message = "缺少用户名或邮箱属性"
+			continue
+		}
+		if person.Role == "" {
+			plan.item.Action = "skip"
+			plan.item.Message = "人员类型未映射到角色"
+			continue
+		}
+
+		// 已关联的账号：比较字段差异
+		if userID, ok := linked[person.Subject]; ok {
+			s.planUpdate(plan, users.byID[userID], userID, "update")
+			continue
+		}
+
+		// 未关联：按用户名和邮箱查找已有账号
+		byUsername, usernameFound := users.byUsername[person.Username]
+		byEmail, emailFound := users.byEmail[person.Email]
+
+		switch {
+		case usernameFound && linkedUsers[byUsername.ID] != "":
+			plan.item.Action = "conflict"
+			plan.item.UserID = byUsername.ID
+			plan.item.Message = "用户名已被另一个目录账号使用"
+		case usernameFound && emailFound && byUsername.ID == byEmail.ID:
+			// 用户名和邮箱都一致，视为同一人
+			s.planUpdate(plan, byUsername, byUsername.ID, "link")
+		case usernameFound && emailFound:
+			plan.item.Action = "conflict"
+			plan.item.UserID = byUsername.ID
+			plan.item.Message = fmt.Sprintf("用户名与邮箱分别属于不同的本地账号（用户ID %d / %d）", byUsername.ID, byEmail.ID)
+		case usernameFound:
+			if conflictPolicy == "link" {
+				s.planUpdate(plan, byUsername, byUsername.ID, "link")
+			} else {
+				plan.item.Action = "conflict"
+				plan.item.UserID = byUsername.ID
+				plan.item.Message = "用户名已存在且邮箱不一致"
+			}
+		case emailFound && linkedUsers[byEmail.ID] != "":
+			plan.item.Action = "conflict"
+			plan.item.UserID = byEmail.ID
+			plan.item.Message = "邮箱已被另一个目录账号使用"
+		case emailFound:
+			if conflictPolicy == "link" {
+				s.planUpdate(plan, byEmail, byEmail.ID, "link")
+			} else {
+				plan.item.Action = "conflict"
+				plan.item.UserID = byEmail.ID
+				plan.item.Message = "邮箱已被用户名不同的本地账号使用"
+			}
+		case recycled[person.Username] || recycled[person.Email]:
+			plan.item.Action = "conflict"
+			plan.item.Message = "用户名或邮箱被回收站中的用户占用"
+		default:
+			plan.item.Action = "create"
+			plan.item.Changes = person.fields().diff(ldapFields{})
+		}
+	}
+
+	// 目录中已不存在的账号：停用
+	if s.settings.GetBool("ldap_sync_deactivate_missing", true) {
+		for _, identity := range identities {
+			if seen[identity.Subject] {
+				continue
+			}
+			user := users.byID[identity.UserID]
+			if user == nil || user.Status != "active" {
+				continue
+			}
+			plans = append(plans, &ldapSyncPlan{item: models.LDAPSyncItem{
+				Action:   "deactivate",
+				Subject:  identity.Subject,
+				Username: user.Username,
+				UserID:   user.ID,
+				Changes:  map[string][2]string{"status": {"active", "inactive"}},
+				Message:  "目录中已不存在该账号",
+			}})
+		}
+	}
+	return plans
+}
+
+// ldapBatchSize 批量查询本地账号时每批的条件数量，避免 IN 列表过长
+const ldapBatchSize = 500
+
+// ldapLocalUsers 同步涉及的本地账号（含资料和角色），按ID、用户名、邮箱索引
+type ldapLocalUsers struct {
+	byID       map[uint]*models.User
+	byUsername map[string]*models.User
+	byEmail    map[string]*models.User
+}
+
+func (u *ldapLocalUsers) add(users []models.User) {
+	for i := range users {
+		user := &users[i]
+		u.byID[user.ID] = user
+		u.byUsername[user.Username] = user
+		u.byEmail[user.Email] = user
+	}
+}
+
+// loadUsers 分批加载已关联目录的账号，以及用户名或邮箱与目录条目相同的账号
+func (s *LDAPSyncService) loadUsers(linkedUsers map[uint]string, usernames, emails []string) *ldapLocalUsers {
+	users := &ldapLocalUsers{byID: map[uint]*models.User{}, byUsername: map[string]*models.User{}, byEmail: map[string]*models.User{}}
+	query := func() *gorm.DB { return s.db.Preload("Profile").Preload("Roles") }
+
+	ids := make([]uint, 0, len(linkedUsers))
+	for id := range linkedUsers {
+		ids = append(ids, id)
+	}
+	for start := 0; start < len(ids); start += ldapBatchSize {
+		var batch []models.User
+		query().Where("id IN ?", ids[start:batchEnd(start, len(ids))]).Find(&batch)
+		users.add(batch)
+	}
+	for _, column := range []struct {
+		name   string
+		values []string
+	}{{"username", usernames}, {"email", emails}} {
+		for start := 0; start < len(column.values); start += ldapBatchSize {
+			var batch []models.User
+			query().Where(column.name+" IN ?", column.values[start:batchEnd(start, len(column.values))]).Find(&batch)
+			users.add(batch)
+		}
+	}
+	return users
+}
+
+// recycledNames 被回收站中的用户占用的用户名和邮箱
+func (s *LDAPSyncService) recycledNames(usernames, emails []string) map[string]bool {
+	held := make(map[string]bool)
+	for _, column := range []struct {
+		name   string
+		values []string
+	}{{"username", usernames}, {"email", emails}} {
+		for start := 0; start < len(column.values); start += ldapBatchSize {
+			var names []string
+			s.db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL").
+				Where(column.name+" IN ?", column.values[start:batchEnd(start, len(column.values))]).Pluck(column.name, &names)
+			for _, name := range names {
+				held[name] = true
+			}
+		}
+	}
+	return held
+}
+
+// batchEnd 从 start 开始的一批的结束下标
+func batchEnd(start, total int) int {
+	if start+ldapBatchSize < total {
+		return start + ldapBatchSize
+	}
+	return total
+}
+
+// planUpdate 比较本地账号与目录字段，生成更新请求；没有差异时动作为 unchanged（关联动作始终保留）
+func (s *LDAPSyncService) planUpdate(plan *ldapSyncPlan, user *models.User, userID uint, action string) {
+	plan.item.UserID = userID
+	if user == nil {
+		plan.item.Action = "skip"
+		plan.item.Message = "本地账号不存在"
+		return
+	}
+
+	current := ldapFields{
+		Email: user.Email, Department: user.Department, Title: user.Title, Grade: user.Grade, Major: user.Major,
+	}
+	if user.Profile != nil {
+		current.RealName = user.Profile.RealName
+		current.Phone = user.Profile.Phone
+		current.StudentID = user.Profile.StudentID
+	}
+
+	changes := plan.person.fields().diff(current)
+	hasRole := false
+	roleKeys := make([]string, 0, len(user.Roles)+1)
+	for _, role := range user.Roles {
+		roleKeys = append(roleKeys, role.RoleKey)
+		if role.RoleKey == plan.person.Role {
+			hasRole = true
+		}
+	}
+
+	update := plan.person.updateRequest(changes)
+	if !hasRole {
+		// 只补充目录角色，不移除本地另外授予的角色
+		changes["role"] = [2]string{"", plan.person.Role}
+		update.RoleKeys = append(roleKeys, plan.person.Role)
+	}
+
+	plan.update = update
+	plan.item.Changes = changes
+	plan.item.Action = action
+	if action == "update" && len(changes) == 0 {
+		plan.item.Action = "unchanged"
+	}
+}
+
+// apply 按差异逐条执行，单条失败不影响其他账号
+func (s *LDAPSyncService) apply(plans []*ldapSyncPlan) {
+	for _, plan := range plans {
+		var err error
+		switch plan.item.Action {
+		case "create":
+			err = s.applyCreate(plan)
+		case "link":
+			if err = s.db.Create(&models.UserIdentity{UserID: plan.item.UserID, Provider: ldapIdentityProvider, Subject: plan.person.Subject}).Error; err == nil && len(plan.item.Changes) > 0 {
+				err = s.userService.UpdateUser(plan.item.UserID, plan.update)
+			}
+		case "update":
+			err = s.userService.UpdateUser(plan.item.UserID, plan.update)
+		case "deactivate":
+			err = s.userService.ToggleUserStatus(plan.item.UserID, "inactive")
+		}
+		if err != nil {
+			plan.item.Error = err.Error()
+			log.Printf("目录同步失败 - 用户名: %s, 动作: %s, 错误: %v", plan.item.Username, plan.item.Action, err)
+		}
+	}
+}
+
+func (s *LDAPSyncService) applyCreate(plan *ldapSyncPlan) error {
+	person := plan.person
+	realName := person.RealName
+	if realName == "" {
+		realName = person.Username
+	}
+
+	// 目录账号的本地密码为随机密码，登录时使用目录密码（见 LDAPAuthService）
+	user, err := s.userService.CreateUser(models.UserCreateRequest{
+		Username:   person.Username,
+		Password:   NewPasswordPolicyService(s.db).GeneratePassword(),
+		Email:      person.Email,
+		RealName:   realName,
+		Phone:      person.Phone,
+		Department: person.Department,
+		Title:      person.Title,
+		Grade:      person.Grade,
+		Major:      person.Major,
+		StudentID:  person.StudentID,
+		RoleKeys:   []string{person.Role},
+	})
+	if err != nil {
+		return err
+	}
+	plan.item.UserID = user.ID
+	return s.db.Create(&models.UserIdentity{UserID: user.ID, Provider: ldapIdentityProvider, Subject: person.Subject}).Error
+}
+
+// finish 汇总结果并更新同步记录
+func (s *LDAPSyncService) finish(run *models.LDAPSyncRun, plans []*ldapSyncPlan, runErr error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = "success"
+
+	var items []models.LDAPSyncItem
+	for _, plan := range plans {
+		if plan.item.Action != "deactivate" {
+			run.Total++
+		}
+		if plan.item.Error != "" {
+			run.Failed++
+			items = append(items, plan.item)
+			continue
+		}
+		switch plan.item.Action {
+		case "create":
+			run.Created++
+		case "update":
+			run.Updated++
+		case "link":
+			run.Linked++
+		case "deactivate":
+			run.Deactivated++
+		case "unchanged":
+			run.Unchanged++
+			continue // 未变化的账号不写入明细
+		case "conflict":
+			run.Conflicts++
+		case "skip":
+			run.Skipped++
+		}
+		items = append(items, plan.item)
+	}
+
+	if runErr != nil {
+		run.Status = "failed"
+		run.ErrorMessage = runErr.Error()
+	} else if run.Failed > 0 {
+		run.Status = "partial"
+	}
+	report, _ := json.Marshal(items)
+	run.Report = string(report)
+	run.Items = items
+
+	if err := s.db.Save(run).Error; err != nil {
+		log.Printf("更新目录同步记录失败 - 记录ID: %d, 错误: %v", run.ID, err)
+	}
+
+	if !run.DryRun {
+		status := "success"
+		if run.Status != "success" {
+			status = "failed"
+		}
+		s.systemLogs.RecordSecurity("ldap_sync", "LDAP目录同步", status, run.OperatorID,
+			fmt.Sprintf("记录ID: %d, 新建: %d, 更新: %d, 关联: %d, 停用: %d, 冲突: %d, 失败: %d, 错误: %s",
+				run.ID, run.Created, run.Updated, run.Linked, run.Deactivated, run.Conflicts, run.Failed, run.ErrorMessage), "", "")
+	}
+	log.Printf("目录同步完成 - 记录ID: %d, 试运行: %t, 状态: %s", run.ID, run.DryRun, run.Status)
+}
+
+// GetRuns 分页获取同步记录
+func (s *LDAPSyncService) GetRuns(page, size int) ([]models.LDAPSyncRun, int64, error) {
+	var runs []models.LDAPSyncRun
+	var total int64
+	query := s.db.Model(&models.LDAPSyncRun{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("report").Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&runs).Error
+	return runs, total, err
+}
+
+// GetRun 获取同步记录及差异明细
+func (s *LDAPSyncService) GetRun(id uint) (*models.LDAPSyncRun, error) {
+	var run models.LDAPSyncRun
+	if err := s.db.First(&run, id).Error; err != nil {
+		return nil, errors.New("同步记录不存在")
+	}
+	if run.Report != "" {
+		json.Unmarshal([]byte(run.Report), &run.Items)
+	}
+	return &run, nil
+}
+
+// StartLDAPSyncScheduler 按固定间隔执行目录同步
+func StartLDAPSyncScheduler(db *gorm.DB, interval time.Duration) {
+	service := NewLDAPSyncService(db)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := service.Run("schedule", false, nil); err != nil {
+				log.Printf("定时目录同步失败: %v", err)
+			}
+		}
+	}()
+	log.Printf("LDAP目录定时同步已启动 - 间隔: %v", interval)
+}
+
+// ldapFields 参与比较的字段
+type ldapFields struct {
+	Email, RealName, Phone, StudentID, Department, Title, Grade, Major string
+}
+
+// diff 返回目录值非空且与本地不同的字段
+func (f ldapFields) diff(current ldapFields) map[string][2]string {
+	changes := make(map[string][2]string)
+	compare := func(name, value, old string) {
+		if value != "" && value != old {
+			changes[name] = [2]string{old, value}
+		}
+	}
+	compare("email", f.Email, current.Email)
+	compare("realName", f.RealName, current.RealName)
+	compare("phone", f.Phone, current.Phone)
+	compare("studentId", f.StudentID, current.StudentID)
+	compare("department", f.Department, current.Department)
+	compare("title", f.Title, current.Title)
+	compare("grade", f.Grade, current.Grade)
+	compare("major", f.Major, current.Major)
+	return changes
+}
+
+func (p ldapPerson) fields() ldapFields {
+	return ldapFields{
+		Email: p.Email, RealName: p.RealName, Phone: p.Phone, StudentID: p.StudentID,
+		Department: p.Department, Title: p.Title, Grade: p.Grade, Major: p.Major,
+	}
+}
+
+// updateRequest 只包含有变化的字段（UpdateUser 忽略空值）
+func (p ldapPerson) updateRequest(changes map[string][2]string) models.UserUpdateRequest {
+	value := func(name string) string {
+		if change, ok := changes[name]; ok {
+			return change[1]
+		}
+		return ""
+	}
+	return models.UserUpdateRequest{
+		Email:      value("email"),
+		RealName:   value("realName"),
+		Phone:      value("phone"),
+		StudentID:  value("studentId"),
+		Department: value("department"),
+		Title:      value("title"),
+		Grade:      value("grade"),
+		Major:      value("major"),
+	}
+}
+
+func mapLDAPEntry(directory *utils.LDAPDirectory, entry utils.LDAPEntry) ldapPerson {
+	m := directory.Mapping
+	subject := entry.Get(m.ID)
+	if subject == "" {
+		subject = entry.DN
+	}
+	return ldapPerson{
+		Subject:    subject,
+		DN:         entry.DN,
+		Username:   entry.Get(m.Username),
+		Email:      entry.Get(m.Email),
+		RealName:   entry.Get(m.RealName),
+		Phone:      entry.Get(m.Phone),
+		StudentID:  entry.Get(m.StudentID),
+		Department: entry.Get(m.Department),
+		Title:      entry.Get(m.Title),
+		Grade:      entry.Get(m.Grade),
+		Major:      entry.Get(m.Major),
+		Role:       directory.Role(entry),
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+	"yunmeng-backend/config"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+)
+
+// loadSampleLDIF 读取 scripts/ldap/sample.ldif 中的 inetOrgPerson 条目（与默认过滤条件一致）
+func loadSampleLDIF(t *testing.T) []utils.LDAPEntry {
+	t.Helper()
+	file, err := os.Open("../scripts/ldap/sample.ldif")
+	if err != nil {
+		t.Fatalf("读取示例数据失败: %v", err)
+	}
+	defer file.Close()
+
+	var entries []utils.LDAPEntry
+	var current *utils.LDAPEntry
+	isPerson := false
+	flush := func() {
+		if current != nil && isPerson {
+			entries = append(entries, *current)
+		}
+		current, isPerson = nil, false
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case name == "dn":
+			current = &utils.LDAPEntry{DN: value, Attributes: map[string]string{}}
+		case name == "objectClass":
+			isPerson = isPerson || value == "inetOrgPerson"
+		case current != nil:
+			current.Attributes[strings.ToLower(name)] = value
+		}
+	}
+	flush()
+	return entries
+}
+
+// useSampleLDAPDirectory 按默认配置（OpenLDAP inetOrgPerson）装载目录
+func useSampleLDAPDirectory(t *testing.T) *utils.LDAPDirectory {
+	t.Helper()
+	t.Setenv("LDAP_URL", "ldap://localhost:389")
+	t.Setenv("LDAP_BASE_DN", "ou=people,dc=yunmeng,dc=edu")
+	config.LoadLDAPDirectory(config.NewLDAPConfig())
+	t.Cleanup(func() { utils.SetLDAPDirectory(nil) })
+	directory, err := utils.GetLDAPDirectory()
+	if err != nil {
+		t.Fatalf("装载目录配置失败: %v", err)
+	}
+	return directory
+}
+
+func TestLDAPSyncSampleDirectory(t *testing.T) {
+	directory := useSampleLDAPDirectory(t)
+	entries := loadSampleLDIF(t)
+	if len(entries) != 4 {
+		t.Fatalf("示例数据应有4个账号，实际 %d", len(entries))
+	}
+
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.Role{}, &models.UserRole{},
+		&models.UserIdentity{}, &models.UserSession{}, &models.SystemSetting{}, &models.PasswordHistory{}, &models.SystemLog{})
+	for _, key := range []string{"student", "teacher"} {
+		db.Create(&models.Role{RoleKey: key, RoleName: key})
+	}
+	// 用户名和邮箱一致的本地账号会被关联；用户名相同但邮箱不同的账号在默认策略下记为冲突
+	existing := &models.User{Username: "s2024001", Email: "s2024001@yunmeng.edu", Password: "x", Status: "active", RoleName: "student"}
+	conflicting := &models.User{Username: "t2024001", Email: "zhang@other.edu", Password: "x", Status: "active", RoleName: "teacher"}
+	// 已关联但目录中不再存在的账号会被停用
+	departed := &models.User{Username: "s2019001", Email: "s2019001@yunmeng.edu", Password: "x", Status: "active", RoleName: "student"}
+	for _, user := range []*models.User{existing, conflicting, departed} {
+		db.Create(user)
+	}
+	db.Create(&models.UserProfile{UserID: existing.ID, RealName: "李同学"})
+	db.Create(&models.UserIdentity{UserID: departed.ID, Provider: ldapIdentityProvider, Subject: "uid=s2019001,ou=people,dc=yunmeng,dc=edu"})
+
+	service := NewLDAPSyncService(db)
+	plans := service.planEntries(directory, entries)
+	actions := make(map[string]string)
+	for _, plan := range plans {
+		actions[plan.item.Username] = plan.item.Action
+	}
+	want := map[string]string{
+		"t2024001": "conflict",
+		"s2024001": "link",
+		"s2024002": "create",
+		"guest01":  "skip",
+		"s2019001": "deactivate",
+	}
+	for username, action := range want {
+		if actions[username] != action {
+			t.Errorf("%s 的同步动作应为 %s，实际 %q", username, action, actions[username])
+		}
+	}
+
+	service.apply(plans)
+	for _, plan := range plans {
+		if plan.item.Error != "" {
+			t.Fatalf("%s 同步失败: %s", plan.item.Username, plan.item.Error)
+		}
+	}
+
+	var created models.User
+	if err := db.Preload("Profile").Preload("Roles").Where("username = ?", "s2024002").First(&created).Error; err != nil {
+		t.Fatalf("应创建 s2024002: %v", err)
+	}
+	if created.Profile == nil || created.Profile.StudentID != "2024002" || created.Department != "软件学院" {
+		t.Errorf("新建账号字段未按目录映射: %+v", created)
+	}
+	if len(created.Roles) != 1 || created.Roles[0].RoleKey != "student" {
+		t.Errorf("新建账号角色应为 student: %+v", created.Roles)
+	}
+	var identity models.UserIdentity
+	if err := db.Where("provider = ? AND user_id = ?", ldapIdentityProvider, created.ID).First(&identity).Error; err != nil ||
+		identity.Subject != "uid=s2024002,ou=people,dc=yunmeng,dc=edu" {
+		t.Errorf("新建账号应关联目录条目（无 entryUUID 时使用 DN）: %+v, %v", identity, err)
+	}
+	var linkedCount int64
+	db.Model(&models.UserIdentity{}).Where("provider = ? AND user_id = ?", ldapIdentityProvider, existing.ID).Count(&linkedCount)
+	if linkedCount != 1 {
+		t.Errorf("s2024001 应关联到目录")
+	}
+	var status string
+	db.Model(&models.User{}).Where("id = ?", departed.ID).Pluck("status", &status)
+	if status != "inactive" {
+		t.Errorf("目录中已不存在的账号应停用，实际 %s", status)
+	}
+
+	// 再次同步：已关联账号没有差异，已停用的账号不再重复处理
+	for _, plan := range service.planEntries(directory, entries) {
+		switch plan.item.Username {
+		case "s2024001", "s2024002":
+			if plan.item.Action != "unchanged" {
+				t.Errorf("%s 再次同步应无变化，实际 %s: %v", plan.item.Username, plan.item.Action, plan.item.Changes)
+			}
+		case "s2019001":
+			t.Errorf("已停用的账号不应再次停用")
+		}
+	}
+}
+
+func TestLDAPAuthRequiresLinkedIdentity(t *testing.T) {
+	useSampleLDAPDirectory(t)
+	db := newTestDB(t, &models.User{}, &models.UserIdentity{})
+	user := &models.User{Username: "s2024001", Email: "s2024001@yunmeng.edu", Password: "x", Status: "active", RoleName: "student"}
+	db.Create(user)
+
+	// 未关联目录的本地账号不会尝试目录认证（测试环境没有目录服务，关联后才会去连接）
+	if NewLDAPAuthService(db).Authenticate(user, "secret") {
+		t.Fatal("未关联目录的账号不应通过目录认证")
+	}
+
+	directory, _ := utils.GetLDAPDirectory()
+	if got := directory.UserFilter("a*)(uid=*"); got != `(&(objectClass=inetOrgPerson)(uid=a\2a\29\28uid=\2a))` {
+		t.Errorf("用户名应转义后拼入过滤条件，实际 %s", got)
+	}
+}
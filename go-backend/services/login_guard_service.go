@@ -0,0 +1,279 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// LoginGuardPolicy 登录防暴力破解策略（取自 system_settings，缺省时使用默认值）
+type LoginGuardPolicy struct {
+	MaxUserAttempts int           // 同一用户名在窗口期内允许的失败次数
+	MaxIPAttempts   int           // 同一IP在窗口期内允许的失败次数
+	Window          time.Duration // 失败次数统计窗口
+	LockDuration    time.Duration // 达到阈值后的锁定时长
+	DelayBase       time.Duration // 渐进延迟基数，第n次失败后需等待 DelayBase*2^(n-1)
+	AlertThreshold  int           // 窗口期内全站失败次数达到该值时触发安全告警
+}
+
+type LoginGuardService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	systemLogs *SystemLogService
+}
+
+func NewLoginGuardService(db *gorm.DB) *LoginGuardService {
+	return &LoginGuardService{
+		db:         db,
+		settings:   NewSettingService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// Policy 读取当前登录防护策略
+func (s *LoginGuardService) Policy() LoginGuardPolicy {
+	return LoginGuardPolicy{
+		MaxUserAttempts: s.settings.GetInt("max_login_attempts", 5),
+		MaxIPAttempts:   s.settings.GetInt("max_login_attempts_per_ip", 20),
+		Window:          time.Duration(s.settings.GetInt("login_attempt_window_minutes", 15)) * time.Minute,
+		LockDuration:    time.Duration(s.settings.GetInt("login_lockout_minutes", 15)) * time.Minute,
+		DelayBase:       time.Duration(s.settings.GetInt("login_delay_base_seconds", 1)) * time.Second,
+		AlertThreshold:  s.settings.GetInt("login_alert_threshold", 50),
+	}
+}
+
+// CheckAllowed 检查用户名和IP当前是否允许尝试登录，返回需要等待的时长（0表示允许）
+func (s *LoginGuardService) CheckAllowed(username, ipAddress string) time.Duration {
+	policy := s.Policy()
+	now := time.Now()
+
+	var lockouts []models.LoginLockout
+	s.db.Where("(scope = ? AND scope_key = ?) OR (scope = ? AND scope_key = ?)",
+		"username", username, "ip", ipAddress).Find(&lockouts)
+
+	var wait time.Duration
+	for _, lockout := range lockouts {
+		if lockout.LockedUntil != nil && now.Before(*lockout.LockedUntil) {
+			if remaining := lockout.LockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+			continue
+		}
+		if lockout.LastFailedAt == nil || lockout.FailedCount == 0 || now.Sub(*lockout.LastFailedAt) > policy.Window {
+			continue
+		}
+		next := lockout.LastFailedAt.Add(progressiveDelay(policy, lockout.FailedCount))
+		if remaining := next.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// RecordFailure 记录一次失败的登录尝试，必要时锁定用户名/IP并触发安全告警
+func (s *LoginGuardService) RecordFailure(username string, userID *uint, ipAddress, userAgent, reason string) {
+	policy := s.Policy()
+
+	attempt := models.LoginAttempt{
+		Username:   username,
+		UserID:     userID,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Success:    false,
+		FailReason: reason,
+	}
+	if err := s.db.Create(&attempt).Error; err != nil {
+		log.Printf("记录登录失败尝试失败: %v", err)
+	}
+	s.systemLogs.RecordSecurity("login", "登录失败", "failed", userID,
+		fmt.Sprintf("用户名: %s, 原因: %s", username, reason), ipAddress, userAgent)
+
+	if locked, count := s.bumpLockout("username", username, policy.MaxUserAttempts, policy); locked {
+		s.systemLogs.RecordSecurity("login_lockout", "用户名登录锁定", "failed", userID,
+			fmt.Sprintf("用户名 %s 连续登录失败 %d 次，锁定 %d 分钟", username, count, int(policy.LockDuration.Minutes())),
+			ipAddress, userAgent)
+	}
+	if locked, count := s.bumpLockout("ip", ipAddress, policy.MaxIPAttempts, policy); locked {
+		s.systemLogs.RecordSecurity("login_lockout", "IP登录锁定", "failed", userID,
+			fmt.Sprintf("IP %s 连续登录失败 %d 次，锁定 %d 分钟", ipAddress, count, int(policy.LockDuration.Minutes())),
+			ipAddress, userAgent)
+	}
+
+	s.checkBurst(policy, ipAddress, userAgent)
+}
+
+// RecordSuccess 记录成功登录并清除该用户名的失败计数（IP计数不清除，防止借合法账号重置）
+func (s *LoginGuardService) RecordSuccess(username string, userID uint, ipAddress, userAgent string) {
+	attempt := models.LoginAttempt{
+		Username:  username,
+		UserID:    &userID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Success:   true,
+	}
+	if err := s.db.Create(&attempt).Error; err != nil {
+		log.Printf("记录登录成功尝试失败: %v", err)
+	}
+
+	s.db.Model(&models.LoginLockout{}).
+		Where("scope = ? AND scope_key = ?", "username", username).
+		Updates(map[string]interface{}{
+			"failed_count": 0,
+			"locked_until": nil,
+		})
+}
+
+// Unlock 管理员解除用户名或IP的登录锁定
+func (s *LoginGuardService) Unlock(req models.LoginUnlockRequest, operatorID uint) (int64, error) {
+	if req.Username == "" && req.IPAddress == "" {
+		return 0, errors.New("用户名和IP至少提供一个")
+	}
+
+	query := s.db.Model(&models.LoginLockout{})
+	switch {
+	case req.Username != "" && req.IPAddress != "":
+		query = query.Where("(scope = ? AND scope_key = ?) OR (scope = ? AND scope_key = ?)",
+			"username", req.Username, "ip", req.IPAddress)
+	case req.Username != "":
+		query = query.Where("scope = ? AND scope_key = ?", "username", req.Username)
+	default:
+		query = query.Where("scope = ? AND scope_key = ?", "ip", req.IPAddress)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"failed_count": 0,
+		"locked_until": nil,
+		"unlocked_by":  operatorID,
+	})
+	if result.Error != nil {
+		log.Printf("解除登录锁定失败: %v", result.Error)
+		return 0, errors.New("解除登录锁定失败")
+	}
+
+	s.systemLogs.RecordSecurity("login_unlock", "管理员解除登录锁定", "success", &operatorID,
+		fmt.Sprintf("用户名: %s, IP: %s", req.Username, req.IPAddress), "", "")
+
+	log.Printf("登录锁定已解除 - 用户名: %s, IP: %s, 操作人ID: %d", req.Username, req.IPAddress, operatorID)
+	return result.RowsAffected, nil
+}
+
+// GetActiveLockouts 获取当前处于锁定状态的用户名/IP
+func (s *LoginGuardService) GetActiveLockouts() ([]models.LoginLockout, error) {
+	var lockouts []models.LoginLockout
+	if err := s.db.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&lockouts).Error; err != nil {
+		log.Printf("获取登录锁定列表失败: %v", err)
+		return nil, err
+	}
+	return lockouts, nil
+}
+
+// bumpLockout 增加失败计数，达到阈值时设置锁定截止时间，返回本次是否触发锁定及当前计数
+func (s *LoginGuardService) bumpLockout(scope, key string, maxAttempts int, policy LoginGuardPolicy) (bool, int) {
+	if key == "" || maxAttempts <= 0 {
+		return false, 0
+	}
+
+	locked := false
+	count := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		lockout := models.LoginLockout{Scope: scope, ScopeKey: key}
+		if err := tx.Where("scope = ? AND scope_key = ?", scope, key).FirstOrCreate(&lockout).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		// 窗口期已过或上一次锁定已到期，重新计数
+		if lockout.LastFailedAt == nil || now.Sub(*lockout.LastFailedAt) > policy.Window ||
+			(lockout.LockedUntil != nil && now.After(*lockout.LockedUntil)) {
+			lockout.FailedCount = 0
+			lockout.LockedUntil = nil
+		}
+
+		lockout.FailedCount++
+		lockout.LastFailedAt = &now
+		if lockout.FailedCount >= maxAttempts && lockout.LockedUntil == nil {
+			lockedUntil := now.Add(policy.LockDuration)
+			lockout.LockedUntil = &lockedUntil
+			locked = true
+		}
+		count = lockout.FailedCount
+
+		return tx.Model(&lockout).Select("failed_count", "last_failed_at", "locked_until").Updates(&lockout).Error
+	})
+	if err != nil {
+		log.Printf("更新登录失败计数失败 - %s: %s, 错误: %v", scope, key, err)
+		return false, 0
+	}
+	return locked, count
+}
+
+// checkBurst 全站失败次数突增时写入安全告警（同一窗口期内只告警一次）
+func (s *LoginGuardService) checkBurst(policy LoginGuardPolicy, ipAddress, userAgent string) {
+	if policy.AlertThreshold <= 0 {
+		return
+	}
+
+	since := time.Now().Add(-policy.Window)
+	var failed int64
+	s.db.Model(&models.LoginAttempt{}).Where("success = ? AND created_at >= ?", false, since).Count(&failed)
+	if failed < int64(policy.AlertThreshold) {
+		return
+	}
+
+	var existing int64
+	s.db.Model(&models.SystemAlert{}).
+		Where("alert_type = ? AND status = ? AND triggered_at >= ?", "security_breach", "active", since).
+		Count(&existing)
+	if existing > 0 {
+		return
+	}
+
+	var distinctIPs int64
+	s.db.Model(&models.LoginAttempt{}).Where("success = ? AND created_at >= ?", false, since).
+		Distinct("ip_address").Count(&distinctIPs)
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"failed_attempts": failed,
+		"distinct_ips":    distinctIPs,
+		"window_minutes":  int(policy.Window.Minutes()),
+		"last_ip":         ipAddress,
+	})
+	jsonMetadata := datatypes.JSON(metadata)
+
+	alert := models.SystemAlert{
+		AlertType:   "security_breach",
+		Severity:    "high",
+		Title:       "登录失败次数异常",
+		Message:     fmt.Sprintf("最近 %d 分钟内登录失败 %d 次，涉及 %d 个IP，可能存在暴力破解", int(policy.Window.Minutes()), failed, distinctIPs),
+		Status:      "active",
+		TriggeredAt: time.Now(),
+		Metadata:    &jsonMetadata,
+	}
+	if err := s.db.Create(&alert).Error; err != nil {
+		log.Printf("创建安全告警失败: %v", err)
+		return
+	}
+
+	s.systemLogs.RecordSecurity("login_burst", "登录失败突增告警", "failed", nil, alert.Message, ipAddress, userAgent)
+}
+
+// progressiveDelay 计算第n次失败后的最小重试间隔，不超过锁定时长
+func progressiveDelay(policy LoginGuardPolicy, failedCount int) time.Duration {
+	if failedCount <= 0 || policy.DelayBase <= 0 {
+		return 0
+	}
+	delay := policy.DelayBase
+	for i := 1; i < failedCount && delay < policy.LockDuration; i++ {
+		delay *= 2
+	}
+	if delay > policy.LockDuration {
+		delay = policy.LockDuration
+	}
+	return delay
+}
@@ -0,0 +1,644 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 旧文本字段可以映射到的节点类型
+var (
+	orgDepartmentTypes = []string{"college", "department"}
+	orgMajorTypes      = []string{"major"}
+)
+
+type OrgUnitService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewOrgUnitService(db *gorm.DB) *OrgUnitService {
+	return &OrgUnitService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// OrgUnitSubtreeIDs 返回节点及其全部子孙节点ID的子查询，节点不存在时不匹配任何数据
+func OrgUnitSubtreeIDs(db *gorm.DB, id uint) *gorm.DB {
+	var unit models.OrgUnit
+	if err := db.Select("id", "path").First(&unit, id).Error; err != nil {
+		return db.Model(&models.OrgUnit{}).Select("id").Where("1 = 0")
+	}
+	return db.Model(&models.OrgUnit{}).Select("id").Where("path LIKE ?", unit.Path+"%")
+}
+
+// OrgUnitAncestorIDs 返回从根到该节点（含自身）的节点ID
+func OrgUnitAncestorIDs(db *gorm.DB, id uint) []uint {
+	var unit models.OrgUnit
+	if err := db.Select("id", "path").First(&unit, id).Error; err != nil {
+		return nil
+	}
+	return parseOrgPath(unit.Path)
+}
+
+// OrgUnitLabels 按节点及其上级计算用户的院系（学院，没有学院时取系）和专业文本
+func OrgUnitLabels(db *gorm.DB, id uint) (department, major string, err error) {
+	ids := OrgUnitAncestorIDs(db, id)
+	if len(ids) == 0 {
+		return "", "", errors.New("组织机构节点不存在")
+	}
+	var units []models.OrgUnit
+	if err := db.Where("id IN ?", ids).Find(&units).Error; err != nil {
+		return "", "", err
+	}
+	byType := make(map[string]string, len(units))
+	for _, unit := range units {
+		byType[unit.Type] = unit.Name
+	}
+	department = byType["college"]
+	if department == "" {
+		department = byType["department"]
+	}
+	return department, byType["major"], nil
+}
+
+// GetTree 获取完整的组织机构树
+func (s *OrgUnitService) GetTree() ([]*models.OrgUnit, error) {
+	var units []*models.OrgUnit
+	if err := s.db.Order("sort_order, id").Find(&units).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*models.OrgUnit, len(units))
+	for _, unit := range units {
+		byID[unit.ID] = unit
+	}
+	roots := make([]*models.OrgUnit, 0)
+	for _, unit := range units {
+		if unit.ParentID != nil {
+			if parent, ok := byID[*unit.ParentID]; ok {
+				parent.Children = append(parent.Children, unit)
+				continue
+			}
+		}
+		roots = append(roots, unit)
+	}
+	return roots, nil
+}
+
+// GetOrgUnit 获取节点及其直接下级
+func (s *OrgUnitService) GetOrgUnit(id uint) (*models.OrgUnit, error) {
+	var unit models.OrgUnit
+	if err := s.db.First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("组织机构节点不存在")
+		}
+		return nil, err
+	}
+	if err := s.db.Where("parent_id = ?", id).Order("sort_order, id").Find(&unit.Children).Error; err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// CreateOrgUnit 创建节点
+func (s *OrgUnitService) CreateOrgUnit(req models.OrgUnitRequest, operatorID uint) (*models.OrgUnit, error) {
+	parent, err := s.validate(0, req)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := models.OrgUnit{
+		ParentID:  req.ParentID,
+		Type:      req.Type,
+		Name:      strings.TrimSpace(req.Name),
+		Code:      strings.TrimSpace(req.Code),
+		Aliases:   req.Aliases,
+		SortOrder: req.SortOrder,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&unit).Error; err != nil {
+			return err
+		}
+		unit.Path = orgChildPath(parent, unit.ID)
+		return tx.Model(&unit).Update("path", unit.Path).Error
+	})
+	if err != nil {
+		log.Printf("创建组织机构节点失败: %v", err)
+		return nil, errors.New("创建组织机构节点失败")
+	}
+
+	s.systemLogs.RecordSecurity("org_unit_create", "创建组织机构节点", "success", &operatorID,
+		fmt.Sprintf("节点ID: %d, 名称: %s, 类型: %s, 路径: %s", unit.ID, unit.Name, unit.Type, unit.Path), "", "")
+	return &unit, nil
+}
+
+// UpdateOrgUnit 更新节点；调整上级时同步更新子孙节点路径，改名后同步所属用户的院系/专业文本
+func (s *OrgUnitService) UpdateOrgUnit(id uint, req models.OrgUnitRequest, operatorID uint) (*models.OrgUnit, error) {
+	var unit models.OrgUnit
+	if err := s.db.First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("组织机构节点不存在")
+		}
+		return nil, err
+	}
+	parent, err := s.validate(id, req)
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil && strings.HasPrefix(parent.Path, unit.Path) {
+		return nil, errors.New("不能把节点移动到自身或其下级节点之下")
+	}
+
+	// 下级节点的层级必须低于本节点
+	var children []models.OrgUnit
+	s.db.Select("id", "type").Where("parent_id = ?", id).Find(&children)
+	for _, child := range children {
+		if orgUnitLevel(child.Type) <= orgUnitLevel(req.Type) {
+			return nil, fmt.Errorf("下级节点（ID %d）的类型不能高于或等于%s", child.ID, req.Type)
+		}
+	}
+
+	oldPath := unit.Path
+	newPath := orgChildPath(parent, unit.ID)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&unit).Updates(map[string]interface{}{
+			"parent_id":  req.ParentID,
+			"type":       req.Type,
+			"name":       strings.TrimSpace(req.Name),
+			"code":       strings.TrimSpace(req.Code),
+			"aliases":    req.Aliases,
+			"sort_order": req.SortOrder,
+			"path":       newPath,
+		}).Error; err != nil {
+			return err
+		}
+		if newPath != oldPath {
+			var descendants []models.OrgUnit
+			if err := tx.Select("id", "path").Where("path LIKE ? AND id <> ?", oldPath+"%", id).Find(&descendants).Error; err != nil {
+				return err
+			}
+			for _, descendant := range descendants {
+				path := newPath + strings.TrimPrefix(descendant.Path, oldPath)
+				if err := tx.Model(&models.OrgUnit{}).Where("id = ?", descendant.ID).Update("path", path).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return s.syncUserLabels(tx, newPath)
+	})
+	if err != nil {
+		log.Printf("更新组织机构节点失败 - 节点ID: %d, 错误: %v", id, err)
+		return nil, errors.New("更新组织机构节点失败")
+	}
+
+	s.systemLogs.RecordSecurity("org_unit_update", "更新组织机构节点", "success", &operatorID,
+		fmt.Sprintf("节点ID: %d, 名称: %s, 路径: %s -> %s", id, req.Name, oldPath, newPath), "", "")
+	return s.GetOrgUnit(id)
+}
+
+// DeleteOrgUnit 删除节点，有下级节点或仍被用户、竞赛、审核流程引用时不能删除
+func (s *OrgUnitService) DeleteOrgUnit(id, operatorID uint) error {
+	var unit models.OrgUnit
+	if err := s.db.First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("组织机构节点不存在")
+		}
+		return err
+	}
+
+	var count int64
+	if s.db.Model(&models.OrgUnit{}).Where("parent_id = ?", id).Count(&count); count > 0 {
+		return errors.New("请先删除下级节点")
+	}
+	if s.db.Unscoped().Model(&models.User{}).Where("org_unit_id = ?", id).Count(&count); count > 0 {
+		return fmt.Errorf("仍有 %d 个用户属于该节点", count)
+	}
+	if s.db.Model(&models.Competition{}).Where("org_unit_id = ?", id).Count(&count); count > 0 {
+		return fmt.Errorf("仍有 %d 个竞赛限定该节点", count)
+	}
+	if s.db.Model(&models.ProjectReviewFlow{}).Where("reviewer_org_unit_id = ?", id).Count(&count); count > 0 {
+		return fmt.Errorf("仍有 %d 个审核流程使用该节点", count)
+	}
+
+	if err := s.db.Delete(&unit).Error; err != nil {
+		log.Printf("删除组织机构节点失败 - 节点ID: %d, 错误: %v", id, err)
+		return errors.New("删除组织机构节点失败")
+	}
+
+	s.systemLogs.RecordSecurity("org_unit_delete", "删除组织机构节点", "success", &operatorID,
+		fmt.Sprintf("节点ID: %d, 名称: %s, 路径: %s", unit.ID, unit.Name, unit.Path), "", "")
+	return nil
+}
+
+// UserStats 统计节点（为0时取顶级节点）每个直接下级节点的用户数，含子孙节点
+func (s *OrgUnitService) UserStats(parentID uint) ([]models.OrgUnitStat, error) {
+	query := s.db.Order("sort_order, id")
+	if parentID > 0 {
+		query = query.Where("parent_id = ?", parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	var units []models.OrgUnit
+	if err := query.Find(&units).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.OrgUnitStat, 0, len(units))
+	for _, unit := range units {
+		stat := models.OrgUnitStat{OrgUnitID: unit.ID, Name: unit.Name, Type: unit.Type}
+		if err := s.db.Model(&models.User{}).
+			Where("org_unit_id IN (?)", s.db.Model(&models.OrgUnit{}).Select("id").Where("path LIKE ?", unit.Path+"%")).
+			Count(&stat.Count).Error; err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// validate 校验节点类型层级、上级节点和同级重名，返回上级节点
+func (s *OrgUnitService) validate(id uint, req models.OrgUnitRequest) (*models.OrgUnit, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("节点名称不能为空")
+	}
+
+	var parent *models.OrgUnit
+	if req.ParentID != nil {
+		if *req.ParentID == id && id != 0 {
+			return nil, errors.New("上级节点不能是自身")
+		}
+		parent = &models.OrgUnit{}
+		if err := s.db.First(parent, *req.ParentID).Error; err != nil {
+			return nil, errors.New("上级节点不存在")
+		}
+		if orgUnitLevel(req.Type) <= orgUnitLevel(parent.Type) {
+			return nil, fmt.Errorf("%s 节点不能设在 %s 节点之下", req.Type, parent.Type)
+		}
+	}
+
+	query := s.db.Model(&models.OrgUnit{}).Where("name = ? AND id <> ?", strings.TrimSpace(req.Name), id)
+	if req.ParentID != nil {
+		query = query.Where("parent_id = ?", *req.ParentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	var count int64
+	if query.Count(&count); count > 0 {
+		return nil, errors.New("同一上级下已存在同名节点")
+	}
+	return parent, nil
+}
+
+// syncUserLabels 按节点重新填写子树内用户的院系/专业文本
+func (s *OrgUnitService) syncUserLabels(tx *gorm.DB, path string) error {
+	var unitIDs []uint
+	if err := tx.Unscoped().Model(&models.User{}).Distinct("org_unit_id").
+		Where("org_unit_id IN (?)", tx.Model(&models.OrgUnit{}).Select("id").Where("path LIKE ?", path+"%")).
+		Pluck("org_unit_id", &unitIDs).Error; err != nil {
+		return err
+	}
+	for _, unitID := range unitIDs {
+		department, major, err := OrgUnitLabels(tx, unitID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.User{}).Where("org_unit_id = ?", unitID).
+			Updates(map[string]interface{}{"department": department, "major": major}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UserProfile{}).
+			Where("user_id IN (?)", tx.Unscoped().Model(&models.User{}).Select("id").Where("org_unit_id = ?", unitID)).
+			Update("department", department).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orgIndex 按规范化后的名称、代码和别名索引节点
+type orgIndex struct {
+	byKey map[string][]models.OrgUnit
+	byID  map[uint]models.OrgUnit
+}
+
+func (s *OrgUnitService) buildIndex() (*orgIndex, error) {
+	var units []models.OrgUnit
+	if err := s.db.Find(&units).Error; err != nil {
+		return nil, err
+	}
+	index := &orgIndex{byKey: make(map[string][]models.OrgUnit), byID: make(map[uint]models.OrgUnit, len(units))}
+	for _, unit := range units {
+		index.byID[unit.ID] = unit
+		keys := map[string]bool{normalizeOrgName(unit.Name): true}
+		if unit.Code != "" {
+			keys[normalizeOrgName(unit.Code)] = true
+		}
+		for _, alias := range splitOrgAliases(unit.Aliases) {
+			keys[normalizeOrgName(alias)] = true
+		}
+		for key := range keys {
+			index.byKey[key] = append(index.byKey[key], unit)
+		}
+	}
+	return index, nil
+}
+
+// lookup 按文本查找允许类型的节点；under 不为空时优先取其子树内的节点
+func (idx *orgIndex) lookup(value string, types []string, under *models.OrgUnit) []models.OrgUnit {
+	var found []models.OrgUnit
+	for _, unit := range idx.byKey[normalizeOrgName(value)] {
+		for _, t := range types {
+			if unit.Type == t {
+				found = append(found, unit)
+				break
+			}
+		}
+	}
+	if under != nil && len(found) > 1 {
+		var inside []models.OrgUnit
+		for _, unit := range found {
+			if strings.HasPrefix(unit.Path, under.Path) {
+				inside = append(inside, unit)
+			}
+		}
+		if len(inside) > 0 {
+			return inside
+		}
+	}
+	return found
+}
+
+// orgMapper 汇总同一来源、同一文本的映射结果
+type orgMapper struct {
+	index    *orgIndex
+	manual   map[string]uint
+	items    map[string]*models.OrgMappingItem
+	order    []string
+	aliasAdd map[uint][]string // 手工映射需写入的节点别名
+}
+
+// resolve 映射一个文本值，返回匹配的节点（未匹配或有歧义时为 nil）
+func (m *orgMapper) resolve(source, context, value string, types []string, under *models.OrgUnit, count int64) *models.OrgUnit {
+	value = strings.TrimSpace(value)
+	key := source + "\x00" + context + "\x00" + value
+	item, ok := m.items[key]
+	if !ok {
+		item = &models.OrgMappingItem{Source: source, Context: context, Value: value}
+		m.classify(item, types, under)
+		m.items[key] = item
+		m.order = append(m.order, key)
+	}
+	item.Count += count
+	if item.Status != "matched" {
+		return nil
+	}
+	unit := m.index.byID[item.OrgUnitID]
+	return &unit
+}
+
+func (m *orgMapper) classify(item *models.OrgMappingItem, types []string, under *models.OrgUnit) {
+	if strings.ContainsAny(item.Value, ",，") {
+		item.Status = "multiple"
+		return
+	}
+
+	// 手工指定的映射优先
+	if id, ok := m.manual[item.Value]; ok {
+		if unit, exists := m.index.byID[id]; exists && orgTypeAllowed(unit.Type, types) {
+			item.Status, item.OrgUnitID, item.OrgUnitName = "matched", unit.ID, unit.Name
+			if normalizeOrgName(unit.Name) != normalizeOrgName(item.Value) {
+				m.aliasAdd[unit.ID] = append(m.aliasAdd[unit.ID], item.Value)
+			}
+			return
+		}
+	}
+
+	found := m.index.lookup(item.Value, types, under)
+	switch len(found) {
+	case 0:
+		item.Status = "unmatched"
+	case 1:
+		item.Status, item.OrgUnitID, item.OrgUnitName = "matched", found[0].ID, found[0].Name
+	default:
+		item.Status = "ambiguous"
+		for _, unit := range found {
+			item.Candidates = append(item.Candidates, unit.ID)
+		}
+	}
+}
+
+// MapLegacy 把用户院系/专业、竞赛院系限制和审核流程审核院系的旧文本映射到组织机构节点
+// 只处理尚未关联节点的记录，不修改原文本；dryRun 时只返回映射报告
+func (s *OrgUnitService) MapLegacy(req models.OrgMappingRequest, operatorID uint) (*models.OrgMappingReport, error) {
+	index, err := s.buildIndex()
+	if err != nil {
+		return nil, err
+	}
+	manual := make(map[string]uint, len(req.Mappings))
+	for value, id := range req.Mappings {
+		manual[strings.TrimSpace(value)] = id
+	}
+	mapper := &orgMapper{
+		index:    index,
+		manual:   manual,
+		items:    make(map[string]*models.OrgMappingItem),
+		aliasAdd: make(map[uint][]string),
+	}
+
+	// 用户：专业节点优先，其次院系节点；院系为空时取档案中的院系
+	var users []struct {
+		ID                uint
+		Department        string
+		Major             string
+		ProfileDepartment string
+	}
+	if err := s.db.Model(&models.User{}).
+		Select("users.id, users.department, users.major, user_profiles.department AS profile_department").
+		Joins("LEFT JOIN user_profiles ON users.id = user_profiles.user_id").
+		Where("users.org_unit_id IS NULL").
+		Scan(&users).Error; err != nil {
+		return nil, err
+	}
+	userTargets := make(map[uint][]uint)
+	for _, user := range users {
+		department := strings.TrimSpace(user.Department)
+		if department == "" {
+			department = strings.TrimSpace(user.ProfileDepartment)
+		}
+		var target *models.OrgUnit
+		if department != "" {
+			target = mapper.resolve("user_department", "", department, orgDepartmentTypes, nil, 1)
+		}
+		if strings.TrimSpace(user.Major) != "" {
+			if major := mapper.resolve("user_major", department, user.Major, orgMajorTypes, target, 1); major != nil {
+				target = major
+			}
+		}
+		if target != nil {
+			userTargets[target.ID] = append(userTargets[target.ID], user.ID)
+		}
+	}
+
+	// 竞赛院系限制：限定多个院系的竞赛需手工处理
+	var competitions []models.Competition
+	if err := s.db.Select("id", "department_limit").
+		Where("org_unit_id IS NULL AND department_limit IS NOT NULL AND department_limit <> ''").
+		Find(&competitions).Error; err != nil {
+		return nil, err
+	}
+	competitionTargets := make(map[uint][]uint)
+	for _, competition := range competitions {
+		if target := mapper.resolve("competition", "", competition.DepartmentLimit, orgDepartmentTypes, nil, 1); target != nil {
+			competitionTargets[target.ID] = append(competitionTargets[target.ID], competition.ID)
+		}
+	}
+
+	// 审核流程审核院系
+	var flows []models.ProjectReviewFlow
+	if err := s.db.Select("id", "reviewer_department").
+		Where("reviewer_org_unit_id IS NULL AND reviewer_department IS NOT NULL AND reviewer_department <> ''").
+		Find(&flows).Error; err != nil {
+		return nil, err
+	}
+	flowTargets := make(map[uint][]uint)
+	for _, flow := range flows {
+		if target := mapper.resolve("review_flow", "", flow.ReviewerDepartment, orgDepartmentTypes, nil, 1); target != nil {
+			flowTargets[target.ID] = append(flowTargets[target.ID], flow.ID)
+		}
+	}
+
+	report := &models.OrgMappingReport{DryRun: req.DryRun, Updated: map[string]int64{}, Items: make([]models.OrgMappingItem, 0, len(mapper.order))}
+	for _, key := range mapper.order {
+		item := mapper.items[key]
+		switch item.Status {
+		case "matched":
+			report.Matched++
+		case "unmatched":
+			report.Unmatched++
+		default:
+			report.Ambiguous++
+		}
+		report.Items = append(report.Items, *item)
+	}
+	if req.DryRun {
+		return report, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for unitID, ids := range userTargets {
+			for start := 0; start < len(ids); start += 500 {
+				end := start + 500
+				if end > len(ids) {
+					end = len(ids)
+				}
+				result := tx.Model(&models.User{}).Where("id IN ?", ids[start:end]).Update("org_unit_id", unitID)
+				if result.Error != nil {
+					return result.Error
+				}
+				report.Updated["users"] += result.RowsAffected
+			}
+		}
+		for unitID, ids := range competitionTargets {
+			result := tx.Model(&models.Competition{}).Where("id IN ?", ids).Update("org_unit_id", unitID)
+			if result.Error != nil {
+				return result.Error
+			}
+			report.Updated["competitions"] += result.RowsAffected
+		}
+		for unitID, ids := range flowTargets {
+			result := tx.Model(&models.ProjectReviewFlow{}).Where("id IN ?", ids).Update("reviewer_org_unit_id", unitID)
+			if result.Error != nil {
+				return result.Error
+			}
+			report.Updated["reviewFlows"] += result.RowsAffected
+		}
+		// 手工映射的文本记为节点别名，下次自动匹配
+		for unitID, values := range mapper.aliasAdd {
+			unit := index.byID[unitID]
+			aliases := splitOrgAliases(unit.Aliases)
+			for _, value := range values {
+				if index.byKey[normalizeOrgName(value)] == nil {
+					aliases = append(aliases, value)
+					index.byKey[normalizeOrgName(value)] = []models.OrgUnit{unit}
+				}
+			}
+			if err := tx.Model(&models.OrgUnit{}).Where("id = ?", unitID).Update("aliases", strings.Join(aliases, ",")).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("组织机构映射失败: %v", err)
+		return nil, errors.New("组织机构映射失败")
+	}
+
+	s.systemLogs.RecordSecurity("org_unit_mapping", "映射旧院系/专业文本", "success", &operatorID,
+		fmt.Sprintf("已匹配: %d, 未匹配: %d, 需手工处理: %d, 更新: %v", report.Matched, report.Unmatched, report.Ambiguous, report.Updated), "", "")
+	return report, nil
+}
+
+// orgUnitLevel 节点类型的层级，未知类型排在最后
+func orgUnitLevel(unitType string) int {
+	for i, t := range models.OrgUnitTypes {
+		if t == unitType {
+			return i
+		}
+	}
+	return len(models.OrgUnitTypes)
+}
+
+func orgTypeAllowed(unitType string, types []string) bool {
+	for _, t := range types {
+		if t == unitType {
+			return true
+		}
+	}
+	return false
+}
+
+func orgChildPath(parent *models.OrgUnit, id uint) string {
+	if parent == nil {
+		return fmt.Sprintf("/%d/", id)
+	}
+	return fmt.Sprintf("%s%d/", parent.Path, id)
+}
+
+func parseOrgPath(path string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// normalizeOrgName 忽略空白、大小写和全角括号差异
+func normalizeOrgName(value string) string {
+	value = strings.NewReplacer("（", "(", "）", ")").Replace(value)
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value))
+}
+
+func splitOrgAliases(value string) []string {
+	var aliases []string
+	for _, alias := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' }) {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
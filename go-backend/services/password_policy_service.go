@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrPasswordReused = errors.New("新密码不能与最近使用过的密码相同")
+
+// PasswordPolicyService 密码规则（复杂度、历史密码、有效期），规则存储在系统设置中
+type PasswordPolicyService struct {
+	db       *gorm.DB
+	settings *SettingService
+}
+
+func NewPasswordPolicyService(db *gorm.DB) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		db:       db,
+		settings: NewSettingService(db),
+	}
+}
+
+// Policy 读取当前密码复杂度规则
+func (s *PasswordPolicyService) Policy() utils.PasswordPolicy {
+	defaults := utils.DefaultPasswordPolicy
+	return utils.PasswordPolicy{
+		MinLength:     s.settings.GetInt("password_min_length", defaults.MinLength),
+		RequireLetter: s.settings.GetBool("password_require_letter", defaults.RequireLetter),
+		RequireUpper:  s.settings.GetBool("password_require_upper", defaults.RequireUpper),
+		RequireLower:  s.settings.GetBool("password_require_lower", defaults.RequireLower),
+		RequireDigit:  s.settings.GetBool("password_require_digit", defaults.RequireDigit),
+		RequireSymbol: s.settings.GetBool("password_require_symbol", defaults.RequireSymbol),
+	}
+}
+
+// HistoryCount 不允许重复使用的最近密码个数（含当前密码），0 表示不检查
+func (s *PasswordPolicyService) HistoryCount() int {
+	return s.settings.GetInt("password_history_count", 5)
+}
+
+// ExpireDays 密码有效期（天），0 表示永不过期
+func (s *PasswordPolicyService) ExpireDays() int {
+	return s.settings.GetInt("password_expire_days", 0)
+}
+
+// Validate 校验新密码的复杂度，userID 非0时同时检查是否与最近N个密码重复
+func (s *PasswordPolicyService) Validate(userID uint, password string) error {
+	if err := utils.ValidatePassword(password, s.Policy()); err != nil {
+		return err
+	}
+	if userID == 0 {
+		return nil
+	}
+
+	historyCount := s.HistoryCount()
+	if historyCount <= 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "password").First(&user, userID).Error; err == nil && utils.CheckPassword(password, user.Password) {
+		return ErrPasswordReused
+	}
+
+	// 当前密码占一个名额，其余从历史记录中取
+	var history []models.PasswordHistory
+	s.db.Where("user_id = ?", userID).Order("id DESC").Limit(historyCount - 1).Find(&history)
+	for _, item := range history {
+		if utils.CheckPassword(password, item.PasswordHash) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// MustChange 用户是否需要先修改密码才能使用系统（管理员重置/新建账号，或密码已过期）
+func (s *PasswordPolicyService) MustChange(user *models.User) bool {
+	if user.MustChangePassword {
+		return true
+	}
+	expireDays := s.ExpireDays()
+	if expireDays <= 0 {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	} else if enabledAt, ok := s.settings.UpdatedAt("password_expire_days"); ok && enabledAt.After(changedAt) {
+		// 启用有效期前就存在、之后未改过密码的账号从启用（最近一次调整）有效期时开始计时，避免启用后老账号同时过期
+		changedAt = enabledAt
+	}
+	return time.Since(changedAt) > time.Duration(expireDays)*24*time.Hour
+}
+
+// SetPassword 更新用户密码并记录历史，mustChange 表示下次登录是否必须修改密码。
+// tx 为空时使用默认连接，调用方可传入事务保证与其他更新一起提交。
+func (s *PasswordPolicyService) SetPassword(tx *gorm.DB, userID uint, hashedPassword string, mustChange bool) error {
+	if tx == nil {
+		tx = s.db
+	}
+
+	// 旧密码进入历史记录
+	var user models.User
+	if err := tx.Select("id", "password").First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.Password != "" {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: user.Password}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":             hashedPassword,
+		"password_changed_at":  time.Now(),
+		"must_change_password": mustChange,
+	}).Error; err != nil {
+		return err
+	}
+
+	s.pruneHistory(tx, userID)
+	return nil
+}
+
+// ChangePassword 用户修改自己的密码（校验原密码、复杂度和历史密码）
+func (s *PasswordPolicyService) ChangePassword(userID uint, oldPassword, newPassword string) error {
+	var user models.User
+	if err := s.db.Select("id", "password").First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if !utils.CheckPassword(oldPassword, user.Password) {
+		return errors.New("原密码错误")
+	}
+	if err := s.Validate(userID, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.SetPassword(tx, userID, hashedPassword, false)
+	}); err != nil {
+		log.Printf("修改密码失败 - 用户ID: %d, 错误: %v", userID, err)
+		return errors.New("修改密码失败")
+	}
+	return nil
+}
+
+// GeneratePassword 生成符合当前规则的随机密码（管理员重置密码时使用）
+func (s *PasswordPolicyService) GeneratePassword() string {
+	return utils.GeneratePolicyPassword(s.Policy())
+}
+
+// pruneHistory 只保留检查所需的历史密码
+func (s *PasswordPolicyService) pruneHistory(tx *gorm.DB, userID uint) {
+	keep := s.HistoryCount() - 1
+	if keep < 0 {
+		keep = 0
+	}
+
+	var ids []uint
+	tx.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).Order("id DESC").Pluck("id", &ids)
+	if len(ids) <= keep {
+		return
+	}
+	if err := tx.Where("id IN ?", ids[keep:]).Delete(&models.PasswordHistory{}).Error; err != nil {
+		log.Printf("清理历史密码失败 - 用户ID: %d, 错误: %v", userID, err)
+	}
+}
+
+// Describe 返回当前密码规则，用于前端提示
+func (s *PasswordPolicyService) Describe() map[string]interface{} {
+	policy := s.Policy()
+	return map[string]interface{}{
+		"minLength":     policy.MinLength,
+		"requireLetter": policy.RequireLetter,
+		"requireUpper":  policy.RequireUpper,
+		"requireLower":  policy.RequireLower,
+		"requireDigit":  policy.RequireDigit,
+		"requireSymbol": policy.RequireSymbol,
+		"historyCount":  s.HistoryCount(),
+		"expireDays":    s.ExpireDays(),
+	}
+}
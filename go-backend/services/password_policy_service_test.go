@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+)
+
+func TestPasswordExpiryStartsWhenPolicyEnabled(t *testing.T) {
+	db := newTestDB(t, &models.SystemSetting{})
+	service := NewPasswordPolicyService(db)
+	setting := models.SystemSetting{SettingKey: "password_expire_days", SettingValue: "90"}
+	if err := db.Create(&setting).Error; err != nil {
+		t.Fatalf("创建系统设置失败: %v", err)
+	}
+
+	// 启用策略前两年创建、从未改过密码的账号：从启用时开始计时，不应立即过期
+	oldAccount := &models.User{CreatedAt: time.Now().AddDate(-2, 0, 0)}
+	if service.MustChange(oldAccount) {
+		t.Fatal("启用有效期后老账号不应立即过期")
+	}
+
+	// 启用策略已超过有效期
+	db.Model(&models.SystemSetting{}).Where("setting_key = ?", "password_expire_days").
+		UpdateColumn("updated_at", time.Now().AddDate(0, 0, -91))
+	if !service.MustChange(oldAccount) {
+		t.Fatal("启用有效期超过90天后老账号应过期")
+	}
+
+	// 修改过密码的账号按修改时间计算
+	changedAt := time.Now().AddDate(0, 0, -10)
+	if service.MustChange(&models.User{CreatedAt: oldAccount.CreatedAt, PasswordChangedAt: &changedAt}) {
+		t.Fatal("10天前修改过密码的账号不应过期")
+	}
+}
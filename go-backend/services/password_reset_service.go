@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// passwordResetPurpose 找回密码令牌的用途标识
+const passwordResetPurpose = "password_reset"
+
+var (
+	ErrPasswordResetTooMany = errors.New("找回密码请求过于频繁，请稍后再试")
+	ErrPasswordResetInvalid = errors.New("重置链接无效、已过期或已使用")
+)
+
+// PasswordResetPolicy 找回密码配置（存储在系统设置中）
+type PasswordResetPolicy struct {
+	ResetURL      string
+	TokenTTL      time.Duration
+	Window        time.Duration
+	MaxPerAccount int
+	MaxPerIP      int
+}
+
+type PasswordResetService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	systemLogs *SystemLogService
+}
+
+func NewPasswordResetService(db *gorm.DB) *PasswordResetService {
+	return &PasswordResetService{
+		db:         db,
+		settings:   NewSettingService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// Policy 读取当前找回密码配置
+func (s *PasswordResetService) Policy() PasswordResetPolicy {
+	return PasswordResetPolicy{
+		ResetURL:      s.settings.GetString("password_reset_url", "http://localhost:5173/reset-password"),
+		TokenTTL:      time.Duration(s.settings.GetInt("password_reset_token_minutes", 30)) * time.Minute,
+		Window:        time.Duration(s.settings.GetInt("password_reset_window_minutes", 60)) * time.Minute,
+		MaxPerAccount: s.settings.GetInt("password_reset_max_per_account", 3),
+		MaxPerIP:      s.settings.GetInt("password_reset_max_per_ip", 10),
+	}
+}
+
+// RequestReset 处理找回密码请求：账号存在且有邮箱时发送一次性重置链接。
+// 为避免暴露账号是否存在，账号不存在时同样返回成功，只有触发限流时返回错误。
+func (s *PasswordResetService) RequestReset(account, ipAddress, userAgent string) error {
+	account = strings.TrimSpace(account)
+	policy := s.Policy()
+	since := time.Now().Add(-policy.Window)
+
+	var accountCount, ipCount int64
+	s.db.Model(&models.PasswordResetToken{}).Where("identifier = ? AND created_at > ?", account, since).Count(&accountCount)
+	s.db.Model(&models.PasswordResetToken{}).Where("ip_address = ? AND created_at > ?", ipAddress, since).Count(&ipCount)
+	if accountCount >= int64(policy.MaxPerAccount) || ipCount >= int64(policy.MaxPerIP) {
+		s.systemLogs.RecordSecurity("password_reset_request", "找回密码请求被限流", "failed", nil,
+			fmt.Sprintf("账号: %s, 账号请求数: %d, IP请求数: %d", account, accountCount, ipCount), ipAddress, userAgent)
+		return ErrPasswordResetTooMany
+	}
+
+	record := models.PasswordResetToken{
+		Identifier: account,
+		ExpiresAt:  time.Now().Add(policy.TokenTTL),
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+	}
+
+	var user models.User
+	err := s.db.Where("username = ? OR email = ?", account, account).First(&user).Error
+	if err != nil || user.Status != "active" || user.Email == "" {
+		// 仍然记录请求用于限流，但不发送邮件
+		s.db.Create(&record)
+		log.Printf("找回密码 - 账号不存在或不可用: %s", account)
+		s.systemLogs.RecordSecurity("password_reset_request", "找回密码账号不存在或不可用", "failed", nil,
+			fmt.Sprintf("账号: %s, 记录ID: %d", account, record.ID), ipAddress, userAgent)
+		return nil
+	}
+
+	link, err := s.issueLink(&user, &record, policy)
+	if err != nil {
+		return errors.New("找回密码请求失败")
+	}
+
+	message := utils.MailMessage{
+		To:      []string{user.Email},
+		Subject: "云梦系统 - 重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号密码的请求。请在 %d 分钟内打开以下链接设置新密码（链接只能使用一次）：\n\n%s\n\n如果这不是您本人的操作，请忽略本邮件，您的密码不会被修改。\n",
+			user.Username, int(policy.TokenTTL.Minutes()), link),
+	}
+	if err := utils.GetMailSender().Send(message); err != nil {
+		log.Printf("发送重置邮件失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		s.systemLogs.RecordSecurity("password_reset_request", "发送重置密码邮件失败", "failed", &user.ID,
+			fmt.Sprintf("记录ID: %d, 错误: %v", record.ID, err), ipAddress, userAgent)
+		return nil
+	}
+
+	s.systemLogs.RecordSecurity("password_reset_request", "发送重置密码邮件", "success", &user.ID,
+		fmt.Sprintf("记录ID: %d, 有效期: %d分钟", record.ID, int(policy.TokenTTL.Minutes())), ipAddress, userAgent)
+	return nil
+}
+
+// SendWelcome 发送账号开通邮件，附带设置密码链接（与找回密码共用一次性令牌，有效期按 user_import_welcome_link_hours）
+func (s *PasswordResetService) SendWelcome(user *models.User) error {
+	if user.Email == "" {
+		return errors.New("用户未设置邮箱")
+	}
+	policy := s.Policy()
+	policy.TokenTTL = time.Duration(s.settings.GetInt("user_import_welcome_link_hours", 72)) * time.Hour
+
+	// 不记录IP，避免批量开通占用操作人所在IP的找回密码限流名额
+	record := models.PasswordResetToken{
+		Identifier: user.Username,
+		ExpiresAt:  time.Now().Add(policy.TokenTTL),
+	}
+	link, err := s.issueLink(user, &record, policy)
+	if err != nil {
+		return err
+	}
+
+	message := utils.MailMessage{
+		To:      []string{user.Email},
+		Subject: "云梦系统 - 账号开通",
+		Body: fmt.Sprintf("%s，您好：\n\n您的云梦系统账号已开通，用户名：%s。请在 %d 小时内打开以下链接设置登录密码（链接只能使用一次）：\n\n%s\n\n链接过期后可在登录页通过“忘记密码”重新获取。\n",
+			user.Username, user.Username, int(policy.TokenTTL.Hours()), link),
+	}
+	if err := utils.GetMailSender().Send(message); err != nil {
+		return err
+	}
+	s.systemLogs.RecordSecurity("account_welcome", "发送账号开通邮件", "success", &user.ID,
+		fmt.Sprintf("记录ID: %d, 有效期: %d小时", record.ID, int(policy.TokenTTL.Hours())), "", "")
+	return nil
+}
+
+// issueLink 保存重置记录并签发一次性令牌，返回重置页面链接
+func (s *PasswordResetService) issueLink(user *models.User, record *models.PasswordResetToken, policy PasswordResetPolicy) (string, error) {
+	record.UserID = &user.ID
+	if err := s.db.Create(record).Error; err != nil {
+		log.Printf("创建找回密码记录失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return "", err
+	}
+
+	token, err := utils.GenerateActionToken(user.ID, passwordResetPurpose, strconv.FormatUint(uint64(record.ID), 10), policy.TokenTTL)
+	if err != nil {
+		log.Printf("生成重置令牌失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return "", err
+	}
+	s.db.Model(record).Update("token_hash", utils.HashToken(token))
+	return utils.AppendQuery(policy.ResetURL, url.Values{"token": {token}}), nil
+}
+
+// VerifyToken 校验重置令牌是否可用（不消耗令牌），返回对应的记录
+func (s *PasswordResetService) VerifyToken(token string) (*models.PasswordResetToken, error) {
+	claims, err := utils.ParseActionToken(token, passwordResetPurpose)
+	if err != nil {
+		return nil, ErrPasswordResetInvalid
+	}
+
+	var record models.PasswordResetToken
+	if err := s.db.Where("id = ? AND token_hash = ?", claims.ID, utils.HashToken(token)).First(&record).Error; err != nil {
+		return nil, ErrPasswordResetInvalid
+	}
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) || record.UserID == nil || *record.UserID != claims.UserID {
+		return nil, ErrPasswordResetInvalid
+	}
+	// 链接签发后账号被禁用、归档或删除的，链接随之失效
+	var user models.User
+	if err := s.db.Select("id", "status").First(&user, *record.UserID).Error; err != nil || user.Status != "active" {
+		return nil, ErrPasswordResetInvalid
+	}
+	return &record, nil
+}
+
+// ResetPassword 使用一次性令牌设置新密码，成功后撤销该用户的全部登录会话和访问令牌
+func (s *PasswordResetService) ResetPassword(token, newPassword, ipAddress, userAgent string) error {
+	record, err := s.VerifyToken(token)
+	if err != nil {
+		return err
+	}
+	userID := *record.UserID
+
+	policyService := NewPasswordPolicyService(s.db)
+	if err := policyService.Validate(userID, newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 条件更新保证令牌只能使用一次
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPasswordResetInvalid
+		}
+
+		if err := policyService.SetPassword(tx, userID, hashedPassword, false); err != nil {
+			return err
+		}
+
+		// 同一用户其他未使用的重置链接一并作废
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", now).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasswordResetInvalid) {
+			return err
+		}
+		log.Printf("重置密码失败 - 用户ID: %d, 错误: %v", userID, err)
+		return errors.New("重置密码失败")
+	}
+
+	revoked, _ := NewAuthService(s.db).RevokeUserSessions(userID, "password_reset")
+	tokens, _ := NewPersonalTokenService(s.db).RevokeUserTokens(userID)
+
+	s.systemLogs.RecordSecurity("password_reset", "通过邮件链接重置密码", "success", &userID,
+		fmt.Sprintf("记录ID: %d, 撤销会话数: %d, 撤销访问令牌数: %d", record.ID, revoked, tokens), ipAddress, userAgent)
+	log.Printf("用户通过邮件重置密码成功 - 用户ID: %d", userID)
+	return nil
+}
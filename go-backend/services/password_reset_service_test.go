@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+)
+
+var resetLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+func newPasswordResetTestDB(t *testing.T) *PasswordResetService {
+	t.Helper()
+	useTestSigningKey(t)
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.SystemSetting{}, &models.SystemLog{},
+		&models.PasswordResetToken{}, &models.PasswordHistory{}, &models.UserSession{}, &models.RefreshToken{},
+		&models.PersonalAccessToken{})
+	return NewPasswordResetService(db)
+}
+
+func createResetTestUser(t *testing.T, service *PasswordResetService, username, email string) *models.User {
+	t.Helper()
+	hashed, _ := utils.HashPassword("Old#Passw0rd")
+	user := &models.User{Username: username, Email: email, Password: hashed, Status: "active", RoleName: "student"}
+	if err := service.db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	return user
+}
+
+// resetTokenFromMail 从捕获的邮件正文中取出重置链接里的令牌
+func resetTokenFromMail(t *testing.T, mail capturedMail) string {
+	t.Helper()
+	link := resetLinkPattern.FindString(mail.Data)
+	if link == "" {
+		t.Fatalf("邮件中没有重置链接: %s", mail.Data)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("重置链接格式错误: %v", err)
+	}
+	token := parsed.Query().Get("token")
+	if token == "" {
+		t.Fatalf("重置链接中没有令牌: %s", link)
+	}
+	return token
+}
+
+func TestPasswordResetFlowWithMailCatcher(t *testing.T) {
+	service := newPasswordResetTestDB(t)
+	catcher := startMailCatcher(t)
+	user := createResetTestUser(t, service, "stu001", "stu001@yunmeng.test")
+
+	if err := service.RequestReset("stu001@yunmeng.test", "127.0.0.1", "test"); err != nil {
+		t.Fatalf("发起找回密码失败: %v", err)
+	}
+	messages := catcher.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("应收到1封重置邮件，实际 %d 封", len(messages))
+	}
+	if len(messages[0].To) != 1 || messages[0].To[0] != user.Email {
+		t.Fatalf("收件人错误: %v", messages[0].To)
+	}
+	token := resetTokenFromMail(t, messages[0])
+
+	if _, err := service.VerifyToken(token); err != nil {
+		t.Fatalf("重置链接应有效: %v", err)
+	}
+	if err := service.ResetPassword(token, "New#Passw0rd", "127.0.0.1", "test"); err != nil {
+		t.Fatalf("重置密码失败: %v", err)
+	}
+
+	var updated models.User
+	service.db.First(&updated, user.ID)
+	if !utils.CheckPassword("New#Passw0rd", updated.Password) {
+		t.Fatal("密码未更新")
+	}
+
+	// 链接只能使用一次
+	if err := service.ResetPassword(token, "Another#Passw0rd", "127.0.0.1", "test"); !errors.Is(err, ErrPasswordResetInvalid) {
+		t.Fatalf("重复使用链接应失败，实际: %v", err)
+	}
+}
+
+func TestPasswordResetUnknownAccountSendsNothing(t *testing.T) {
+	service := newPasswordResetTestDB(t)
+	catcher := startMailCatcher(t)
+
+	if err := service.RequestReset("nobody", "127.0.0.1", "test"); err != nil {
+		t.Fatalf("账号不存在时也应返回成功: %v", err)
+	}
+	if count := len(catcher.Messages()); count != 0 {
+		t.Fatalf("账号不存在时不应发送邮件，实际 %d 封", count)
+	}
+}
+
+func TestPasswordResetRejectsDisabledAccount(t *testing.T) {
+	service := newPasswordResetTestDB(t)
+	catcher := startMailCatcher(t)
+	user := createResetTestUser(t, service, "stu002", "stu002@yunmeng.test")
+
+	if err := service.RequestReset("stu002", "127.0.0.1", "test"); err != nil {
+		t.Fatalf("发起找回密码失败: %v", err)
+	}
+	messages := catcher.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("应收到1封重置邮件，实际 %d 封", len(messages))
+	}
+	token := resetTokenFromMail(t, messages[0])
+
+	// 链接签发后账号被禁用
+	service.db.Model(&models.User{}).Where("id = ?", user.ID).Update("status", "inactive")
+
+	if _, err := service.VerifyToken(token); !errors.Is(err, ErrPasswordResetInvalid) {
+		t.Fatalf("禁用账号的链接应失效，实际: %v", err)
+	}
+	if err := service.ResetPassword(token, "New#Passw0rd", "127.0.0.1", "test"); !errors.Is(err, ErrPasswordResetInvalid) {
+		t.Fatalf("禁用账号不应能重置密码，实际: %v", err)
+	}
+}
+
+func TestPasswordResetRateLimit(t *testing.T) {
+	service := newPasswordResetTestDB(t)
+	catcher := startMailCatcher(t)
+	createResetTestUser(t, service, "stu003", "stu003@yunmeng.test")
+
+	limit := service.Policy().MaxPerAccount
+	for i := 0; i < limit; i++ {
+		if err := service.RequestReset("stu003", "127.0.0.1", "test"); err != nil {
+			t.Fatalf("第%d次请求不应被限流: %v", i+1, err)
+		}
+	}
+	if err := service.RequestReset("stu003", "127.0.0.1", "test"); !errors.Is(err, ErrPasswordResetTooMany) {
+		t.Fatalf("超过次数应被限流，实际: %v", err)
+	}
+	if count := len(catcher.Messages()); count != limit {
+		t.Fatalf("应发送 %d 封邮件，实际 %d 封", limit, count)
+	}
+}
+
+func TestResetLinkKeepsExistingQuery(t *testing.T) {
+	link := utils.AppendQuery("https://yunmeng.test/reset?lang=zh", url.Values{"token": {"abc.def"}})
+	if !strings.HasSuffix(link, "?lang=zh&token=abc.def") {
+		t.Fatalf("重置链接拼接错误: %s", link)
+	}
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// permissionCacheTTL 角色权限缓存有效期（本实例修改时立即失效，多实例部署时依赖过期刷新）
+const permissionCacheTTL = time.Minute
+
+// rolePermissionCache 角色键 -> 权限键集合及数据范围，所有 PermissionService 实例共享
+var rolePermissionCache = struct {
+	sync.RWMutex
+	roles    map[string]map[string]bool
+	scopes   map[string]string
+	loadedAt time.Time
+}{}
+
+type PermissionService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewPermissionService(db *gorm.DB) *PermissionService {
+	return &PermissionService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// HasPermission 判断角色是否拥有指定权限（支持 * 和 module.* 通配）
+func (s *PermissionService) HasPermission(roleKey, permKey string) bool {
+	granted := s.rolePermissions(roleKey)
+	if granted[models.PermissionAll] || granted[permKey] {
+		return true
+	}
+	if i := strings.Index(permKey, "."); i > 0 && granted[permKey[:i]+".*"] {
+		return true
+	}
+	return false
+}
+
+// GetRolePermissionKeys 获取角色拥有的全部权限键
+func (s *PermissionService) GetRolePermissionKeys(roleKey string) []string {
+	granted := s.rolePermissions(roleKey)
+	keys := make([]string, 0, len(granted))
+	for key := range granted {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InvalidateCache 清空角色权限缓存
+func (s *PermissionService) InvalidateCache() {
+	rolePermissionCache.Lock()
+	rolePermissionCache.roles = nil
+	rolePermissionCache.Unlock()
+}
+
+// ResolveDataScope 计算用户在当前角色下的数据范围（拥有全部权限的超级管理员始终为全局）
+func (s *PermissionService) ResolveDataScope(userID uint, roleKey string) *models.DataScope {
+	if s.HasPermission(roleKey, models.PermissionAll) {
+		return &models.DataScope{Global: true}
+	}
+
+	rolePermissionCache.RLock()
+	dataScope, ok := rolePermissionCache.scopes[roleKey]
+	rolePermissionCache.RUnlock()
+	// 只有明确配置为全部数据的角色才是全局范围；角色不存在或缓存加载失败时按未设置院系的最小范围处理
+	if !ok {
+		return &models.DataScope{}
+	}
+	if dataScope == models.DataScopeAll {
+		return &models.DataScope{Global: true}
+	}
+
+	scope := &models.DataScope{}
+	var profile models.UserProfile
+	if err := s.db.Select("department").Where("user_id = ?", userID).First(&profile).Error; err == nil {
+		scope.Department = profile.Department
+	}
+	if scope.Department == "" {
+		var user models.User
+		if err := s.db.Select("department").First(&user, userID).Error; err == nil {
+			scope.Department = user.Department
+		}
+	}
+	return scope
+}
+
+func (s *PermissionService) rolePermissions(roleKey string) map[string]bool {
+	rolePermissionCache.RLock()
+	if rolePermissionCache.roles != nil && time.Since(rolePermissionCache.loadedAt) < permissionCacheTTL {
+		granted := rolePermissionCache.roles[roleKey]
+		rolePermissionCache.RUnlock()
+		return granted
+	}
+	rolePermissionCache.RUnlock()
+
+	var roleRows []models.Role
+	if err := s.db.Select("role_key", "data_scope").Find(&roleRows).Error; err != nil {
+		log.Printf("加载角色数据范围失败: %v", err)
+		return nil
+	}
+	scopes := make(map[string]string, len(roleRows))
+	for _, role := range roleRows {
+		scopes[role.RoleKey] = role.DataScope
+	}
+
+	var rows []struct {
+		RoleKey string
+		PermKey string
+	}
+	if err := s.db.Table("role_permissions").
+		Select("roles.role_key, permissions.perm_key").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Scan(&rows).Error; err != nil {
+		log.Printf("加载角色权限失败: %v", err)
+		return nil
+	}
+
+	roles := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if roles[row.RoleKey] == nil {
+			roles[row.RoleKey] = make(map[string]bool)
+		}
+		roles[row.RoleKey][row.PermKey] = true
+	}
+
+	rolePermissionCache.Lock()
+	rolePermissionCache.roles = roles
+	rolePermissionCache.scopes = scopes
+	rolePermissionCache.loadedAt = time.Now()
+	rolePermissionCache.Unlock()
+
+	return roles[roleKey]
+}
+
+// =============================================
+// 角色管理
+// =============================================
+
+// GetRoles 获取角色列表（包含权限和用户数量）
+func (s *PermissionService) GetRoles() ([]models.RoleWithStats, error) {
+	var roles []models.Role
+	if err := s.db.Preload("Permissions").Order("id ASC").Find(&roles).Error; err != nil {
+		log.Printf("获取角色列表失败: %v", err)
+		return nil, err
+	}
+
+	result := make([]models.RoleWithStats, 0, len(roles))
+	for _, role := range roles {
+		var count int64
+		s.db.Model(&models.UserRole{}).Where("role_id = ?", role.ID).Count(&count)
+		result = append(result, models.RoleWithStats{Role: role, UserCount: count})
+	}
+	return result, nil
+}
+
+// GetRoleByID 获取角色详情
+func (s *PermissionService) GetRoleByID(id uint) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("角色不存在")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// CreateRole 创建角色
+func (s *PermissionService) CreateRole(req models.RoleRequest, operatorID uint) (*models.Role, error) {
+	var count int64
+	s.db.Model(&models.Role{}).Where("role_key = ?", req.RoleKey).Count(&count)
+	if count > 0 {
+		return nil, errors.New("角色标识已存在")
+	}
+
+	role := models.Role{
+		RoleKey:     req.RoleKey,
+		RoleName:    req.RoleName,
+		Description: req.Description,
+		DataScope:   req.DataScope,
+	}
+	// 未指定数据范围时按最小范围（本院系）创建，全局范围须显式选择
+	if role.DataScope == "" {
+		role.DataScope = models.DataScopeDepartment
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		if req.Permissions != nil {
+			return s.replaceRolePermissions(tx, &role, req.Permissions)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("创建角色失败: %v", err)
+		return nil, err
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "role_create", "创建角色", fmt.Sprintf("角色: %s, 权限: %v", role.RoleKey, req.Permissions))
+	return s.GetRoleByID(role.ID)
+}
+
+// UpdateRole 更新角色（内置角色不允许修改角色标识）
+func (s *PermissionService) UpdateRole(id uint, req models.RoleRequest, operatorID uint) (*models.Role, error) {
+	role, err := s.GetRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem && role.RoleKey != req.RoleKey {
+		return nil, errors.New("内置角色不允许修改角色标识")
+	}
+	if role.RoleKey != req.RoleKey {
+		var count int64
+		s.db.Model(&models.Role{}).Where("role_key = ? AND id <> ?", req.RoleKey, id).Count(&count)
+		if count > 0 {
+			return nil, errors.New("角色标识已存在")
+		}
+	}
+	dataScope := req.DataScope
+	if dataScope == "" {
+		dataScope = role.DataScope
+	}
+	if role.RoleKey == "admin" && dataScope != models.DataScopeAll {
+		return nil, errors.New("系统管理员角色的数据范围必须为全部")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Updates(map[string]interface{}{
+			"role_key":    req.RoleKey,
+			"role_name":   req.RoleName,
+			"description": req.Description,
+			"data_scope":  dataScope,
+		}).Error; err != nil {
+			return err
+		}
+		if req.Permissions != nil {
+			return s.replaceRolePermissions(tx, role, req.Permissions)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新角色失败 - 角色ID: %d, 错误: %v", id, err)
+		return nil, err
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "role_update", "更新角色", fmt.Sprintf("角色: %s, 权限: %v", req.RoleKey, req.Permissions))
+	return s.GetRoleByID(id)
+}
+
+// SetRolePermissions 设置角色的权限（整体替换）
+func (s *PermissionService) SetRolePermissions(id uint, permKeys []string, operatorID uint) (*models.Role, error) {
+	role, err := s.GetRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.replaceRolePermissions(tx, role, permKeys)
+	}); err != nil {
+		log.Printf("设置角色权限失败 - 角色ID: %d, 错误: %v", id, err)
+		return nil, err
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "role_permissions", "设置角色权限", fmt.Sprintf("角色: %s, 权限: %v", role.RoleKey, permKeys))
+	return s.GetRoleByID(id)
+}
+
+// DeleteRole 删除角色（内置角色或仍有用户使用的角色不允许删除）
+func (s *PermissionService) DeleteRole(id uint, operatorID uint) error {
+	role, err := s.GetRoleByID(id)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return errors.New("内置角色不允许删除")
+	}
+
+	var count int64
+	s.db.Model(&models.UserRole{}).Where("role_id = ?", id).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("仍有 %d 个用户使用该角色，不能删除", count)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Role{}, id).Error
+	})
+	if err != nil {
+		log.Printf("删除角色失败 - 角色ID: %d, 错误: %v", id, err)
+		return errors.New("删除角色失败")
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "role_delete", "删除角色", "角色: "+role.RoleKey)
+	return nil
+}
+
+// =============================================
+// 权限管理
+// =============================================
+
+// GetPermissions 获取权限列表，可按模块过滤
+func (s *PermissionService) GetPermissions(module string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	query := s.db.Model(&models.Permission{})
+	if module != "" {
+		query = query.Where("module = ?", module)
+	}
+	if err := query.Order("module ASC, perm_key ASC").Find(&permissions).Error; err != nil {
+		log.Printf("获取权限列表失败: %v", err)
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// CreatePermission 创建权限
+func (s *PermissionService) CreatePermission(req models.PermissionRequest, operatorID uint) (*models.Permission, error) {
+	var count int64
+	s.db.Model(&models.Permission{}).Where("perm_key = ?", req.PermKey).Count(&count)
+	if count > 0 {
+		return nil, errors.New("权限标识已存在")
+	}
+
+	permission := models.Permission{
+		PermKey:     req.PermKey,
+		Name:        req.Name,
+		Module:      permissionModule(req.PermKey, req.Module),
+		Description: req.Description,
+	}
+	if err := s.db.Create(&permission).Error; err != nil {
+		log.Printf("创建权限失败: %v", err)
+		return nil, errors.New("创建权限失败")
+	}
+
+	s.recordChange(operatorID, "permission_create", "创建权限", "权限: "+permission.PermKey)
+	return &permission, nil
+}
+
+// UpdatePermission 更新权限（内置权限不允许修改权限标识）
+func (s *PermissionService) UpdatePermission(id uint, req models.PermissionRequest, operatorID uint) (*models.Permission, error) {
+	var permission models.Permission
+	if err := s.db.First(&permission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("权限不存在")
+		}
+		return nil, err
+	}
+	if permission.IsSystem && permission.PermKey != req.PermKey {
+		return nil, errors.New("内置权限不允许修改权限标识")
+	}
+	if permission.PermKey != req.PermKey {
+		var count int64
+		s.db.Model(&models.Permission{}).Where("perm_key = ? AND id <> ?", req.PermKey, id).Count(&count)
+		if count > 0 {
+			return nil, errors.New("权限标识已存在")
+		}
+	}
+
+	if err := s.db.Model(&permission).Updates(map[string]interface{}{
+		"perm_key":    req.PermKey,
+		"name":        req.Name,
+		"module":      permissionModule(req.PermKey, req.Module),
+		"description": req.Description,
+	}).Error; err != nil {
+		log.Printf("更新权限失败 - 权限ID: %d, 错误: %v", id, err)
+		return nil, errors.New("更新权限失败")
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "permission_update", "更新权限", "权限: "+req.PermKey)
+	return &permission, nil
+}
+
+// DeletePermission 删除权限（内置权限不允许删除）
+func (s *PermissionService) DeletePermission(id uint, operatorID uint) error {
+	var permission models.Permission
+	if err := s.db.First(&permission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("权限不存在")
+		}
+		return err
+	}
+	if permission.IsSystem {
+		return errors.New("内置权限不允许删除")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("permission_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Permission{}, id).Error
+	})
+	if err != nil {
+		log.Printf("删除权限失败 - 权限ID: %d, 错误: %v", id, err)
+		return errors.New("删除权限失败")
+	}
+
+	s.InvalidateCache()
+	s.recordChange(operatorID, "permission_delete", "删除权限", "权限: "+permission.PermKey)
+	return nil
+}
+
+// replaceRolePermissions 用给定的权限键整体替换角色权限，未知的权限键报错
+func (s *PermissionService) replaceRolePermissions(tx *gorm.DB, role *models.Role, permKeys []string) error {
+	var permissions []models.Permission
+	if len(permKeys) > 0 {
+		if err := tx.Where("perm_key IN ?", permKeys).Find(&permissions).Error; err != nil {
+			return err
+		}
+		if len(permissions) != len(uniqueStrings(permKeys)) {
+			found := make(map[string]bool, len(permissions))
+			for _, p := range permissions {
+				found[p.PermKey] = true
+			}
+			for _, key := range permKeys {
+				if !found[key] {
+					return fmt.Errorf("权限不存在: %s", key)
+				}
+			}
+		}
+	}
+
+	// 防止管理员误操作移除自身全部权限导致无法管理
+	if role.RoleKey == "admin" {
+		hasAll := false
+		for _, p := range permissions {
+			if p.PermKey == models.PermissionAll {
+				hasAll = true
+				break
+			}
+		}
+		if !hasAll {
+			return errors.New("系统管理员角色必须保留全部权限(*)")
+		}
+	}
+
+	return tx.Model(role).Association("Permissions").Replace(permissions)
+}
+
+func (s *PermissionService) recordChange(operatorID uint, operation, action, details string) {
+	s.systemLogs.RecordSecurity(operation, action, "success", &operatorID, details, "", "")
+}
+
+// permissionModule 未指定模块时取权限键的前缀作为模块
+func permissionModule(permKey, module string) string {
+	if module != "" {
+		return module
+	}
+	if i := strings.Index(permKey, "."); i > 0 {
+		return permKey[:i]
+	}
+	return permKey
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"yunmeng-backend/models"
+)
+
+func TestCreateRoleDefaultsToDepartmentScope(t *testing.T) {
+	db := newTestDB(t, &models.Role{}, &models.Permission{}, &models.RolePermission{}, &models.SystemLog{})
+	service := NewPermissionService(db)
+
+	role, err := service.CreateRole(models.RoleRequest{RoleKey: "counselor", RoleName: "辅导员"}, 1)
+	if err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+	if role.DataScope != models.DataScopeDepartment {
+		t.Fatalf("未指定数据范围的角色应为本院系，实际: %s", role.DataScope)
+	}
+
+	global, err := service.CreateRole(models.RoleRequest{RoleKey: "auditor", RoleName: "审计员", DataScope: models.DataScopeAll}, 1)
+	if err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+	if global.DataScope != models.DataScopeAll {
+		t.Fatalf("显式指定的全局范围应保留，实际: %s", global.DataScope)
+	}
+
+	// 直接写入数据库、未经服务设置的角色同样取最小范围
+	direct := models.Role{RoleKey: "direct", RoleName: "直接创建"}
+	db.Create(&direct)
+	var stored models.Role
+	db.First(&stored, direct.ID)
+	if stored.DataScope != models.DataScopeDepartment {
+		t.Fatalf("数据范围列默认值应为本院系，实际: %s", stored.DataScope)
+	}
+}
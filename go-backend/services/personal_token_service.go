@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// PersonalTokenPrefix 个人访问令牌前缀，用于在认证头中区分JWT
+	PersonalTokenPrefix = "ymp_"
+
+	maxPersonalTokensPerUser = 20
+	// personalTokenTouchInterval 最近使用时间的最小更新间隔，避免每个请求都写库
+	personalTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrPersonalTokenInvalid = errors.New("访问令牌无效、已过期或已撤销")
+	ErrPersonalTokenScope   = errors.New("访问令牌无权访问该接口")
+)
+
+// PersonalTokenScopeGroups 令牌作用域对应的路由分组（作用域格式为 分组:read 或 分组:write）
+var PersonalTokenScopeGroups = map[string][]string{
+	"competitions":  {"/api/competitions", "/api/student-competitions", "/api/teacher-competitions", "/api/admin/competitions"},
+	"projects":      {"/api/projects", "/api/teacher-projects", "/api/admin/projects", "/api/project-types"},
+	"teachers":      {"/api/teachers", "/api/student-teachers", "/api/students"},
+	"users":         {"/api/users"},
+	"notifications": {"/api/notifications"},
+	"files":         {"/api/files"},
+}
+
+type PersonalTokenService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewPersonalTokenService(db *gorm.DB) *PersonalTokenService {
+	return &PersonalTokenService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// IsPersonalToken 判断认证头中的令牌是否为个人访问令牌
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// GetAvailableScopes 获取可选的令牌作用域
+func (s *PersonalTokenService) GetAvailableScopes() []string {
+	scopes := make([]string, 0, len(PersonalTokenScopeGroups)*2)
+	for group := range PersonalTokenScopeGroups {
+		scopes = append(scopes, group+":read", group+":write")
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// CreateToken 创建个人访问令牌，返回令牌记录和明文令牌（明文只返回这一次）
+func (s *PersonalTokenService) CreateToken(userID uint, currentRole string, req models.PersonalAccessTokenRequest, ipAddress, userAgent string) (*models.PersonalAccessToken, string, error) {
+	scopes, err := normalizeTokenScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = currentRole
+	}
+	var count int64
+	s.db.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.role_key = ?", userID, role).
+		Count(&count)
+	if count == 0 {
+		return nil, "", ErrRoleNotHeld
+	}
+
+	var active int64
+	s.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Count(&active)
+	if active >= maxPersonalTokensPerUser {
+		return nil, "", fmt.Errorf("每个用户最多保留 %d 个有效令牌，请先撤销不再使用的令牌", maxPersonalTokensPerUser)
+	}
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.New("生成访问令牌失败")
+	}
+	rawToken := PersonalTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenPrefix: rawToken[:len(PersonalTokenPrefix)+6],
+		TokenHash:   utils.HashToken(rawToken),
+		Role:        role,
+		Scopes:      scopes,
+		ExpiresAt:   time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+	if err := s.db.Create(&token).Error; err != nil {
+		log.Printf("创建访问令牌失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, "", errors.New("创建访问令牌失败")
+	}
+
+	s.systemLogs.RecordSecurity("personal_token_create", "创建个人访问令牌", "success", &userID,
+		fmt.Sprintf("令牌ID: %d, 名称: %s, 角色: %s, 作用域: %v, 有效期: %d天", token.ID, token.Name, role, scopes, req.ExpiresInDays),
+		ipAddress, userAgent)
+	return &token, rawToken, nil
+}
+
+// GetUserTokens 获取用户的个人访问令牌列表
+func (s *PersonalTokenService) GetUserTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		log.Printf("获取访问令牌列表失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken 撤销用户自己的个人访问令牌
+func (s *PersonalTokenService) RevokeToken(userID, tokenID uint, ipAddress, userAgent string) error {
+	result := s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Printf("撤销访问令牌失败 - 令牌ID: %d, 错误: %v", tokenID, result.Error)
+		return errors.New("撤销访问令牌失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("访问令牌不存在或已撤销")
+	}
+
+	s.systemLogs.RecordSecurity("personal_token_revoke", "撤销个人访问令牌", "success", &userID,
+		fmt.Sprintf("令牌ID: %d", tokenID), ipAddress, userAgent)
+	return nil
+}
+
+// RevokeUserTokens 撤销用户的全部个人访问令牌（账号禁用、重置密码等场景）
+func (s *PersonalTokenService) RevokeUserTokens(userID uint) (int64, error) {
+	result := s.db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Printf("撤销用户访问令牌失败 - 用户ID: %d, 错误: %v", userID, result.Error)
+		return 0, errors.New("撤销用户访问令牌失败")
+	}
+	return result.RowsAffected, nil
+}
+
+// Authenticate 校验个人访问令牌，返回令牌记录和所属用户
+func (s *PersonalTokenService) Authenticate(rawToken, ipAddress string) (*models.PersonalAccessToken, *models.User, error) {
+	var token models.PersonalAccessToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token).Error; err != nil {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+	if !token.IsActive() {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "username", "status", "must_change_password").First(&user, token.UserID).Error; err != nil ||
+		user.Status != "active" || user.MustChangePassword {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+
+	// 用户已不再拥有令牌绑定的角色时令牌失效
+	var count int64
+	s.db.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.role_key = ?", token.UserID, token.Role).
+		Count(&count)
+	if count == 0 {
+		return nil, nil, ErrPersonalTokenInvalid
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > personalTokenTouchInterval {
+		s.db.Model(&models.PersonalAccessToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ipAddress,
+		})
+	}
+	return &token, &user, nil
+}
+
+// CheckScope 判断令牌作用域是否允许访问该请求
+func (s *PersonalTokenService) CheckScope(token *models.PersonalAccessToken, method, path string) error {
+	readOnly := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	for _, scope := range token.Scopes {
+		group, access, _ := strings.Cut(scope, ":")
+		if access != "write" && !readOnly {
+			continue
+		}
+		for _, prefix := range PersonalTokenScopeGroups[group] {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return nil
+			}
+		}
+	}
+	return ErrPersonalTokenScope
+}
+
+// normalizeTokenScopes 校验并去重作用域
+func normalizeTokenScopes(scopes []string) (models.JSONArray, error) {
+	seen := make(map[string]bool, len(scopes))
+	result := make(models.JSONArray, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		group, access, ok := strings.Cut(scope, ":")
+		if !ok || (access != "read" && access != "write") {
+			return nil, fmt.Errorf("无效的作用域: %s（格式为 分组:read 或 分组:write）", scope)
+		}
+		if _, exists := PersonalTokenScopeGroups[group]; !exists {
+			return nil, fmt.Errorf("未知的作用域分组: %s", group)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
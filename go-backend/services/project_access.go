@@ -0,0 +1,21 @@
+package services
+
+import "yunmeng-backend/models"
+
+// CanViewProject 能否查看项目详情和状态历史：团队成员、指导教师、审核人，
+// 以及有项目管理权限且项目负责人在其数据范围内的管理员
+func (s *ProjectService) CanViewProject(projectID, userID uint, role string, scope *models.DataScope) bool {
+	var project models.Project
+	if err := s.db.Select("id", "student_id", "teacher_id").First(&project, projectID).Error; err != nil {
+		return false
+	}
+	if project.TeacherID == userID || projectMemberRole(s.db, &project, userID) != "" {
+		return true
+	}
+	var reviews int64
+	s.db.Model(&models.ProjectReview{}).Where("project_id = ? AND reviewer_id = ?", projectID, userID).Count(&reviews)
+	if reviews > 0 {
+		return true
+	}
+	return NewPermissionService(s.db).HasPermission(role, "project.admin") && UserInScope(s.db, scope, project.StudentID)
+}
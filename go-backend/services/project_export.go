@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+	"yunmeng-backend/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// projectExportHeader 项目导出的表头
+var projectExportHeader = []string{"项目ID", "项目名称", "类型", "状态", "是否立项", "负责人", "负责人学号", "指导教师", "成员数", "文件数", "审核记录数", "提交时间", "创建时间", "更新时间"}
+
+// projectExportRow 单个项目对应的导出行
+func projectExportRow(project models.ProjectListResponse) []string {
+	approved := "否"
+	if project.IsApproved {
+		approved = "是"
+	}
+	submittedAt := ""
+	if project.SubmittedAt != nil {
+		submittedAt = project.SubmittedAt.Format("2006-01-02 15:04:05")
+	}
+	return []string{
+		strconv.FormatUint(uint64(project.ID), 10),
+		project.Title,
+		project.Type,
+		project.Status,
+		approved,
+		project.StudentName,
+		project.StudentID,
+		project.TeacherName,
+		strconv.Itoa(project.MemberCount),
+		strconv.Itoa(project.FileCount),
+		strconv.Itoa(project.ReviewCount),
+		submittedAt,
+		project.CreatedAt.Format("2006-01-02 15:04:05"),
+		project.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// WriteProjectExport 按格式（excel/csv）写出项目列表，返回文件名
+func WriteProjectExport(w io.Writer, format string, projects []models.ProjectListResponse) (string, error) {
+	filename := "projects_" + time.Now().Format("20060102150405")
+
+	switch format {
+	case "csv":
+		// 带BOM，Excel直接打开时中文不乱码
+		if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+			return "", err
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(projectExportHeader)
+		for _, project := range projects {
+			writer.Write(projectExportRow(project))
+		}
+		writer.Flush()
+		return filename + ".csv", writer.Error()
+	case "excel":
+		file := excelize.NewFile()
+		defer file.Close()
+		sheet := file.GetSheetName(0)
+		if err := file.SetSheetRow(sheet, "A1", &projectExportHeader); err != nil {
+			return "", err
+		}
+		for index, project := range projects {
+			cell, err := excelize.CoordinatesToCellName(1, index+2)
+			if err != nil {
+				return "", err
+			}
+			row := projectExportRow(project)
+			if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+				return "", err
+			}
+		}
+		if err := file.Write(w); err != nil {
+			return "", err
+		}
+		return filename + ".xlsx", nil
+	}
+	return "", errors.New("导出格式只支持 excel 或 csv")
+}
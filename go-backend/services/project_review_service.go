@@ -0,0 +1,822 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 审核任务状态
+const (
+	ReviewTaskWaiting   = "waiting"
+	ReviewTaskPending   = "pending"
+	ReviewTaskApproved  = "approved"
+	ReviewTaskRejected  = "rejected"
+	ReviewTaskSkipped   = "skipped"
+	ReviewTaskCancelled = "cancelled"
+)
+
+// 审核流程驱动的项目状态流转动作
+const (
+	ProjectActionStartReview = "start_review"
+	ProjectActionApprove     = "approve"
+	ProjectActionReject      = "reject"
+)
+
+// reviewerRoleAdvisor 审核流程中表示项目指导教师的角色
+const reviewerRoleAdvisor = "advisor"
+
+// ProjectReviewService 多级审核执行：提交时按审核流程配置生成审核任务，逐级推进，驳回即终止，最后一级通过后项目立项
+type ProjectReviewService struct {
+	db       *gorm.DB
+	workflow *ProjectWorkflowService
+	settings *SettingService
+}
+
+// NewProjectReviewService 与状态流转共用同一个 ProjectWorkflowService，提交和驳回等状态变更互相触发
+func NewProjectReviewService(db *gorm.DB) *ProjectReviewService {
+	return NewProjectWorkflowService(db).reviews
+}
+
+// reviewFlows 项目分类的审核流程配置，分类未配置时沿用上级分类的配置
+func (s *ProjectReviewService) reviewFlows(db *gorm.DB, typeID *uint) []models.ProjectReviewFlow {
+	visited := make(map[uint]bool)
+	for typeID != nil && !visited[*typeID] {
+		visited[*typeID] = true
+		var flows []models.ProjectReviewFlow
+		db.Where("project_type_id = ?", *typeID).Order("review_level ASC, review_order ASC, id ASC").Find(&flows)
+		if len(flows) > 0 {
+			return flows
+		}
+		var projectType models.ProjectType
+		if err := db.Select("id", "parent_id").First(&projectType, *typeID).Error; err != nil {
+			return nil
+		}
+		typeID = projectType.ParentID
+	}
+	return nil
+}
+
+// projectTeamIDs 项目负责人和正式成员的用户ID，他们不能审核自己的项目
+func projectTeamIDs(db *gorm.DB, project *models.Project) []uint {
+	var teamIDs []uint
+	db.Model(&models.ProjectMember{}).Where("project_id = ? AND status = ? AND user_id IS NOT NULL", project.ID, ProjectMemberActive).
+		Pluck("user_id", &teamIDs)
+	return append(teamIDs, project.StudentID)
+}
+
+// checkDelegate 受托人不能是项目团队成员，且须具备该级审核要求的角色（指导教师审核的级别要求教师角色）
+func (s *ProjectReviewService) checkDelegate(db *gorm.DB, task *models.ProjectReview, delegateID uint) error {
+	var project models.Project
+	if err := db.Select("id", "student_id").First(&project, task.ProjectID).Error; err != nil {
+		return errors.New("项目不存在")
+	}
+	for _, id := range projectTeamIDs(db, &project) {
+		if id == delegateID {
+			return errors.New("不能委托给项目团队成员")
+		}
+	}
+
+	role := "teacher"
+	var flow models.ProjectReviewFlow
+	if task.FlowID != nil && db.Select("reviewer_role").First(&flow, *task.FlowID).Error == nil && flow.ReviewerRole != reviewerRoleAdvisor {
+		role = flow.ReviewerRole
+	}
+	var count int64
+	db.Model(&models.UserRole{}).Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.role_key = ?", delegateID, role).Count(&count)
+	if count == 0 {
+		return fmt.Errorf("受托人须具有 %s 角色", role)
+	}
+	return nil
+}
+
+// resolveReviewers 按角色和院系/组织机构确定审核人，不含项目团队成员
+func (s *ProjectReviewService) resolveReviewers(db *gorm.DB, flow *models.ProjectReviewFlow, project *models.Project) []uint {
+	teamIDs := projectTeamIDs(db, project)
+
+	if flow.ReviewerRole == reviewerRoleAdvisor {
+		if project.TeacherID == 0 {
+			return nil
+		}
+		for _, id := range teamIDs {
+			if id == project.TeacherID {
+				return nil
+			}
+		}
+		return []uint{project.TeacherID}
+	}
+
+	base := func() *gorm.DB {
+		return db.Model(&models.User{}).
+			Joins("JOIN user_roles ON user_roles.user_id = users.id").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Where("roles.role_key = ? AND users.status = ? AND users.id NOT IN ?", flow.ReviewerRole, "active", teamIDs)
+	}
+	byDepartment := func(query *gorm.DB, department string) *gorm.DB {
+		return query.Where("users.department = ? OR users.id IN (?)", department, DepartmentUserIDs(db, department))
+	}
+
+	var ids []uint
+	switch {
+	case flow.ReviewerOrgUnitID != nil:
+		base().Where("users.org_unit_id IN (?)", OrgUnitSubtreeIDs(db, *flow.ReviewerOrgUnitID)).Distinct().Pluck("users.id", &ids)
+	case flow.ReviewerDepartment != "":
+		byDepartment(base(), flow.ReviewerDepartment).Distinct().Pluck("users.id", &ids)
+	default:
+		// 未限定院系时优先由负责人所在院系审核，该院系没有对应角色的用户时不限院系
+		if department := s.leaderDepartment(db, project.StudentID); department != "" {
+			byDepartment(base(), department).Distinct().Pluck("users.id", &ids)
+		}
+		if len(ids) == 0 {
+			base().Distinct().Pluck("users.id", &ids)
+		}
+	}
+	return ids
+}
+
+// leaderDepartment 项目负责人所在院系
+func (s *ProjectReviewService) leaderDepartment(db *gorm.DB, userID uint) string {
+	var user models.User
+	if err := db.Preload("Profile").Select("id", "department").First(&user, userID).Error; err != nil {
+		return ""
+	}
+	if user.Department != "" {
+		return user.Department
+	}
+	if user.Profile != nil {
+		return user.Profile.Department
+	}
+	return ""
+}
+
+// hasActiveChain 项目是否有进行中的多级审核
+func (s *ProjectReviewService) hasActiveChain(db *gorm.DB, projectID uint) bool {
+	var count int64
+	db.Model(&models.ProjectReview{}).
+		Where("project_id = ? AND status IN ?", projectID, []string{ReviewTaskWaiting, ReviewTaskPending}).Count(&count)
+	return count > 0
+}
+
+// cancelActive 终止进行中的多级审核（撤回、驳回、强制修改状态时）
+func (s *ProjectReviewService) cancelActive(db *gorm.DB, projectID uint, reason string) error {
+	return db.Model(&models.ProjectReview{}).
+		Where("project_id = ? AND status IN ?", projectID, []string{ReviewTaskWaiting, ReviewTaskPending}).
+		Updates(map[string]interface{}{"status": ReviewTaskCancelled, "comments": reason}).Error
+}
+
+// start 项目提交后生成本轮审核任务并启动第一级；分类未配置审核流程时保持单级审核
+func (s *ProjectReviewService) start(tx *gorm.DB, project *models.Project, operatorID uint) error {
+	flows := s.reviewFlows(tx, project.TypeID)
+	if len(flows) == 0 {
+		return nil
+	}
+	if err := s.cancelActive(tx, project.ID, "重新提交，上一轮审核已终止"); err != nil {
+		return err
+	}
+
+	var round int
+	tx.Model(&models.ProjectReview{}).Where("project_id = ?", project.ID).Select("COALESCE(MAX(round), 0)").Scan(&round)
+	round++
+
+	for i := range flows {
+		flow := &flows[i]
+		reviewers := s.resolveReviewers(tx, flow, project)
+		if len(reviewers) == 0 {
+			// 非必审或自动通过的配置没有审核人时直接略过
+			if flow.IsRequired && !flow.AutoApprove {
+				return fmt.Errorf("第%d级审核（%s）找不到审核人，请联系管理员检查审核流程配置", flow.ReviewLevel, reviewerLabel(flow))
+			}
+			continue
+		}
+		for _, reviewerID := range reviewers {
+			flowID := flow.ID
+			task := models.ProjectReview{
+				ProjectID:   project.ID,
+				ReviewerID:  reviewerID,
+				Status:      ReviewTaskWaiting,
+				FlowID:      &flowID,
+				Round:       round,
+				ReviewLevel: flow.ReviewLevel,
+				ReviewOrder: flow.ReviewOrder,
+				IsRequired:  flow.IsRequired,
+				CanDelegate: flow.CanDelegate,
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				log.Printf("创建审核任务失败: %v", err)
+				return errors.New("创建审核任务失败")
+			}
+		}
+	}
+
+	if s.workflow.hasTransition(tx, project, ProjectActionStartReview) {
+		if _, err := s.workflow.transit(tx, project, operatorID, models.ProjectStatusUpdateRequest{Action: ProjectActionStartReview}, true); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("多级审核已启动 - 项目ID: %d, 轮次: %d", project.ID, round)
+	return s.activateNext(tx, project, round, 0, operatorID)
+}
+
+func reviewerLabel(flow *models.ProjectReviewFlow) string {
+	label := flow.ReviewerRole
+	if flow.ReviewerDepartment != "" {
+		label = flow.ReviewerDepartment + " " + label
+	}
+	return label
+}
+
+// activateNext 启动 afterLevel 之后的下一级审核；自动通过的配置直接通过，整级通过时继续推进，没有下一级时项目审核通过
+func (s *ProjectReviewService) activateNext(tx *gorm.DB, project *models.Project, round, afterLevel int, operatorID uint) error {
+	for {
+		var level int
+		tx.Model(&models.ProjectReview{}).
+			Where("project_id = ? AND round = ? AND status = ? AND review_level > ?", project.ID, round, ReviewTaskWaiting, afterLevel).
+			Select("COALESCE(MIN(review_level), 0)").Scan(&level)
+		if level == 0 {
+			return s.finish(tx, project, operatorID)
+		}
+
+		var tasks []models.ProjectReview
+		tx.Where("project_id = ? AND round = ? AND review_level = ? AND status = ?", project.ID, round, level, ReviewTaskWaiting).
+			Order("review_order ASC, id ASC").Find(&tasks)
+		flows := s.taskFlows(tx, tasks)
+
+		now := time.Now()
+		autoApproved := make(map[uint]bool)
+		for _, task := range tasks {
+			flow, ok := flows[*task.FlowID]
+			if !ok {
+				// 流程配置已删除的任务不再分配给审核人，取消后不阻塞本级
+				if err := tx.Model(&models.ProjectReview{}).Where("id = ?", task.ID).
+					Updates(map[string]interface{}{"status": ReviewTaskCancelled, "comments": "审核流程配置已删除，无需处理"}).Error; err != nil {
+					return err
+				}
+				log.Printf("审核流程配置已删除，取消审核任务 - 任务ID: %d, 流程配置ID: %d", task.ID, *task.FlowID)
+				continue
+			}
+			updates := map[string]interface{}{"status": ReviewTaskPending, "assigned_at": &now}
+			if flow.DeadlineHours > 0 {
+				updates["deadline"] = now.Add(time.Duration(flow.DeadlineHours) * time.Hour)
+			}
+			if flow.AutoApprove {
+				// 同一配置只保留一条自动通过记录，自动通过的任务不计入审核时效
+				if autoApproved[flow.ID] {
+					updates = map[string]interface{}{"status": ReviewTaskCancelled}
+				} else {
+					autoApproved[flow.ID] = true
+					updates = map[string]interface{}{"status": ReviewTaskApproved, "comments": "系统自动通过", "reviewed_at": &now}
+				}
+			}
+			if err := tx.Model(&models.ProjectReview{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if !flow.AutoApprove {
+				s.notify(tx, project.ID, task.ReviewerID, "待审核项目",
+					fmt.Sprintf("项目《%s》已进入第%d级审核，请及时处理", project.Title, level))
+			}
+		}
+
+		if !s.levelPassed(tx, project.ID, round, level) {
+			log.Printf("审核推进到第%d级 - 项目ID: %d, 轮次: %d", level, project.ID, round)
+			return nil
+		}
+		if err := s.closeLevel(tx, project.ID, round, level); err != nil {
+			return err
+		}
+		afterLevel = level
+	}
+}
+
+// taskFlows 审核任务对应的流程配置，配置已删除的任务不在结果中
+func (s *ProjectReviewService) taskFlows(db *gorm.DB, tasks []models.ProjectReview) map[uint]models.ProjectReviewFlow {
+	flows := make(map[uint]models.ProjectReviewFlow)
+	var flowList []models.ProjectReviewFlow
+	db.Where("id IN ?", taskFlowIDs(tasks)).Find(&flowList)
+	for _, flow := range flowList {
+		flows[flow.ID] = flow
+	}
+	return flows
+}
+
+func (s *ProjectReviewService) notify(db *gorm.DB, projectID, userID uint, title, content string) {
+	notification := models.ProjectNotification{
+		ProjectID: projectID,
+		UserID:    userID,
+		Type:      "review",
+		Title:     title,
+		Content:   content,
+		Priority:  "normal",
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		log.Printf("发送审核通知失败 - 项目ID: %d, 用户ID: %d, 错误: %v", projectID, userID, err)
+	}
+}
+
+func taskFlowIDs(tasks []models.ProjectReview) []uint {
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		if task.FlowID != nil {
+			ids = append(ids, *task.FlowID)
+		}
+	}
+	return ids
+}
+
+// levelPassed 必审配置全部通过即本级通过；没有必审配置时每条配置都须通过或跳过
+func (s *ProjectReviewService) levelPassed(tx *gorm.DB, projectID uint, round, level int) bool {
+	var tasks []models.ProjectReview
+	tx.Where("project_id = ? AND round = ? AND review_level = ? AND status <> ?", projectID, round, level, ReviewTaskCancelled).Find(&tasks)
+
+	type slot struct {
+		required bool
+		decided  string
+	}
+	slots := make(map[uint]*slot)
+	for _, task := range tasks {
+		if task.FlowID == nil {
+			continue
+		}
+		item, ok := slots[*task.FlowID]
+		if !ok {
+			item = &slot{required: task.IsRequired}
+			slots[*task.FlowID] = item
+		}
+		if task.Status == ReviewTaskApproved || task.Status == ReviewTaskSkipped {
+			item.decided = task.Status
+		}
+	}
+
+	hasRequired := false
+	for _, item := range slots {
+		if item.required {
+			hasRequired = true
+			if item.decided != ReviewTaskApproved {
+				return false
+			}
+		}
+	}
+	if hasRequired {
+		return true
+	}
+	for _, item := range slots {
+		if item.decided == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// closeLevel 本级通过后，尚未处理的非必审任务无需再处理（不计入审核人的审核时效）
+func (s *ProjectReviewService) closeLevel(tx *gorm.DB, projectID uint, round, level int) error {
+	return tx.Model(&models.ProjectReview{}).
+		Where("project_id = ? AND round = ? AND review_level = ? AND status = ?", projectID, round, level, ReviewTaskPending).
+		Updates(map[string]interface{}{"status": ReviewTaskCancelled, "comments": "本级必审项已通过，无需处理"}).Error
+}
+
+// finish 最后一级通过，项目按流转规则审核通过
+func (s *ProjectReviewService) finish(tx *gorm.DB, project *models.Project, operatorID uint) error {
+	if _, err := s.workflow.transit(tx, project, operatorID, models.ProjectStatusUpdateRequest{Action: ProjectActionApprove, StatusChangeReason: "多级审核全部通过"}, true); err != nil {
+		return err
+	}
+	log.Printf("多级审核全部通过 - 项目ID: %d", project.ID)
+	return nil
+}
+
+// Decide 审核人处理自己的待审任务：通过后推进到下一级，驳回则终止本轮审核并驳回项目，非必审任务可以跳过
+func (s *ProjectReviewService) Decide(taskID, userID uint, req models.ReviewTaskDecisionRequest) (*models.ProjectReview, error) {
+	var task models.ProjectReview
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&task, taskID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("审核任务不存在")
+			}
+			return err
+		}
+		if task.ReviewerID != userID {
+			return errors.New("无权限处理此审核任务")
+		}
+		return s.decide(tx, &task, req, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// DecideForProject 按项目处理当前用户的待审任务，供原单级审核接口使用
+func (s *ProjectReviewService) DecideForProject(projectID, userID uint, req models.ReviewTaskDecisionRequest) (*models.ProjectReview, error) {
+	var task models.ProjectReview
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("project_id = ? AND reviewer_id = ? AND status = ?", projectID, userID, ReviewTaskPending).
+			Order("review_level ASC, id ASC").Limit(1).Find(&task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("没有轮到你审核该项目")
+		}
+		return s.decide(tx, &task, req, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (s *ProjectReviewService) decide(tx *gorm.DB, task *models.ProjectReview, req models.ReviewTaskDecisionRequest, userID uint) error {
+	if task.Status != ReviewTaskPending {
+		return errors.New("该审核任务当前不可处理")
+	}
+	comments := strings.TrimSpace(req.Comments)
+
+	var status string
+	switch req.Decision {
+	case "approve":
+		status = ReviewTaskApproved
+	case "reject":
+		if comments == "" {
+			return errors.New("驳回须填写审核意见")
+		}
+		status = ReviewTaskRejected
+	case "skip":
+		if task.IsRequired {
+			return errors.New("必审任务不能跳过")
+		}
+		status = ReviewTaskSkipped
+	default:
+		return errors.New("不支持的审核操作")
+	}
+
+	var project models.Project
+	if err := tx.First(&project, task.ProjectID).Error; err != nil {
+		return errors.New("项目不存在")
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.ProjectReview{}).Where("id = ? AND status = ?", task.ID, ReviewTaskPending).
+		Updates(map[string]interface{}{"status": status, "comments": comments, "reviewed_at": &now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("该审核任务已被处理")
+	}
+	task.Status, task.Comments, task.ReviewedAt = status, comments, &now
+
+	// 同一配置的其他审核人无需再处理
+	if err := tx.Model(&models.ProjectReview{}).
+		Where("project_id = ? AND round = ? AND review_flow_id = ? AND status = ?", task.ProjectID, task.Round, task.FlowID, ReviewTaskPending).
+		Updates(map[string]interface{}{"status": ReviewTaskCancelled, "comments": "已由其他审核人处理"}).Error; err != nil {
+		return err
+	}
+
+	log.Printf("审核任务已处理 - 任务ID: %d, 项目ID: %d, 第%d级, 结果: %s, 审核人ID: %d", task.ID, task.ProjectID, task.ReviewLevel, status, userID)
+
+	if status == ReviewTaskRejected {
+		if err := s.cancelActive(tx, task.ProjectID, fmt.Sprintf("第%d级审核驳回，审核终止", task.ReviewLevel)); err != nil {
+			return err
+		}
+		_, err := s.workflow.transit(tx, &project, userID, models.ProjectStatusUpdateRequest{Action: ProjectActionReject, StatusChangeReason: comments}, true)
+		return err
+	}
+
+	if !s.levelPassed(tx, task.ProjectID, task.Round, task.ReviewLevel) {
+		return nil
+	}
+	if err := s.closeLevel(tx, task.ProjectID, task.Round, task.ReviewLevel); err != nil {
+		return err
+	}
+	return s.activateNext(tx, &project, task.Round, task.ReviewLevel, userID)
+}
+
+// RemindDueTasks 给截止前 review_reminder_hours 小时内仍未处理的审核人发送提醒，每个任务只提醒一次；
+// 审核期限不长于提醒时间时，过半后才提醒，避免刚分配就提醒
+func (s *ProjectReviewService) RemindDueTasks() int {
+	hours := s.settings.GetInt("review_reminder_hours", 24)
+	if hours <= 0 {
+		return 0
+	}
+	now := time.Now()
+	var tasks []models.ProjectReview
+	s.db.Preload("Project").
+		Where("round > 0 AND status = ? AND reminded_at IS NULL AND deadline > ? AND deadline <= ?", ReviewTaskPending, now, now.Add(time.Duration(hours)*time.Hour)).
+		Find(&tasks)
+
+	reminded := 0
+	for _, task := range tasks {
+		remindAt := task.Deadline.Add(-time.Duration(hours) * time.Hour)
+		if task.AssignedAt != nil {
+			if half := task.AssignedAt.Add(task.Deadline.Sub(*task.AssignedAt) / 2); half.After(remindAt) {
+				remindAt = half
+			}
+		}
+		if now.Before(remindAt) {
+			continue
+		}
+		result := s.db.Model(&models.ProjectReview{}).Where("id = ? AND status = ? AND reminded_at IS NULL", task.ID, ReviewTaskPending).
+			Update("reminded_at", &now)
+		if result.Error != nil || result.RowsAffected == 0 || task.Project == nil {
+			continue
+		}
+		s.notify(s.db, task.ProjectID, task.ReviewerID, "审核即将到期",
+			fmt.Sprintf("项目《%s》的第%d级审核将于 %s 到期，请及时处理", task.Project.Title, task.ReviewLevel, task.Deadline.Format("2006-01-02 15:04")))
+		reminded++
+	}
+	return reminded
+}
+
+// ProcessOverdueTasks 处理超过截止时间仍未处理的审核任务：流程配置了超期自动通过（TimeoutApprove）的由系统通过，
+// 否则升级给同级下一个配置的审核人，没有时升级给项目负责人所在院系的管理员（review_escalation_role）
+func (s *ProjectReviewService) ProcessOverdueTasks() (approved, escalated int) {
+	escalationRole := s.settings.GetString("review_escalation_role", "admin")
+	var tasks []models.ProjectReview
+	s.db.Where("round > 0 AND status = ? AND escalated_at IS NULL AND deadline < ?", ReviewTaskPending, time.Now()).
+		Order("id ASC").Find(&tasks)
+
+	// 同一流程配置的任务一起处理
+	handled := make(map[string]bool)
+	for _, task := range tasks {
+		key := fmt.Sprintf("%d-%d-%d", task.ProjectID, task.Round, *task.FlowID)
+		if handled[key] {
+			continue
+		}
+		handled[key] = true
+
+		autoApproved := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var current models.ProjectReview
+			if err := tx.First(&current, task.ID).Error; err != nil {
+				return err
+			}
+			if current.Status != ReviewTaskPending {
+				return nil
+			}
+			var flow models.ProjectReviewFlow
+			flowExists := tx.Limit(1).Find(&flow, *current.FlowID).RowsAffected > 0
+			if flowExists && flow.TimeoutApprove {
+				// 操作人为 0 表示系统，状态历史中操作人为空
+				autoApproved = true
+				return s.decide(tx, &current, models.ReviewTaskDecisionRequest{Decision: "approve", Comments: "超过审核期限，系统自动通过"}, 0)
+			}
+			return s.escalate(tx, &current, &flow, escalationRole)
+		})
+		if err != nil {
+			log.Printf("处理超期审核任务失败 - 任务ID: %d, 错误: %v", task.ID, err)
+			continue
+		}
+		if autoApproved {
+			approved++
+		} else {
+			escalated++
+		}
+	}
+	return approved, escalated
+}
+
+// escalate 把超期任务所在的流程配置升级给新的审核人，原审核人仍可处理
+func (s *ProjectReviewService) escalate(tx *gorm.DB, task *models.ProjectReview, flow *models.ProjectReviewFlow, escalationRole string) error {
+	var project models.Project
+	if err := tx.First(&project, task.ProjectID).Error; err != nil {
+		return err
+	}
+
+	slot := tx.Model(&models.ProjectReview{}).
+		Where("project_id = ? AND round = ? AND review_flow_id = ?", task.ProjectID, task.Round, task.FlowID).Session(&gorm.Session{})
+	var existing []uint
+	slot.Pluck("reviewer_id", &existing)
+	now := time.Now()
+	if err := slot.Where("status = ?", ReviewTaskPending).
+		Updates(map[string]interface{}{"is_urgent": true, "escalated_at": &now}).Error; err != nil {
+		return err
+	}
+
+	targets := s.escalationTargets(tx, &project, task, existing, escalationRole)
+	if len(targets) == 0 {
+		log.Printf("审核任务超期，未找到可升级的审核人 - 项目ID: %d, 第%d级", task.ProjectID, task.ReviewLevel)
+		return nil
+	}
+
+	hours := flow.DeadlineHours
+	if hours <= 0 {
+		hours = 72
+	}
+	deadline := now.Add(time.Duration(hours) * time.Hour)
+	for _, reviewerID := range targets {
+		escalation := models.ProjectReview{
+			ProjectID:   task.ProjectID,
+			ReviewerID:  reviewerID,
+			Status:      ReviewTaskPending,
+			FlowID:      task.FlowID,
+			Round:       task.Round,
+			ReviewLevel: task.ReviewLevel,
+			ReviewOrder: task.ReviewOrder,
+			IsRequired:  task.IsRequired,
+			CanDelegate: task.CanDelegate,
+			Deadline:    &deadline,
+			AssignedAt:  &now,
+			IsUrgent:    true,
+		}
+		if err := tx.Create(&escalation).Error; err != nil {
+			return err
+		}
+		s.notify(tx, project.ID, reviewerID, "超期审核升级",
+			fmt.Sprintf("项目《%s》的第%d级审核已超期未处理，现转由你审核", project.Title, task.ReviewLevel))
+	}
+	log.Printf("审核任务超期已升级 - 项目ID: %d, 第%d级, 升级给: %v", task.ProjectID, task.ReviewLevel, targets)
+	return nil
+}
+
+// escalationTargets 同级审核顺序靠后的配置的审核人，没有时为项目负责人所在院系的管理员
+func (s *ProjectReviewService) escalationTargets(tx *gorm.DB, project *models.Project, task *models.ProjectReview, existing []uint, escalationRole string) []uint {
+	exclude := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		exclude[id] = true
+	}
+	filter := func(ids []uint) []uint {
+		var result []uint
+		for _, id := range ids {
+			if !exclude[id] {
+				result = append(result, id)
+			}
+		}
+		return result
+	}
+
+	for _, flow := range s.reviewFlows(tx, project.TypeID) {
+		if flow.ReviewLevel != task.ReviewLevel || flow.ReviewOrder <= task.ReviewOrder {
+			continue
+		}
+		if targets := filter(s.resolveReviewers(tx, &flow, project)); len(targets) > 0 {
+			return targets
+		}
+	}
+
+	return filter(s.resolveReviewers(tx, &models.ProjectReviewFlow{ReviewerRole: escalationRole}, project))
+}
+
+// StartReviewDeadlineScheduler 每10分钟检查一次审核期限：发送到期提醒，处理超期任务
+// ctx 取消后不再开始新的检查，返回的通道在正在进行的检查结束、定时任务退出后关闭
+func StartReviewDeadlineScheduler(ctx context.Context, db *gorm.DB) <-chan struct{} {
+	service := NewProjectReviewService(db)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("审核期限检查已停止")
+				return
+			case <-ticker.C:
+			}
+			reminded := service.RemindDueTasks()
+			approved, escalated := service.ProcessOverdueTasks()
+			if reminded+approved+escalated > 0 {
+				log.Printf("审核期限检查完成 - 提醒: %d, 自动通过: %d, 升级: %d", reminded, approved, escalated)
+			}
+		}
+	}()
+	return done
+}
+
+// SLAReport 审核时效统计，按审核人和审核级别汇总多级审核任务
+// 由同一配置的其他审核人处理而取消的任务不计入，但超期升级后被取消的任务计为该审核人超期
+func (s *ProjectReviewService) SLAReport(params models.ReviewSLAQueryParams) (*models.ReviewSLAReport, error) {
+	query := s.db.Model(&models.ProjectReview{}).
+		Where("project_reviews.round > 0 AND project_reviews.assigned_at IS NOT NULL").
+		Where("project_reviews.status IN ? OR (project_reviews.status = ? AND project_reviews.escalated_at IS NOT NULL)",
+			[]string{ReviewTaskPending, ReviewTaskApproved, ReviewTaskRejected, ReviewTaskSkipped}, ReviewTaskCancelled)
+	if params.StartDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		if err != nil {
+			return nil, errors.New("开始日期格式错误，应为 yyyy-MM-dd")
+		}
+		query = query.Where("project_reviews.assigned_at >= ?", start)
+	}
+	if params.EndDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if err != nil {
+			return nil, errors.New("结束日期格式错误，应为 yyyy-MM-dd")
+		}
+		query = query.Where("project_reviews.assigned_at < ?", end.AddDate(0, 0, 1))
+	}
+	if params.ProjectTypeID != nil {
+		query = query.Joins("JOIN projects ON projects.id = project_reviews.project_id").
+			Where("projects.type_id = ?", *params.ProjectTypeID)
+	}
+
+	var tasks []models.ProjectReview
+	if err := query.Select("project_reviews.*").Preload("Reviewer.Profile").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	type accumulator struct {
+		stats      models.ReviewSLAStats
+		totalHours float64
+		onTime     int64
+	}
+	add := func(acc *accumulator, task *models.ProjectReview, now time.Time) {
+		acc.stats.Assigned++
+		if task.EscalatedAt != nil {
+			acc.stats.Escalated++
+		}
+		if task.Status == ReviewTaskCancelled {
+			acc.stats.Overdue++
+			return
+		}
+		if task.Status == ReviewTaskPending {
+			acc.stats.Pending++
+			if task.Deadline != nil && task.Deadline.Before(now) {
+				acc.stats.Overdue++
+			}
+			return
+		}
+		acc.stats.Decided++
+		if task.ReviewedAt == nil {
+			return
+		}
+		hours := task.ReviewedAt.Sub(*task.AssignedAt).Hours()
+		acc.totalHours += hours
+		if hours > acc.stats.MaxHours {
+			acc.stats.MaxHours = hours
+		}
+		if task.Deadline != nil && task.ReviewedAt.After(*task.Deadline) {
+			acc.stats.Overdue++
+		} else {
+			acc.onTime++
+		}
+	}
+	finish := func(acc *accumulator) models.ReviewSLAStats {
+		result := acc.stats
+		if result.Decided > 0 {
+			result.AvgHours = roundHours(acc.totalHours / float64(result.Decided))
+			result.OnTimeRate = roundHours(float64(acc.onTime) * 100 / float64(result.Decided))
+		}
+		result.MaxHours = roundHours(result.MaxHours)
+		return result
+	}
+
+	now := time.Now()
+	overall := &accumulator{}
+	reviewers := make(map[uint]*accumulator)
+	levels := make(map[int]*accumulator)
+	var reviewerOrder []uint
+	var levelOrder []int
+	for i := range tasks {
+		task := &tasks[i]
+		add(overall, task, now)
+
+		acc, ok := reviewers[task.ReviewerID]
+		if !ok {
+			acc = &accumulator{stats: models.ReviewSLAStats{ReviewerID: task.ReviewerID}}
+			if task.Reviewer != nil {
+				acc.stats.ReviewerName = task.Reviewer.Username
+				if task.Reviewer.Profile != nil && task.Reviewer.Profile.RealName != "" {
+					acc.stats.ReviewerName = task.Reviewer.Profile.RealName
+				}
+			}
+			reviewers[task.ReviewerID] = acc
+			reviewerOrder = append(reviewerOrder, task.ReviewerID)
+		}
+		add(acc, task, now)
+
+		levelAcc, ok := levels[task.ReviewLevel]
+		if !ok {
+			levelAcc = &accumulator{stats: models.ReviewSLAStats{ReviewLevel: task.ReviewLevel}}
+			levels[task.ReviewLevel] = levelAcc
+			levelOrder = append(levelOrder, task.ReviewLevel)
+		}
+		add(levelAcc, task, now)
+	}
+
+	report := &models.ReviewSLAReport{
+		Overall:   finish(overall),
+		Reviewers: make([]models.ReviewSLAStats, 0, len(reviewerOrder)),
+		Levels:    make([]models.ReviewSLAStats, 0, len(levelOrder)),
+	}
+	for _, id := range reviewerOrder {
+		report.Reviewers = append(report.Reviewers, finish(reviewers[id]))
+	}
+	for _, level := range levelOrder {
+		report.Levels = append(report.Levels, finish(levels[level]))
+	}
+	// 审核人按平均时长倒序，便于发现处理慢的审核人；级别按顺序
+	sort.SliceStable(report.Reviewers, func(i, j int) bool { return report.Reviewers[i].AvgHours > report.Reviewers[j].AvgHours })
+	sort.Slice(report.Levels, func(i, j int) bool { return report.Levels[i].ReviewLevel < report.Levels[j].ReviewLevel })
+	return report, nil
+}
+
+func roundHours(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// createReviewTestUser 创建指定角色的启用账号
+func createReviewTestUser(t *testing.T, db *gorm.DB, username, roleKey string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@yunmeng.test", Password: "x", Status: "active", RoleName: roleKey}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	var role models.Role
+	db.Where(models.Role{RoleKey: roleKey}).Attrs(models.Role{RoleName: roleKey}).FirstOrCreate(&role)
+	db.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID})
+	return user
+}
+
+// newReviewChainTestDB 多级审核测试库，状态流转与默认流程一致（提交后进入审核中，审核通过或驳回）
+func newReviewChainTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.Role{}, &models.UserRole{}, &models.Project{},
+		&models.ProjectMember{}, &models.ProjectReview{}, &models.ProjectReviewFlow{}, &models.ProjectWorkflowState{},
+		&models.ProjectWorkflowTransition{}, &models.ProjectStatusHistory{}, &models.ProjectNotification{}, &models.SystemSetting{})
+	for _, state := range []models.ProjectWorkflowState{
+		{StateKey: "draft", Name: "草稿", IsInitial: true},
+		{StateKey: "submitted", Name: "已提交"},
+		{StateKey: "reviewing", Name: "审核中"},
+		{StateKey: "approved", Name: "已立项"},
+		{StateKey: "rejected", Name: "已驳回"},
+	} {
+		db.Create(&state)
+	}
+	for _, transition := range []models.ProjectWorkflowTransition{
+		{Action: "submit", Name: "提交审核", FromStatus: "draft", ToStatus: "submitted", AllowedRoles: models.JSONArray{"leader"}},
+		{Action: "start_review", Name: "开始审核", FromStatus: "submitted", ToStatus: "reviewing", AllowedRoles: models.JSONArray{"admin"}},
+		{Action: "approve", Name: "审核通过", FromStatus: "reviewing", ToStatus: "approved", AllowedRoles: models.JSONArray{"admin"},
+			SideEffects: models.JSONArray{"mark_approved"}},
+		{Action: "reject", Name: "驳回", FromStatus: "reviewing", ToStatus: "rejected", AllowedRoles: models.JSONArray{"admin"},
+			RequireReason: true, SideEffects: models.JSONArray{"mark_rejected"}},
+	} {
+		db.Create(&transition)
+	}
+	return db
+}
+
+// submitForReview 创建分类1下的项目并由负责人提交
+func submitForReview(t *testing.T, db *gorm.DB, leader, advisor *models.User) *models.Project {
+	t.Helper()
+	typeID := uint(1)
+	project := &models.Project{Title: "多级审核", StudentID: leader.ID, TeacherID: advisor.ID, TypeID: &typeID, Status: "draft"}
+	db.Create(project)
+	if err := NewProjectWorkflowService(db).Transition(project.ID, leader.ID, models.ProjectStatusUpdateRequest{Action: "submit"}); err != nil {
+		t.Fatalf("提交项目失败: %v", err)
+	}
+	return project
+}
+
+// flowTasks 某个流程配置本轮的审核任务
+func flowTasks(db *gorm.DB, flow *models.ProjectReviewFlow) []models.ProjectReview {
+	var tasks []models.ProjectReview
+	db.Where("review_flow_id = ?", flow.ID).Order("id").Find(&tasks)
+	return tasks
+}
+
+func projectStatus(db *gorm.DB, projectID uint) string {
+	var status string
+	db.Model(&models.Project{}).Where("id = ?", projectID).Pluck("status", &status)
+	return status
+}
+
+func TestReviewChainAdvancesLevels(t *testing.T) {
+	db := newReviewChainTestDB(t)
+	leader := createReviewTestUser(t, db, "leader", "student")
+	expert := createReviewTestUser(t, db, "expert", "expert")
+	admin := createReviewTestUser(t, db, "admin", "admin")
+	advisor := createReviewTestUser(t, db, "advisor", "teacher")
+
+	// 第1级：专家必审、管理员可跳过；第2级自动通过；第3级指导教师审核
+	required := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "expert", ReviewOrder: 1, IsRequired: true, DeadlineHours: 24}
+	optional := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "admin", ReviewOrder: 2, DeadlineHours: 24}
+	auto := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 2, ReviewerRole: "admin", ReviewOrder: 1, IsRequired: true, AutoApprove: true}
+	final := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 3, ReviewerRole: "advisor", ReviewOrder: 1, IsRequired: true, DeadlineHours: 24}
+	for _, flow := range []*models.ProjectReviewFlow{&required, &optional, &auto, &final} {
+		db.Create(flow)
+	}
+
+	project := submitForReview(t, db, leader, advisor)
+	if status := projectStatus(db, project.ID); status != "reviewing" {
+		t.Fatalf("提交后应进入审核中，实际 %s", status)
+	}
+	if tasks := flowTasks(db, &auto); len(tasks) != 1 || tasks[0].Status != ReviewTaskWaiting {
+		t.Fatalf("第2级应等待第1级通过: %+v", tasks)
+	}
+
+	reviews := NewProjectReviewService(db)
+	requiredTask := flowTasks(db, &required)[0]
+	if _, err := reviews.Decide(requiredTask.ID, expert.ID, models.ReviewTaskDecisionRequest{Decision: "skip"}); err == nil {
+		t.Errorf("必审任务不应允许跳过")
+	}
+	if _, err := reviews.Decide(flowTasks(db, &optional)[0].ID, admin.ID, models.ReviewTaskDecisionRequest{Decision: "skip"}); err != nil {
+		t.Fatalf("跳过非必审任务失败: %v", err)
+	}
+	if tasks := flowTasks(db, &auto); tasks[0].Status != ReviewTaskWaiting {
+		t.Errorf("必审任务未处理时不应进入下一级: %s", tasks[0].Status)
+	}
+
+	if _, err := reviews.Decide(requiredTask.ID, expert.ID, models.ReviewTaskDecisionRequest{Decision: "approve"}); err != nil {
+		t.Fatalf("审核通过失败: %v", err)
+	}
+	if tasks := flowTasks(db, &auto); tasks[0].Status != ReviewTaskApproved || tasks[0].AssignedAt != nil {
+		t.Errorf("自动通过的级别应轮到时直接通过且不计入审核时效: %+v", tasks[0])
+	}
+	finalTask := flowTasks(db, &final)[0]
+	if finalTask.Status != ReviewTaskPending || finalTask.ReviewerID != advisor.ID || finalTask.Deadline == nil {
+		t.Fatalf("第2级自动通过后应轮到指导教师: %+v", finalTask)
+	}
+
+	if _, err := reviews.Decide(finalTask.ID, advisor.ID, models.ReviewTaskDecisionRequest{Decision: "approve"}); err != nil {
+		t.Fatalf("最后一级审核失败: %v", err)
+	}
+	if status := projectStatus(db, project.ID); status != "approved" {
+		t.Errorf("全部通过后项目应立项，实际 %s", status)
+	}
+	var history models.ProjectStatusHistory
+	db.Where("project_id = ?", project.ID).Order("id DESC").First(&history)
+	if history.ChangedBy == nil || *history.ChangedBy != advisor.ID {
+		t.Errorf("立项记录的操作人应为最后一级审核人: %+v", history.ChangedBy)
+	}
+}
+
+func TestReviewChainCancelsTasksOfDeletedFlow(t *testing.T) {
+	db := newReviewChainTestDB(t)
+	leader := createReviewTestUser(t, db, "leader", "student")
+	expert := createReviewTestUser(t, db, "expert", "expert")
+	advisor := createReviewTestUser(t, db, "advisor", "teacher")
+
+	first := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "expert", ReviewOrder: 1, IsRequired: true, DeadlineHours: 24}
+	second := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 2, ReviewerRole: "advisor", ReviewOrder: 1, IsRequired: true, DeadlineHours: 24}
+	for _, flow := range []*models.ProjectReviewFlow{&first, &second} {
+		db.Create(flow)
+	}
+	project := submitForReview(t, db, leader, advisor)
+
+	// 审核进行中删除第2级配置，轮到时该任务取消，项目不会卡在没有配置的级别
+	orphan := flowTasks(db, &second)[0]
+	db.Delete(&second)
+	if _, err := NewProjectReviewService(db).Decide(flowTasks(db, &first)[0].ID, expert.ID, models.ReviewTaskDecisionRequest{Decision: "approve"}); err != nil {
+		t.Fatalf("审核通过失败: %v", err)
+	}
+	db.First(&orphan, orphan.ID)
+	if orphan.Status != ReviewTaskCancelled || orphan.Deadline != nil {
+		t.Errorf("配置已删除的任务应取消且不设截止时间: %+v", orphan)
+	}
+	if status := projectStatus(db, project.ID); status != "approved" {
+		t.Errorf("剩余级别没有有效配置时项目应立项，实际 %s", status)
+	}
+}
+
+func TestOverdueReviewEscalatesAndTimesOut(t *testing.T) {
+	db := newReviewChainTestDB(t)
+	leader := createReviewTestUser(t, db, "leader", "student")
+	expert := createReviewTestUser(t, db, "expert", "expert")
+	admin := createReviewTestUser(t, db, "admin", "admin")
+	advisor := createReviewTestUser(t, db, "advisor", "teacher")
+
+	first := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "expert", ReviewOrder: 1, IsRequired: true, DeadlineHours: 1}
+	backup := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "admin", ReviewOrder: 2, DeadlineHours: 24}
+	timeout := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 2, ReviewerRole: "expert", ReviewOrder: 1, IsRequired: true,
+		DeadlineHours: 1, TimeoutApprove: true}
+	for _, flow := range []*models.ProjectReviewFlow{&first, &backup, &timeout} {
+		db.Create(flow)
+	}
+	project := submitForReview(t, db, leader, advisor)
+	overdue := func(flow *models.ProjectReviewFlow) {
+		db.Model(&models.ProjectReview{}).Where("review_flow_id = ? AND status = ?", flow.ID, ReviewTaskPending).
+			Update("deadline", time.Now().Add(-time.Hour))
+	}
+
+	// 不能超期自动通过的级别升级给同级后续配置的审核人，原审核人仍可处理
+	overdue(&first)
+	reviews := NewProjectReviewService(db)
+	if approved, escalated := reviews.ProcessOverdueTasks(); approved != 0 || escalated != 1 {
+		t.Fatalf("应升级1个超期任务，实际通过 %d, 升级 %d", approved, escalated)
+	}
+	tasks := flowTasks(db, &first)
+	if len(tasks) != 2 || tasks[0].EscalatedAt == nil || tasks[0].Status != ReviewTaskPending {
+		t.Fatalf("原任务应标记为已升级并保持待审: %+v", tasks)
+	}
+	if tasks[1].ReviewerID != admin.ID || !tasks[1].IsUrgent || tasks[1].Deadline == nil {
+		t.Fatalf("升级任务应分配给后续配置的审核人: %+v", tasks[1])
+	}
+	if approved, escalated := reviews.ProcessOverdueTasks(); approved+escalated != 0 {
+		t.Errorf("已升级的任务不应重复处理")
+	}
+
+	if _, err := reviews.Decide(tasks[1].ID, admin.ID, models.ReviewTaskDecisionRequest{Decision: "approve"}); err != nil {
+		t.Fatalf("升级后的审核人处理失败: %v", err)
+	}
+	if tasks := flowTasks(db, &first); tasks[0].Status != ReviewTaskCancelled {
+		t.Errorf("升级审核人处理后原任务无需再处理: %s", tasks[0].Status)
+	}
+	if tasks := flowTasks(db, &timeout); len(tasks) != 1 || tasks[0].Status != ReviewTaskPending || tasks[0].ReviewerID != expert.ID {
+		t.Fatalf("第1级通过后应轮到第2级: %+v", tasks)
+	}
+
+	// 配置了超期自动通过的级别由系统通过，状态历史不记录操作人
+	overdue(&timeout)
+	if approved, escalated := reviews.ProcessOverdueTasks(); approved != 1 || escalated != 0 {
+		t.Fatalf("应自动通过1个超期任务，实际通过 %d, 升级 %d", approved, escalated)
+	}
+	if status := projectStatus(db, project.ID); status != "approved" {
+		t.Errorf("最后一级超期自动通过后项目应立项，实际 %s", status)
+	}
+	var history models.ProjectStatusHistory
+	db.Where("project_id = ?", project.ID).Order("id DESC").First(&history)
+	if history.NewStatus != "approved" || history.ChangedBy != nil {
+		t.Errorf("系统自动通过的状态历史操作人应为空: %+v", history)
+	}
+	responses, _ := NewProjectService(db).GetProjectStatusHistory(project.ID)
+	if len(responses) == 0 || responses[0].OperatorName != "系统" {
+		t.Errorf("系统操作的状态历史应显示为系统: %+v", responses)
+	}
+}
+
+func TestReviewSLAReport(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.ProjectReview{})
+	slow := &models.User{Username: "slow", Email: "slow@yunmeng.test", Password: "x", Status: "active", RoleName: "teacher"}
+	fast := &models.User{Username: "fast", Email: "fast@yunmeng.test", Password: "x", Status: "active", RoleName: "teacher"}
+	db.Create(slow)
+	db.Create(fast)
+
+	assigned := time.Now().Add(-100 * time.Hour)
+	at := func(hours int) *time.Time {
+		value := assigned.Add(time.Duration(hours) * time.Hour)
+		return &value
+	}
+	for _, task := range []models.ProjectReview{
+		{ReviewerID: slow.ID, ReviewLevel: 1, Status: ReviewTaskApproved, ReviewedAt: at(2), Deadline: at(24)},  // 按时
+		{ReviewerID: slow.ID, ReviewLevel: 1, Status: ReviewTaskRejected, ReviewedAt: at(30), Deadline: at(24)}, // 超期处理
+		{ReviewerID: slow.ID, ReviewLevel: 1, Status: ReviewTaskPending, Deadline: at(24)},                      // 超期未处理
+		{ReviewerID: slow.ID, ReviewLevel: 1, Status: ReviewTaskCancelled, EscalatedAt: at(25), Deadline: at(24)},
+		{ReviewerID: slow.ID, ReviewLevel: 1, Status: ReviewTaskCancelled, Deadline: at(24)}, // 由他人处理，不计入
+		{ReviewerID: fast.ID, ReviewLevel: 2, Status: ReviewTaskApproved, ReviewedAt: at(1)},
+	} {
+		task.ProjectID, task.Round, task.AssignedAt = 1, 1, &assigned
+		db.Create(&task)
+	}
+
+	report, err := NewProjectReviewService(db).SLAReport(models.ReviewSLAQueryParams{})
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	want := models.ReviewSLAStats{Assigned: 5, Decided: 3, Pending: 1, Overdue: 3, Escalated: 1, AvgHours: 11, MaxHours: 30, OnTimeRate: 66.67}
+	if report.Overall != want {
+		t.Errorf("总体统计应为 %+v，实际 %+v", want, report.Overall)
+	}
+	if len(report.Reviewers) != 2 || report.Reviewers[0].ReviewerID != slow.ID ||
+		report.Reviewers[0].AvgHours != 16 || report.Reviewers[0].OnTimeRate != 50 {
+		t.Errorf("审核人应按平均时长倒序: %+v", report.Reviewers)
+	}
+	if len(report.Levels) != 2 || report.Levels[0].ReviewLevel != 1 || report.Levels[0].Assigned != 4 || report.Levels[1].AvgHours != 1 {
+		t.Errorf("按级别统计有误: %+v", report.Levels)
+	}
+}
+
+func TestCreateReviewFlowKeepsExplicitOptional(t *testing.T) {
+	db := newTestDB(t, &models.ProjectReviewFlow{})
+	service := NewProjectService(db)
+	typeID := uint(1)
+	optional := false
+
+	created, err := service.CreateReviewFlow(models.ReviewFlowCreateRequest{ProjectTypeID: &typeID, ReviewLevel: 1,
+		ReviewerRole: "teacher", ReviewOrder: 1, DeadlineHours: 24})
+	if err != nil || !created.IsRequired {
+		t.Fatalf("未传 isRequired 时应默认必审: %+v, %v", created, err)
+	}
+	created, err = service.CreateReviewFlow(models.ReviewFlowCreateRequest{ProjectTypeID: &typeID, ReviewLevel: 1,
+		ReviewerRole: "teacher", ReviewOrder: 2, DeadlineHours: 24, IsRequired: &optional})
+	if err != nil {
+		t.Fatalf("创建审核流程失败: %v", err)
+	}
+	var flow models.ProjectReviewFlow
+	db.First(&flow, created.ID)
+	if flow.IsRequired {
+		t.Errorf("显式设置为非必审的配置应保存为 false")
+	}
+}
+
+func TestDelegateReviewChecksDelegate(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Role{}, &models.UserRole{}, &models.Project{}, &models.ProjectMember{},
+		&models.ProjectReview{}, &models.ProjectReviewFlow{}, &models.ReviewDelegation{})
+	leader := createReviewTestUser(t, db, "leader", "student")
+	member := createReviewTestUser(t, db, "member", "teacher") // 兼任教师的团队成员
+	reviewer := createReviewTestUser(t, db, "reviewer", "teacher")
+	student := createReviewTestUser(t, db, "student", "student")
+	colleague := createReviewTestUser(t, db, "colleague", "teacher")
+
+	project := models.Project{Title: "委托测试", StudentID: leader.ID, Status: "reviewing"}
+	db.Create(&project)
+	db.Create(&models.ProjectMember{ProjectID: project.ID, UserID: &member.ID, Role: ProjectMemberMember, Status: ProjectMemberActive})
+	flow := models.ProjectReviewFlow{ProjectTypeID: 1, ReviewLevel: 1, ReviewerRole: "teacher", ReviewOrder: 1, IsRequired: true, CanDelegate: true}
+	db.Create(&flow)
+	task := models.ProjectReview{ProjectID: project.ID, ReviewerID: reviewer.ID, Status: ReviewTaskPending, FlowID: &flow.ID,
+		Round: 1, ReviewLevel: 1, ReviewOrder: 1, CanDelegate: true}
+	db.Create(&task)
+
+	service := NewProjectService(db)
+	delegate := func(delegateID uint) error {
+		_, err := service.DelegateReview(task.ID, reviewer.ID, models.ReviewDelegationRequest{
+			DelegatedReviewerID: delegateID, Reason: "出差", EndDate: time.Now().Add(72 * time.Hour)})
+		return err
+	}
+	for _, c := range []struct {
+		user *models.User
+		want string
+	}{
+		{leader, "团队成员"}, {member, "团队成员"}, {student, "teacher 角色"},
+	} {
+		if err := delegate(c.user.ID); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("委托给 %s 应被拒绝（%s），实际: %v", c.user.Username, c.want, err)
+		}
+	}
+	if err := delegate(colleague.ID); err != nil {
+		t.Fatalf("委托给同角色教师失败: %v", err)
+	}
+	db.First(&task, task.ID)
+	if task.ReviewerID != colleague.ID {
+		t.Errorf("审核任务应转交给受托人，实际审核人ID %d", task.ReviewerID)
+	}
+}
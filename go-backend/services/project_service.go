@@ -12,11 +12,12 @@ import (
 )
 
 type ProjectService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	workflow *ProjectWorkflowService
 }
 
 func NewProjectService(db *gorm.DB) *ProjectService {
-	return &ProjectService{db: db}
+	return &ProjectService{db: db, workflow: NewProjectWorkflowService(db)}
 }
 
 // GetProjectList 获取项目列表
@@ -53,6 +54,9 @@ func (s *ProjectService) GetProjectList(params models.ProjectQueryParams) ([]mod
 		query = query.Where("projects.student_id = ?", params.StudentID)
 	}
 
+	// 数据范围：院系管理员只能查看本院系学生的项目
+	query = ApplyUserScope(s.db, query, params.Scope, "projects.student_id")
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		log.Printf("获取项目总数失败: %v", err)
@@ -137,11 +141,17 @@ func (s *ProjectService) GetProjectByID(id uint) (*models.ProjectDetailResponse,
 		IsApproved:  project.IsApproved,
 		Plan:        project.Plan,
 		Type:        project.Type,
+		TypeID:      project.TypeID,
 		Status:      project.Status,
 		CreatedAt:   project.CreatedAt,
 		UpdatedAt:   project.UpdatedAt,
 	}
 
+	// 团队正式成员，队长在前
+	response.Members = []models.ProjectMember{}
+	s.db.Where("project_id = ? AND status = ?", project.ID, ProjectMemberActive).
+		Order("CASE WHEN role = 'leader' THEN 0 ELSE 1 END, id").Find(&response.Members)
+
 	// 添加学生信息
 	if project.Student != nil && project.Student.Profile != nil {
 		response.Student.ID = project.Student.ID
@@ -182,11 +192,12 @@ func (s *ProjectService) CreateProject(studentID uint, req models.ProjectCreateR
 		Title:       req.Title,
 		Description: req.Description,
 		Type:        req.Type,
+		TypeID:      req.TypeID,
 		StudentID:   studentID,
 		TeacherID:   req.TeacherID,
 		Plan:        req.Plan,
 		FinishTime:  req.FinishedAt,
-		Status:      "draft",
+		Status:      s.workflow.InitialStatus(tx, req.TypeID),
 		Progress:    0,
 		Deleted:     false,
 		IsApproved:  false,
@@ -198,6 +209,14 @@ func (s *ProjectService) CreateProject(studentID uint, req models.ProjectCreateR
 		return nil, errors.New("创建项目失败")
 	}
 
+	// 创建者为团队队长
+	leader := newProjectMember(tx, project.ID, studentID, ProjectMemberLeader, ProjectMemberActive)
+	if err := tx.Create(&leader).Error; err != nil {
+		tx.Rollback()
+		log.Printf("创建项目队长记录失败: %v", err)
+		return nil, errors.New("创建项目失败")
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("提交事务失败: %v", err)
@@ -225,17 +244,25 @@ func (s *ProjectService) CreateExtensionApplication(
 		}
 	}()
 
-	// 1️⃣ 校验项目是否存在 & 是否属于该学生 & 是否已通过
+	// 1️⃣ 校验项目是否存在 & 是否已通过 & 是否由项目负责人申请（团队成员不能申请延期）
 	var project models.Project
 	if err := tx.Where(
-		"id = ? AND student_id = ? AND status = ? AND deleted = 0",
+		"id = ? AND status = ? AND deleted = 0",
 		req.ProjectID,
-		studentID,
 		"approved",
 	).First(&project).Error; err != nil {
 		tx.Rollback()
 		return nil, errors.New("项目不存在或未通过审批，无法申请延期")
 	}
+	switch projectMemberRole(tx, &project, studentID) {
+	case ProjectMemberLeader:
+	case ProjectMemberMember:
+		tx.Rollback()
+		return nil, errors.New("只有项目负责人可以申请延期")
+	default:
+		tx.Rollback()
+		return nil, errors.New("项目不存在或未通过审批，无法申请延期")
+	}
 
 	// 2️⃣ 去重：是否已有待审批延期申请
 	var count int64
@@ -353,8 +380,12 @@ func (s *ProjectService) UpdateProject(id uint, studentID uint, req models.Proje
 		return err
 	}
 
-	// 检查权限：只有项目创建者可以修�?
-	if project.StudentID != studentID {
+	// 检查权限：标题、简介、时间等核心信息只有项目负责人（队长）可以修改，团队成员不可修改
+	switch projectMemberRole(s.db, &project, studentID) {
+	case ProjectMemberLeader:
+	case ProjectMemberMember:
+		return errors.New("只有项目负责人可以修改项目信息")
+	default:
 		return errors.New("无权限修改此项目")
 	}
 
@@ -382,9 +413,6 @@ func (s *ProjectService) UpdateProject(id uint, studentID uint, req models.Proje
 	if req.Type != "" {
 		updates["type"] = req.Type
 	}
-	if req.Status != "" {
-		updates["status"] = req.Status
-	}
 	if req.TeacherID > 0 {
 		updates["teacher_id"] = req.TeacherID
 	}
@@ -422,6 +450,18 @@ func (s *ProjectService) UpdateProject(id uint, studentID uint, req models.Proje
 		}
 	}
 
+	// 同时修改状态的按流转规则处理（必填项按修改后的内容校验）
+	if req.Status != "" && req.Status != project.Status {
+		if err := tx.First(&project, id).Error; err != nil {
+			tx.Rollback()
+			return errors.New("更新项目失败")
+		}
+		if _, err := s.workflow.apply(tx, &project, studentID, models.ProjectStatusUpdateRequest{Status: req.Status}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("提交事务失败: %v", err)
@@ -484,6 +524,12 @@ func (s *ProjectService) ReviewProject(projectID uint, reviewerID uint, req mode
 		return err
 	}
 
+	// 多级审核进行中时处理当前审核人的审核任务
+	if s.workflow.reviews.hasActiveChain(s.db, projectID) {
+		_, err := s.decideReviewTask(projectID, reviewerID, req)
+		return err
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -507,10 +553,10 @@ func (s *ProjectService) ReviewProject(projectID uint, reviewerID uint, req mode
 	}
 
 	// 更新项目状�?
-	if err := tx.Model(&project).Update("status", req.Status).Error; err != nil {
+	// 按流转规则变更项目状态，审核意见作为变更原因
+	if _, err := s.workflow.apply(tx, &project, reviewerID, models.ProjectStatusUpdateRequest{Status: req.Status, StatusChangeReason: req.Comments}); err != nil {
 		tx.Rollback()
-		log.Printf("更新项目状态失败: %v", err)
-		return errors.New("更新项目状态失败")
+		return err
 	}
 
 	// 提交事务
@@ -523,10 +569,10 @@ func (s *ProjectService) ReviewProject(projectID uint, reviewerID uint, req mode
 	return nil
 }
 
-// GetMyProjects 获取我的项目列表
+// GetMyProjects 获取我的项目列表（含作为团队成员参与的项目）
 func (s *ProjectService) GetMyProjects(studentID uint, status string, page, size int) ([]models.ProjectMyListResponse, int64, error) {
 	var projects []models.Project
-	query := s.db.Where("student_id = ?", studentID)
+	query := s.db.Where("student_id = ? OR id IN (?)", studentID, memberProjectIDs(s.db, studentID))
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -556,6 +602,7 @@ func (s *ProjectService) GetMyProjects(studentID uint, status string, page, size
 			Description: project.Description,
 			Progress:    project.Progress,
 			Plan:        project.Plan,
+			MemberRole:  memberRoleOf(&project, studentID),
 		})
 	}
 
@@ -594,9 +641,9 @@ func (s *ProjectService) GetProjectStats(userID uint) (*ProjectStats, error) {
 		Where("student_id = ? AND status = ?", userID, "completed").
 		Count(&stats.CompletedProjects)
 
-	// 待审核项目
+	// 待审核项目（按状态流转配置归类）
 	s.db.Table("projects").
-		Where("student_id = ? AND status = ?", userID, "pending").
+		Where("student_id = ? AND status IN ?", userID, s.workflow.StatusGroups().Pending).
 		Count(&stats.PendingProjects)
 
 	// 竞赛总数
@@ -629,6 +676,9 @@ func (s *ProjectService) GetProjectsForTeacher(params models.ProjectQueryParams)
 		query = query.Where("projects.type = ?", params.Type)
 	}
 
+	// 数据范围：院系管理员只能查看本院系学生的项目
+	query = ApplyUserScope(s.db, query, params.Scope, "projects.student_id")
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -676,6 +726,26 @@ func (s *ProjectService) ReviewProjectWithResponse(projectID uint, reviewerID ui
 		return nil, err
 	}
 
+	// 多级审核进行中时处理当前审核人的审核任务
+	if s.workflow.reviews.hasActiveChain(s.db, projectID) {
+		task, err := s.decideReviewTask(projectID, reviewerID, req)
+		if err != nil {
+			return nil, err
+		}
+		var reviewer models.User
+		if err := s.db.Preload("Profile").First(&reviewer, reviewerID).Error; err != nil {
+			log.Printf("获取审核者信息失败: %v", err)
+		}
+		reviewerName := reviewer.Username
+		if reviewer.Profile != nil {
+			reviewerName = reviewer.Profile.RealName
+		}
+		return &models.ProjectReviewResponse{
+			Reviewer:   reviewerName,
+			ReviewTime: *task.ReviewedAt,
+		}, nil
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -699,10 +769,10 @@ func (s *ProjectService) ReviewProjectWithResponse(projectID uint, reviewerID ui
 	}
 
 	// 更新项目状�?
-	if err := tx.Model(&project).Update("status", req.Status).Error; err != nil {
+	// 按流转规则变更项目状态，审核意见作为变更原因
+	if _, err := s.workflow.apply(tx, &project, reviewerID, models.ProjectStatusUpdateRequest{Status: req.Status, StatusChangeReason: req.Comments}); err != nil {
 		tx.Rollback()
-		log.Printf("更新项目状态失败: %v", err)
-		return nil, errors.New("更新项目状态失败")
+		return nil, err
 	}
 
 	// 提交事务
@@ -728,10 +798,35 @@ func (s *ProjectService) ReviewProjectWithResponse(projectID uint, reviewerID ui
 	}, nil
 }
 
-// GetProjectReviews 获取项目审核记录
+// decideReviewTask 把原单级审核的结果转换为当前审核人的多级审核任务处理
+func (s *ProjectService) decideReviewTask(projectID, reviewerID uint, req models.ProjectReviewRequest) (*models.ProjectReview, error) {
+	decision := models.ReviewTaskDecisionRequest{Comments: req.Comments}
+	switch req.Status {
+	case "approved":
+		decision.Decision = "approve"
+	case "rejected":
+		decision.Decision = "reject"
+	default:
+		return nil, errors.New("项目正在多级审核中，只能通过或驳回")
+	}
+	return s.workflow.reviews.DecideForProject(projectID, reviewerID, decision)
+}
+
+// DecideReviewTask 处理多级审核任务
+func (s *ProjectService) DecideReviewTask(taskID, userID uint, req models.ReviewTaskDecisionRequest) (*models.ProjectReview, error) {
+	return s.workflow.reviews.Decide(taskID, userID, req)
+}
+
+// GetReviewSLAReport 审核时效统计
+func (s *ProjectService) GetReviewSLAReport(params models.ReviewSLAQueryParams) (*models.ReviewSLAReport, error) {
+	return s.workflow.reviews.SLAReport(params)
+}
+
+// GetProjectReviews 获取项目审核记录，多级审核按轮次倒序、级别顺序排列
 func (s *ProjectService) GetProjectReviews(projectID uint) ([]models.ProjectReviewRecordResponse, error) {
 	var reviews []models.ProjectReview
-	err := s.db.Preload("Reviewer.Profile").Where("project_id = ?", projectID).Order("review_time DESC").Find(&reviews).Error
+	err := s.db.Preload("Reviewer.Profile").Where("project_id = ?", projectID).
+		Order("round DESC, review_level ASC, review_order ASC, review_time DESC").Find(&reviews).Error
 	if err != nil {
 		return nil, err
 	}
@@ -744,10 +839,16 @@ func (s *ProjectService) GetProjectReviews(projectID uint) ([]models.ProjectRevi
 		}
 
 		responses = append(responses, models.ProjectReviewRecordResponse{
-			Reviewer:   reviewerName,
-			Status:     review.Status,
-			Comments:   review.Comments,
-			ReviewTime: review.ReviewTime,
+			ID:          review.ID,
+			Reviewer:    reviewerName,
+			Status:      review.Status,
+			Comments:    review.Comments,
+			ReviewTime:  review.ReviewTime,
+			Round:       review.Round,
+			ReviewLevel: review.ReviewLevel,
+			IsRequired:  review.IsRequired,
+			Deadline:    review.Deadline,
+			ReviewedAt:  review.ReviewedAt,
 		})
 	}
 
@@ -1039,37 +1140,13 @@ func (s *ProjectService) GetStudentTeachers(studentID uint) ([]models.TeacherLis
 
 // SubmitProject 提交项目审核
 func (s *ProjectService) SubmitProject(projectID, studentID uint) error {
-	// 检查项目是否存�?
-	var project models.Project
-	if err := s.db.First(&project, projectID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("项目不存在")
-		}
+	// 提交是流程中的 submit 动作，角色和必填项按项目分类的流转规则校验
+	err := s.workflow.Transition(projectID, studentID, models.ProjectStatusUpdateRequest{Action: ProjectActionSubmit})
+	if err != nil {
+		log.Printf("提交项目失败 - 项目ID: %d, 错误: %v", projectID, err)
 		return err
 	}
 
-	// 检查权限：只有项目创建者可以提�?
-	if project.StudentID != studentID {
-		return errors.New("无权限提交此项目")
-	}
-
-	// 检查状态：只有草稿状态的项目可以提交
-	if project.Status != "draft" {
-		return errors.New("只有草稿状态的项目可以提交")
-	}
-
-	// 更新项目状态和提交时间
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status":       "pending",
-		"submitted_at": &now,
-	}
-
-	if err := s.db.Model(&project).Updates(updates).Error; err != nil {
-		log.Printf("提交项目失败: %v", err)
-		return errors.New("提交项目失败")
-	}
-
 	log.Printf("项目提交成功 - 项目ID: %d", projectID)
 	return nil
 }
@@ -1100,85 +1177,21 @@ func (s *ProjectService) GetDB() *gorm.DB {
 
 // UpdateProjectStatus 更新项目状态
 func (s *ProjectService) UpdateProjectStatus(projectID uint, userID uint, req models.ProjectStatusUpdateRequest) error {
-	// 检查项目是否存在
-	var project models.Project
-	if err := s.db.First(&project, projectID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("项目不存在")
-		}
+	// 可执行的流转、角色、必填项和附加动作由项目分类的状态流转配置决定
+	if err := s.workflow.Transition(projectID, userID, req); err != nil {
+		log.Printf("更新项目状态失败 - 项目ID: %d, 错误: %v", projectID, err)
 		return err
 	}
 
-	// 检查权限：只有项目相关人员可以更新状态
-	if project.StudentID != userID && project.TeacherID != userID {
-		// 检查是否为管理员
-		var user models.User
-		if err := s.db.Preload("Roles").First(&user, userID).Error; err != nil {
-			return errors.New("用户不存在")
-		}
-
-		isAdmin := false
-		for _, role := range user.Roles {
-			if role.RoleKey == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-
-		if !isAdmin {
-			return errors.New("无权限更新此项目状态")
-		}
-	}
-
-	// 开始事务
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 记录状态变更历史
-	statusHistory := models.ProjectStatusHistory{
-		ProjectID:    projectID,
-		OldStatus:    project.Status,
-		NewStatus:    req.Status,
-		ChangeReason: req.StatusChangeReason,
-		ChangedBy:    userID,
-		ChangedAt:    time.Now(),
-	}
-
-	if err := tx.Create(&statusHistory).Error; err != nil {
-		tx.Rollback()
-		log.Printf("创建状态变更历史失败: %v", err)
-		return errors.New("创建状态变更历史失败")
-	}
-
-	// 更新项目状态
-	updates := map[string]interface{}{
-		"status":               req.Status,
-		"status_change_reason": req.StatusChangeReason,
-		"status_changed_by":    userID,
-		"status_changed_at":    time.Now(),
-		"updated_at":           time.Now(),
-	}
-
-	if err := tx.Model(&project).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		log.Printf("更新项目状态失败: %v", err)
-		return errors.New("更新项目状态失败")
-	}
-
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("提交事务失败: %v", err)
-		return errors.New("更新项目状态失败")
-	}
-
-	log.Printf("项目状态更新成功 - 项目ID: %d, 新状态: %s", projectID, req.Status)
+	log.Printf("项目状态更新成功 - 项目ID: %d, 动作: %s, 目标状态: %s", projectID, req.Action, req.Status)
 	return nil
 }
 
+// GetAvailableTransitions 获取当前用户可对项目发起的状态流转
+func (s *ProjectService) GetAvailableTransitions(projectID uint, userID uint) ([]models.ProjectAvailableTransition, error) {
+	return s.workflow.AvailableTransitions(projectID, userID)
+}
+
 // GetProjectStatusHistory 获取项目状态变更历史
 func (s *ProjectService) GetProjectStatusHistory(projectID uint) ([]models.ProjectStatusHistoryResponse, error) {
 	var histories []models.ProjectStatusHistory
@@ -1199,6 +1212,8 @@ func (s *ProjectService) GetProjectStatusHistory(projectID uint) ([]models.Proje
 			OldStatus:    history.OldStatus,
 			NewStatus:    history.NewStatus,
 			ChangeReason: history.ChangeReason,
+			Action:       history.Action,
+			IsForced:     history.IsForced,
 			ChangedBy:    history.ChangedBy,
 			ChangedAt:    history.ChangedAt,
 		}
@@ -1206,8 +1221,10 @@ func (s *ProjectService) GetProjectStatusHistory(projectID uint) ([]models.Proje
 		// 获取操作人姓名
 		if history.ChangedByUser != nil && history.ChangedByUser.Profile != nil {
 			response.OperatorName = history.ChangedByUser.Profile.RealName
-		} else {
+		} else if history.ChangedByUser != nil {
 			response.OperatorName = history.ChangedByUser.Username
+		} else if history.ChangedBy == nil {
+			response.OperatorName = "系统"
 		}
 
 		responses = append(responses, response)
@@ -1231,8 +1248,8 @@ func (s *ProjectService) CreateProjectMilestone(projectID uint, userID uint, req
 		return nil, err
 	}
 
-	// 检查权限：只有项目相关人员可以创建里程碑
-	if project.StudentID != userID && project.TeacherID != userID {
+	// 检查权限：指导教师和团队成员可以创建里程碑
+	if project.TeacherID != userID && projectMemberRole(s.db, &project, userID) == "" {
 		return nil, errors.New("无权限为此项目创建里程碑")
 	}
 
@@ -1286,7 +1303,7 @@ func (s *ProjectService) UpdateProjectMilestone(milestoneID uint, userID uint, r
 		return errors.New("项目不存在")
 	}
 
-	if project.StudentID != userID && project.TeacherID != userID {
+	if project.TeacherID != userID && projectMemberRole(s.db, &project, userID) == "" {
 		return errors.New("无权限更新此里程碑")
 	}
 
@@ -1364,8 +1381,8 @@ func (s *ProjectService) UpdateProjectProgress(projectID uint, userID uint, req
 		return nil
 	}
 
-	// 检查权限：只有项目相关人员可以更新进度
-	if project.StudentID != userID && project.TeacherID != userID {
+	// 检查权限：指导教师和团队成员可以更新进度
+	if project.TeacherID != userID && projectMemberRole(s.db, &project, userID) == "" {
 		return errors.New("无权限更新此项目进度")
 	}
 
@@ -1410,8 +1427,8 @@ func (s *ProjectService) UploadProjectFile(projectID uint, userID uint, req mode
 		return nil, err
 	}
 
-	// 检查权限：只有项目相关人员可以上传文件
-	if project.StudentID != userID && project.TeacherID != userID {
+	// 检查权限：指导教师和团队成员可以上传文件
+	if project.TeacherID != userID && projectMemberRole(s.db, &project, userID) == "" {
 		return nil, errors.New("无权限为此项目上传文件")
 	}
 
@@ -1589,6 +1606,7 @@ func (s *ProjectService) CreateProjectType(req models.ProjectTypeCreateRequest)
 		IsActive:    req.IsActive,
 		Icon:        req.Icon,
 		Color:       req.Color,
+		MaxMembers:  req.MaxMembers,
 	}
 
 	if err := s.db.Create(&projectType).Error; err != nil {
@@ -1607,6 +1625,7 @@ func (s *ProjectService) CreateProjectType(req models.ProjectTypeCreateRequest)
 		IsActive:     projectType.IsActive,
 		Icon:         projectType.Icon,
 		Color:        projectType.Color,
+		MaxMembers:   projectType.MaxMembers,
 		ProjectCount: 0,
 		CreatedAt:    projectType.CreatedAt,
 		UpdatedAt:    projectType.UpdatedAt,
@@ -1650,6 +1669,9 @@ func (s *ProjectService) UpdateProjectType(typeID uint, req models.ProjectTypeUp
 	if req.Color != "" {
 		updates["color"] = req.Color
 	}
+	if req.MaxMembers != nil {
+		updates["max_members"] = *req.MaxMembers
+	}
 
 	if len(updates) > 0 {
 		updates["updated_at"] = time.Now()
@@ -1738,16 +1760,27 @@ func (s *ProjectService) CreateReviewFlow(req models.ReviewFlowCreateRequest) (*
 		return nil, errors.New("项目类型ID不能为空")
 	}
 
+	// 指定审核人组织机构时，审核院系按节点填写
+	if req.ReviewerOrgUnitID != nil {
+		department, _, err := OrgUnitLabels(s.db, *req.ReviewerOrgUnitID)
+		if err != nil {
+			return nil, err
+		}
+		req.ReviewerDepartment = department
+	}
+
 	// 创建审核流程配置
 	flow := models.ProjectReviewFlow{
 		ProjectTypeID:      *req.ProjectTypeID,
 		ReviewLevel:        req.ReviewLevel,
 		ReviewerRole:       req.ReviewerRole,
 		ReviewerDepartment: req.ReviewerDepartment,
+		ReviewerOrgUnitID:  req.ReviewerOrgUnitID,
 		ReviewOrder:        req.ReviewOrder,
-		IsRequired:         req.IsRequired,
+		IsRequired:         req.IsRequired == nil || *req.IsRequired,
 		DeadlineHours:      req.DeadlineHours,
 		AutoApprove:        req.AutoApprove,
+		TimeoutApprove:     req.TimeoutApprove,
 		CanDelegate:        req.CanDelegate,
 	}
 
@@ -1763,10 +1796,12 @@ func (s *ProjectService) CreateReviewFlow(req models.ReviewFlowCreateRequest) (*
 		ReviewLevel:        flow.ReviewLevel,
 		ReviewerRole:       flow.ReviewerRole,
 		ReviewerDepartment: flow.ReviewerDepartment,
+		ReviewerOrgUnitID:  flow.ReviewerOrgUnitID,
 		ReviewOrder:        flow.ReviewOrder,
 		IsRequired:         flow.IsRequired,
 		DeadlineHours:      flow.DeadlineHours,
 		AutoApprove:        flow.AutoApprove,
+		TimeoutApprove:     flow.TimeoutApprove,
 		CanDelegate:        flow.CanDelegate,
 		CreatedAt:          flow.CreatedAt,
 		UpdatedAt:          flow.UpdatedAt,
@@ -1791,6 +1826,27 @@ func (s *ProjectService) DelegateReview(reviewID uint, userID uint, req models.R
 	if review.ReviewerID != userID {
 		return nil, errors.New("无权限委托此审核任务")
 	}
+	if req.DelegatedReviewerID == userID {
+		return nil, errors.New("不能委托给自己")
+	}
+	var delegateCount int64
+	s.db.Model(&models.User{}).Where("id = ? AND status = ?", req.DelegatedReviewerID, "active").Count(&delegateCount)
+	if delegateCount == 0 {
+		return nil, errors.New("受托人不存在或已停用")
+	}
+	if err := s.workflow.reviews.checkDelegate(s.db, &review, req.DelegatedReviewerID); err != nil {
+		return nil, err
+	}
+
+	// 多级审核任务须在待审核时委托，且审核流程允许委托
+	if review.Round > 0 {
+		if review.Status != ReviewTaskPending {
+			return nil, errors.New("该审核任务当前不可委托")
+		}
+		if !review.CanDelegate {
+			return nil, errors.New("该级审核不允许委托")
+		}
+	}
 
 	// 创建委托记录
 	delegation := models.ReviewDelegation{
@@ -1803,9 +1859,27 @@ func (s *ProjectService) DelegateReview(reviewID uint, userID uint, req models.R
 		Status:              "active",
 	}
 
-	if err := s.db.Create(&delegation).Error; err != nil {
-		log.Printf("创建审核委托失败: %v", err)
-		return nil, errors.New("创建审核委托失败")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delegation).Error; err != nil {
+			log.Printf("创建审核委托失败: %v", err)
+			return errors.New("创建审核委托失败")
+		}
+		if review.Round == 0 {
+			return nil
+		}
+		// 多级审核任务转交给受托人处理
+		result := tx.Model(&models.ProjectReview{}).Where("id = ? AND reviewer_id = ? AND status = ?", review.ID, userID, ReviewTaskPending).
+			Update("reviewer_id", req.DelegatedReviewerID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该审核任务已被处理")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 转换为响应格式
@@ -1874,8 +1948,11 @@ func (s *ProjectService) GetMyReviewTasks(userID uint, params models.ReviewTaskQ
 			ID:           review.ID,
 			ProjectID:    review.ProjectID,
 			ProjectTitle: review.Project.Title,
+			Round:        review.Round,
 			ReviewLevel:  review.ReviewLevel,
 			ReviewOrder:  review.ReviewOrder,
+			IsRequired:   review.IsRequired,
+			CanDelegate:  review.CanDelegate,
 			Deadline:     review.Deadline,
 			IsUrgent:     review.IsUrgent,
 			Status:       review.Status,
@@ -1918,10 +1995,12 @@ func (s *ProjectService) GetReviewFlowConfig(projectTypeID *uint) ([]models.Revi
 			ReviewLevel:        flow.ReviewLevel,
 			ReviewerRole:       flow.ReviewerRole,
 			ReviewerDepartment: flow.ReviewerDepartment,
+			ReviewerOrgUnitID:  flow.ReviewerOrgUnitID,
 			ReviewOrder:        flow.ReviewOrder,
 			IsRequired:         flow.IsRequired,
 			DeadlineHours:      flow.DeadlineHours,
 			AutoApprove:        flow.AutoApprove,
+			TimeoutApprove:     flow.TimeoutApprove,
 			CanDelegate:        flow.CanDelegate,
 			CreatedAt:          flow.CreatedAt,
 			UpdatedAt:          flow.UpdatedAt,
@@ -1933,30 +2012,9 @@ func (s *ProjectService) GetReviewFlowConfig(projectTypeID *uint) ([]models.Revi
 }
 
 // ForceUpdateProjectStatus 强制更新项目状态
-func (s *ProjectService) ForceUpdateProjectStatus(projectID uint, status string, reason string, operatorID uint) error {
-	// 检查项目是否存在
-	var project models.Project
-	if err := s.db.First(&project, projectID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("项目不存在")
-		}
-		return err
-	}
-
-	// 更新项目状态
-	updates := map[string]interface{}{
-		"status":              status,
-		"force_status_reason": reason,
-		"updated_at":          time.Now(),
-	}
-
-	if err := s.db.Model(&project).Updates(updates).Error; err != nil {
-		log.Printf("强制更新项目状态失败: %v", err)
-		return errors.New("强制更新项目状态失败")
-	}
-
-	log.Printf("项目状态强制更新成功 - 项目ID: %d, 新状态: %s", projectID, status)
-	return nil
+func (s *ProjectService) ForceUpdateProjectStatus(projectID uint, status string, reason string, operatorID uint, ip, userAgent string) error {
+	// 跳过流转规则，但目标状态须在项目流程中定义，并写入状态历史和审计日志
+	return s.workflow.ForceStatus(projectID, status, reason, operatorID, ip, userAgent)
 }
 
 // SoftDeleteProject 软删除项目
@@ -2019,7 +2077,7 @@ func (s *ProjectService) GetTeacherProjects(teacherID uint, params models.Teache
 		query = query.Where("level = ?", params.Level)
 	}
 	if params.CategoryID != nil {
-		query = query.Where("category_id = ?", *params.CategoryID)
+		query = query.Where("type_id = ?", *params.CategoryID)
 	}
 
 	// 获取总数
@@ -2069,7 +2127,7 @@ func (s *ProjectService) GetTeacherProjects(teacherID uint, params models.Teache
 
 		// 获取成员数量
 		var memberCount int64
-		s.db.Model(&models.ProjectMember{}).Where("project_id = ?", project.ID).Count(&memberCount)
+		s.db.Model(&models.ProjectMember{}).Where("project_id = ? AND status = ?", project.ID, ProjectMemberActive).Count(&memberCount)
 		response.MemberCount = int(memberCount)
 
 		responses = append(responses, response)
@@ -2083,17 +2141,23 @@ func (s *ProjectService) GetTeacherListWithFilter(params models.TeacherQueryPara
 	var teachers []models.User
 	var total int64
 
-	query := s.db.Model(&models.User{}).Where("role = 'teacher' AND deleted = ?", false)
+	query := s.db.Model(&models.User{}).
+		Joins("JOIN user_roles ON users.id = user_roles.user_id").
+		Joins("JOIN roles ON user_roles.role_id = roles.id").
+		Where("roles.role_key = ?", "teacher")
 
 	// 应用查询参数
 	if params.Department != "" {
-		query = query.Where("department = ?", params.Department)
+		query = query.Where("users.department = ?", params.Department)
+	}
+	if params.OrgUnitID > 0 {
+		query = query.Where("users.org_unit_id IN (?)", OrgUnitSubtreeIDs(s.db, params.OrgUnitID))
 	}
 	if params.Title != "" {
-		query = query.Where("title = ?", params.Title)
+		query = query.Where("users.title = ?", params.Title)
 	}
 	if params.Status != "" {
-		query = query.Where("status = ?", params.Status)
+		query = query.Where("users.status = ?", params.Status)
 	}
 
 	// 获取总数
@@ -2109,7 +2173,7 @@ func (s *ProjectService) GetTeacherListWithFilter(params models.TeacherQueryPara
 		}
 		query = query.Order(order)
 	} else {
-		query = query.Order("created_at DESC")
+		query = query.Order("users.created_at DESC")
 	}
 
 	// 分页
@@ -2303,12 +2367,12 @@ func (s *ProjectService) GetMyStudents(teacherID uint, params models.StudentQuer
 	return responses, total, nil
 }
 
-// GetStudentProjects 获取学生项目列表（支持分页和查询）
+// GetStudentProjects 获取学生项目列表（支持分页和查询，含作为团队成员参与的项目）
 func (s *ProjectService) GetStudentProjects(studentID uint, params models.ProjectQueryParams) ([]models.ProjectMyListResponse, int64, error) {
 	var projects []models.Project
 	var total int64
 
-	query := s.db.Model(&models.Project{}).Where("student_id = ?", studentID)
+	query := s.db.Model(&models.Project{}).Where("student_id = ? OR id IN (?)", studentID, memberProjectIDs(s.db, studentID))
 
 	// 搜索条件
 	if params.Search != "" {
@@ -2359,53 +2423,60 @@ func (s *ProjectService) GetStudentProjects(studentID uint, params models.Projec
 	var responses []models.ProjectMyListResponse
 	for _, project := range projects {
 		responses = append(responses, models.ProjectMyListResponse{
-			ID:        project.ID,
-			Title:     project.Title,
-			Type:      project.Type,
-			Status:    project.Status,
-			CreatedAt: project.CreatedAt,
+			ID:         project.ID,
+			Title:      project.Title,
+			Type:       project.Type,
+			Status:     project.Status,
+			CreatedAt:  project.CreatedAt,
+			MemberRole: memberRoleOf(&project, studentID),
 		})
 	}
 
 	return responses, total, nil
 }
 
-// GetStudentProjectStats 获取学生项目统计信息
+// memberRoleOf 列表中的项目只包含负责人或正式成员的项目，据此判断当前用户的团队角色
+func memberRoleOf(project *models.Project, userID uint) string {
+	if project.StudentID == userID {
+		return ProjectMemberLeader
+	}
+	return ProjectMemberMember
+}
+
+// GetStudentProjectStats 获取学生项目统计信息（含作为团队成员参与的项目）
 func (s *ProjectService) GetStudentProjectStats(studentID uint) (*models.ProjectStats, error) {
 	var stats models.ProjectStats
+	mine := func() *gorm.DB {
+		return s.db.Model(&models.Project{}).Where("student_id = ? OR id IN (?)", studentID, memberProjectIDs(s.db, studentID))
+	}
 
 	// 获取项目总数
-	if err := s.db.Model(&models.Project{}).Where("student_id = ?", studentID).Count(&stats.TotalProjects).Error; err != nil {
+	if err := mine().Count(&stats.TotalProjects).Error; err != nil {
 		log.Printf("获取学生项目总数失败: %v", err)
 		return nil, err
 	}
 
-	// 获取各状态项目数量
-	statuses := []string{"draft", "pending", "approved", "rejected"}
-	for _, status := range statuses {
-		var count int64
-		if err := s.db.Model(&models.Project{}).Where("student_id = ? AND status = ?", studentID, status).Count(&count).Error; err != nil {
-			log.Printf("获取状态 %s 项目数量失败: %v", status, err)
-			continue
-		}
-
-		switch status {
-		case "draft":
-			stats.DraftProjects = count
-		case "pending":
-			stats.PendingProjects = count
-		case "approved":
-			stats.ApprovedProjects = count
-		case "rejected":
-			stats.RejectedProjects = count
+	// 获取各状态项目数量，状态按流转配置归类
+	groups := s.workflow.StatusGroups()
+	for name, group := range map[string]struct {
+		statuses []string
+		count    *int64
+	}{
+		"draft":    {groups.Draft, &stats.DraftProjects},
+		"pending":  {groups.Pending, &stats.PendingProjects},
+		"approved": {groups.Approved, &stats.ApprovedProjects},
+		"rejected": {groups.Rejected, &stats.RejectedProjects},
+	} {
+		if err := mine().Where("status IN ?", group.statuses).Count(group.count).Error; err != nil {
+			log.Printf("获取状态 %s 项目数量失败: %v", name, err)
 		}
 	}
 
 	// 获取类型统计
-	if err := s.db.Model(&models.Project{}).Where("student_id = ? AND type = ?", studentID, "科研").Count(&stats.ResearchProjects).Error; err != nil {
+	if err := mine().Where("type = ?", "科研").Count(&stats.ResearchProjects).Error; err != nil {
 		log.Printf("获取科研项目数量失败: %v", err)
 	}
-	if err := s.db.Model(&models.Project{}).Where("student_id = ? AND type = ?", studentID, "竞赛").Count(&stats.CompetitionProjects).Error; err != nil {
+	if err := mine().Where("type = ?", "竞赛").Count(&stats.CompetitionProjects).Error; err != nil {
 		log.Printf("获取竞赛项目数量失败: %v", err)
 	}
 
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 团队成员角色与状态
+const (
+	ProjectMemberLeader   = "leader"
+	ProjectMemberMember   = "member"
+	ProjectMemberInvited  = "invited"
+	ProjectMemberActive   = "active"
+	ProjectMemberDeclined = "declined"
+)
+
+// memberProjectIDs 用户作为正式成员参与的项目ID子查询（不含作为负责人的项目）
+func memberProjectIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.ProjectMember{}).Select("project_id").
+		Where("user_id = ? AND status = ?", userID, ProjectMemberActive)
+}
+
+// projectMemberRole 用户在项目团队中的角色：负责人为 leader，已接受邀请的成员为 member，其他人为空
+func projectMemberRole(db *gorm.DB, project *models.Project, userID uint) string {
+	if project.StudentID == userID {
+		return ProjectMemberLeader
+	}
+	var count int64
+	db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ? AND status = ?", project.ID, userID, ProjectMemberActive).Count(&count)
+	if count > 0 {
+		return ProjectMemberMember
+	}
+	return ""
+}
+
+// projectTeamEditable 只有草稿或已驳回的项目可以调整团队成员
+func projectTeamEditable(project *models.Project) bool {
+	return project.Status == "draft" || project.Status == "rejected"
+}
+
+// newProjectMember 按用户档案生成成员记录
+func newProjectMember(db *gorm.DB, projectID, userID uint, role, status string) models.ProjectMember {
+	member := models.ProjectMember{ProjectID: projectID, UserID: &userID, Role: role, Status: status}
+	var user models.User
+	if err := db.Preload("Profile").First(&user, userID).Error; err == nil {
+		member.Name = user.Username
+		if user.Profile != nil {
+			if user.Profile.RealName != "" {
+				member.Name = user.Profile.RealName
+			}
+			member.StudentNumber = user.Profile.StudentID
+		}
+	}
+	if status == ProjectMemberActive {
+		now := time.Now()
+		member.JoinedAt = &now
+	}
+	return member
+}
+
+type ProjectTeamService struct {
+	db *gorm.DB
+}
+
+func NewProjectTeamService(db *gorm.DB) *ProjectTeamService {
+	return &ProjectTeamService{db: db}
+}
+
+// findProject 查找项目
+func (s *ProjectTeamService) findProject(projectID uint) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("项目不存在")
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ensureLeader 早期创建的项目没有队长的成员记录，首次调整团队时补建
+func (s *ProjectTeamService) ensureLeader(db *gorm.DB, project *models.Project) error {
+	var count int64
+	db.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", project.ID, project.StudentID).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	leader := newProjectMember(db, project.ID, project.StudentID, ProjectMemberLeader, ProjectMemberActive)
+	return db.Create(&leader).Error
+}
+
+// memberLimit 项目分类的团队人数上限，0 表示不限；未关联分类的项目使用系统设置的默认上限
+func (s *ProjectTeamService) memberLimit(db *gorm.DB, project *models.Project) int {
+	var projectType models.ProjectType
+	if project.TypeID == nil || db.Select("max_members").First(&projectType, *project.TypeID).Error != nil {
+		return NewSettingService(db).GetInt("project_default_max_members", 5)
+	}
+	return projectType.MaxMembers
+}
+
+// lockProject 在事务中锁定项目行，同一项目的邀请和接受邀请串行执行，人数统计不会被并发请求绕过
+func (s *ProjectTeamService) lockProject(tx *gorm.DB, projectID uint) (*models.Project, error) {
+	var project models.Project
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("项目不存在")
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+// notify 给团队成员发送项目通知，失败只记录日志
+func (s *ProjectTeamService) notify(projectID, userID uint, title, content string) {
+	notification := models.ProjectNotification{
+		ProjectID: projectID,
+		UserID:    userID,
+		Type:      "team",
+		Title:     title,
+		Content:   content,
+		Priority:  "normal",
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		log.Printf("发送团队通知失败 - 项目ID: %d, 用户ID: %d, 错误: %v", projectID, userID, err)
+	}
+}
+
+// GetMembers 获取团队成员（含待接受的邀请），团队成员和指导教师可以查看
+func (s *ProjectTeamService) GetMembers(projectID, userID uint) ([]models.ProjectMember, error) {
+	project, err := s.findProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.TeacherID != userID && projectMemberRole(s.db, project, userID) == "" {
+		return nil, errors.New("无权限查看此项目的团队")
+	}
+	if err := s.ensureLeader(s.db, project); err != nil {
+		return nil, err
+	}
+
+	var members []models.ProjectMember
+	err = s.db.Where("project_id = ? AND status IN ?", projectID, []string{ProjectMemberActive, ProjectMemberInvited}).
+		Order("CASE WHEN role = 'leader' THEN 0 ELSE 1 END, id").Find(&members).Error
+	return members, err
+}
+
+// Invite 队长邀请学生加入团队，被邀请人接受后才成为正式成员；待接受的邀请也占用人数上限
+func (s *ProjectTeamService) Invite(projectID, leaderID uint, req models.ProjectMemberInviteRequest) (*models.ProjectMember, error) {
+	project, err := s.findProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.StudentID != leaderID {
+		return nil, errors.New("只有队长可以邀请成员")
+	}
+	if !projectTeamEditable(project) {
+		return nil, errors.New("只有草稿或已驳回状态的项目可以调整团队")
+	}
+
+	var invitee models.User
+	query := s.db.Preload("Roles")
+	switch {
+	case req.UserID > 0:
+		err = query.First(&invitee, req.UserID).Error
+	case req.StudentID != "":
+		err = query.Where("id IN (?)", s.db.Model(&models.UserProfile{}).Select("user_id").Where("student_id = ?", req.StudentID)).
+			First(&invitee).Error
+	default:
+		return nil, errors.New("请指定被邀请的学生")
+	}
+	if err != nil {
+		return nil, errors.New("被邀请的学生不存在")
+	}
+	if invitee.ID == leaderID {
+		return nil, errors.New("不能邀请自己")
+	}
+	if invitee.Status != "active" {
+		return nil, errors.New("被邀请的账号未启用")
+	}
+	isStudent := false
+	for _, role := range invitee.Roles {
+		if role.RoleKey == "student" {
+			isStudent = true
+			break
+		}
+	}
+	if !isStudent {
+		return nil, errors.New("只能邀请学生加入团队")
+	}
+
+	var member models.ProjectMember
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		project, err := s.lockProject(tx, projectID)
+		if err != nil {
+			return err
+		}
+		if project.StudentID != leaderID || !projectTeamEditable(project) {
+			return errors.New("项目状态已变化，请刷新后重试")
+		}
+		if err := s.ensureLeader(tx, project); err != nil {
+			return err
+		}
+		var existing models.ProjectMember
+		found := tx.Where("project_id = ? AND user_id = ?", projectID, invitee.ID).Limit(1).Find(&existing).RowsAffected > 0
+		if found && existing.Status != ProjectMemberDeclined {
+			if existing.Status == ProjectMemberInvited {
+				return errors.New("已邀请该学生，等待对方接受")
+			}
+			return errors.New("该学生已是团队成员")
+		}
+
+		if limit := s.memberLimit(tx, project); limit > 0 {
+			var count int64
+			tx.Model(&models.ProjectMember{}).
+				Where("project_id = ? AND status IN ?", projectID, []string{ProjectMemberActive, ProjectMemberInvited}).Count(&count)
+			if count >= int64(limit) {
+				return fmt.Errorf("团队人数已达上限（%d人，含待接受的邀请）", limit)
+			}
+		}
+
+		// 曾拒绝过的邀请直接重新发出
+		member = newProjectMember(tx, projectID, invitee.ID, ProjectMemberMember, ProjectMemberInvited)
+		member.InvitedBy = &leaderID
+		if found {
+			member.ID = existing.ID
+			member.CreatedAt = time.Now()
+			return tx.Save(&member).Error
+		}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(projectID, invitee.ID, "项目团队邀请", fmt.Sprintf("邀请你加入项目《%s》的团队，请在“我的团队邀请”中确认", project.Title))
+	log.Printf("团队邀请已发出 - 项目ID: %d, 被邀请人ID: %d", projectID, invitee.ID)
+	return &member, nil
+}
+
+// GetMyInvitations 获取我收到的待处理团队邀请
+func (s *ProjectTeamService) GetMyInvitations(userID uint) ([]models.ProjectInvitationResponse, error) {
+	var members []models.ProjectMember
+	if err := s.db.Preload("Project").Where("user_id = ? AND status = ?", userID, ProjectMemberInvited).
+		Order("created_at DESC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	inviterIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		if member.InvitedBy != nil {
+			inviterIDs = append(inviterIDs, *member.InvitedBy)
+		}
+	}
+	inviters := make(map[uint]string)
+	if len(inviterIDs) > 0 {
+		var users []models.User
+		s.db.Preload("Profile").Where("id IN ?", inviterIDs).Find(&users)
+		for _, user := range users {
+			inviters[user.ID] = user.Username
+			if user.Profile != nil && user.Profile.RealName != "" {
+				inviters[user.ID] = user.Profile.RealName
+			}
+		}
+	}
+
+	invitations := make([]models.ProjectInvitationResponse, 0, len(members))
+	for _, member := range members {
+		invitation := models.ProjectInvitationResponse{
+			ID:        member.ID,
+			ProjectID: member.ProjectID,
+			InvitedBy: member.InvitedBy,
+			CreatedAt: member.CreatedAt,
+		}
+		if member.Project != nil {
+			invitation.ProjectTitle = member.Project.Title
+		}
+		if member.InvitedBy != nil {
+			invitation.InviterName = inviters[*member.InvitedBy]
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, nil
+}
+
+// RespondInvitation 被邀请人接受或拒绝团队邀请
+func (s *ProjectTeamService) RespondInvitation(memberID, userID uint, accept bool) error {
+	var member models.ProjectMember
+	if err := s.db.Where("id = ? AND user_id = ? AND status = ?", memberID, userID, ProjectMemberInvited).
+		First(&member).Error; err != nil {
+		return errors.New("邀请不存在或已处理")
+	}
+	project, err := s.findProject(member.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	if !accept {
+		if err := s.db.Model(&member).Update("status", ProjectMemberDeclined).Error; err != nil {
+			log.Printf("处理团队邀请失败 - 成员记录ID: %d, 错误: %v", memberID, err)
+			return errors.New("处理团队邀请失败")
+		}
+	} else {
+		// 锁定项目后重新检查邀请状态和人数上限，并发接受时不会超员
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			project, err := s.lockProject(tx, member.ProjectID)
+			if err != nil {
+				return err
+			}
+			if !projectTeamEditable(project) {
+				return errors.New("项目已提交，不能再加入团队")
+			}
+			if limit := s.memberLimit(tx, project); limit > 0 {
+				var count int64
+				tx.Model(&models.ProjectMember{}).Where("project_id = ? AND status = ?", project.ID, ProjectMemberActive).Count(&count)
+				if count >= int64(limit) {
+					return fmt.Errorf("团队人数已达上限（%d人）", limit)
+				}
+			}
+			result := tx.Model(&models.ProjectMember{}).Where("id = ? AND status = ?", member.ID, ProjectMemberInvited).
+				Updates(map[string]interface{}{"status": ProjectMemberActive, "joined_at": time.Now()})
+			if result.Error != nil {
+				log.Printf("处理团队邀请失败 - 成员记录ID: %d, 错误: %v", memberID, result.Error)
+				return errors.New("处理团队邀请失败")
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("邀请不存在或已处理")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	result := "拒绝"
+	if accept {
+		result = "接受"
+	}
+	s.notify(project.ID, project.StudentID, "团队邀请已"+result, fmt.Sprintf("%s已%s加入项目《%s》团队的邀请", member.Name, result, project.Title))
+	log.Printf("团队邀请已%s - 项目ID: %d, 用户ID: %d", result, project.ID, userID)
+	return nil
+}
+
+// RemoveMember 队长移除成员或撤回邀请，成员也可以自行退出；队长需先转让队长才能退出
+func (s *ProjectTeamService) RemoveMember(projectID, memberID, operatorID uint) error {
+	project, err := s.findProject(projectID)
+	if err != nil {
+		return err
+	}
+	var member models.ProjectMember
+	if err := s.db.Where("id = ? AND project_id = ?", memberID, projectID).First(&member).Error; err != nil {
+		return errors.New("团队成员不存在")
+	}
+
+	self := member.UserID != nil && *member.UserID == operatorID
+	if project.StudentID != operatorID && !self {
+		return errors.New("只有队长可以移除成员")
+	}
+	if member.Role == ProjectMemberLeader || (member.UserID != nil && *member.UserID == project.StudentID) {
+		return errors.New("队长不能退出团队，请先转让队长")
+	}
+	if !projectTeamEditable(project) {
+		return errors.New("只有草稿或已驳回状态的项目可以调整团队")
+	}
+
+	if err := s.db.Delete(&member).Error; err != nil {
+		log.Printf("移除团队成员失败 - 成员记录ID: %d, 错误: %v", memberID, err)
+		return errors.New("移除团队成员失败")
+	}
+	if self {
+		s.notify(projectID, project.StudentID, "成员退出团队", fmt.Sprintf("%s已退出项目《%s》的团队", member.Name, project.Title))
+	} else if member.UserID != nil {
+		s.notify(projectID, *member.UserID, "已被移出团队", fmt.Sprintf("你已被移出项目《%s》的团队", project.Title))
+	}
+	log.Printf("团队成员已移除 - 项目ID: %d, 成员记录ID: %d, 操作人ID: %d", projectID, memberID, operatorID)
+	return nil
+}
+
+// TransferLeader 队长把队长身份转让给团队中的正式成员，项目负责人随之变更
+func (s *ProjectTeamService) TransferLeader(projectID, leaderID uint, req models.ProjectLeaderTransferRequest) error {
+	project, err := s.findProject(projectID)
+	if err != nil {
+		return err
+	}
+	if project.StudentID != leaderID {
+		return errors.New("只有队长可以转让队长")
+	}
+	if !projectTeamEditable(project) {
+		return errors.New("只有草稿或已驳回状态的项目可以调整团队")
+	}
+
+	// 队长和受让成员在锁定项目后重新读取，避免与邀请、退出或另一次转让并发时基于过期数据修改
+	var target models.ProjectMember
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		project, err = s.lockProject(tx, projectID)
+		if err != nil {
+			return err
+		}
+		if project.StudentID != leaderID || !projectTeamEditable(project) {
+			return errors.New("项目状态已变化，请刷新后重试")
+		}
+		if err := tx.Where("id = ? AND project_id = ? AND status = ?", req.MemberID, projectID, ProjectMemberActive).
+			First(&target).Error; err != nil || target.UserID == nil {
+			return errors.New("只能转让给已加入团队的成员")
+		}
+		if *target.UserID == leaderID {
+			return errors.New("你已经是队长")
+		}
+
+		if err := s.ensureLeader(tx, project); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProjectMember{}).Where("project_id = ? AND user_id = ?", projectID, leaderID).
+			Update("role", ProjectMemberMember).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&target).Update("role", ProjectMemberLeader).Error; err != nil {
+			return err
+		}
+		return tx.Model(project).Update("student_id", *target.UserID).Error
+	})
+	if err != nil {
+		log.Printf("转让队长失败 - 项目ID: %d, 错误: %v", projectID, err)
+		return err
+	}
+
+	s.notify(projectID, *target.UserID, "你已成为队长", fmt.Sprintf("你已成为项目《%s》的队长", project.Title))
+	log.Printf("队长已转让 - 项目ID: %d, 原队长ID: %d, 新队长ID: %d", projectID, leaderID, *target.UserID)
+	return nil
+}
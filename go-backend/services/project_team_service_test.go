@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"yunmeng-backend/models"
+)
+
+func TestProjectTeamDefaultLimitAndAccess(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.Role{}, &models.UserRole{}, &models.Project{},
+		&models.ProjectType{}, &models.ProjectMember{}, &models.ProjectNotification{}, &models.ProjectReview{}, &models.SystemSetting{})
+	db.Create(&models.Role{RoleKey: "student", RoleName: "student"})
+	users := make([]*models.User, 0, 5)
+	for _, name := range []string{"leader", "m1", "m2", "outsider", "teacher"} {
+		user := &models.User{Username: name, Email: name + "@yunmeng.test", Password: "x", Status: "active", RoleName: "student"}
+		db.Create(user)
+		db.Create(&models.UserRole{UserID: user.ID, RoleID: 1})
+		users = append(users, user)
+	}
+	leader, m1, m2, outsider, teacher := users[0], users[1], users[2], users[3], users[4]
+	// 未关联分类的项目按默认上限：队长加两个成员
+	db.Create(&models.SystemSetting{SettingKey: "project_default_max_members", SettingValue: "3"})
+	project := models.Project{Title: "无分类项目", StudentID: leader.ID, TeacherID: teacher.ID, Status: "draft"}
+	db.Create(&project)
+
+	service := NewProjectTeamService(db)
+	first, err := service.Invite(project.ID, leader.ID, models.ProjectMemberInviteRequest{UserID: m1.ID})
+	if err != nil {
+		t.Fatalf("邀请失败: %v", err)
+	}
+	second, err := service.Invite(project.ID, leader.ID, models.ProjectMemberInviteRequest{UserID: m2.ID})
+	if err != nil {
+		t.Fatalf("邀请失败: %v", err)
+	}
+	if _, err := service.Invite(project.ID, leader.ID, models.ProjectMemberInviteRequest{UserID: outsider.ID}); err == nil ||
+		!strings.Contains(err.Error(), "上限") {
+		t.Errorf("未关联分类的项目也应限制人数，实际: %v", err)
+	}
+
+	// 接受邀请时按当时的上限重新检查
+	db.Model(&models.SystemSetting{}).Where("setting_key = ?", "project_default_max_members").Update("setting_value", "2")
+	if err := service.RespondInvitation(first.ID, m1.ID, true); err != nil {
+		t.Fatalf("接受邀请失败: %v", err)
+	}
+	if err := service.RespondInvitation(second.ID, m2.ID, true); err == nil || !strings.Contains(err.Error(), "上限") {
+		t.Errorf("团队已满时不应再接受邀请，实际: %v", err)
+	}
+
+	projects := NewProjectService(db)
+	scope := &models.DataScope{}
+	for _, c := range []struct {
+		user *models.User
+		want bool
+	}{
+		{leader, true}, {m1, true}, {teacher, true}, {m2, false}, {outsider, false},
+	} {
+		if got := projects.CanViewProject(project.ID, c.user.ID, "student", scope); got != c.want {
+			t.Errorf("%s 查看项目详情应为 %v，实际 %v", c.user.Username, c.want, got)
+		}
+	}
+	db.Create(&models.ProjectReview{ProjectID: project.ID, ReviewerID: outsider.ID, Status: "pending"})
+	if !projects.CanViewProject(project.ID, outsider.ID, "teacher", scope) {
+		t.Errorf("审核人应能查看项目详情")
+	}
+
+	// 核心信息只有队长可以修改
+	if err := projects.UpdateProject(project.ID, m1.ID, models.ProjectUpdateRequest{Title: "成员改名"}); err == nil ||
+		!strings.Contains(err.Error(), "负责人") {
+		t.Errorf("团队成员不应能修改项目信息，实际: %v", err)
+	}
+
+	// 审核中的项目不能转让队长
+	var m1Member models.ProjectMember
+	db.Where("project_id = ? AND user_id = ?", project.ID, m1.ID).First(&m1Member)
+	db.Model(&project).Update("status", "submitted")
+	if err := service.TransferLeader(project.ID, leader.ID, models.ProjectLeaderTransferRequest{MemberID: m1Member.ID}); err == nil {
+		t.Errorf("审核中的项目不应能转让队长")
+	}
+	db.Model(&project).Update("status", "draft")
+	if err := service.TransferLeader(project.ID, leader.ID, models.ProjectLeaderTransferRequest{MemberID: m1Member.ID}); err != nil {
+		t.Fatalf("转让队长失败: %v", err)
+	}
+	db.First(&project, project.ID)
+	if project.StudentID != m1.ID {
+		t.Errorf("转让后负责人应为 %d，实际 %d", m1.ID, project.StudentID)
+	}
+	if err := service.TransferLeader(project.ID, leader.ID, models.ProjectLeaderTransferRequest{MemberID: m1Member.ID}); err == nil {
+		t.Errorf("原队长不应能再次转让")
+	}
+}
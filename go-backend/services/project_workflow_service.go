@@ -0,0 +1,835 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 项目状态流转动作
+const (
+	ProjectActionSubmit = "submit"
+	ProjectActionForce  = "force"
+)
+
+// defaultMilestoneDueDays create_milestone 未设置截止天数时使用
+const defaultMilestoneDueDays = 30
+
+var workflowStateKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// projectWorkflowFieldLabels 流转前要求的项目字段及其是否已填写
+var projectWorkflowFieldLabels = map[string]struct {
+	label  string
+	filled func(p *models.Project) bool
+}{
+	"title":       {"项目名称", func(p *models.Project) bool { return strings.TrimSpace(p.Title) != "" }},
+	"description": {"项目简介", func(p *models.Project) bool { return strings.TrimSpace(p.Description) != "" }},
+	"plan":        {"项目计划", func(p *models.Project) bool { return strings.TrimSpace(p.Plan) != "" }},
+	"teacher_id":  {"指导教师", func(p *models.Project) bool { return p.TeacherID != 0 }},
+	"type_id":     {"项目分类", func(p *models.Project) bool { return p.TypeID != nil }},
+	"finish_time": {"预计完成时间", func(p *models.Project) bool { return !p.FinishTime.IsZero() }},
+	"progress":    {"项目进度达到100%", func(p *models.Project) bool { return p.Progress >= 100 }},
+}
+
+// workflow 一个分类实际生效的状态流转配置
+type workflow struct {
+	source      *uint // 配置所属的分类，nil 为默认流程
+	states      []models.ProjectWorkflowState
+	transitions []models.ProjectWorkflowTransition
+}
+
+func (w *workflow) state(key string) *models.ProjectWorkflowState {
+	for i := range w.states {
+		if w.states[i].StateKey == key {
+			return &w.states[i]
+		}
+	}
+	return nil
+}
+
+// stateName 状态显示名称，未定义的状态直接显示状态值
+func (w *workflow) stateName(key string) string {
+	if state := w.state(key); state != nil {
+		return state.Name
+	}
+	return key
+}
+
+type ProjectWorkflowService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+	reviews    *ProjectReviewService
+}
+
+func NewProjectWorkflowService(db *gorm.DB) *ProjectWorkflowService {
+	w := &ProjectWorkflowService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+	w.reviews = &ProjectReviewService{db: db, workflow: w, settings: NewSettingService(db)}
+	return w
+}
+
+// workflowScope 按配置所属分类过滤
+func workflowScope(db *gorm.DB, source *uint) *gorm.DB {
+	if source == nil {
+		return db.Where("project_type_id IS NULL")
+	}
+	return db.Where("project_type_id = ?", *source)
+}
+
+// resolveSource 沿分类的上级链查找已单独配置流程的分类，都未配置时返回 nil（默认流程）
+func (s *ProjectWorkflowService) resolveSource(db *gorm.DB, typeID *uint) *uint {
+	visited := make(map[uint]bool)
+	for typeID != nil && !visited[*typeID] {
+		visited[*typeID] = true
+		var count int64
+		db.Model(&models.ProjectWorkflowState{}).Where("project_type_id = ?", *typeID).Count(&count)
+		if count > 0 {
+			id := *typeID
+			return &id
+		}
+		var projectType models.ProjectType
+		if err := db.Select("id", "parent_id").First(&projectType, *typeID).Error; err != nil {
+			return nil
+		}
+		typeID = projectType.ParentID
+	}
+	return nil
+}
+
+// load 读取分类实际生效的流程
+func (s *ProjectWorkflowService) load(db *gorm.DB, typeID *uint) (*workflow, error) {
+	w := &workflow{source: s.resolveSource(db, typeID)}
+	if err := workflowScope(db, w.source).Order("sort_order ASC, id ASC").Find(&w.states).Error; err != nil {
+		return nil, err
+	}
+	if err := workflowScope(db, w.source).Order("sort_order ASC, id ASC").Find(&w.transitions).Error; err != nil {
+		return nil, err
+	}
+	if len(w.states) == 0 {
+		return nil, errors.New("未配置项目状态流转")
+	}
+	return w, nil
+}
+
+// InitialStatus 新建项目的初始状态，未配置流程时为 draft
+func (s *ProjectWorkflowService) InitialStatus(db *gorm.DB, typeID *uint) string {
+	w, err := s.load(db, typeID)
+	if err != nil {
+		return "draft"
+	}
+	for _, state := range w.states {
+		if state.IsInitial {
+			return state.StateKey
+		}
+	}
+	return "draft"
+}
+
+// GetWorkflow 获取分类实际生效的流程，typeID 为空表示默认流程
+func (s *ProjectWorkflowService) GetWorkflow(typeID *uint) (*models.ProjectWorkflowResponse, error) {
+	if typeID != nil {
+		var count int64
+		s.db.Model(&models.ProjectType{}).Where("id = ?", *typeID).Count(&count)
+		if count == 0 {
+			return nil, errors.New("项目分类不存在")
+		}
+	}
+	w, err := s.load(s.db, typeID)
+	if err != nil {
+		return nil, err
+	}
+	inherited := typeID != nil && (w.source == nil || *w.source != *typeID)
+	return &models.ProjectWorkflowResponse{
+		ProjectTypeID: typeID,
+		SourceTypeID:  w.source,
+		Inherited:     inherited,
+		States:        w.states,
+		Transitions:   w.transitions,
+	}, nil
+}
+
+// ProjectStatusGroups 按流转规则归类的项目状态（汇总全部分类的流程），用于统计
+type ProjectStatusGroups struct {
+	Draft    []string // 初始状态
+	Pending  []string // 可发起审批或驳回的状态，即待审核
+	Approved []string // 审批流转（mark_approved）的目标状态
+	Rejected []string // 驳回流转（mark_rejected）的目标状态
+}
+
+// StatusGroups 按各分类流程的初始状态和审批/驳回流转归类项目状态
+func (s *ProjectWorkflowService) StatusGroups() ProjectStatusGroups {
+	var groups ProjectStatusGroups
+	seen := make(map[string]bool)
+	add := func(list *[]string, group, status string) {
+		if !seen[group+"|"+status] {
+			seen[group+"|"+status] = true
+			*list = append(*list, status)
+		}
+	}
+
+	var initial []string
+	s.db.Model(&models.ProjectWorkflowState{}).Where("is_initial = ?", true).Distinct().Pluck("state_key", &initial)
+	for _, status := range initial {
+		add(&groups.Draft, "draft", status)
+	}
+	var transitions []models.ProjectWorkflowTransition
+	s.db.Select("from_status", "to_status", "side_effects").Find(&transitions)
+	for _, t := range transitions {
+		for _, effect := range t.SideEffects {
+			switch effect {
+			case "mark_approved":
+				add(&groups.Pending, "pending", t.FromStatus)
+				add(&groups.Approved, "approved", t.ToStatus)
+			case "mark_rejected":
+				add(&groups.Pending, "pending", t.FromStatus)
+				add(&groups.Rejected, "rejected", t.ToStatus)
+			}
+		}
+	}
+	return groups
+}
+
+// affectedTypeIDs 使用 source 对应流程的全部分类（含未单独配置流程的下级分类），source 视为已单独配置
+func (s *ProjectWorkflowService) affectedTypeIDs(db *gorm.DB, source *uint) []uint {
+	var types []models.ProjectType
+	db.Select("id", "parent_id").Find(&types)
+	var configured []uint
+	db.Model(&models.ProjectWorkflowState{}).Where("project_type_id IS NOT NULL").Distinct().Pluck("project_type_id", &configured)
+
+	parents := make(map[uint]*uint, len(types))
+	for _, t := range types {
+		parents[t.ID] = t.ParentID
+	}
+	hasOwn := make(map[uint]bool, len(configured))
+	for _, id := range configured {
+		hasOwn[id] = true
+	}
+
+	var ids []uint
+	for _, t := range types {
+		var resolved *uint
+		visited := make(map[uint]bool)
+		for cur := &t.ID; cur != nil && !visited[*cur]; cur = parents[*cur] {
+			visited[*cur] = true
+			if hasOwn[*cur] || (source != nil && *cur == *source) {
+				resolved = cur
+				break
+			}
+		}
+		if (source == nil && resolved == nil) || (source != nil && resolved != nil && *resolved == *source) {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// projectsUsing 使用 source 对应流程的项目查询
+func (s *ProjectWorkflowService) projectsUsing(db *gorm.DB, source *uint) *gorm.DB {
+	query := db.Model(&models.Project{}).Where("deleted = ?", false)
+	ids := s.affectedTypeIDs(db, source)
+	if source == nil {
+		if len(ids) == 0 {
+			return query.Where("type_id IS NULL")
+		}
+		return query.Where("type_id IS NULL OR type_id IN ?", ids)
+	}
+	return query.Where("type_id IN ?", ids)
+}
+
+// validateWorkflow 校验流程配置的完整性
+func (s *ProjectWorkflowService) validateWorkflow(req models.ProjectWorkflowSaveRequest) error {
+	states := make(map[string]models.ProjectWorkflowStateRequest, len(req.States))
+	initial := 0
+	for _, state := range req.States {
+		if !workflowStateKeyPattern.MatchString(state.StateKey) {
+			return fmt.Errorf("状态标识 %s 只能包含小写字母、数字和下划线，且以字母开头", state.StateKey)
+		}
+		if _, ok := states[state.StateKey]; ok {
+			return fmt.Errorf("状态标识 %s 重复", state.StateKey)
+		}
+		states[state.StateKey] = state
+		if state.IsInitial {
+			initial++
+			if state.IsFinal {
+				return fmt.Errorf("初始状态 %s 不能同时是终态", state.StateKey)
+			}
+		}
+	}
+	if initial != 1 {
+		return errors.New("必须有且只有一个初始状态")
+	}
+
+	var roleKeys []string
+	s.db.Model(&models.Role{}).Pluck("role_key", &roleKeys)
+	knownRoles := make(map[string]bool)
+	for _, role := range append(roleKeys, models.ProjectWorkflowRoles...) {
+		knownRoles[role] = true
+	}
+	knownEffects := make(map[string]bool)
+	for _, effect := range models.ProjectWorkflowSideEffects {
+		knownEffects[effect] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range req.Transitions {
+		from, ok := states[t.FromStatus]
+		if !ok {
+			return fmt.Errorf("流转 %s 的起始状态 %s 未定义", t.Action, t.FromStatus)
+		}
+		if _, ok := states[t.ToStatus]; !ok {
+			return fmt.Errorf("流转 %s 的目标状态 %s 未定义", t.Action, t.ToStatus)
+		}
+		if t.FromStatus == t.ToStatus {
+			return fmt.Errorf("流转 %s 的起始状态和目标状态相同", t.Action)
+		}
+		if from.IsFinal {
+			return fmt.Errorf("终态 %s 不能再发起流转", t.FromStatus)
+		}
+		key := t.Action + "|" + t.FromStatus
+		if seen[key] {
+			return fmt.Errorf("状态 %s 下的流转动作 %s 重复", t.FromStatus, t.Action)
+		}
+		seen[key] = true
+		if t.Action == ProjectActionForce {
+			return fmt.Errorf("流转动作 %s 为系统保留", ProjectActionForce)
+		}
+		for _, role := range t.AllowedRoles {
+			if !knownRoles[role] {
+				return fmt.Errorf("流转 %s 的角色 %s 不存在", t.Action, role)
+			}
+		}
+		for _, field := range t.RequiredFields {
+			if _, ok := projectWorkflowFieldLabels[field]; !ok {
+				return fmt.Errorf("流转 %s 的必填字段 %s 不支持", t.Action, field)
+			}
+		}
+		for _, effect := range t.SideEffects {
+			if !knownEffects[effect] {
+				return fmt.Errorf("流转 %s 的附加动作 %s 不支持", t.Action, effect)
+			}
+		}
+	}
+	return nil
+}
+
+// SaveWorkflow 保存分类的流程配置（整体替换），typeID 为空表示默认流程
+// 仍有项目处于被删除的状态时拒绝保存，避免项目停留在未定义的状态上
+func (s *ProjectWorkflowService) SaveWorkflow(typeID *uint, req models.ProjectWorkflowSaveRequest, operatorID uint, ip, userAgent string) (*models.ProjectWorkflowResponse, error) {
+	if typeID != nil {
+		var count int64
+		s.db.Model(&models.ProjectType{}).Where("id = ?", *typeID).Count(&count)
+		if count == 0 {
+			return nil, errors.New("项目分类不存在")
+		}
+	}
+	if err := s.validateWorkflow(req); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(req.States))
+	for _, state := range req.States {
+		keys = append(keys, state.StateKey)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var orphaned []string
+		s.projectsUsing(tx, typeID).Where("status NOT IN ?", keys).Distinct().Pluck("status", &orphaned)
+		if len(orphaned) > 0 {
+			return fmt.Errorf("仍有项目处于以下状态，新流程中必须包含：%s", strings.Join(orphaned, "、"))
+		}
+
+		if err := workflowScope(tx, typeID).Delete(&models.ProjectWorkflowState{}).Error; err != nil {
+			return err
+		}
+		if err := workflowScope(tx, typeID).Delete(&models.ProjectWorkflowTransition{}).Error; err != nil {
+			return err
+		}
+		for _, item := range req.States {
+			state := models.ProjectWorkflowState{
+				ProjectTypeID: typeID,
+				StateKey:      item.StateKey,
+				Name:          item.Name,
+				IsInitial:     item.IsInitial,
+				IsFinal:       item.IsFinal,
+				SortOrder:     item.SortOrder,
+			}
+			if err := tx.Create(&state).Error; err != nil {
+				return err
+			}
+		}
+		for _, item := range req.Transitions {
+			transition := models.ProjectWorkflowTransition{
+				ProjectTypeID:     typeID,
+				Action:            item.Action,
+				Name:              item.Name,
+				FromStatus:        item.FromStatus,
+				ToStatus:          item.ToStatus,
+				AllowedRoles:      models.JSONArray(item.AllowedRoles),
+				RequiredFields:    models.JSONArray(item.RequiredFields),
+				RequiredFileTypes: models.JSONArray(item.RequiredFileTypes),
+				RequireReason:     item.RequireReason,
+				SideEffects:       models.JSONArray(item.SideEffects),
+				MilestoneTitle:    item.MilestoneTitle,
+				MilestoneDueDays:  item.MilestoneDueDays,
+				SortOrder:         item.SortOrder,
+			}
+			if err := tx.Create(&transition).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.systemLogs.RecordSecurity("project_workflow_save", "保存项目状态流转配置", "success", &operatorID,
+		fmt.Sprintf("分类ID: %s, 状态数: %d, 流转数: %d", workflowTypeLabel(typeID), len(req.States), len(req.Transitions)),
+		ip, userAgent)
+	log.Printf("项目状态流转配置已保存 - 分类ID: %s, 状态数: %d, 流转数: %d, 操作人ID: %d",
+		workflowTypeLabel(typeID), len(req.States), len(req.Transitions), operatorID)
+	return s.GetWorkflow(typeID)
+}
+
+// ResetWorkflow 删除分类单独配置的流程，改为沿用上级分类或默认流程
+func (s *ProjectWorkflowService) ResetWorkflow(typeID uint, operatorID uint, ip, userAgent string) (*models.ProjectWorkflowResponse, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&models.ProjectWorkflowState{}).Where("project_type_id = ?", typeID).Count(&count)
+		if count == 0 {
+			return errors.New("该分类未单独配置状态流转")
+		}
+
+		// 删除后生效的流程须包含该分类项目当前所处的全部状态
+		var projectType models.ProjectType
+		if err := tx.Select("id", "parent_id").First(&projectType, typeID).Error; err != nil {
+			return errors.New("项目分类不存在")
+		}
+		fallback := s.resolveSource(tx, projectType.ParentID)
+		var keys []string
+		workflowScope(tx.Model(&models.ProjectWorkflowState{}), fallback).Pluck("state_key", &keys)
+		var orphaned []string
+		query := s.projectsUsing(tx, &typeID)
+		if len(keys) > 0 {
+			query = query.Where("status NOT IN ?", keys)
+		}
+		query.Distinct().Pluck("status", &orphaned)
+		if len(orphaned) > 0 {
+			return fmt.Errorf("沿用的流程中没有以下状态，仍有项目处于这些状态：%s", strings.Join(orphaned, "、"))
+		}
+
+		if err := tx.Where("project_type_id = ?", typeID).Delete(&models.ProjectWorkflowState{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("project_type_id = ?", typeID).Delete(&models.ProjectWorkflowTransition{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.systemLogs.RecordSecurity("project_workflow_reset", "删除项目分类的状态流转配置", "success", &operatorID,
+		fmt.Sprintf("分类ID: %d", typeID), ip, userAgent)
+	log.Printf("项目状态流转配置已删除 - 分类ID: %d, 操作人ID: %d", typeID, operatorID)
+	return s.GetWorkflow(&typeID)
+}
+
+func workflowTypeLabel(typeID *uint) string {
+	if typeID == nil {
+		return "默认"
+	}
+	return strconv.FormatUint(uint64(*typeID), 10)
+}
+
+// actorRoles 用户对项目的身份（leader/member/advisor）和系统角色
+func (s *ProjectWorkflowService) actorRoles(db *gorm.DB, project *models.Project, userID uint) map[string]bool {
+	roles := make(map[string]bool)
+	if role := projectMemberRole(db, project, userID); role != "" {
+		roles[role] = true
+	}
+	if project.TeacherID != 0 && project.TeacherID == userID {
+		roles["advisor"] = true
+	}
+	var user models.User
+	if err := db.Preload("Roles").First(&user, userID).Error; err == nil {
+		for _, role := range user.Roles {
+			roles[role.RoleKey] = true
+		}
+	}
+	return roles
+}
+
+func transitionAllowed(t *models.ProjectWorkflowTransition, roles map[string]bool) bool {
+	for _, role := range t.AllowedRoles {
+		if roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// missingRequirements 流转前尚未满足的字段和文件要求
+func (s *ProjectWorkflowService) missingRequirements(db *gorm.DB, project *models.Project, t *models.ProjectWorkflowTransition) []string {
+	var missing []string
+	for _, field := range t.RequiredFields {
+		if check, ok := projectWorkflowFieldLabels[field]; ok && !check.filled(project) {
+			missing = append(missing, check.label)
+		}
+	}
+	for _, fileType := range t.RequiredFileTypes {
+		var count int64
+		db.Model(&models.ProjectFile{}).
+			Where("project_id = ? AND file_type = ? AND review_status <> ?", project.ID, fileType, "rejected").Count(&count)
+		if count == 0 {
+			missing = append(missing, "文件："+s.fileTypeName(db, fileType))
+		}
+	}
+	return missing
+}
+
+// fileTypeName 文件类型显示名称
+func (s *ProjectWorkflowService) fileTypeName(db *gorm.DB, fileType string) string {
+	var config models.FileTypeConfig
+	if err := db.Where("file_type = ?", fileType).Limit(1).Find(&config).Error; err == nil && config.DisplayName != "" {
+		return config.DisplayName
+	}
+	return fileType
+}
+
+// AvailableTransitions 当前用户可以对项目发起的流转及尚未满足的要求
+func (s *ProjectWorkflowService) AvailableTransitions(projectID, userID uint) ([]models.ProjectAvailableTransition, error) {
+	var project models.Project
+	if err := s.db.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("项目不存在")
+		}
+		return nil, err
+	}
+	w, err := s.load(s.db, project.TypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := s.actorRoles(s.db, &project, userID)
+	result := make([]models.ProjectAvailableTransition, 0)
+	for i := range w.transitions {
+		t := &w.transitions[i]
+		if t.FromStatus != project.Status || !transitionAllowed(t, roles) {
+			continue
+		}
+		result = append(result, models.ProjectAvailableTransition{
+			Action:        t.Action,
+			Name:          t.Name,
+			ToStatus:      t.ToStatus,
+			ToStatusName:  w.stateName(t.ToStatus),
+			RequireReason: t.RequireReason,
+			Missing:       s.missingRequirements(s.db, &project, t),
+		})
+	}
+	return result, nil
+}
+
+// Transition 按流转规则变更项目状态
+func (s *ProjectWorkflowService) Transition(projectID, userID uint, req models.ProjectStatusUpdateRequest) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var project models.Project
+		if err := tx.First(&project, projectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("项目不存在")
+			}
+			return err
+		}
+		_, err := s.apply(tx, &project, userID, req)
+		return err
+	})
+}
+
+// hasTransition 项目当前状态下是否定义了该流转动作
+func (s *ProjectWorkflowService) hasTransition(db *gorm.DB, project *models.Project, action string) bool {
+	w, err := s.load(db, project.TypeID)
+	if err != nil {
+		return false
+	}
+	for _, t := range w.transitions {
+		if t.FromStatus == project.Status && t.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 在事务中执行一次用户发起的流转
+func (s *ProjectWorkflowService) apply(tx *gorm.DB, project *models.Project, userID uint, req models.ProjectStatusUpdateRequest) (*models.ProjectWorkflowTransition, error) {
+	return s.transit(tx, project, userID, req, false)
+}
+
+// transit 执行一次流转：匹配规则、校验角色和必填要求、更新状态、记录历史并执行附加动作
+// fromReview 为多级审核推进的流转，不再校验角色；项目多级审核进行中时用户只能撤回，不能直接审批
+func (s *ProjectWorkflowService) transit(tx *gorm.DB, project *models.Project, userID uint, req models.ProjectStatusUpdateRequest, fromReview bool) (*models.ProjectWorkflowTransition, error) {
+	if req.Action == "" && req.Status == "" {
+		return nil, errors.New("请指定流转动作或目标状态")
+	}
+	w, err := s.load(tx, project.TypeID)
+	if err != nil {
+		return nil, err
+	}
+	if state := w.state(project.Status); state != nil && state.IsFinal {
+		return nil, fmt.Errorf("项目当前为「%s」，不能再变更状态", state.Name)
+	}
+
+	var candidates []*models.ProjectWorkflowTransition
+	for i := range w.transitions {
+		t := &w.transitions[i]
+		if t.FromStatus != project.Status {
+			continue
+		}
+		if (req.Action != "" && t.Action != req.Action) || (req.Status != "" && t.ToStatus != req.Status) {
+			continue
+		}
+		candidates = append(candidates, t)
+	}
+	if len(candidates) == 0 {
+		if req.Status != "" {
+			return nil, fmt.Errorf("%s的项目不能变更为%s", w.stateName(project.Status), w.stateName(req.Status))
+		}
+		return nil, fmt.Errorf("%s的项目不能执行该操作", w.stateName(project.Status))
+	}
+
+	var t *models.ProjectWorkflowTransition
+	if fromReview {
+		t = candidates[0]
+	} else {
+		roles := s.actorRoles(tx, project, userID)
+		for _, candidate := range candidates {
+			if transitionAllowed(candidate, roles) {
+				t = candidate
+				break
+			}
+		}
+		if t == nil {
+			return nil, fmt.Errorf("无权限%s此项目", candidates[0].Name)
+		}
+		if s.reviews.hasActiveChain(tx, project.ID) {
+			if !roles["leader"] || !transitionAllowed(t, map[string]bool{"leader": true}) {
+				return nil, errors.New("项目正在多级审核中，请通过审核任务处理")
+			}
+			if err := s.reviews.cancelActive(tx, project.ID, t.Name+"，审核终止"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	reason := strings.TrimSpace(req.StatusChangeReason)
+	if t.RequireReason && reason == "" {
+		return nil, fmt.Errorf("%s须填写原因", t.Name)
+	}
+	if missing := s.missingRequirements(tx, project, t); len(missing) > 0 {
+		return nil, fmt.Errorf("%s前请先完善：%s", t.Name, strings.Join(missing, "、"))
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": t.ToStatus}
+	for _, effect := range t.SideEffects {
+		switch effect {
+		case "mark_submitted":
+			updates["submitted_at"] = &now
+		case "mark_approved":
+			updates["approved_at"] = &now
+			if userID != 0 {
+				updates["approved_by"] = userID
+			}
+			updates["is_approved"] = true
+			updates["rejection_reason"] = ""
+		case "mark_rejected":
+			updates["rejection_reason"] = reason
+			updates["is_approved"] = false
+		}
+	}
+	// 按原状态条件更新，防止并发流转覆盖
+	result := tx.Model(&models.Project{}).Where("id = ? AND status = ?", project.ID, project.Status).Updates(updates)
+	if result.Error != nil {
+		log.Printf("更新项目状态失败: %v", result.Error)
+		return nil, errors.New("更新项目状态失败")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("项目状态已变化，请刷新后重试")
+	}
+
+	changeReason := reason
+	if changeReason == "" {
+		changeReason = t.Name
+	}
+	history := models.ProjectStatusHistory{
+		ProjectID:    project.ID,
+		OldStatus:    project.Status,
+		NewStatus:    t.ToStatus,
+		ChangeReason: changeReason,
+		Action:       t.Action,
+		ChangedAt:    now,
+	}
+	if userID != 0 {
+		history.ChangedBy = &userID
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		log.Printf("创建状态变更历史失败: %v", err)
+		return nil, errors.New("创建状态变更历史失败")
+	}
+
+	oldStatus := project.Status
+	project.Status = t.ToStatus
+	if err := s.runSideEffects(tx, project, t, w.stateName(oldStatus), w.stateName(t.ToStatus), userID, reason); err != nil {
+		return nil, err
+	}
+
+	log.Printf("项目状态流转 - 项目ID: %d, 动作: %s, %s -> %s, 操作人ID: %d", project.ID, t.Action, oldStatus, t.ToStatus, userID)
+
+	// 提交后按项目分类的审核流程生成多级审核任务
+	if t.Action == ProjectActionSubmit && !fromReview {
+		if err := s.reviews.start(tx, project, userID); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// runSideEffects 执行通知、创建里程碑等附加动作，通知失败只记录日志
+func (s *ProjectWorkflowService) runSideEffects(tx *gorm.DB, project *models.Project, t *models.ProjectWorkflowTransition, fromName, toName string, userID uint, reason string) error {
+	content := fmt.Sprintf("项目《%s》状态由「%s」变更为「%s」", project.Title, fromName, toName)
+	if reason != "" {
+		content += "，原因：" + reason
+	}
+
+	for _, effect := range t.SideEffects {
+		switch effect {
+		case "notify_team":
+			var memberIDs []uint
+			tx.Model(&models.ProjectMember{}).
+				Where("project_id = ? AND status = ? AND user_id IS NOT NULL", project.ID, ProjectMemberActive).
+				Pluck("user_id", &memberIDs)
+			recipients := map[uint]bool{project.StudentID: true}
+			for _, id := range memberIDs {
+				recipients[id] = true
+			}
+			for id := range recipients {
+				if id != userID {
+					s.notify(tx, project.ID, id, t.Name, content)
+				}
+			}
+		case "notify_teacher":
+			if project.TeacherID != 0 && project.TeacherID != userID {
+				s.notify(tx, project.ID, project.TeacherID, t.Name, content)
+			}
+		case "create_milestone":
+			title := t.MilestoneTitle
+			if title == "" {
+				title = toName
+			}
+			days := t.MilestoneDueDays
+			if days <= 0 {
+				days = defaultMilestoneDueDays
+			}
+			milestone := models.ProjectMilestone{
+				ProjectID: project.ID,
+				Title:     title,
+				DueDate:   time.Now().AddDate(0, 0, days),
+				Status:    "pending",
+			}
+			if err := tx.Create(&milestone).Error; err != nil {
+				log.Printf("创建里程碑失败: %v", err)
+				return errors.New("创建里程碑失败")
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ProjectWorkflowService) notify(db *gorm.DB, projectID, userID uint, title, content string) {
+	notification := models.ProjectNotification{
+		ProjectID: projectID,
+		UserID:    userID,
+		Type:      "status",
+		Title:     title,
+		Content:   content,
+		Priority:  "normal",
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		log.Printf("发送状态变更通知失败 - 项目ID: %d, 用户ID: %d, 错误: %v", projectID, userID, err)
+	}
+}
+
+// ForceStatus 管理员强制修改项目状态，跳过流转规则，目标状态须在项目流程中定义；写入状态历史和审计日志
+func (s *ProjectWorkflowService) ForceStatus(projectID uint, status, reason string, operatorID uint, ip, userAgent string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("请填写强制修改的原因")
+	}
+
+	var oldStatus string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var project models.Project
+		if err := tx.First(&project, projectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("项目不存在")
+			}
+			return err
+		}
+		w, err := s.load(tx, project.TypeID)
+		if err != nil {
+			return err
+		}
+		if w.state(status) == nil {
+			return fmt.Errorf("项目流程中没有状态 %s", status)
+		}
+		if project.Status == status {
+			return fmt.Errorf("项目已处于%s状态", w.stateName(status))
+		}
+		oldStatus = project.Status
+		if err := s.reviews.cancelActive(tx, project.ID, "管理员强制修改项目状态，审核终止"); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Project{}).Where("id = ? AND status = ?", project.ID, project.Status).Update("status", status)
+		if result.Error != nil {
+			log.Printf("强制更新项目状态失败: %v", result.Error)
+			return errors.New("强制更新项目状态失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("项目状态已变化，请刷新后重试")
+		}
+		return tx.Create(&models.ProjectStatusHistory{
+			ProjectID:    project.ID,
+			OldStatus:    oldStatus,
+			NewStatus:    status,
+			ChangeReason: reason,
+			Action:       ProjectActionForce,
+			IsForced:     true,
+			ChangedBy:    &operatorID,
+			ChangedAt:    time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.systemLogs.Record(models.SystemLog{
+		LogType:    "audit",
+		Operation:  "project_force_status",
+		UserID:     &operatorID,
+		Action:     "强制修改项目状态",
+		Details:    fmt.Sprintf("项目ID: %d, %s -> %s, 原因: %s", projectID, oldStatus, status, reason),
+		EntityType: "project",
+		EntityID:   strconv.FormatUint(uint64(projectID), 10),
+		IPAddress:  ip,
+		UserAgent:  userAgent,
+	})
+	log.Printf("项目状态强制更新成功 - 项目ID: %d, %s -> %s, 操作人ID: %d", projectID, oldStatus, status, operatorID)
+	return nil
+}
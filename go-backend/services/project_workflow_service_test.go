@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"yunmeng-backend/models"
+)
+
+func TestStudentStatsFollowWorkflowStates(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.ProjectMember{},
+		&models.ProjectWorkflowState{}, &models.ProjectWorkflowTransition{})
+	// 与默认流程一致：submitted、reviewing 都可以审批或驳回
+	for _, state := range []models.ProjectWorkflowState{
+		{StateKey: "draft", Name: "草稿", IsInitial: true},
+		{StateKey: "submitted", Name: "已提交"},
+		{StateKey: "reviewing", Name: "审核中"},
+		{StateKey: "approved", Name: "已立项"},
+		{StateKey: "rejected", Name: "已驳回"},
+	} {
+		db.Create(&state)
+	}
+	for _, transition := range []models.ProjectWorkflowTransition{
+		{Action: "submit", FromStatus: "draft", ToStatus: "submitted", SideEffects: models.JSONArray{"mark_submitted"}},
+		{Action: "start_review", FromStatus: "submitted", ToStatus: "reviewing"},
+		{Action: "approve", FromStatus: "submitted", ToStatus: "approved", SideEffects: models.JSONArray{"mark_approved"}},
+		{Action: "approve", FromStatus: "reviewing", ToStatus: "approved", SideEffects: models.JSONArray{"mark_approved"}},
+		{Action: "reject", FromStatus: "reviewing", ToStatus: "rejected", SideEffects: models.JSONArray{"mark_rejected"}},
+	} {
+		db.Create(&transition)
+	}
+
+	student := &models.User{Username: "leader", Email: "leader@yunmeng.test", Password: "x", Status: "active", RoleName: "student"}
+	db.Create(student)
+	for _, status := range []string{"draft", "submitted", "reviewing", "approved", "rejected"} {
+		db.Create(&models.Project{Title: status, StudentID: student.ID, Status: status})
+	}
+
+	stats, err := NewProjectService(db).GetStudentProjectStats(student.ID)
+	if err != nil {
+		t.Fatalf("获取统计失败: %v", err)
+	}
+	if stats.DraftProjects != 1 || stats.PendingProjects != 2 || stats.ApprovedProjects != 1 || stats.RejectedProjects != 1 {
+		t.Errorf("统计应按流程状态归类: %+v", stats)
+	}
+}
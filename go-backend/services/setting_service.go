@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type SettingService struct {
+	db *gorm.DB
+}
+
+func NewSettingService(db *gorm.DB) *SettingService {
+	return &SettingService{db: db}
+}
+
+// GetString 读取系统设置，不存在或为空时返回默认值
+func (s *SettingService) GetString(key, defaultValue string) string {
+	var setting models.SystemSetting
+	if err := s.db.Select("setting_value").Where("setting_key = ?", key).First(&setting).Error; err != nil {
+		return defaultValue
+	}
+	if strings.TrimSpace(setting.SettingValue) == "" {
+		return defaultValue
+	}
+	return strings.TrimSpace(setting.SettingValue)
+}
+
+// UpdatedAt 读取系统设置最近一次修改的时间
+func (s *SettingService) UpdatedAt(key string) (time.Time, bool) {
+	var setting models.SystemSetting
+	if err := s.db.Select("updated_at").Where("setting_key = ?", key).First(&setting).Error; err != nil {
+		return time.Time{}, false
+	}
+	return setting.UpdatedAt, true
+}
+
+// GetInt 读取整数类型的系统设置
+func (s *SettingService) GetInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(s.GetString(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetBool 读取布尔类型的系统设置
+func (s *SettingService) GetBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(s.GetString(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetList 读取逗号分隔的系统设置，去除空项
+func (s *SettingService) GetList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(s.GetString(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
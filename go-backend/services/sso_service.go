@@ -0,0 +1,385 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSSOAccountNotFound = errors.New("该统一身份认证账号尚未开通本系统账号，请联系管理员")
+	ErrSSOAccountConflict = errors.New("本地账号已绑定其他统一身份认证账号，请联系管理员")
+	ErrSSONoRole          = errors.New("统一身份认证账号未匹配到任何角色，请联系管理员")
+	ErrSSOStateInvalid    = errors.New("登录状态无效或已过期，请重新发起登录")
+	ErrSSOLinkInvalid     = errors.New("账号关联确认已过期，请重新登录")
+)
+
+// ssoLinkConfirmTTL 关联已有账号时等待用户输入本地密码确认的最长时间
+const ssoLinkConfirmTTL = 10 * time.Minute
+
+// SSOLinkConfirmError 匹配到的已有账号需要用户输入本地密码确认后才能关联
+type SSOLinkConfirmError struct {
+	Username  string
+	LinkToken string
+}
+
+func (e *SSOLinkConfirmError) Error() string {
+	return "该账号已在使用本地密码或拥有管理权限，请输入本地密码确认关联"
+}
+
+// ssoLinkPurpose 关联确认令牌用途，绑定认证提供方
+func ssoLinkPurpose(providerKey string) string {
+	return "sso_link:" + providerKey
+}
+
+// ssoProfileFields 可从身份平台属性同步的资料字段 -> user_profiles 列名
+var ssoProfileFields = map[string]string{
+	"realName":   "real_name",
+	"phone":      "phone",
+	"studentId":  "student_id",
+	"department": "department",
+}
+
+// ssoUserFields 可从身份平台属性同步的账号字段 -> users 列名
+var ssoUserFields = map[string]string{
+	"department": "department",
+	"grade":      "grade",
+	"major":      "major",
+	"title":      "title",
+}
+
+// SSOService 统一身份认证账号关联：首次登录时关联或自动创建本地账号，每次登录同步资料和角色
+type SSOService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewSSOService(db *gorm.DB) *SSOService {
+	return &SSOService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// CreateLoginState 记录一次统一身份认证发起，nonce 同时写入状态令牌和浏览器Cookie
+func (s *SSOService) CreateLoginState(providerKey, nonce string, ttl time.Duration, ipAddress, userAgent string) error {
+	state := models.SSOLoginState{
+		NonceHash: utils.HashToken(nonce),
+		Provider:  providerKey,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.db.Create(&state).Error; err != nil {
+		log.Printf("记录统一身份认证发起失败 - 认证方式: %s, 错误: %v", providerKey, err)
+		return err
+	}
+	return nil
+}
+
+// ConsumeLoginState 回调时消费发起记录，每个 nonce 只能使用一次
+func (s *SSOService) ConsumeLoginState(providerKey, nonce string) error {
+	now := time.Now()
+	// 条件更新保证并发回调中只有一个能成功
+	result := s.db.Model(&models.SSOLoginState{}).
+		Where("nonce_hash = ? AND provider = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(nonce), providerKey, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSSOStateInvalid
+	}
+	return nil
+}
+
+// ResolveUser 根据身份平台返回的身份得到本地账号：
+// 已绑定的直接使用；未绑定时按 mapping.LinkBy 关联已有账号，仍找不到且允许自动开通时创建账号。
+func (s *SSOService) ResolveUser(providerKey string, mapping utils.SSOMapping, identity *utils.SSOIdentity, ipAddress, userAgent string) (*models.User, error) {
+	var link models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", providerKey, identity.Subject).First(&link).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var user models.User
+	if err == nil {
+		if err := s.db.First(&user, link.UserID).Error; err != nil {
+			return nil, ErrSSOAccountNotFound
+		}
+	} else {
+		found, err := s.findLinkTarget(mapping, identity, &user)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case found:
+			var count int64
+			s.db.Model(&models.UserIdentity{}).Where("provider = ? AND user_id = ?", providerKey, user.ID).Count(&count)
+			if count > 0 {
+				return nil, ErrSSOAccountConflict
+			}
+			// 用户名、邮箱等字段可能被他人在身份平台上占用，已在使用的账号和特权账号须由本人确认
+			if s.linkNeedsConfirm(&user) {
+				token, err := utils.GenerateActionToken(user.ID, ssoLinkPurpose(providerKey), identity.Subject, ssoLinkConfirmTTL)
+				if err != nil {
+					return nil, errors.New("生成关联确认失败")
+				}
+				s.systemLogs.RecordSecurity("sso_link", "统一身份认证关联已有账号待本人确认", "pending", &user.ID,
+					fmt.Sprintf("认证方式: %s, 身份标识: %s, 关联字段: %s", providerKey, identity.Subject, mapping.LinkBy), ipAddress, userAgent)
+				return nil, &SSOLinkConfirmError{Username: user.Username, LinkToken: token}
+			}
+			link = models.UserIdentity{UserID: user.ID, Provider: providerKey, Subject: identity.Subject}
+			if err := s.db.Create(&link).Error; err != nil {
+				log.Printf("绑定统一身份认证账号失败 - 用户ID: %d, 错误: %v", user.ID, err)
+				return nil, errors.New("绑定统一身份认证账号失败")
+			}
+			s.systemLogs.RecordSecurity("sso_link", "统一身份认证账号关联已有账号", "success", &user.ID,
+				fmt.Sprintf("认证方式: %s, 身份标识: %s, 关联字段: %s", providerKey, identity.Subject, mapping.LinkBy), ipAddress, userAgent)
+		case mapping.AutoProvision:
+			if err := s.provision(providerKey, mapping, identity, &user, &link); err != nil {
+				return nil, err
+			}
+			s.systemLogs.RecordSecurity("sso_provision", "统一身份认证首次登录自动开通账号", "success", &user.ID,
+				fmt.Sprintf("认证方式: %s, 身份标识: %s, 用户名: %s", providerKey, identity.Subject, user.Username), ipAddress, userAgent)
+		default:
+			s.systemLogs.RecordSecurity("sso_login", "统一身份认证账号未开通", "failed", nil,
+				fmt.Sprintf("认证方式: %s, 身份标识: %s", providerKey, identity.Subject), ipAddress, userAgent)
+			return nil, ErrSSOAccountNotFound
+		}
+	}
+
+	s.syncUser(&user, mapping, identity)
+
+	now := time.Now()
+	attributes, _ := json.Marshal(map[string]interface{}{"attributes": identity.Attributes, "groups": identity.Groups})
+	s.db.Model(&models.UserIdentity{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+		"attributes":    string(attributes),
+		"last_login_at": now,
+	})
+	return &user, nil
+}
+
+// findLinkTarget 按关联字段查找尚未绑定的已有账号
+func (s *SSOService) findLinkTarget(mapping utils.SSOMapping, identity *utils.SSOIdentity, user *models.User) (bool, error) {
+	value := mapping.Attribute(identity, mapping.LinkBy)
+	if value == "" {
+		return false, nil
+	}
+
+	var err error
+	switch mapping.LinkBy {
+	case "username":
+		err = s.db.Where("username = ?", value).First(user).Error
+	case "email":
+		err = s.db.Where("email = ?", value).First(user).Error
+	case "studentId":
+		err = s.db.Where("id = (?)", s.db.Model(&models.UserProfile{}).Select("user_id").Where("student_id = ?", value).Limit(1)).
+			First(user).Error
+	default:
+		return false, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// linkNeedsConfirm 已有账号是否须本人确认后才能关联：
+// 已设置过自己的本地密码的在用账号，或拥有学生、教师以外角色的账号
+func (s *SSOService) linkNeedsConfirm(user *models.User) bool {
+	if user.Status == "active" && !user.MustChangePassword {
+		return true
+	}
+	var count int64
+	s.db.Model(&models.UserRole{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.role_key NOT IN ?", user.ID, basicRoleKeys).
+		Count(&count)
+	return count > 0
+}
+
+// PendingLink 解析关联确认令牌，返回待关联的本地账号和身份标识
+func (s *SSOService) PendingLink(providerKey, token string) (*models.User, string, error) {
+	claims, err := utils.ParseActionToken(token, ssoLinkPurpose(providerKey))
+	if err != nil {
+		return nil, "", ErrSSOLinkInvalid
+	}
+	var user models.User
+	if err := s.db.First(&user, claims.UserID).Error; err != nil {
+		return nil, "", ErrSSOLinkInvalid
+	}
+	return &user, claims.ID, nil
+}
+
+// ConfirmLink 用户输入本地密码确认后建立关联（密码由调用方校验）
+func (s *SSOService) ConfirmLink(providerKey, subject string, user *models.User, ipAddress, userAgent string) error {
+	var count int64
+	s.db.Model(&models.UserIdentity{}).
+		Where("provider = ? AND (subject = ? OR user_id = ?)", providerKey, subject, user.ID).
+		Count(&count)
+	if count > 0 {
+		return ErrSSOAccountConflict
+	}
+	link := models.UserIdentity{UserID: user.ID, Provider: providerKey, Subject: subject}
+	if err := s.db.Create(&link).Error; err != nil {
+		log.Printf("绑定统一身份认证账号失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return errors.New("绑定统一身份认证账号失败")
+	}
+	now := time.Now()
+	s.db.Model(&link).Update("last_login_at", now)
+	s.systemLogs.RecordSecurity("sso_link", "本人确认后关联统一身份认证账号", "success", &user.ID,
+		fmt.Sprintf("认证方式: %s, 身份标识: %s", providerKey, subject), ipAddress, userAgent)
+	return nil
+}
+
+// provision 自动开通账号：创建账号、资料、角色和绑定关系。
+// 本地密码为随机密码，账号只能通过统一身份认证或管理员重置密码后登录。
+func (s *SSOService) provision(providerKey string, mapping utils.SSOMapping, identity *utils.SSOIdentity, user *models.User, link *models.UserIdentity) error {
+	roleKeys := mapping.Roles(identity)
+	if len(roleKeys) == 0 {
+		return ErrSSONoRole
+	}
+	var roles []models.Role
+	s.db.Where("role_key IN ?", roleKeys).Find(&roles)
+	if len(roles) == 0 {
+		return ErrSSONoRole
+	}
+
+	username := mapping.Attribute(identity, "username")
+	if username == "" {
+		username = identity.Subject
+	}
+	email := mapping.Attribute(identity, "email")
+	if email == "" {
+		email = fmt.Sprintf("%s@%s.sso.local", identity.Subject, providerKey)
+	}
+
+	var count int64
+	s.db.Unscoped().Model(&models.User{}).Where("username = ? OR email = ?", username, email).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("用户名或邮箱已被本地账号使用（%s），请联系管理员绑定", username)
+	}
+
+	policy := NewPasswordPolicyService(s.db)
+	hashedPassword, err := utils.HashPassword(policy.GeneratePassword())
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		*user = models.User{
+			Username:          username,
+			Password:          hashedPassword,
+			Email:             email,
+			Status:            "active",
+			PasswordChangedAt: &now,
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.UserProfile{UserID: user.ID}).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+		}
+		*link = models.UserIdentity{UserID: user.ID, Provider: providerKey, Subject: identity.Subject}
+		return tx.Create(link).Error
+	})
+	if err != nil {
+		log.Printf("统一身份认证自动开通账号失败 - 身份标识: %s, 错误: %v", identity.Subject, err)
+		return errors.New("自动开通账号失败")
+	}
+
+	log.Printf("统一身份认证自动开通账号 - 用户ID: %d, 用户名: %s", user.ID, user.Username)
+	return nil
+}
+
+// syncUser 用身份平台属性更新资料字段（空值不覆盖），并补充组映射到的角色（不移除已有角色）
+func (s *SSOService) syncUser(user *models.User, mapping utils.SSOMapping, identity *utils.SSOIdentity) {
+	profileUpdates := make(map[string]interface{})
+	for field, column := range ssoProfileFields {
+		if value := mapping.Attribute(identity, field); value != "" {
+			profileUpdates[column] = value
+		}
+	}
+	if len(profileUpdates) > 0 {
+		if err := s.db.Model(&models.UserProfile{}).Where("user_id = ?", user.ID).Updates(profileUpdates).Error; err != nil {
+			log.Printf("同步统一身份认证资料失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		}
+	}
+
+	userUpdates := make(map[string]interface{})
+	for field, column := range ssoUserFields {
+		if value := mapping.Attribute(identity, field); value != "" {
+			userUpdates[column] = value
+		}
+	}
+	if len(userUpdates) > 0 {
+		if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(userUpdates).Error; err != nil {
+			log.Printf("同步统一身份认证账号信息失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		}
+	}
+
+	if len(mapping.GroupRoles) == 0 || len(identity.Groups) == 0 {
+		return
+	}
+	var roleKeys []string
+	for _, group := range identity.Groups {
+		if roleKey, ok := mapping.GroupRoles[group]; ok {
+			roleKeys = append(roleKeys, roleKey)
+		}
+	}
+	if len(roleKeys) == 0 {
+		return
+	}
+
+	var missing []models.Role
+	s.db.Where("role_key IN ?", roleKeys).
+		Where("id NOT IN (?)", s.db.Model(&models.UserRole{}).Select("role_id").Where("user_id = ?", user.ID)).
+		Find(&missing)
+	for _, role := range missing {
+		if err := s.db.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
+			log.Printf("同步统一身份认证角色失败 - 用户ID: %d, 角色: %s, 错误: %v", user.ID, role.RoleKey, err)
+			continue
+		}
+		log.Printf("统一身份认证组映射授予角色 - 用户ID: %d, 角色: %s", user.ID, role.RoleKey)
+	}
+}
+
+// GetUserIdentities 获取用户绑定的统一身份认证账号
+func (s *SSOService) GetUserIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := s.db.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error
+	return identities, err
+}
+
+// UnlinkIdentity 解除统一身份认证账号绑定（关联错误时由管理员处理，下次登录会重新关联或开通）
+func (s *SSOService) UnlinkIdentity(userID, identityID, operatorID uint, ipAddress, userAgent string) error {
+	var identity models.UserIdentity
+	if err := s.db.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+		return errors.New("绑定关系不存在")
+	}
+	if err := s.db.Delete(&identity).Error; err != nil {
+		return err
+	}
+	s.systemLogs.RecordSecurity("sso_unlink", "解除统一身份认证账号绑定", "success", &userID,
+		fmt.Sprintf("认证方式: %s, 身份标识: %s, 操作人ID: %d", identity.Provider, identity.Subject, operatorID), ipAddress, userAgent)
+	return nil
+}
+
+// SSOLoginMethod 会话中记录的统一身份认证登录方式
+func SSOLoginMethod(providerKey string) string {
+	return LoginMethodSSOPrefix + providerKey
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+)
+
+func TestSSOLoginStateIsSingleUse(t *testing.T) {
+	db := newTestDB(t, &models.SSOLoginState{}, &models.SystemLog{})
+	service := NewSSOService(db)
+
+	if err := service.CreateLoginState("campus", "nonce-1", time.Minute, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("记录发起失败: %v", err)
+	}
+	if err := service.ConsumeLoginState("other", "nonce-1"); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("其他认证方式不能消费，实际: %v", err)
+	}
+	if err := service.ConsumeLoginState("campus", "nonce-1"); err != nil {
+		t.Fatalf("首次回调应成功: %v", err)
+	}
+	if err := service.ConsumeLoginState("campus", "nonce-1"); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("重放应失败，实际: %v", err)
+	}
+
+	if err := service.CreateLoginState("campus", "nonce-2", -time.Second, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("记录发起失败: %v", err)
+	}
+	if err := service.ConsumeLoginState("campus", "nonce-2"); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("过期记录应失败，实际: %v", err)
+	}
+}
+
+func newSSOLinkTestDB(t *testing.T) *SSOService {
+	t.Helper()
+	useTestSigningKey(t)
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.Role{}, &models.UserRole{},
+		&models.UserIdentity{}, &models.SystemLog{})
+	for _, key := range []string{"student", "teacher", "admin"} {
+		db.Create(&models.Role{RoleKey: key, RoleName: key})
+	}
+	return NewSSOService(db)
+}
+
+func createSSOLinkTestUser(t *testing.T, service *SSOService, username, roleKey string, mustChange bool) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@yunmeng.test", Password: "x", Status: "active", RoleName: roleKey, MustChangePassword: mustChange}
+	if err := service.db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	var role models.Role
+	service.db.Where("role_key = ?", roleKey).First(&role)
+	service.db.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID})
+	return user
+}
+
+func TestSSOAutoLinkRequiresConfirmation(t *testing.T) {
+	service := newSSOLinkTestDB(t)
+	mapping := utils.SSOMapping{LinkBy: "username", Attributes: map[string]string{"username": "uid"}}
+	identity := func(uid string) *utils.SSOIdentity {
+		return &utils.SSOIdentity{Subject: "sub-" + uid, Attributes: map[string]string{"uid": uid}}
+	}
+
+	// 尚未设置自己密码的学生账号（导入、LDAP开通）可直接关联
+	fresh := createSSOLinkTestUser(t, service, "fresh", "student", true)
+	user, err := service.ResolveUser("campus", mapping, identity("fresh"), "127.0.0.1", "test")
+	if err != nil || user.ID != fresh.ID {
+		t.Fatalf("未启用本地密码的学生账号应自动关联: %v", err)
+	}
+
+	// 已在使用本地密码的账号须确认
+	createSSOLinkTestUser(t, service, "active", "student", false)
+	var confirm *SSOLinkConfirmError
+	if _, err := service.ResolveUser("campus", mapping, identity("active"), "127.0.0.1", "test"); !errors.As(err, &confirm) {
+		t.Fatalf("已在使用本地密码的账号应要求确认，实际: %v", err)
+	}
+
+	// 特权账号即使未设置密码也须确认
+	admin := createSSOLinkTestUser(t, service, "admin1", "admin", true)
+	if _, err := service.ResolveUser("campus", mapping, identity("admin1"), "127.0.0.1", "test"); !errors.As(err, &confirm) {
+		t.Fatalf("管理员账号应要求确认，实际: %v", err)
+	}
+	var count int64
+	service.db.Model(&models.UserIdentity{}).Where("user_id = ?", admin.ID).Count(&count)
+	if count != 0 {
+		t.Fatal("确认前不应建立关联")
+	}
+
+	// 确认后建立关联，之后登录直接使用绑定关系
+	pending, subject, err := service.PendingLink("campus", confirm.LinkToken)
+	if err != nil || pending.ID != admin.ID || subject != "sub-admin1" {
+		t.Fatalf("解析关联确认令牌失败: %v", err)
+	}
+	if _, _, err := service.PendingLink("other", confirm.LinkToken); !errors.Is(err, ErrSSOLinkInvalid) {
+		t.Fatalf("其他认证方式不能使用该令牌，实际: %v", err)
+	}
+	if err := service.ConfirmLink("campus", subject, pending, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("确认关联失败: %v", err)
+	}
+	if err := service.ConfirmLink("campus", subject, pending, "127.0.0.1", "test"); !errors.Is(err, ErrSSOAccountConflict) {
+		t.Fatalf("重复确认应失败，实际: %v", err)
+	}
+	user, err = service.ResolveUser("campus", mapping, identity("admin1"), "127.0.0.1", "test")
+	if err != nil || user.ID != admin.ID {
+		t.Fatalf("关联后应直接登录: %v", err)
+	}
+}
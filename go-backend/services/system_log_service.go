@@ -0,0 +1,43 @@
+package services
+
+import (
+	"log"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type SystemLogService struct {
+	db *gorm.DB
+}
+
+func NewSystemLogService(db *gorm.DB) *SystemLogService {
+	return &SystemLogService{db: db}
+}
+
+// Record 写入系统日志，写入失败只记录到标准日志，不影响业务流程
+func (s *SystemLogService) Record(entry models.SystemLog) {
+	if entry.LogType == "" {
+		entry.LogType = "info"
+	}
+	if entry.Status == "" {
+		entry.Status = "success"
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		log.Printf("写入系统日志失败 - 操作: %s, 错误: %v", entry.Operation, err)
+	}
+}
+
+// RecordSecurity 写入安全类系统日志
+func (s *SystemLogService) RecordSecurity(operation, action, status string, userID *uint, details, ipAddress, userAgent string) {
+	s.Record(models.SystemLog{
+		LogType:   "security",
+		Operation: operation,
+		Status:    status,
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"yunmeng-backend/utils"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// newTestDB 创建临时 SQLite 数据库并迁移给定模型。
+// SQLite 不支持 MySQL 的 enum/json 类型，迁移前改为 text，未加引号的默认值补上引号。
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=off"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	visited := make(map[*schema.Schema]bool)
+	for _, table := range tables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(table); err != nil {
+			t.Fatalf("解析模型失败: %v", err)
+		}
+		sqliteCompatible(stmt.Schema, visited)
+		if err := db.AutoMigrate(table); err != nil {
+			t.Fatalf("迁移测试表失败: %v", err)
+		}
+	}
+	return db
+}
+
+// sqliteCompatible 调整模型及其关联模型的字段类型（关联表会随模型一起迁移）
+func sqliteCompatible(s *schema.Schema, visited map[*schema.Schema]bool) {
+	if s == nil || visited[s] {
+		return
+	}
+	visited[s] = true
+	for _, field := range s.Fields {
+		dataType := strings.ToLower(string(field.DataType))
+		if strings.HasPrefix(dataType, "enum") || dataType == "json" || strings.Contains(dataType, "longtext") {
+			field.DataType = "text"
+			if value, ok := field.TagSettings["DEFAULT"]; ok && !strings.HasPrefix(value, "'") {
+				field.DefaultValue = "'" + value + "'"
+			}
+		}
+	}
+	for _, relation := range s.Relationships.Relations {
+		sqliteCompatible(relation.FieldSchema, visited)
+		if relation.JoinTable != nil {
+			sqliteCompatible(relation.JoinTable, visited)
+		}
+	}
+}
+
+// useTestSigningKey 使用测试密钥签发令牌
+func useTestSigningKey(t *testing.T) {
+	t.Helper()
+	if err := utils.SetSigningKeys([]*utils.SigningKey{utils.NewHMACKey("test", []byte("test-secret"))}, "test"); err != nil {
+		t.Fatalf("设置签名密钥失败: %v", err)
+	}
+}
+
+// capturedMail 邮件捕获服务器收到的一封邮件
+type capturedMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// mailCatcher 本地SMTP邮件捕获服务器（类似 MailHog/Mailpit），只接收不投递
+type mailCatcher struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []capturedMail
+}
+
+// startMailCatcher 启动邮件捕获服务器，并把全局邮件发送器指向它，测试结束后恢复
+func startMailCatcher(t *testing.T) *mailCatcher {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动邮件捕获服务器失败: %v", err)
+	}
+	catcher := &mailCatcher{listener: listener}
+	go catcher.serve()
+
+	previous := utils.GetMailSender()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	utils.SetMailSender(&utils.SMTPSender{Host: host, Port: port, From: "noreply@yunmeng.test"})
+	t.Cleanup(func() {
+		utils.SetMailSender(previous)
+		listener.Close()
+	})
+	return catcher
+}
+
+// Messages 已收到的邮件
+func (m *mailCatcher) Messages() []capturedMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]capturedMail(nil), m.messages...)
+}
+
+func (m *mailCatcher) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+// handle 实现 net/smtp 客户端用到的最小SMTP会话
+func (m *mailCatcher) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 mailcatcher")
+	var mail capturedMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 mailcatcher")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			mail = capturedMail{From: strings.Trim(strings.TrimSpace(line)[10:], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			mail.To = append(mail.To, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			mail.Data = data.String()
+			m.mu.Lock()
+			m.messages = append(m.messages, mail)
+			m.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorChallengeTTL         = 5 * time.Minute
+	twoFactorChallengeMaxAttempts = 5
+	twoFactorRecoveryCodeCount    = 10
+)
+
+var (
+	ErrTwoFactorInvalidCode      = errors.New("验证码错误")
+	ErrTwoFactorChallengeInvalid = errors.New("验证已过期，请重新登录")
+	ErrTwoFactorTooManyAttempts  = errors.New("验证码错误次数过多，请稍后再试")
+)
+
+type TwoFactorService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	systemLogs *SystemLogService
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{
+		db:         db,
+		settings:   NewSettingService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// IsEnforced 角色是否被要求强制启用两步验证（system_settings.two_factor_required_roles）
+func (s *TwoFactorService) IsEnforced(role string) bool {
+	for _, r := range strings.Split(s.settings.GetString("two_factor_required_roles", ""), ",") {
+		if strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled 用户是否已启用两步验证
+func (s *TwoFactorService) IsEnabled(userID uint) bool {
+	var count int64
+	s.db.Model(&models.UserTwoFactor{}).Where("user_id = ? AND enabled = ?", userID, true).Count(&count)
+	return count > 0
+}
+
+// GetStatus 获取用户两步验证状态
+func (s *TwoFactorService) GetStatus(userID uint, role string) (*models.TwoFactorStatusResponse, error) {
+	status := &models.TwoFactorStatusResponse{Enforced: s.IsEnforced(role)}
+
+	var record models.UserTwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return status, nil
+		}
+		return nil, err
+	}
+
+	status.Enabled = record.Enabled
+	status.EnabledAt = record.EnabledAt
+	s.db.Model(&models.TwoFactorRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&status.RemainingRecoveryCodes)
+	return status, nil
+}
+
+// Setup 生成新的TOTP密钥（尚未启用，需提交一次验证码确认）
+func (s *TwoFactorService) Setup(userID uint, username string) (*models.TwoFactorSetupResponse, error) {
+	if s.IsEnabled(userID) {
+		return nil, errors.New("两步验证已启用，如需更换请先关闭")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("生成密钥失败")
+	}
+
+	record := models.UserTwoFactor{UserID: userID, Secret: secret}
+	if err := s.db.Where("user_id = ?", userID).Assign(map[string]interface{}{
+		"secret":         secret,
+		"enabled":        false,
+		"last_used_step": 0,
+	}).FirstOrCreate(&record).Error; err != nil {
+		log.Printf("保存两步验证密钥失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, errors.New("保存两步验证密钥失败")
+	}
+
+	issuer := s.settings.GetString("system_name", "云梦科研竞赛管理系统")
+	return &models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(issuer, username, secret),
+	}, nil
+}
+
+// Enable 校验验证码后启用两步验证，返回一次性恢复码（仅展示这一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	var record models.UserTwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&record).Error; err != nil {
+		return nil, errors.New("请先获取两步验证密钥")
+	}
+	if record.Enabled {
+		return nil, errors.New("两步验证已启用")
+	}
+	if !s.verifyTOTP(&record, code) {
+		return nil, ErrTwoFactorInvalidCode
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.UserTwoFactor{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("启用两步验证失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, errors.New("启用两步验证失败")
+	}
+
+	// 强制启用两步验证的受限会话在绑定完成后恢复正常
+	NewAuthService(s.db).ClearSessionRestriction(userID, SessionRestrictionTwoFactorSetup)
+
+	s.systemLogs.RecordSecurity("two_factor_enable", "启用两步验证", "success", &userID, "", "", "")
+	log.Printf("两步验证已启用 - 用户ID: %d", userID)
+	return codes, nil
+}
+
+// Disable 关闭两步验证（角色强制要求时不允许关闭）
+func (s *TwoFactorService) Disable(userID uint, role, code string) error {
+	if s.IsEnforced(role) {
+		return errors.New("当前角色要求启用两步验证，不能关闭")
+	}
+	if err := s.verifyCodeLimited(userID, code); err != nil {
+		return err
+	}
+	if err := s.removeTwoFactor(userID); err != nil {
+		return err
+	}
+
+	s.systemLogs.RecordSecurity("two_factor_disable", "关闭两步验证", "success", &userID, "", "", "")
+	log.Printf("两步验证已关闭 - 用户ID: %d", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.verifyCodeLimited(userID, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("重新生成恢复码失败 - 用户ID: %d, 错误: %v", userID, err)
+		return nil, errors.New("重新生成恢复码失败")
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的两步验证（用户丢失设备且无恢复码时使用）
+func (s *TwoFactorService) Reset(userID, operatorID uint) error {
+	if err := s.removeTwoFactor(userID); err != nil {
+		return err
+	}
+
+	s.systemLogs.RecordSecurity("two_factor_reset", "管理员重置两步验证", "success", &operatorID,
+		fmt.Sprintf("目标用户ID: %d", userID), "", "")
+	log.Printf("两步验证已被管理员重置 - 用户ID: %d, 操作人ID: %d", userID, operatorID)
+	return nil
+}
+
+// VerifyCode 校验TOTP口令或恢复码
+func (s *TwoFactorService) VerifyCode(userID uint, code string) error {
+	var record models.UserTwoFactor
+	if err := s.db.Where("user_id = ? AND enabled = ?", userID, true).First(&record).Error; err != nil {
+		return errors.New("未启用两步验证")
+	}
+
+	code = strings.TrimSpace(code)
+	if strings.Contains(code, "-") {
+		// 恢复码：条件更新保证只能使用一次
+		result := s.db.Model(&models.TwoFactorRecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(strings.ToLower(code))).
+			Update("used_at", time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return ErrTwoFactorInvalidCode
+		}
+		s.systemLogs.RecordSecurity("two_factor_recovery", "使用两步验证恢复码", "success", &userID, "", "", "")
+		return nil
+	}
+
+	if !s.verifyTOTP(&record, code) {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+// verifyCodeLimited 已登录用户修改两步验证设置时校验验证码，与登录挑战一样最多连续输错
+// twoFactorChallengeMaxAttempts 次，达到上限后锁定 twoFactorChallengeTTL，校验成功时清零
+func (s *TwoFactorService) verifyCodeLimited(userID uint, code string) error {
+	var record models.UserTwoFactor
+	if err := s.db.Where("user_id = ? AND enabled = ?", userID, true).First(&record).Error; err != nil {
+		return errors.New("未启用两步验证")
+	}
+	if record.LockedUntil != nil && time.Now().Before(*record.LockedUntil) {
+		return ErrTwoFactorTooManyAttempts
+	}
+
+	if err := s.VerifyCode(userID, code); err != nil {
+		s.db.Model(&models.UserTwoFactor{}).Where("user_id = ?", userID).
+			Update("failed_count", gorm.Expr("failed_count + 1"))
+		locked := s.db.Model(&models.UserTwoFactor{}).
+			Where("user_id = ? AND failed_count >= ?", userID, twoFactorChallengeMaxAttempts).
+			Updates(map[string]interface{}{"failed_count": 0, "locked_until": time.Now().Add(twoFactorChallengeTTL)})
+		if locked.Error == nil && locked.RowsAffected > 0 {
+			s.systemLogs.RecordSecurity("two_factor_locked", "两步验证码连续输错，暂停校验", "failed", &userID, "", "", "")
+			return ErrTwoFactorTooManyAttempts
+		}
+		return err
+	}
+
+	if record.FailedCount > 0 || record.LockedUntil != nil {
+		s.db.Model(&models.UserTwoFactor{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"failed_count": 0, "locked_until": nil})
+	}
+	return nil
+}
+
+// ChallengeTTL 第二步验证挑战的有效期
+func (s *TwoFactorService) ChallengeTTL() time.Duration {
+	return twoFactorChallengeTTL
+}
+
+// CreateChallenge 密码校验通过后创建第二步验证挑战，返回挑战令牌
+func (s *TwoFactorService) CreateChallenge(user *models.User, role, loginMethod, ipAddress, userAgent string) (string, error) {
+	rawToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	challenge := models.TwoFactorChallenge{
+		TokenHash:   utils.HashToken(rawToken),
+		UserID:      user.ID,
+		Role:        role,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		ExpiresAt:   time.Now().Add(twoFactorChallengeTTL),
+		LoginMethod: loginMethod,
+	}
+	if err := s.db.Create(&challenge).Error; err != nil {
+		log.Printf("创建两步验证挑战失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return "", errors.New("创建两步验证挑战失败")
+	}
+	return rawToken, nil
+}
+
+// VerifyChallenge 校验第二步验证，成功后挑战作废并返回挑战记录
+func (s *TwoFactorService) VerifyChallenge(rawToken, code string) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	if err := s.db.Where("token_hash = ?", utils.HashToken(rawToken)).First(&challenge).Error; err != nil {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= twoFactorChallengeMaxAttempts {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+
+	if err := s.VerifyCode(challenge.UserID, code); err != nil {
+		s.db.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1"))
+		return &challenge, err
+	}
+
+	result := s.db.Model(&models.TwoFactorChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, ErrTwoFactorChallengeInvalid
+	}
+	return &challenge, nil
+}
+
+// verifyTOTP 校验TOTP口令，同一时间步的口令只能使用一次
+func (s *TwoFactorService) verifyTOTP(record *models.UserTwoFactor, code string) bool {
+	step := utils.ValidateTOTP(record.Secret, code, time.Now())
+	if step < 0 {
+		return false
+	}
+
+	result := s.db.Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", record.UserID, step).
+		Update("last_used_step", step)
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, twoFactorRecoveryCodeCount)
+	for i := 0; i < twoFactorRecoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		record := models.TwoFactorRecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)}
+		if err := tx.Create(&record).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (s *TwoFactorService) removeTwoFactor(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error
+	})
+}
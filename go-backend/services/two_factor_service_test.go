@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+)
+
+func TestTwoFactorManagementCodeAttemptsAreLimited(t *testing.T) {
+	db := newTestDB(t, &models.UserTwoFactor{}, &models.TwoFactorRecoveryCode{}, &models.SystemSetting{}, &models.SystemLog{})
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	db.Create(&models.UserTwoFactor{UserID: 1, Secret: secret, Enabled: true})
+	service := NewTwoFactorService(db)
+
+	for i := 1; i < twoFactorChallengeMaxAttempts; i++ {
+		if _, err := service.RegenerateRecoveryCodes(1, "000000"); !errors.Is(err, ErrTwoFactorInvalidCode) {
+			t.Fatalf("第%d次输错应提示验证码错误，实际: %v", i, err)
+		}
+	}
+	if err := service.Disable(1, "student", "000000"); !errors.Is(err, ErrTwoFactorTooManyAttempts) {
+		t.Fatalf("连续输错%d次后应暂停校验，实际: %v", twoFactorChallengeMaxAttempts, err)
+	}
+
+	// 锁定期间正确的验证码也不再校验
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err := service.Disable(1, "student", code); !errors.Is(err, ErrTwoFactorTooManyAttempts) {
+		t.Fatalf("锁定期间不应校验验证码，实际: %v", err)
+	}
+	if !service.IsEnabled(1) {
+		t.Fatalf("锁定期间两步验证不应被关闭")
+	}
+
+	// 锁定到期后可以继续，校验成功即关闭
+	db.Model(&models.UserTwoFactor{}).Where("user_id = ?", 1).Update("locked_until", time.Now().Add(-time.Second))
+	if err := service.Disable(1, "student", code); err != nil {
+		t.Fatalf("锁定到期后应能关闭两步验证: %v", err)
+	}
+	if service.IsEnabled(1) {
+		t.Errorf("两步验证应已关闭")
+	}
+}
@@ -0,0 +1,562 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"yunmeng-backend/models"
+	"yunmeng-backend/utils"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// userImportMaxRows 单次导入的最大数据行数
+const userImportMaxRows = 10000
+
+// DefaultUserGrades 年级设置（user_grades）的默认值，按升级顺序排列
+const DefaultUserGrades = "大一,大二,大三,大四,研一,研二,研三"
+
+var ErrUserImportInvalid = errors.New("导入数据存在错误，未导入任何用户")
+
+// userImportColumns 表头 -> 字段，支持字段名（不区分大小写）和中文列名
+var userImportColumns = map[string]string{
+	"username": "username", "用户名": "username", "账号": "username",
+	"email": "email", "邮箱": "email",
+	"realname": "realName", "姓名": "realName",
+	"phone": "phone", "手机号": "phone", "电话": "phone",
+	"department": "department", "院系": "department", "学院": "department",
+	"title": "title", "职称": "title",
+	"grade": "grade", "年级": "grade",
+	"major": "major", "专业": "major",
+	"studentid": "studentId", "学号": "studentId", "工号": "studentId",
+	"roles": "roles", "rolekeys": "roles", "角色": "roles",
+	"password": "password", "初始密码": "password",
+}
+
+// UserImportTemplateHeader 导入模板表头
+var UserImportTemplateHeader = []string{"username", "email", "realName", "phone", "department", "title", "grade", "major", "studentId", "roles", "password"}
+
+var (
+	importUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+	importPhonePattern    = regexp.MustCompile(`^[0-9+\- ]{6,20}$`)
+	importRoleSeparators  = strings.NewReplacer("，", ",", "；", ",", ";", ",", "、", ",", "|", ",")
+)
+
+// userImportRow 一行导入数据
+type userImportRow struct {
+	Row      int
+	Request  models.UserCreateRequest
+	Password string // 明文初始密码，写入前哈希
+}
+
+// UserImportService 批量导入用户：解析 CSV/XLSX，逐行校验后分批在事务中写入
+type UserImportService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	policy     *PasswordPolicyService
+	resets     *PasswordResetService
+	systemLogs *SystemLogService
+}
+
+func NewUserImportService(db *gorm.DB) *UserImportService {
+	return &UserImportService{
+		db:         db,
+		settings:   NewSettingService(db),
+		policy:     NewPasswordPolicyService(db),
+		resets:     NewPasswordResetService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// Import 导入用户。DryRun 时只返回校验结果；存在错误且未设置 SkipInvalid 时不写入并返回 ErrUserImportInvalid
+func (s *UserImportService) Import(filename string, content []byte, options models.UserImportOptions, operatorID uint) (*models.UserImportReport, error) {
+	records, err := readImportRecords(filename, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, errors.New("文件中没有数据行")
+	}
+	if len(records)-1 > userImportMaxRows {
+		return nil, fmt.Errorf("单次最多导入 %d 行", userImportMaxRows)
+	}
+
+	columns, err := mapImportHeader(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.UserImportReport{DryRun: options.DryRun, Errors: []models.UserImportRowError{}}
+	rows := s.validate(records[1:], columns, options, report)
+	report.Valid = len(rows)
+	report.Invalid = report.Total - report.Valid
+
+	if options.DryRun {
+		return report, nil
+	}
+	if report.Invalid > 0 && !options.SkipInvalid {
+		return report, ErrUserImportInvalid
+	}
+
+	created := s.commit(rows, report)
+	if options.Notify && len(created) > 0 {
+		report.Notified = len(created)
+		go s.notify(created)
+	}
+
+	s.systemLogs.RecordSecurity("user_import", "批量导入用户", "success", &operatorID,
+		fmt.Sprintf("文件: %s, 数据行: %d, 创建: %d, 校验失败: %d, 写入失败: %d, 发送开通邮件: %t",
+			filename, report.Total, report.Created, report.Invalid, report.Failed, options.Notify), "", "")
+	log.Printf("批量导入用户完成 - 操作人ID: %d, 创建: %d, 校验失败: %d, 写入失败: %d", operatorID, report.Created, report.Invalid, report.Failed)
+	return report, nil
+}
+
+// validate 逐行校验，返回校验通过的行；重复检查同时覆盖文件内和数据库中已有的账号
+func (s *UserImportService) validate(records [][]string, columns map[int]string, options models.UserImportOptions, report *models.UserImportReport) []*userImportRow {
+	roleKeys := s.roleLookup()
+	grades := s.settings.GetList("user_grades", DefaultUserGrades)
+	validGrades := make(map[string]bool, len(grades))
+	for _, grade := range grades {
+		validGrades[grade] = true
+	}
+	passwordPolicy := s.policy.Policy()
+
+	// 先收集文件中的用户名和邮箱，一次查询数据库中已存在的值
+	seenUsernames := make(map[string]int)
+	seenEmails := make(map[string]int)
+	var usernames, emails []string
+	for _, record := range records {
+		values := importValues(record, columns)
+		if values["username"] != "" {
+			usernames = append(usernames, values["username"])
+		}
+		if values["email"] != "" {
+			emails = append(emails, values["email"])
+		}
+	}
+	existingUsernames := s.existing("username", usernames)
+	existingEmails := s.existing("email", emails)
+
+	var rows []*userImportRow
+	for index, record := range records {
+		rowNumber := index + 2
+		values := importValues(record, columns)
+		if isBlankRecord(values) {
+			continue
+		}
+		report.Total++
+
+		var rowErrors []models.UserImportRowError
+		fail := func(field, message string) {
+			rowErrors = append(rowErrors, models.UserImportRowError{Row: rowNumber, Field: field, Value: values[field], Message: message})
+		}
+
+		username := values["username"]
+		switch {
+		case username == "":
+			fail("username", "用户名不能为空")
+		case utf8.RuneCountInString(username) < 3 || utf8.RuneCountInString(username) > 20:
+			fail("username", "用户名长度应为3-20个字符")
+		case !importUsernamePattern.MatchString(username):
+			fail("username", "用户名只能包含字母、数字和 _ . @ -")
+		case existingUsernames[strings.ToLower(username)]:
+			fail("username", "用户名已存在")
+		case seenUsernames[strings.ToLower(username)] > 0:
+			fail("username", fmt.Sprintf("用户名与第%d行重复", seenUsernames[strings.ToLower(username)]))
+		}
+		if username != "" && seenUsernames[strings.ToLower(username)] == 0 {
+			seenUsernames[strings.ToLower(username)] = rowNumber
+		}
+
+		email := values["email"]
+		emailKey := strings.ToLower(email)
+		if email == "" {
+			fail("email", "邮箱不能为空")
+		} else if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			fail("email", "邮箱格式不正确")
+		} else if existingEmails[emailKey] {
+			fail("email", "邮箱已存在")
+		} else if seenEmails[emailKey] > 0 {
+			fail("email", fmt.Sprintf("邮箱与第%d行重复", seenEmails[emailKey]))
+		}
+		if email != "" && seenEmails[emailKey] == 0 {
+			seenEmails[emailKey] = rowNumber
+		}
+
+		if values["realName"] == "" {
+			fail("realName", "姓名不能为空")
+		} else if utf8.RuneCountInString(values["realName"]) > 50 {
+			fail("realName", "姓名不能超过50个字符")
+		}
+		if values["phone"] != "" && !importPhonePattern.MatchString(values["phone"]) {
+			fail("phone", "手机号格式不正确")
+		}
+		if values["grade"] != "" && !validGrades[values["grade"]] {
+			fail("grade", "年级无效，可选值："+strings.Join(grades, "、"))
+		}
+		if utf8.RuneCountInString(values["studentId"]) > 20 {
+			fail("studentId", "学号/工号不能超过20个字符")
+		}
+
+		// 角色：支持角色标识或角色名称，多个角色用逗号分隔
+		roleValue := values["roles"]
+		if roleValue == "" {
+			roleValue = options.DefaultRole
+		}
+		var roles []string
+		for _, item := range strings.Split(importRoleSeparators.Replace(roleValue), ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, ok := roleKeys[strings.ToLower(item)]
+			if !ok {
+				fail("roles", "角色不存在: "+item)
+				continue
+			}
+			roles = append(roles, key)
+		}
+		if roleValue == "" {
+			fail("roles", "角色不能为空")
+		}
+
+		// 院系管理员只能导入本院系的非管理员账号
+		department := values["department"]
+		if !options.Scope.IsGlobal() {
+			if department == "" {
+				department = options.Scope.Department
+			}
+			if department != options.Scope.Department || department == "" {
+				fail("department", "只能导入本院系的用户")
+			}
+			for _, role := range roles {
+				if role == "admin" {
+					fail("roles", "院系管理员不能分配系统管理员角色")
+				}
+			}
+		}
+
+		// 未提供初始密码时生成随机密码，用户通过开通邮件或管理员重置后登录
+		password := values["password"]
+		if password == "" {
+			password = utils.GeneratePolicyPassword(passwordPolicy)
+		} else if len(password) > 72 {
+			fail("password", "密码不能超过72个字符")
+		} else if err := utils.ValidatePassword(password, passwordPolicy); err != nil {
+			fail("password", err.Error())
+		}
+		// 错误报告中不回显密码
+		for i := range rowErrors {
+			if rowErrors[i].Field == "password" {
+				rowErrors[i].Value = ""
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.Errors = append(report.Errors, rowErrors...)
+			continue
+		}
+		rows = append(rows, &userImportRow{
+			Row:      rowNumber,
+			Password: password,
+			Request: models.UserCreateRequest{
+				Username:   username,
+				Email:      email,
+				RealName:   values["realName"],
+				Phone:      values["phone"],
+				Department: department,
+				Title:      values["title"],
+				Grade:      values["grade"],
+				Major:      values["major"],
+				StudentID:  values["studentId"],
+				RoleKeys:   roles,
+			},
+		})
+	}
+	return rows
+}
+
+// commit 按批次写入，每批一个事务；某批失败时该批全部回滚并记为写入失败，不影响其他批次
+func (s *UserImportService) commit(rows []*userImportRow, report *models.UserImportReport) []models.User {
+	chunkSize := s.settings.GetInt("user_import_chunk_size", 200)
+	if chunkSize <= 0 {
+		chunkSize = 200
+	}
+
+	var roles []models.Role
+	s.db.Find(&roles)
+	roleIDs := make(map[string]uint, len(roles))
+	for _, role := range roles {
+		roleIDs[role.RoleKey] = role.ID
+	}
+
+	var created []models.User
+	for start := 0; start < len(rows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := rows[start:end]
+		report.Chunks++
+
+		hashes, err := hashImportPasswords(chunk)
+		if err == nil {
+			var users []models.User
+			err = s.db.Transaction(func(tx *gorm.DB) error {
+				users, err = createImportChunk(tx, chunk, hashes, roleIDs)
+				return err
+			})
+			if err == nil {
+				for i, user := range users {
+					report.Users = append(report.Users, models.UserImportCreated{Row: chunk[i].Row, UserID: user.ID, Username: user.Username})
+				}
+				report.Created += len(users)
+				created = append(created, users...)
+				continue
+			}
+		}
+
+		log.Printf("批量导入用户批次写入失败 - 行: %d-%d, 错误: %v", chunk[0].Row, chunk[len(chunk)-1].Row, err)
+		report.Failed += len(chunk)
+		for _, row := range chunk {
+			report.Errors = append(report.Errors, models.UserImportRowError{
+				Row:     row.Row,
+				Message: "所在批次写入失败，已回滚: " + err.Error(),
+			})
+		}
+	}
+	return created
+}
+
+// createImportChunk 在事务中写入一批用户、资料和角色
+func createImportChunk(tx *gorm.DB, chunk []*userImportRow, hashes []string, roleIDs map[string]uint) ([]models.User, error) {
+	users := make([]models.User, len(chunk))
+	for i, row := range chunk {
+		req := row.Request
+		users[i] = models.User{
+			Username:           req.Username,
+			Password:           hashes[i],
+			Email:              req.Email,
+			Status:             "active",
+			Department:         req.Department,
+			Title:              req.Title,
+			Grade:              req.Grade,
+			Major:              req.Major,
+			MustChangePassword: true,
+		}
+	}
+	if err := tx.Create(&users).Error; err != nil {
+		return nil, err
+	}
+
+	profiles := make([]models.UserProfile, len(chunk))
+	var userRoles []models.UserRole
+	for i, row := range chunk {
+		req := row.Request
+		profiles[i] = models.UserProfile{
+			UserID:     users[i].ID,
+			RealName:   req.RealName,
+			Phone:      req.Phone,
+			Department: req.Department,
+			StudentID:  req.StudentID,
+		}
+		for _, roleKey := range req.RoleKeys {
+			roleID, ok := roleIDs[roleKey]
+			if !ok {
+				return nil, errors.New("角色不存在: " + roleKey)
+			}
+			userRoles = append(userRoles, models.UserRole{UserID: users[i].ID, RoleID: roleID})
+		}
+	}
+	if err := tx.Create(&profiles).Error; err != nil {
+		return nil, err
+	}
+	if len(userRoles) > 0 {
+		if err := tx.Create(&userRoles).Error; err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// hashImportPasswords 并发计算一批密码的哈希（bcrypt 较慢，逐行计算会拖长导入时间）
+func hashImportPasswords(chunk []*userImportRow) ([]string, error) {
+	hashes := make([]string, len(chunk))
+	errs := make([]error, len(chunk))
+	workers := runtime.NumCPU()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hashes[i], errs[i] = utils.HashPassword(chunk[i].Password)
+			}
+		}()
+	}
+	for i := range chunk {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, errors.New("密码加密失败")
+		}
+	}
+	return hashes, nil
+}
+
+// notify 发送开通邮件（在后台执行，失败只记录日志）
+func (s *UserImportService) notify(users []models.User) {
+	sent := 0
+	for i := range users {
+		if err := s.resets.SendWelcome(&users[i]); err != nil {
+			log.Printf("发送账号开通邮件失败 - 用户ID: %d, 错误: %v", users[i].ID, err)
+			continue
+		}
+		sent++
+	}
+	log.Printf("账号开通邮件发送完成 - 成功: %d, 失败: %d", sent, len(users)-sent)
+}
+
+// roleLookup 角色标识和角色名称（小写） -> 角色标识
+func (s *UserImportService) roleLookup() map[string]string {
+	var roles []models.Role
+	s.db.Find(&roles)
+	lookup := make(map[string]string, len(roles)*2)
+	for _, role := range roles {
+		lookup[strings.ToLower(role.RoleKey)] = role.RoleKey
+		lookup[strings.ToLower(role.RoleName)] = role.RoleKey
+	}
+	return lookup
+}
+
+// existing 查询数据库中已存在的用户名或邮箱（小写，含回收站中的用户）
+func (s *UserImportService) existing(column string, values []string) map[string]bool {
+	result := make(map[string]bool)
+	for start := 0; start < len(values); start += 1000 {
+		end := start + 1000
+		if end > len(values) {
+			end = len(values)
+		}
+		var found []string
+		s.db.Unscoped().Model(&models.User{}).Where(column+" IN ?", values[start:end]).Pluck(column, &found)
+		for _, value := range found {
+			result[strings.ToLower(value)] = true
+		}
+	}
+	return result
+}
+
+// readImportRecords 按扩展名读取 CSV 或 XLSX（第一个工作表）
+func readImportRecords(filename string, content []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+		if !utf8.Valid(content) {
+			return nil, errors.New("CSV 文件须为 UTF-8 编码（Excel 另存为“CSV UTF-8”）")
+		}
+		reader := csv.NewReader(bytes.NewReader(content))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		var records [][]string
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("CSV 解析失败: %v", err)
+			}
+			records = append(records, record)
+			if len(records) > userImportMaxRows+1 {
+				break
+			}
+		}
+		return records, nil
+	case ".xlsx":
+		file, err := excelize.OpenReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("XLSX 解析失败: %v", err)
+		}
+		defer file.Close()
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("XLSX 文件中没有工作表")
+		}
+		records, err := file.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("XLSX 解析失败: %v", err)
+		}
+		return records, nil
+	}
+	return nil, errors.New("只支持 .csv 和 .xlsx 文件")
+}
+
+// mapImportHeader 解析表头，返回列序号 -> 字段
+func mapImportHeader(header []string) (map[int]string, error) {
+	columns := make(map[int]string)
+	found := make(map[string]bool)
+	for index, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		field, ok := userImportColumns[key]
+		if !ok {
+			continue
+		}
+		if found[field] {
+			return nil, fmt.Errorf("表头重复: %s", name)
+		}
+		found[field] = true
+		columns[index] = field
+	}
+	for _, required := range []string{"username", "email", "realName"} {
+		if !found[required] {
+			return nil, fmt.Errorf("缺少必填列: %s", required)
+		}
+	}
+	return columns, nil
+}
+
+func importValues(record []string, columns map[int]string) map[string]string {
+	values := make(map[string]string, len(columns))
+	for index, field := range columns {
+		if index < len(record) {
+			values[field] = strings.TrimSpace(record[index])
+		}
+	}
+	return values
+}
+
+func isBlankRecord(values map[string]string) bool {
+	for _, value := range values {
+		if value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// UserImportTemplate 生成CSV导入模板（含一行示例）
+func UserImportTemplate() []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("\xef\xbb\xbf")
+	writer := csv.NewWriter(&buffer)
+	writer.Write(UserImportTemplateHeader)
+	writer.Write([]string{"s" + time.Now().Format("2006") + "001", "student@example.edu", "张三", "13800000000", "计算机学院", "", "大一", "软件工程", time.Now().Format("2006") + "001", "student", ""})
+	writer.Flush()
+	return buffer.Bytes()
+}
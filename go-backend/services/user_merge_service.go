@@ -0,0 +1,444 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// errMergeDryRun 试运行时用于回滚合并事务
+var errMergeDryRun = errors.New("dry run")
+
+type UserMergeService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewUserMergeService(db *gorm.DB) *UserMergeService {
+	return &UserMergeService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// FindDuplicates 查找疑似重复账号：邮箱（忽略大小写和+后缀）、学号相同为高可信度，
+// 姓名相同时同院系为中等可信度，否则为低可信度；reason 为空时返回全部
+func (s *UserMergeService) FindDuplicates(reason string, scope *models.DataScope) ([]models.DuplicateGroup, error) {
+	var users []models.User
+	query := s.db.Model(&models.User{}).Preload("Profile").Preload("Roles")
+	if err := ApplyUserScope(s.db, query, scope, "users.id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	type bucket struct {
+		reason, value string
+		users         []*models.User
+	}
+	buckets := make(map[string]*bucket)
+	add := func(reason, value string, user *models.User) {
+		if value == "" {
+			return
+		}
+		key := reason + "\x00" + value
+		if buckets[key] == nil {
+			buckets[key] = &bucket{reason: reason, value: value}
+		}
+		buckets[key].users = append(buckets[key].users, user)
+	}
+	for i := range users {
+		user := &users[i]
+		add("email", normalizeEmail(user.Email), user)
+		if user.Profile != nil {
+			add("student_id", strings.ToUpper(strings.TrimSpace(user.Profile.StudentID)), user)
+			add("real_name", strings.Join(strings.Fields(user.Profile.RealName), ""), user)
+		}
+	}
+
+	groups := make([]models.DuplicateGroup, 0)
+	seen := make(map[string]bool)
+	userIDs := make([]uint, 0)
+	for _, reasonKey := range []string{"email", "student_id", "real_name"} {
+		if reason != "" && reason != reasonKey {
+			continue
+		}
+		keys := make([]string, 0)
+		for key, b := range buckets {
+			if b.reason == reasonKey && len(b.users) > 1 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b := buckets[key]
+			ids := make([]string, 0, len(b.users))
+			for _, user := range b.users {
+				ids = append(ids, fmt.Sprint(user.ID))
+			}
+			sort.Strings(ids)
+			// 同一组账号已按更可靠的条件列出时不再重复
+			if idKey := strings.Join(ids, ","); seen[idKey] {
+				continue
+			} else {
+				seen[idKey] = true
+			}
+
+			group := models.DuplicateGroup{Reason: b.reason, Value: b.value, Confidence: "high"}
+			if b.reason == "real_name" {
+				group.Confidence = "medium"
+				for _, user := range b.users {
+					if userDepartment(user) == "" || userDepartment(user) != userDepartment(b.users[0]) {
+						group.Confidence = "low"
+						break
+					}
+				}
+			}
+			for _, user := range b.users {
+				group.Users = append(group.Users, duplicateUser(user))
+				userIDs = append(userIDs, user.ID)
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	// 各账号的项目数，帮助判断保留哪个账号
+	if len(userIDs) > 0 {
+		var counts []struct {
+			StudentID uint
+			Count     int64
+		}
+		s.db.Model(&models.Project{}).Select("student_id, COUNT(*) AS count").
+			Where("student_id IN ?", userIDs).Group("student_id").Scan(&counts)
+		projects := make(map[uint]int64, len(counts))
+		for _, c := range counts {
+			projects[c.StudentID] = c.Count
+		}
+		for i := range groups {
+			for j := range groups[i].Users {
+				groups[i].Users[j].Projects = projects[groups[i].Users[j].ID]
+			}
+		}
+	}
+	return groups, nil
+}
+
+// Merge 把 source 账号的项目、报名、作品、评分、通知、师生绑定等数据迁移到 target 账号，
+// 补全 target 的角色和档案空缺字段，然后将 source 移入回收站；全部在一个事务中完成并记录审计
+func (s *UserMergeService) Merge(req models.UserMergeRequest, operatorID uint) (*models.UserMergeResult, error) {
+	if req.SourceID == req.TargetID {
+		return nil, errors.New("不能把账号合并到自身")
+	}
+	var source, target models.User
+	if err := s.db.Preload("Profile").Preload("Roles").First(&source, req.SourceID).Error; err != nil {
+		return nil, errors.New("被合并的账号不存在")
+	}
+	if err := s.db.Preload("Profile").Preload("Roles").First(&target, req.TargetID).Error; err != nil {
+		return nil, errors.New("保留的账号不存在")
+	}
+
+	// 迁移后会违反唯一约束、又无法自动取舍的数据须先人工处理
+	if conflicts := s.uniqueConflicts(source.ID, target.ID); len(conflicts) > 0 {
+		return nil, errors.New("无法自动合并：" + strings.Join(conflicts, "；"))
+	}
+
+	result := &models.UserMergeResult{
+		DryRun:       req.DryRun,
+		Moved:        make(map[string]int64),
+		Removed:      make(map[string]int64),
+		Roles:        []string{},
+		DroppedRoles: []string{},
+		Profile:      []string{},
+	}
+	// step 记录事务当前所处的步骤，失败时告知管理员
+	step := "清理重复记录"
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 同一竞赛的报名：一方已撤销时删除已撤销的一条（双方都未撤销已在上面拒绝），先删被合并账号的
+		var targetCompetitions []uint
+		if err := tx.Model(&models.CompetitionRegistration{}).Where("student_id = ?", target.ID).Pluck("competition_id", &targetCompetitions).Error; err != nil {
+			return err
+		}
+		if len(targetCompetitions) > 0 {
+			removed := tx.Where("student_id = ? AND status = ? AND competition_id IN ?", source.ID, "withdrawn", targetCompetitions).
+				Delete(&models.CompetitionRegistration{})
+			if removed.Error != nil {
+				return removed.Error
+			}
+			result.Removed["competition_registrations.student_id"] += removed.RowsAffected
+
+			var sourceCompetitions []uint
+			if err := tx.Model(&models.CompetitionRegistration{}).Where("student_id = ?", source.ID).Pluck("competition_id", &sourceCompetitions).Error; err != nil {
+				return err
+			}
+			if len(sourceCompetitions) > 0 {
+				removed := tx.Where("student_id = ? AND status = ? AND competition_id IN ?", target.ID, "withdrawn", sourceCompetitions).
+					Delete(&models.CompetitionRegistration{})
+				if removed.Error != nil {
+					return removed.Error
+				}
+				result.Removed["competition_registrations.student_id"] += removed.RowsAffected
+			}
+		}
+
+		// 合并后会重复的记录：相同的师生绑定、同一项目的团队成员、同一竞赛的评委（MySQL 不允许删除时子查询同一张表，先查出对方ID）
+		for _, column := range [][2]string{{"student_id", "teacher_id"}, {"teacher_id", "student_id"}} {
+			var others []uint
+			if err := tx.Model(&models.StudentTeacher{}).Where(column[0]+" = ?", target.ID).Pluck(column[1], &others).Error; err != nil {
+				return err
+			}
+			if len(others) == 0 {
+				continue
+			}
+			removed := tx.Where(column[0]+" = ? AND "+column[1]+" IN ?", source.ID, others).Delete(&models.StudentTeacher{})
+			if removed.Error != nil {
+				return removed.Error
+			}
+			result.Removed["student_teacher."+column[0]] += removed.RowsAffected
+		}
+		var teams []uint
+		if err := tx.Model(&models.ProjectMember{}).Where("user_id = ?", target.ID).Pluck("project_id", &teams).Error; err != nil {
+			return err
+		}
+		if len(teams) > 0 {
+			removed := tx.Where("user_id = ? AND project_id IN ?", source.ID, teams).Delete(&models.ProjectMember{})
+			if removed.Error != nil {
+				return removed.Error
+			}
+			result.Removed["project_members.user_id"] = removed.RowsAffected
+		}
+		var judged []uint
+		if err := tx.Model(&models.CompetitionJudge{}).Where("teacher_id = ?", target.ID).Pluck("competition_id", &judged).Error; err != nil {
+			return err
+		}
+		if len(judged) > 0 {
+			removed := tx.Where("teacher_id = ? AND competition_id IN ?", source.ID, judged).Delete(&models.CompetitionJudge{})
+			if removed.Error != nil {
+				return removed.Error
+			}
+			result.Removed["competition_judges.teacher_id"] = removed.RowsAffected
+		}
+
+		// 迁移引用该账号的业务数据（与回收站引用检查使用同一组数据列）和统一身份认证绑定
+		columns := append([]userReferenceColumn{}, userReferenceColumns...)
+		columns = append(columns, userReferenceColumn{"user_identities", "user_id", "统一身份认证绑定"})
+		for _, ref := range columns {
+			if !tx.Migrator().HasTable(ref.table) {
+				continue
+			}
+			step = "迁移" + ref.label
+			moved := tx.Table(ref.table).Where(ref.column+" = ?", source.ID).Update(ref.column, target.ID)
+			if moved.Error != nil {
+				return moved.Error
+			}
+			if moved.RowsAffected > 0 {
+				result.Moved[ref.table+"."+ref.column] = moved.RowsAffected
+			}
+		}
+
+		// 补充 target 缺少的角色；管理类角色须显式确认才授予，避免合并时顺带提权
+		step = "补充角色"
+		has := make(map[uint]bool, len(target.Roles))
+		for _, role := range target.Roles {
+			has[role.ID] = true
+		}
+		for _, role := range source.Roles {
+			if has[role.ID] {
+				continue
+			}
+			if !isBasicRole(role.RoleKey) && !req.GrantPrivilegedRoles {
+				result.DroppedRoles = append(result.DroppedRoles, role.RoleKey)
+				continue
+			}
+			if err := tx.Create(&models.UserRole{UserID: target.ID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+			result.Roles = append(result.Roles, role.RoleKey)
+		}
+
+		// 补全 target 为空的账号和档案字段
+		step = "补全档案"
+		userUpdates := make(map[string]interface{})
+		fillUser := func(column, current, value string) {
+			if current == "" && value != "" {
+				userUpdates[column] = value
+				result.Profile = append(result.Profile, column)
+			}
+		}
+		fillUser("department", target.Department, source.Department)
+		fillUser("major", target.Major, source.Major)
+		fillUser("grade", target.Grade, source.Grade)
+		fillUser("title", target.Title, source.Title)
+		if target.OrgUnitID == nil && source.OrgUnitID != nil {
+			userUpdates["org_unit_id"] = *source.OrgUnitID
+			result.Profile = append(result.Profile, "org_unit_id")
+		}
+		if len(userUpdates) > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", target.ID).Updates(userUpdates).Error; err != nil {
+				return err
+			}
+		}
+		if source.Profile != nil {
+			profileUpdates := make(map[string]interface{})
+			current := models.UserProfile{}
+			if target.Profile != nil {
+				current = *target.Profile
+			}
+			fillProfile := func(column, currentValue, value string) {
+				if currentValue == "" && value != "" {
+					profileUpdates[column] = value
+					result.Profile = append(result.Profile, "profile."+column)
+				}
+			}
+			fillProfile("real_name", current.RealName, source.Profile.RealName)
+			fillProfile("phone", current.Phone, source.Profile.Phone)
+			fillProfile("student_id", current.StudentID, source.Profile.StudentID)
+			fillProfile("department", current.Department, source.Profile.Department)
+			fillProfile("avatar", current.Avatar, source.Profile.Avatar)
+			fillProfile("bio", current.Bio, source.Profile.Bio)
+			if len(profileUpdates) > 0 {
+				if target.Profile == nil {
+					if err := tx.Create(&models.UserProfile{UserID: target.ID}).Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Model(&models.UserProfile{}).Where("user_id = ?", target.ID).Updates(profileUpdates).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// source 移入回收站，保留期内仍可恢复（数据已迁移，恢复后为空账号）
+		step = "移入回收站"
+		if err := softDeleteUser(tx, &source, operatorID); err != nil {
+			return err
+		}
+		if req.DryRun {
+			return errMergeDryRun
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"moved": result.Moved, "removed": result.Removed, "roles": result.Roles, "profile": result.Profile,
+		})
+		record := models.UserMerge{
+			SourceUserID:   source.ID,
+			SourceUsername: source.Username,
+			TargetUserID:   target.ID,
+			TargetUsername: target.Username,
+			Reason:         req.Reason,
+			Details:        string(details),
+			OperatorID:     operatorID,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		result.MergeID = record.ID
+		return nil
+	})
+	if errors.Is(err, errMergeDryRun) {
+		return result, nil
+	}
+	if err != nil {
+		log.Printf("合并账号失败 - 源账号ID: %d, 目标账号ID: %d, 步骤: %s, 错误: %v", source.ID, target.ID, step, err)
+		return nil, fmt.Errorf("合并账号失败（%s时出错），数据未做任何修改", step)
+	}
+
+	revokeDeletedUserAccess(s.db, source.ID)
+	s.systemLogs.RecordSecurity("user_merge", "合并重复账号", "success", &operatorID,
+		fmt.Sprintf("合并记录ID: %d, %s(%d) -> %s(%d), 迁移: %v, 补充角色: %v, 未授予角色: %v, 原因: %s",
+			result.MergeID, source.Username, source.ID, target.Username, target.ID, result.Moved, result.Roles, result.DroppedRoles, req.Reason), "", "")
+	log.Printf("账号合并完成 - 源账号ID: %d, 目标账号ID: %d", source.ID, target.ID)
+	return result, nil
+}
+
+// mergeUniqueKeys 迁移用户列后可能重复的唯一键（另一列 + 用户列），以及冲突时的处理提示
+var mergeUniqueKeys = []struct {
+	table, keyColumn, userColumn, message string
+}{
+	{"competition_registrations", "competition_id", "student_id", "两个账号都报名了竞赛（竞赛ID %v），请先撤销其中一个报名"},
+	{"competition_results", "competition_id", "student_id", "两个账号在同一竞赛都有成绩（竞赛ID %v），请先删除其中一条"},
+	{"competition_feedback", "submission_id", "teacher_id", "两个账号对同一作品都写了评语（作品ID %v），请先删除其中一条"},
+	{"competition_scores", "submission_id", "judge_id", "两个账号对同一作品都打了分（作品ID %v），请先删除其中一条"},
+}
+
+// uniqueConflicts 检查合并后会重复且无法自动取舍的记录；已撤销的报名不算冲突，合并时删除
+func (s *UserMergeService) uniqueConflicts(sourceID, targetID uint) []string {
+	var conflicts []string
+	for _, key := range mergeUniqueKeys {
+		if !s.db.Migrator().HasTable(key.table) {
+			continue
+		}
+		targetKeys := s.db.Table(key.table).Select(key.keyColumn).Where(key.userColumn+" = ?", targetID)
+		query := s.db.Table(key.table).Where(key.userColumn+" = ?", sourceID)
+		if key.table == "competition_registrations" {
+			targetKeys = targetKeys.Where("status <> ?", "withdrawn")
+			query = query.Where("status <> ?", "withdrawn")
+		}
+		var keys []uint
+		query.Where(key.keyColumn+" IN (?)", targetKeys).Pluck(key.keyColumn, &keys)
+		if len(keys) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf(key.message, keys))
+		}
+	}
+	return conflicts
+}
+
+// GetMerges 获取账号合并记录
+func (s *UserMergeService) GetMerges(page, size int) ([]models.UserMerge, int64, error) {
+	var records []models.UserMerge
+	var total int64
+	query := s.db.Model(&models.UserMerge{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+func duplicateUser(user *models.User) models.DuplicateUser {
+	item := models.DuplicateUser{
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		Status:     user.Status,
+		Department: userDepartment(user),
+		RoleNames:  []string{},
+		CreatedAt:  user.CreatedAt,
+	}
+	if user.Profile != nil {
+		item.RealName = user.Profile.RealName
+		item.StudentID = user.Profile.StudentID
+		item.LastLogin = user.Profile.LastLogin
+	}
+	for _, role := range user.Roles {
+		item.RoleNames = append(item.RoleNames, role.RoleName)
+	}
+	return item
+}
+
+// userDepartment 账号院系，为空时取档案中的院系
+func userDepartment(user *models.User) string {
+	if user.Department != "" || user.Profile == nil {
+		return user.Department
+	}
+	return user.Profile.Department
+}
+
+// normalizeEmail 邮箱忽略大小写，并去掉本地部分的 +后缀
+func normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local := email[:at]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + email[at:]
+}
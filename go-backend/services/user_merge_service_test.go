@@ -0,0 +1,121 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+func newMergeTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.Role{}, &models.UserRole{}, &models.UserIdentity{},
+		&models.UserSession{}, &models.PersonalAccessToken{}, &models.UserMerge{}, &models.SystemLog{},
+		&models.StudentTeacher{}, &models.ProjectMember{}, &models.CompetitionJudge{},
+		&models.CompetitionRegistration{}, &models.CompetitionFeedback{}, &models.CompetitionScore{}, &models.CompetitionResult{})
+	// 与 sql/database_setup.sql 中的唯一约束一致
+	for _, index := range []string{
+		"CREATE UNIQUE INDEX unique_competition_student ON competition_registrations (competition_id, student_id)",
+		"CREATE UNIQUE INDEX unique_submission_teacher ON competition_feedback (submission_id, teacher_id)",
+		"CREATE UNIQUE INDEX unique_submission_judge ON competition_scores (submission_id, judge_id)",
+		"CREATE UNIQUE INDEX unique_competition_student_result ON competition_results (competition_id, student_id)",
+	} {
+		if err := db.Exec(index).Error; err != nil {
+			t.Fatalf("创建唯一索引失败: %v", err)
+		}
+	}
+	for _, key := range []string{"student", "teacher", "admin"} {
+		db.Create(&models.Role{RoleKey: key, RoleName: key})
+	}
+	return db
+}
+
+func createMergeTestUser(t *testing.T, db *gorm.DB, username string, roleKeys ...string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@yunmeng.test", Password: "x", Status: "active", RoleName: roleKeys[0]}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	for _, key := range roleKeys {
+		var role models.Role
+		db.Where("role_key = ?", key).First(&role)
+		db.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID})
+	}
+	return user
+}
+
+func TestMergeRejectsUniqueKeyConflicts(t *testing.T) {
+	db := newMergeTestDB(t)
+	source := createMergeTestUser(t, db, "teacher_old", "teacher")
+	target := createMergeTestUser(t, db, "teacher_new", "teacher")
+	db.Create(&models.CompetitionScore{SubmissionID: 7, JudgeID: source.ID, Score: 80})
+	db.Create(&models.CompetitionScore{SubmissionID: 7, JudgeID: target.ID, Score: 90})
+
+	service := NewUserMergeService(db)
+	_, err := service.Merge(models.UserMergeRequest{SourceID: source.ID, TargetID: target.ID}, 1)
+	if err == nil || !strings.Contains(err.Error(), "作品ID [7]") {
+		t.Fatalf("同一作品的两条评分应在合并前报告冲突，实际: %v", err)
+	}
+	var judges []uint
+	db.Model(&models.CompetitionScore{}).Order("judge_id").Pluck("judge_id", &judges)
+	if len(judges) != 2 || judges[0] != source.ID {
+		t.Errorf("冲突时不应修改数据: %v", judges)
+	}
+}
+
+func TestMergeRemovesWithdrawnRegistrations(t *testing.T) {
+	db := newMergeTestDB(t)
+	source := createMergeTestUser(t, db, "student_old", "student")
+	target := createMergeTestUser(t, db, "student_new", "student")
+	// 竞赛1：被合并账号已撤销；竞赛2：保留账号已撤销；竞赛3：只有被合并账号报名
+	db.Create(&models.CompetitionRegistration{CompetitionID: 1, StudentID: source.ID, Status: "withdrawn"})
+	db.Create(&models.CompetitionRegistration{CompetitionID: 1, StudentID: target.ID, Status: "registered"})
+	db.Create(&models.CompetitionRegistration{CompetitionID: 2, StudentID: source.ID, Status: "approved"})
+	db.Create(&models.CompetitionRegistration{CompetitionID: 2, StudentID: target.ID, Status: "withdrawn"})
+	db.Create(&models.CompetitionRegistration{CompetitionID: 3, StudentID: source.ID, Status: "registered"})
+
+	result, err := NewUserMergeService(db).Merge(models.UserMergeRequest{SourceID: source.ID, TargetID: target.ID}, 1)
+	if err != nil {
+		t.Fatalf("一方已撤销的报名应自动去重: %v", err)
+	}
+	if result.Removed["competition_registrations.student_id"] != 2 {
+		t.Errorf("应删除两条已撤销的报名: %v", result.Removed)
+	}
+
+	var registrations []models.CompetitionRegistration
+	db.Order("competition_id").Find(&registrations)
+	if len(registrations) != 3 {
+		t.Fatalf("合并后应剩3条报名，实际 %d", len(registrations))
+	}
+	for _, registration := range registrations {
+		if registration.StudentID != target.ID || registration.Status == "withdrawn" {
+			t.Errorf("保留的报名应属于保留账号且未撤销: %+v", registration)
+		}
+	}
+}
+
+func TestMergePrivilegedRolesRequireFlag(t *testing.T) {
+	db := newMergeTestDB(t)
+	source := createMergeTestUser(t, db, "admin_old", "teacher", "admin")
+	target := createMergeTestUser(t, db, "plain_new", "student")
+	service := NewUserMergeService(db)
+
+	preview, err := service.Merge(models.UserMergeRequest{SourceID: source.ID, TargetID: target.ID, DryRun: true}, 1)
+	if err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+	if strings.Join(preview.Roles, ",") != "teacher" || strings.Join(preview.DroppedRoles, ",") != "admin" {
+		t.Errorf("未确认时只补充普通角色: roles=%v dropped=%v", preview.Roles, preview.DroppedRoles)
+	}
+
+	if _, err := service.Merge(models.UserMergeRequest{SourceID: source.ID, TargetID: target.ID, GrantPrivilegedRoles: true}, 1); err != nil {
+		t.Fatalf("合并失败: %v", err)
+	}
+	var roleKeys []string
+	db.Model(&models.Role{}).Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", target.ID).Order("roles.role_key").Pluck("roles.role_key", &roleKeys)
+	if strings.Join(roleKeys, ",") != "admin,student,teacher" {
+		t.Errorf("显式确认后应授予管理角色，实际 %v", roleKeys)
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// anonymizedRealName 匿名化后档案中显示的姓名
+const anonymizedRealName = "已注销用户"
+
+type UserPrivacyService struct {
+	db         *gorm.DB
+	systemLogs *SystemLogService
+}
+
+func NewUserPrivacyService(db *gorm.DB) *UserPrivacyService {
+	return &UserPrivacyService{
+		db:         db,
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// InScope 判断用户（含回收站中的用户）是否在数据范围内
+func (s *UserPrivacyService) InScope(userID uint, scope *models.DataScope) bool {
+	if scope.IsGlobal() {
+		return true
+	}
+	var count int64
+	ApplyUserScope(s.db, s.db.Unscoped().Model(&models.User{}), scope, "users.id").Where("users.id = ?", userID).Count(&count)
+	return count > 0
+}
+
+// Export 汇总与用户关联的全部数据（含回收站中的用户）
+func (s *UserPrivacyService) Export(userID uint) (*models.UserDataExport, error) {
+	export := &models.UserDataExport{ExportedAt: time.Now()}
+	if err := s.db.Unscoped().Preload("Profile").Preload("Roles").First(&export.User, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&export.LoginLogs, s.db.Where("user_id = ?", userID).Order("login_time DESC")},
+		{&export.LoginAttempts, s.db.Where("user_id = ? OR username IN ?", userID, []string{export.User.Username, export.User.Email}).Order("created_at DESC")},
+		{&export.Sessions, s.db.Where("user_id = ?", userID).Order("created_at DESC")},
+		{&export.Identities, s.db.Where("user_id = ?", userID)},
+		{&export.Projects, s.db.Where("student_id = ? OR id IN (?)", userID, memberProjectIDs(s.db, userID)).Order("id")},
+		{&export.ProjectFiles, s.db.Where("project_id IN (?)", s.db.Model(&models.Project{}).Select("id").
+			Where("student_id = ? OR id IN (?)", userID, memberProjectIDs(s.db, userID))).Order("id")},
+		{&export.Registrations, s.db.Where("student_id = ?", userID).Order("id")},
+		{&export.Submissions, s.db.Where("student_id = ?", userID).Order("id")},
+		{&export.Feedback, s.db.Where("student_id = ?", userID).Order("id")},
+		{&export.Scores, s.db.Where("submission_id IN (?)", s.db.Model(&models.CompetitionSubmission{}).Select("id").Where("student_id = ?", userID)).Order("id")},
+		{&export.Results, s.db.Where("student_id = ?", userID).Order("id")},
+		{&export.Notifications, s.db.Where("user_id = ?", userID).Order("created_at DESC")},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	export.Files = make([]models.UserDataFile, 0, len(export.ProjectFiles)+len(export.Submissions))
+	for _, file := range export.ProjectFiles {
+		export.Files = append(export.Files, models.UserDataFile{Source: "project_file", RecordID: file.ID, FileName: file.FileName, FileURL: file.FileURL})
+	}
+	for _, submission := range export.Submissions {
+		if submission.FileURL != "" {
+			export.Files = append(export.Files, models.UserDataFile{Source: "competition_submission", RecordID: submission.ID, FileName: submission.FileName, FileURL: submission.FileURL})
+		}
+	}
+	return export, nil
+}
+
+// WriteArchive 把导出数据写成 ZIP：data.json 加上 files/ 目录下的附件；本地找不到的附件只保留在清单中
+func (s *UserPrivacyService) WriteArchive(w io.Writer, export *models.UserDataExport) error {
+	archive := zip.NewWriter(w)
+	for i := range export.Files {
+		file := &export.Files[i]
+		localPath, ok := uploadedFilePath(file.FileURL)
+		if !ok {
+			continue
+		}
+		src, err := os.Open(localPath)
+		if err != nil {
+			log.Printf("导出个人数据时附件不存在 - 记录: %s/%d, 路径: %s", file.Source, file.RecordID, localPath)
+			continue
+		}
+		name := file.FileName
+		if name == "" {
+			name = path.Base(localPath)
+		}
+		file.ArchivePath = fmt.Sprintf("files/%s/%d_%s", file.Source, file.RecordID, path.Base(filepath.ToSlash(name)))
+		dst, err := archive.Create(file.ArchivePath)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// uploadedFilePath 把附件URL（/uploads/... 或 uploads/...）转换为本地路径，不在上传目录内的一律拒绝
+func uploadedFilePath(fileURL string) (string, bool) {
+	cleaned := path.Clean("/" + strings.TrimSpace(fileURL))
+	if !strings.HasPrefix(cleaned, "/uploads/") {
+		return "", false
+	}
+	return filepath.FromSlash(strings.TrimPrefix(cleaned, "/")), true
+}
+
+// Anonymize 清除用户的个人身份信息：账号改为不可登录的占位账号，删除登录记录、身份绑定和通知，
+// 清空报名联系方式；院系、专业、年级以及项目、作品、评分、获奖记录保留，统计结果不受影响
+func (s *UserPrivacyService) Anonymize(userID uint, req models.UserAnonymizeRequest, operatorID uint) (*models.UserAnonymizeResult, error) {
+	if userID == operatorID {
+		return nil, errors.New("不能匿名化自己的账号")
+	}
+	var user models.User
+	if err := s.db.Unscoped().Preload("Profile").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return nil, errors.New("该用户已匿名化")
+	}
+	if strings.TrimSpace(req.Confirm) != user.Username {
+		return nil, errors.New("确认内容与用户名不一致")
+	}
+
+	result := &models.UserAnonymizeResult{
+		UserID:   user.ID,
+		Username: fmt.Sprintf("anonymized_%d", user.ID),
+		Cleared:  make(map[string]int64),
+	}
+	identifiers := []string{user.Username, user.Email}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 密码置为无法通过校验的值，账号不能再登录
+		if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"username":             result.Username,
+			"email":                result.Username + "@anonymized.invalid",
+			"password":             "!",
+			"status":               "inactive",
+			"must_change_password": false,
+			"anonymized_at":        now,
+		}).Error; err != nil {
+			return err
+		}
+		if user.Profile != nil {
+			if err := tx.Model(&models.UserProfile{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+				"real_name":  anonymizedRealName,
+				"phone":      "",
+				"student_id": "",
+				"avatar":     "",
+				"bio":        "",
+				"interests":  nil,
+				"last_login": nil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		deletions := []struct {
+			key   string
+			model interface{}
+			query *gorm.DB
+		}{
+			{"login_logs", &models.LoginLog{}, tx.Where("user_id = ?", user.ID)},
+			{"login_attempts", &models.LoginAttempt{}, tx.Where("user_id = ? OR username IN ?", user.ID, identifiers)},
+			{"password_reset_tokens", &models.PasswordResetToken{}, tx.Where("user_id = ? OR identifier IN ?", user.ID, identifiers)},
+			{"password_histories", &models.PasswordHistory{}, tx.Where("user_id = ?", user.ID)},
+			{"user_identities", &models.UserIdentity{}, tx.Where("user_id = ?", user.ID)},
+			{"user_two_factors", &models.UserTwoFactor{}, tx.Where("user_id = ?", user.ID)},
+			{"two_factor_recovery_codes", &models.TwoFactorRecoveryCode{}, tx.Where("user_id = ?", user.ID)},
+			{"project_notifications", &models.ProjectNotification{}, tx.Where("user_id = ?", user.ID)},
+		}
+		for _, d := range deletions {
+			deleted := d.query.Delete(d.model)
+			if deleted.Error != nil {
+				return deleted.Error
+			}
+			result.Cleared[d.key] = deleted.RowsAffected
+		}
+
+		type fieldUpdate struct {
+			key    string
+			model  interface{}
+			query  *gorm.DB
+			values map[string]interface{}
+		}
+		updates := []fieldUpdate{
+			{"user_sessions", &models.UserSession{}, tx.Where("user_id = ?", user.ID),
+				map[string]interface{}{"ip_address": "", "user_agent": "", "last_active_ip": ""}},
+			{"competition_registrations", &models.CompetitionRegistration{}, tx.Where("student_id = ?", user.ID),
+				map[string]interface{}{"contact_phone": "", "contact_email": "", "additional_info": nil}},
+		}
+		// 项目团队名单中的本人信息（早期成员只按学号登记）
+		members := tx.Where("user_id = ?", user.ID)
+		if user.Profile != nil && user.Profile.StudentID != "" {
+			members = tx.Where("user_id = ? OR student_number = ?", user.ID, user.Profile.StudentID)
+		}
+		updates = append(updates, fieldUpdate{"project_members", &models.ProjectMember{}, members,
+			map[string]interface{}{"name": anonymizedRealName, "student_number": ""}})
+		for _, u := range updates {
+			updated := u.query.Model(u.model).Updates(u.values)
+			if updated.Error != nil {
+				return updated.Error
+			}
+			result.Cleared[u.key] = updated.RowsAffected
+		}
+		return anonymizeRecords(tx, &user, result.Username, result.Cleared)
+	})
+	if err != nil {
+		log.Printf("匿名化用户失败 - 用户ID: %d, 错误: %v", user.ID, err)
+		return nil, errors.New("匿名化用户失败")
+	}
+
+	revokeDeletedUserAccess(s.db, user.ID)
+	// 日志只记录用户ID，不再写入姓名等个人信息
+	s.systemLogs.RecordSecurity("user_anonymize", "匿名化用户", "success", &operatorID,
+		fmt.Sprintf("用户ID: %d, 清除: %v, 原因: %s", user.ID, result.Cleared, req.Reason), "", "")
+	log.Printf("用户已匿名化 - 用户ID: %d", user.ID)
+	return result, nil
+}
+
+// anonymizeRecords 改写日志和历史记录中的个人信息副本：用户实体的审计字段变更清空；
+// 系统日志详情、账号合并记录、目录同步报告中的用户名和邮箱替换为占位用户名
+func anonymizeRecords(tx *gorm.DB, user *models.User, placeholder string, cleared map[string]int64) error {
+	updated := tx.Model(&models.SystemLog{}).
+		Where("entity_type = ? AND entity_id = ? AND changes <> ?", "user", fmt.Sprint(user.ID), "").
+		Update("changes", "")
+	if updated.Error != nil {
+		return updated.Error
+	}
+	cleared["system_logs.changes"] = updated.RowsAffected
+
+	// 邮箱在前，避免用户名是邮箱前缀时只替换一半
+	identifiers := []string{user.Email, user.Username}
+	var logs []models.SystemLog
+	if err := tx.Select("id", "details").
+		Where("INSTR(details, ?) > 0 OR INSTR(details, ?) > 0", user.Email, user.Username).Find(&logs).Error; err != nil {
+		return err
+	}
+	for _, entry := range logs {
+		details := replaceIdentifiers(entry.Details, identifiers, placeholder)
+		if details == entry.Details {
+			continue
+		}
+		if err := tx.Model(&models.SystemLog{}).Where("id = ?", entry.ID).Update("details", details).Error; err != nil {
+			return err
+		}
+		cleared["system_logs.details"]++
+	}
+
+	for _, column := range []string{"source", "target"} {
+		updated := tx.Model(&models.UserMerge{}).Where(column+"_user_id = ?", user.ID).Update(column+"_username", placeholder)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		cleared["user_merges"] += updated.RowsAffected
+	}
+
+	var runs []models.LDAPSyncRun
+	if err := tx.Select("id", "report").Where("INSTR(report, ?) > 0", user.Username).Find(&runs).Error; err != nil {
+		return err
+	}
+	for _, run := range runs {
+		var items []models.LDAPSyncItem
+		if json.Unmarshal([]byte(run.Report), &items) != nil {
+			continue
+		}
+		changed := false
+		for i := range items {
+			if items[i].UserID != user.ID && items[i].Username != user.Username {
+				continue
+			}
+			// DN、唯一标识和字段差异中都可能含有姓名、邮箱等目录属性，一并清除
+			items[i] = models.LDAPSyncItem{Action: items[i].Action, Username: placeholder, UserID: items[i].UserID, Message: items[i].Message, Error: items[i].Error}
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		report, _ := json.Marshal(items)
+		if err := tx.Model(&models.LDAPSyncRun{}).Where("id = ?", run.ID).Update("report", string(report)).Error; err != nil {
+			return err
+		}
+		cleared["ldap_sync_runs"]++
+	}
+	return nil
+}
+
+// identifierBoundary 用户名、邮箱中可能出现的字符，替换时前后不能紧接这些字符（避免替换其他用户名的一部分）
+const identifierBoundary = `[^A-Za-z0-9_.@+\-]`
+
+// replaceIdentifiers 把文本中作为完整词出现的用户名、邮箱替换为占位用户名
+func replaceIdentifiers(text string, identifiers []string, placeholder string) string {
+	for _, identifier := range identifiers {
+		if identifier == "" || identifier == placeholder {
+			continue
+		}
+		pattern := regexp.MustCompile(`(^|` + identifierBoundary + `)` + regexp.QuoteMeta(identifier) + `($|` + identifierBoundary + `)`)
+		// 相邻的两次出现共用分隔符，替换到不再变化为止
+		for {
+			replaced := pattern.ReplaceAllString(text, "${1}"+placeholder+"${2}")
+			if replaced == text {
+				break
+			}
+			text = replaced
+		}
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"yunmeng-backend/models"
+)
+
+func TestAnonymizeScrubsRecordedCopies(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.UserProfile{}, &models.LoginLog{}, &models.LoginAttempt{},
+		&models.PasswordResetToken{}, &models.PasswordHistory{}, &models.UserIdentity{}, &models.UserTwoFactor{},
+		&models.TwoFactorRecoveryCode{}, &models.ProjectNotification{}, &models.UserSession{}, &models.PersonalAccessToken{},
+		&models.CompetitionRegistration{}, &models.ProjectMember{}, &models.UserMerge{}, &models.LDAPSyncRun{}, &models.SystemLog{})
+	user := &models.User{Username: "alice", Email: "alice@yunmeng.test", Password: "x", Status: "active", RoleName: "student"}
+	other := &models.User{Username: "alice2", Email: "alice2@yunmeng.test", Password: "x", Status: "active", RoleName: "student"}
+	db.Create(user)
+	db.Create(other)
+
+	// 匿名化前留下的个人信息副本
+	db.Create(&models.SystemLog{LogType: "audit", Action: "PUT /api/users/:id", EntityType: "user", EntityID: "1",
+		Changes: `{"email":{"before":"alice@old.test","after":"alice@yunmeng.test"}}`})
+	failed := models.SystemLog{LogType: "security", Action: "登录失败", Details: "用户名: alice, 原因: bad_password"}
+	reset := models.SystemLog{LogType: "security", Action: "密码找回", Details: "账号: alice@yunmeng.test, 记录ID: 3"}
+	unrelated := models.SystemLog{LogType: "security", Action: "登录失败", Details: "用户名: alice2, 原因: bad_password"}
+	for _, entry := range []*models.SystemLog{&failed, &reset, &unrelated} {
+		db.Create(entry)
+	}
+	merge := models.UserMerge{SourceUserID: user.ID, SourceUsername: "alice", TargetUserID: other.ID, TargetUsername: "alice2"}
+	db.Create(&merge)
+	report, _ := json.Marshal([]models.LDAPSyncItem{
+		{Action: "update", DN: "uid=alice,ou=people", Subject: "uid=alice,ou=people", Username: "alice", UserID: user.ID,
+			Changes: map[string][2]string{"realName": {"", "爱丽丝"}}},
+		{Action: "create", DN: "uid=bob,ou=people", Username: "bob"},
+	})
+	run := models.LDAPSyncRun{TriggerType: "manual", Status: "success", StartedAt: time.Now(), Report: string(report)}
+	db.Create(&run)
+
+	result, err := NewUserPrivacyService(db).Anonymize(user.ID, models.UserAnonymizeRequest{Confirm: "alice"}, 99)
+	if err != nil {
+		t.Fatalf("匿名化失败: %v", err)
+	}
+	placeholder := result.Username
+
+	var audit models.SystemLog
+	db.Where("entity_type = ? AND entity_id = ?", "user", "1").First(&audit)
+	if audit.Changes != "" {
+		t.Errorf("用户实体的审计字段变更应清空: %s", audit.Changes)
+	}
+	for _, entry := range []struct {
+		id   uint
+		want string
+	}{
+		{failed.ID, "用户名: " + placeholder + ", 原因: bad_password"},
+		{reset.ID, "账号: " + placeholder + ", 记录ID: 3"},
+		{unrelated.ID, "用户名: alice2, 原因: bad_password"},
+	} {
+		var details string
+		db.Model(&models.SystemLog{}).Where("id = ?", entry.id).Pluck("details", &details)
+		if details != entry.want {
+			t.Errorf("日志详情应为 %q，实际 %q", entry.want, details)
+		}
+	}
+
+	db.First(&merge, merge.ID)
+	if merge.SourceUsername != placeholder || merge.TargetUsername != "alice2" {
+		t.Errorf("合并记录只应替换被匿名化用户的用户名: %+v", merge)
+	}
+
+	db.First(&run, run.ID)
+	if strings.Contains(run.Report, "alice") || strings.Contains(run.Report, "爱丽丝") || !strings.Contains(run.Report, "uid=bob") {
+		t.Errorf("同步报告中只应清除被匿名化用户的条目: %s", run.Report)
+	}
+
+	var logged int64
+	db.Model(&models.SystemLog{}).Where("operation = ? AND INSTR(details, ?) > 0", "user_anonymize", "alice").Count(&logged)
+	if logged != 0 {
+		t.Errorf("匿名化日志不应包含原用户名")
+	}
+}
+
+func TestReplaceIdentifiersMatchesWholeWords(t *testing.T) {
+	got := replaceIdentifiers("合并记录ID: 1, li(5) -> lily(6), 邮箱 li@x.test, 名称: li", []string{"li@x.test", "li"}, "anonymized_5")
+	want := "合并记录ID: 1, anonymized_5(5) -> lily(6), 邮箱 anonymized_5, 名称: anonymized_5"
+	if got != want {
+		t.Errorf("替换结果应为 %q，实际 %q", want, got)
+	}
+}
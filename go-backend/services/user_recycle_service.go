@@ -0,0 +1,346 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"yunmeng-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrUserHasReferences 回收站中的用户仍被业务数据引用，需确认后才能永久删除
+var ErrUserHasReferences = errors.New("该用户仍被业务数据引用，永久删除会使这些数据失去关联或被级联删除")
+
+type userReferenceColumn struct {
+	table, column, label string
+}
+
+// userReferenceColumns 引用 users.id 的业务数据列（不含随用户一起删除的档案、角色、会话等）
+var userReferenceColumns = []userReferenceColumn{
+	{"projects", "student_id", "学生项目"},
+	{"projects", "teacher_id", "指导的项目"},
+	{"projects", "approved_by", "审批的项目"},
+	{"project_reviews", "reviewer_id", "项目审核记录"},
+	{"project_files", "reviewed_by", "项目文件审核"},
+	{"project_extensions", "applicant_id", "延期申请"},
+	{"project_extensions", "reviewer_id", "延期审批"},
+	{"project_status_history", "changed_by", "项目状态变更记录"},
+	{"review_delegations", "original_reviewer_id", "委托出的审核"},
+	{"review_delegations", "delegated_reviewer_id", "受托的审核"},
+	{"project_notifications", "user_id", "项目通知"},
+	{"project_members", "user_id", "项目团队成员"},
+	{"project_members", "invited_by", "发出的团队邀请"},
+	{"student_teacher", "student_id", "师生绑定（学生）"},
+	{"student_teacher", "teacher_id", "师生绑定（教师）"},
+	{"competitions", "created_by", "创建的竞赛"},
+	{"competition_registrations", "student_id", "竞赛报名"},
+	{"competition_registrations", "teacher_id", "指导的竞赛报名"},
+	{"competition_submissions", "student_id", "竞赛作品"},
+	{"competition_feedback", "student_id", "竞赛反馈（学生）"},
+	{"competition_feedback", "teacher_id", "竞赛反馈（教师）"},
+	{"competition_feedback", "reviewer_id", "竞赛反馈（评审）"},
+	{"competition_judges", "teacher_id", "竞赛评委"},
+	{"competition_scores", "judge_id", "竞赛评分"},
+	{"competition_results", "student_id", "竞赛成绩"},
+}
+
+type UserRecycleService struct {
+	db         *gorm.DB
+	settings   *SettingService
+	systemLogs *SystemLogService
+}
+
+func NewUserRecycleService(db *gorm.DB) *UserRecycleService {
+	return &UserRecycleService{
+		db:         db,
+		settings:   NewSettingService(db),
+		systemLogs: NewSystemLogService(db),
+	}
+}
+
+// softDeleteUser 将用户移入回收站：记录删除人和原角色，解除角色关联（恢复时重新分配）
+func softDeleteUser(tx *gorm.DB, user *models.User, operatorID uint) error {
+	var roleIDs []uint
+	if err := tx.Model(&models.UserRole{}).Where("user_id = ?", user.ID).Pluck("role_id", &roleIDs).Error; err != nil {
+		return err
+	}
+	encoded, _ := json.Marshal(roleIDs)
+
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"deleted_by":       operatorID,
+		"deleted_role_ids": string(encoded),
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserRole{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(user).Error
+}
+
+// revokeDeletedUserAccess 撤销已删除用户的全部会话和个人访问令牌
+func revokeDeletedUserAccess(db *gorm.DB, userID uint) {
+	if _, err := NewAuthService(db).RevokeUserSessions(userID, "user_deleted"); err != nil {
+		log.Printf("撤销已删除用户会话失败 - 用户ID: %d, 错误: %v", userID, err)
+	}
+	if _, err := NewPersonalTokenService(db).RevokeUserTokens(userID); err != nil {
+		log.Printf("撤销已删除用户访问令牌失败 - 用户ID: %d, 错误: %v", userID, err)
+	}
+}
+
+// retentionDays 回收站保留天数，0 表示不自动清理
+func (s *UserRecycleService) retentionDays() int {
+	return s.settings.GetInt("user_recycle_retention_days", 30)
+}
+
+// findRecycled 查找回收站中的用户
+func (s *UserRecycleService) findRecycled(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("回收站中不存在该用户")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RecycledInScope 判断回收站中的用户是否在数据范围内
+func (s *UserRecycleService) RecycledInScope(id uint, scope *models.DataScope) bool {
+	if scope.IsGlobal() {
+		return true
+	}
+	var count int64
+	ApplyUserScope(s.db, s.db.Unscoped().Model(&models.User{}), scope, "users.id").
+		Where("users.id = ? AND users.deleted_at IS NOT NULL", id).Count(&count)
+	return count > 0
+}
+
+// GetRecycleBin 获取回收站中的用户列表
+func (s *UserRecycleService) GetRecycleBin(params models.UserQueryParams) ([]models.RecycledUserResponse, int64, error) {
+	query := s.db.Unscoped().Model(&models.User{}).Where("users.deleted_at IS NOT NULL")
+	if params.Search != "" {
+		search := "%" + params.Search + "%"
+		query = query.Where("users.username LIKE ? OR users.email LIKE ?", search, search)
+	}
+	if params.Department != "" {
+		query = query.Where("users.id IN (?)", DepartmentUserIDs(s.db, params.Department))
+	}
+	query = ApplyUserScope(s.db, query, params.Scope, "users.id")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("获取回收站用户总数失败: %v", err)
+		return nil, 0, err
+	}
+
+	var users []models.User
+	offset := (params.Page - 1) * params.Size
+	if err := query.Preload("Profile").Order("users.deleted_at DESC").
+		Offset(offset).Limit(params.Size).Find(&users).Error; err != nil {
+		log.Printf("获取回收站用户列表失败: %v", err)
+		return nil, 0, err
+	}
+
+	// 角色名和删除人
+	roleNames := make(map[uint]string)
+	var roles []models.Role
+	s.db.Find(&roles)
+	for _, role := range roles {
+		roleNames[role.ID] = role.RoleName
+	}
+	operatorIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		if user.DeletedBy != nil {
+			operatorIDs = append(operatorIDs, *user.DeletedBy)
+		}
+	}
+	operatorNames := make(map[uint]string)
+	if len(operatorIDs) > 0 {
+		var operators []models.User
+		s.db.Unscoped().Select("id", "username").Where("id IN ?", operatorIDs).Find(&operators)
+		for _, operator := range operators {
+			operatorNames[operator.ID] = operator.Username
+		}
+	}
+
+	retention := s.retentionDays()
+	list := make([]models.RecycledUserResponse, 0, len(users))
+	for _, user := range users {
+		item := models.RecycledUserResponse{
+			ID:         user.ID,
+			Username:   user.Username,
+			Email:      user.Email,
+			Status:     user.Status,
+			Department: user.Department,
+			RoleNames:  []string{},
+			DeletedAt:  user.DeletedAt.Time,
+			DeletedBy:  user.DeletedBy,
+		}
+		if user.Profile != nil {
+			item.RealName = user.Profile.RealName
+			item.Department = user.Profile.Department
+		}
+		for _, roleID := range decodeDeletedRoleIDs(user.DeletedRoleIDs) {
+			if name, ok := roleNames[roleID]; ok {
+				item.RoleNames = append(item.RoleNames, name)
+			}
+		}
+		if user.DeletedBy != nil {
+			item.DeletedByName = operatorNames[*user.DeletedBy]
+		}
+		if retention > 0 {
+			item.PurgeAt = user.DeletedAt.Time.AddDate(0, 0, retention)
+		}
+		list = append(list, item)
+	}
+	return list, total, nil
+}
+
+// Restore 从回收站恢复用户，并重新分配删除前仍然存在的角色
+func (s *UserRecycleService) Restore(id, operatorID uint) (*models.User, error) {
+	user, err := s.findRecycled(id)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := decodeDeletedRoleIDs(user.DeletedRoleIDs)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(user).Updates(map[string]interface{}{
+			"deleted_at":       nil,
+			"deleted_by":       nil,
+			"deleted_role_ids": "",
+		}).Error; err != nil {
+			return err
+		}
+		if len(roleIDs) == 0 {
+			return nil
+		}
+		// 删除期间被移除的角色不再恢复
+		var existing []uint
+		if err := tx.Model(&models.Role{}).Where("id IN ?", roleIDs).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		for _, roleID := range existing {
+			if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+				return err
+			}
+		}
+		roleIDs = existing
+		return nil
+	})
+	if err != nil {
+		log.Printf("恢复用户失败 - 用户ID: %d, 错误: %v", id, err)
+		return nil, errors.New("恢复用户失败")
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.DeletedBy = nil
+
+	s.systemLogs.RecordSecurity("user_restore", "从回收站恢复用户", "success", &operatorID,
+		fmt.Sprintf("用户ID: %d, 用户名: %s, 恢复角色ID: %v", user.ID, user.Username, roleIDs), "", "")
+	log.Printf("用户已从回收站恢复 - 用户ID: %d, 操作人ID: %d", id, operatorID)
+	return user, nil
+}
+
+// References 统计引用该用户的业务数据，供永久删除前确认
+func (s *UserRecycleService) References(id uint) (*models.UserReferenceReport, error) {
+	var user models.User
+	if err := s.db.Unscoped().Select("id", "username").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+
+	report := &models.UserReferenceReport{UserID: user.ID, Username: user.Username, References: []models.UserReference{}}
+	for _, ref := range userReferenceColumns {
+		var count int64
+		// 未启用的模块可能没有对应的表，忽略查询错误
+		if err := s.db.Table(ref.table).Where(ref.column+" = ?", id).Count(&count).Error; err != nil || count == 0 {
+			continue
+		}
+		report.References = append(report.References, models.UserReference{
+			Table: ref.table, Column: ref.column, Label: ref.label, Count: count,
+		})
+		report.Total += count
+	}
+	return report, nil
+}
+
+// Purge 永久删除回收站中的用户；仍有引用时须 force 才会删除（外键级联删除或置空引用数据）
+func (s *UserRecycleService) Purge(id uint, force bool, operatorID *uint) (*models.UserReferenceReport, error) {
+	user, err := s.findRecycled(id)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.References(id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Total > 0 && !force {
+		return report, ErrUserHasReferences
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserProfile{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(user).Error
+	})
+	if err != nil {
+		log.Printf("永久删除用户失败 - 用户ID: %d, 错误: %v", id, err)
+		return report, errors.New("永久删除用户失败，仍有数据限制删除该用户")
+	}
+
+	s.systemLogs.RecordSecurity("user_purge", "永久删除用户", "success", operatorID,
+		fmt.Sprintf("用户ID: %d, 用户名: %s, 引用数据: %d, 强制: %t", user.ID, user.Username, report.Total, force), "", "")
+	log.Printf("用户已永久删除 - 用户ID: %d, 引用数据: %d", id, report.Total)
+	return report, nil
+}
+
+// PurgeExpired 永久删除超过保留期且没有引用数据的用户，有引用的用户留在回收站等待管理员处理
+func (s *UserRecycleService) PurgeExpired() (purged, skipped int) {
+	retention := s.retentionDays()
+	if retention <= 0 {
+		return 0, 0
+	}
+	cutoff := time.Now().AddDate(0, 0, -retention)
+
+	var ids []uint
+	s.db.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Pluck("id", &ids)
+	for _, id := range ids {
+		if _, err := s.Purge(id, false, nil); err != nil {
+			skipped++
+			continue
+		}
+		purged++
+	}
+	if purged > 0 || skipped > 0 {
+		log.Printf("回收站清理完成 - 永久删除: %d, 因仍有引用保留: %d", purged, skipped)
+	}
+	return purged, skipped
+}
+
+// StartUserPurgeScheduler 每小时清理一次超过保留期的回收站用户
+func StartUserPurgeScheduler(db *gorm.DB) {
+	service := NewUserRecycleService(db)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			service.PurgeExpired()
+		}
+	}()
+}
+
+func decodeDeletedRoleIDs(value string) []uint {
+	var roleIDs []uint
+	if value != "" {
+		_ = json.Unmarshal([]byte(value), &roleIDs)
+	}
+	return roleIDs
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"time"
 	"yunmeng-backend/models"
@@ -18,6 +19,18 @@ func NewUserService(db *gorm.DB) *UserService {
 	return &UserService{db: db}
 }
 
+// basicRoleKeys 普通角色；拥有其他角色（管理员、院系管理员等）的账号在统一身份认证关联、账号合并时须显式确认
+var basicRoleKeys = []string{"student", "teacher"}
+
+func isBasicRole(roleKey string) bool {
+	for _, key := range basicRoleKeys {
+		if key == roleKey {
+			return true
+		}
+	}
+	return false
+}
+
 // GetUserList 获取用户列表
 func (s *UserService) GetUserList(params models.UserQueryParams) ([]models.UserListResponse, int64, error) {
 	var users []models.User
@@ -52,6 +65,14 @@ func (s *UserService) GetUserList(params models.UserQueryParams) ([]models.UserL
 			Where("user_profiles.department = ?", params.Department)
 	}
 
+	// 组织机构筛选（含下级节点）
+	if params.OrgUnitID > 0 {
+		query = query.Where("users.org_unit_id IN (?)", OrgUnitSubtreeIDs(s.db, params.OrgUnitID))
+	}
+
+	// 数据范围：院系管理员只能查看本院系用户
+	query = ApplyUserScope(s.db, query, params.Scope, "users.id")
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		log.Printf("获取用户总数失败: %v", err)
@@ -89,6 +110,8 @@ func (s *UserService) GetUserList(params models.UserQueryParams) ([]models.UserL
 			Username:   user.Username,
 			Email:      user.Email,
 			Status:     user.Status,
+			Major:      user.Major,
+			OrgUnitID:  user.OrgUnitID,
 			CreateTime: user.CreateTime,
 		}
 
@@ -164,6 +187,8 @@ func (s *UserService) GetUserByID(id uint) (*models.UserDetailResponse, error) {
 		})
 	}
 
+	response.ActiveSessions = NewAuthService(s.db).CountActiveSessions(user.ID)
+
 	log.Printf("用户详情查询完成 - 用户ID: %d, 用户名: %s", id, user.Username)
 	return response, nil
 }
@@ -171,16 +196,28 @@ func (s *UserService) GetUserByID(id uint) (*models.UserDetailResponse, error) {
 // CreateUser 创建用户
 func (s *UserService) CreateUser(req models.UserCreateRequest) (*models.User, error) {
 	// 检查用户名是否已存在
+	// 回收站中的用户仍占用用户名和邮箱，需先恢复或永久删除
 	var existingUser models.User
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := s.db.Unscoped().Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+		if existingUser.DeletedAt.Valid {
+			return nil, errors.New("用户名已被回收站中的用户占用")
+		}
 		return nil, errors.New("用户名已存在")
 	}
 
 	// 检查邮箱是否已存在
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := s.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+		if existingUser.DeletedAt.Valid {
+			return nil, errors.New("邮箱已被回收站中的用户占用")
+		}
 		return nil, errors.New("邮箱已存在")
 	}
 
+	// 初始密码须符合密码规则，用户首次登录后必须修改
+	if err := NewPasswordPolicyService(s.db).Validate(0, req.Password); err != nil {
+		return nil, err
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -197,10 +234,16 @@ func (s *UserService) CreateUser(req models.UserCreateRequest) (*models.User, er
 	}
 
 	user := models.User{
-		Username: req.Username,
-		Password: hashedPassword,
-		Email:    req.Email,
-		Status:   "active",
+		Username:           req.Username,
+		Password:           hashedPassword,
+		Email:              req.Email,
+		Status:             "active",
+		Department:         req.Department,
+		Title:              req.Title,
+		Grade:              req.Grade,
+		Major:              req.Major,
+		OrgUnitID:          req.OrgUnitID,
+		MustChangePassword: true,
 	}
 
 	if err := tx.Create(&user).Error; err != nil {
@@ -265,6 +308,15 @@ func (s *UserService) UpdateUser(id uint, req models.UserUpdateRequest) error {
 		return err
 	}
 
+	// 检查邮箱是否被其他用户使用
+	if req.Email != "" && req.Email != user.Email {
+		var count int64
+		s.db.Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, id).Count(&count)
+		if count > 0 {
+			return errors.New("邮箱已存在")
+		}
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -273,6 +325,35 @@ func (s *UserService) UpdateUser(id uint, req models.UserUpdateRequest) error {
 		}
 	}()
 
+	// 更新账号信息
+	userUpdates := make(map[string]interface{})
+	if req.Email != "" {
+		userUpdates["email"] = req.Email
+	}
+	if req.Department != "" {
+		userUpdates["department"] = req.Department
+	}
+	if req.Title != "" {
+		userUpdates["title"] = req.Title
+	}
+	if req.Grade != "" {
+		userUpdates["grade"] = req.Grade
+	}
+	if req.Major != "" {
+		userUpdates["major"] = req.Major
+	}
+	if req.OrgUnitID != nil {
+		userUpdates["org_unit_id"] = *req.OrgUnitID
+	}
+
+	if len(userUpdates) > 0 {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Updates(userUpdates).Error; err != nil {
+			tx.Rollback()
+			log.Printf("更新用户账号信息失败: %v", err)
+			return errors.New("更新用户信息失败")
+		}
+	}
+
 	// 更新用户详细信息
 	updates := make(map[string]interface{})
 	if req.RealName != "" {
@@ -336,8 +417,8 @@ func (s *UserService) UpdateUser(id uint, req models.UserUpdateRequest) error {
 	return nil
 }
 
-// DeleteUser 删除用户
-func (s *UserService) DeleteUser(id uint) error {
+// DeleteUser 删除用户（移入回收站，保留期内可恢复）
+func (s *UserService) DeleteUser(id, operatorID uint) error {
 	// 检查用户是否存在
 	var user models.User
 	if err := s.db.First(&user, id).Error; err != nil {
@@ -355,8 +436,8 @@ func (s *UserService) DeleteUser(id uint) error {
 		}
 	}()
 
-	// 删除用户（级联删除相关数据）
-	if err := tx.Delete(&user).Error; err != nil {
+	// 软删除用户，项目、报名、成绩等关联数据保持不变
+	if err := softDeleteUser(tx, &user, operatorID); err != nil {
 		tx.Rollback()
 		log.Printf("删除用户失败: %v", err)
 		return errors.New("删除用户失败")
@@ -368,7 +449,8 @@ func (s *UserService) DeleteUser(id uint) error {
 		return errors.New("删除用户失败")
 	}
 
-	log.Printf("用户删除成功 - 用户ID: %d", id)
+	revokeDeletedUserAccess(s.db, id)
+	log.Printf("用户已移入回收站 - 用户ID: %d, 操作人ID: %d", id, operatorID)
 	return nil
 }
 
@@ -389,6 +471,13 @@ func (s *UserService) ToggleUserStatus(id uint, status string) error {
 		return errors.New("更新用户状态失败")
 	}
 
+	// 禁用用户或转为校友时立即撤销其全部登录会话
+	if status != "active" {
+		if _, err := NewAuthService(s.db).RevokeUserSessions(id, "user_disabled"); err != nil {
+			return err
+		}
+	}
+
 	log.Printf("用户状态更新成功 - 用户ID: %d, 新状态: %s", id, status)
 	return nil
 }
@@ -404,30 +493,122 @@ func (s *UserService) ResetUserPassword(id uint) (string, error) {
 		return "", err
 	}
 
-	// 生成新密码
-	newPassword := utils.GenerateRandomPassword(8)
+	// 生成符合密码规则的新密码
+	policyService := NewPasswordPolicyService(s.db)
+	newPassword := policyService.GeneratePassword()
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
 		log.Printf("密码加密失败: %v", err)
 		return "", errors.New("密码加密失败")
 	}
 
-	// 更新密码
-	if err := s.db.Model(&user).Update("password", hashedPassword).Error; err != nil {
+	// 更新密码，用户下次登录必须先修改密码
+	if err := policyService.SetPassword(nil, user.ID, hashedPassword, true); err != nil {
 		log.Printf("更新密码失败: %v", err)
 		return "", errors.New("更新密码失败")
 	}
 
+	// 旧密码登录的会话全部失效
+	NewAuthService(s.db).RevokeUserSessions(id, "password_reset_by_admin")
+
 	log.Printf("用户密码重置成功 - 用户ID: %d", id)
 	return newPassword, nil
 }
 
-// BatchDeleteUsers 批量删除用户
-func (s *UserService) BatchDeleteUsers(userIDs []uint) (int64, error) {
+// ResetUserTwoFactor 重置用户两步验证（用户丢失验证设备时由管理员操作）
+func (s *UserService) ResetUserTwoFactor(id, operatorID uint) error {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return err
+	}
+
+	if err := NewTwoFactorService(s.db).Reset(id, operatorID); err != nil {
+		log.Printf("重置两步验证失败: %v", err)
+		return errors.New("重置两步验证失败")
+	}
+
+	// 重置后撤销已有会话，要求重新登录
+	NewAuthService(s.db).RevokeUserSessions(id, "two_factor_reset")
+	return nil
+}
+
+// GetUserSessions 获取用户当前有效的登录会话（管理员查看登录设备）
+func (s *UserService) GetUserSessions(id uint) ([]models.UserSession, error) {
+	var user models.User
+	if err := s.db.Select("id").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	return NewAuthService(s.db).GetActiveSessions(id, 0)
+}
+
+// TerminateUserSessions 管理员强制下线用户的指定会话，sessionID 为0时下线全部会话
+func (s *UserService) TerminateUserSessions(id, sessionID, operatorID uint, ipAddress, userAgent string) (int64, error) {
+	authService := NewAuthService(s.db)
+
+	var count int64
+	if sessionID == 0 {
+		revoked, err := authService.RevokeUserSessions(id, "admin_terminate")
+		if err != nil {
+			return 0, err
+		}
+		count = revoked
+	} else {
+		if err := authService.RevokeUserSession(id, sessionID, "admin_terminate"); err != nil {
+			return 0, err
+		}
+		count = 1
+	}
+
+	NewSystemLogService(s.db).RecordSecurity("session_terminate", "强制下线用户会话", "success", &operatorID,
+		fmt.Sprintf("用户ID: %d, 会话ID: %d（0表示全部）, 下线数量: %d", id, sessionID, count), ipAddress, userAgent)
+	return count, nil
+}
+
+// GetUserIdentities 获取用户绑定的统一身份认证账号
+func (s *UserService) GetUserIdentities(id uint) ([]models.UserIdentity, error) {
+	var user models.User
+	if err := s.db.Select("id").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	return NewSSOService(s.db).GetUserIdentities(id)
+}
+
+// UnlinkUserIdentity 解除用户的统一身份认证账号绑定
+func (s *UserService) UnlinkUserIdentity(id, identityID, operatorID uint, ipAddress, userAgent string) error {
+	return NewSSOService(s.db).UnlinkIdentity(id, identityID, operatorID, ipAddress, userAgent)
+}
+
+// ImpersonateUser 管理员以指定用户身份登录查看（限时令牌，默认只读）
+func (s *UserService) ImpersonateUser(id, operatorID uint, req models.ImpersonationRequest, ipAddress, userAgent string) (*models.ImpersonationResponse, error) {
+	return NewImpersonationService(s.db).Start(operatorID, id, req, ipAddress, userAgent)
+}
+
+// UserInScope 判断用户是否在数据范围内
+func (s *UserService) UserInScope(id uint, scope *models.DataScope) bool {
+	return UserInScope(s.db, scope, id)
+}
+
+// BatchDeleteUsers 批量删除用户（移入回收站）
+func (s *UserService) BatchDeleteUsers(userIDs []uint, operatorID uint) (int64, error) {
 	if len(userIDs) == 0 {
 		return 0, errors.New("用户ID列表不能为空")
 	}
 
+	var users []models.User
+	if err := s.db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		log.Printf("查询待删除用户失败: %v", err)
+		return 0, errors.New("批量删除用户失败")
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -436,12 +617,13 @@ func (s *UserService) BatchDeleteUsers(userIDs []uint) (int64, error) {
 		}
 	}()
 
-	// 批量删除用户
-	result := tx.Where("id IN ?", userIDs).Delete(&models.User{})
-	if result.Error != nil {
-		tx.Rollback()
-		log.Printf("批量删除用户失败: %v", result.Error)
-		return 0, errors.New("批量删除用户失败")
+	// 批量软删除用户
+	for i := range users {
+		if err := softDeleteUser(tx, &users[i], operatorID); err != nil {
+			tx.Rollback()
+			log.Printf("批量删除用户失败 - 用户ID: %d, 错误: %v", users[i].ID, err)
+			return 0, errors.New("批量删除用户失败")
+		}
 	}
 
 	// 提交事务
@@ -450,31 +632,44 @@ func (s *UserService) BatchDeleteUsers(userIDs []uint) (int64, error) {
 		return 0, errors.New("批量删除用户失败")
 	}
 
-	log.Printf("批量删除用户成功 - 删除数量: %d", result.RowsAffected)
-	return result.RowsAffected, nil
+	for _, user := range users {
+		revokeDeletedUserAccess(s.db, user.ID)
+	}
+
+	log.Printf("批量删除用户成功 - 删除数量: %d", len(users))
+	return int64(len(users)), nil
 }
 
-// GetUserStats 获取用户统计信息
-func (s *UserService) GetUserStats() (*models.UserStats, error) {
+// GetUserStats 获取用户统计信息，orgUnitID 大于0时只统计该组织机构节点（含下级节点）的用户
+func (s *UserService) GetUserStats(orgUnitID uint) (*models.UserStats, error) {
 	stats := &models.UserStats{
 		RoleStats:       make(map[string]int64),
 		DepartmentStats: make(map[string]int64),
 	}
 
+	// 统计范围内的用户（不含回收站中的用户）
+	users := func() *gorm.DB {
+		query := s.db.Model(&models.User{})
+		if orgUnitID > 0 {
+			query = query.Where("org_unit_id IN (?)", OrgUnitSubtreeIDs(s.db, orgUnitID))
+		}
+		return query
+	}
+
 	// 获取总用户数
-	if err := s.db.Model(&models.User{}).Count(&stats.TotalUsers).Error; err != nil {
+	if err := users().Count(&stats.TotalUsers).Error; err != nil {
 		log.Printf("获取总用户数失败: %v", err)
 		return nil, err
 	}
 
 	// 获取活跃用户数
-	if err := s.db.Model(&models.User{}).Where("status = ?", "active").Count(&stats.ActiveUsers).Error; err != nil {
+	if err := users().Where("status = ?", "active").Count(&stats.ActiveUsers).Error; err != nil {
 		log.Printf("获取活跃用户数失败: %v", err)
 		return nil, err
 	}
 
 	// 获取禁用用户数
-	if err := s.db.Model(&models.User{}).Where("status = ?", "inactive").Count(&stats.InactiveUsers).Error; err != nil {
+	if err := users().Where("status = ?", "inactive").Count(&stats.InactiveUsers).Error; err != nil {
 		log.Printf("获取禁用用户数失败: %v", err)
 		return nil, err
 	}
@@ -486,7 +681,7 @@ func (s *UserService) GetUserStats() (*models.UserStats, error) {
 	}
 	if err := s.db.Table("roles").
 		Select("roles.role_key, COUNT(user_roles.user_id) as count").
-		Joins("LEFT JOIN user_roles ON roles.id = user_roles.role_id").
+		Joins("LEFT JOIN user_roles ON roles.id = user_roles.role_id AND user_roles.user_id IN (?)", users().Select("id")).
 		Group("roles.id, roles.role_key").
 		Scan(&roleStats).Error; err != nil {
 		log.Printf("获取角色统计失败: %v", err)
@@ -505,6 +700,7 @@ func (s *UserService) GetUserStats() (*models.UserStats, error) {
 	if err := s.db.Table("user_profiles").
 		Select("department, COUNT(*) as count").
 		Where("department IS NOT NULL AND department != ''").
+		Where("user_id IN (?)", users().Select("id")).
 		Group("department").
 		Scan(&deptStats).Error; err != nil {
 		log.Printf("获取部门统计失败: %v", err)
@@ -515,14 +711,23 @@ func (s *UserService) GetUserStats() (*models.UserStats, error) {
 		stats.DepartmentStats[ds.Department] = ds.Count
 	}
 
+	// 获取组织机构统计（下一级节点）
+	orgStats, err := NewOrgUnitService(s.db).UserStats(orgUnitID)
+	if err != nil {
+		log.Printf("获取组织机构统计失败: %v", err)
+		return nil, err
+	}
+	stats.OrgUnitStats = orgStats
+
 	log.Printf("用户统计信息获取成功 - 总用户数: %d", stats.TotalUsers)
 	return stats, nil
 }
 
-// RecordLoginLog 记录登录日志
-func (s *UserService) RecordLoginLog(userID uint, ipAddress, userAgent string) error {
+// RecordLoginLog 记录登录日志，关联本次登录创建的会话
+func (s *UserService) RecordLoginLog(userID, sessionID uint, ipAddress, userAgent string) error {
 	loginLog := models.LoginLog{
 		UserID:    userID,
+		SessionID: &sessionID,
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
 	}
@@ -550,3 +755,19 @@ func (s *UserService) UpdateLastLogin(userID uint) error {
 func (s *UserService) GetDB() *gorm.DB {
 	return s.db
 }
+
+// ResolveOrgUnit 按组织机构节点填写院系和专业文本
+func (s *UserService) ResolveOrgUnit(orgUnitID *uint, department, major *string) error {
+	if orgUnitID == nil {
+		return nil
+	}
+	dept, majorName, err := OrgUnitLabels(s.db, *orgUnitID)
+	if err != nil {
+		return err
+	}
+	*department = dept
+	if majorName != "" {
+		*major = majorName
+	}
+	return nil
+}
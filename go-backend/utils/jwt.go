@@ -1,34 +1,91 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("yunmeng-secret-key")
+// AccessTokenTTL 访问令牌有效期，过期后需使用刷新令牌换取新令牌
+const AccessTokenTTL = 30 * time.Minute
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
+	// ImpersonatorID 模拟登录时发起模拟的管理员ID，UserID 为被模拟的用户
+	ImpersonatorID uint `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// 生成JWT Token
-func GenerateToken(userID uint, username, role string) (string, error) {
+// 生成JWT Token（绑定登录会话）
+func GenerateToken(userID uint, username, role string, sessionID uint) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)), // 7天有效期
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()), // 立即生效
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
+}
+
+// GenerateImpersonationToken 生成模拟登录令牌（同时携带管理员ID和被模拟用户ID，不可刷新）
+func GenerateImpersonationToken(userID uint, username, role string, sessionID, impersonatorID uint, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:         userID,
+		Username:       username,
+		Role:           role,
+		SessionID:      sessionID,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return signClaims(claims)
+}
+
+// ActionClaims 一次性操作令牌（如重置密码链接），Purpose 限定用途，ID 对应服务端记录
+type ActionClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken 生成签名的一次性操作令牌，是否已使用由调用方按 jti 记录
+func GenerateActionToken(userID uint, purpose, jti string, ttl time.Duration) (string, error) {
+	claims := ActionClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return signClaims(claims)
+}
+
+// ParseActionToken 解析一次性操作令牌并校验用途
+func ParseActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, lookupVerifyKey)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*ActionClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.ID == "" {
+		return nil, errors.New("令牌用途不匹配")
+	}
+	return claims, nil
 }
 
 // 生成短期Token（用于刷新）
@@ -43,15 +100,12 @@ func GenerateShortToken(userID uint, username, role string) (string, error) {
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signClaims(claims)
 }
 
 // 解析JWT Token
 func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, lookupVerifyKey)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SigningKey JWT签名密钥（私钥为空时仅用于验签，例如已轮换下线的旧密钥）
+type SigningKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// CanSign 是否可用于签发令牌
+func (k *SigningKey) CanSign() bool {
+	return k.SignKey != nil
+}
+
+// IsAsymmetric 是否为非对称密钥（可通过JWKS公开）
+func (k *SigningKey) IsAsymmetric() bool {
+	switch k.VerifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return true
+	}
+	return false
+}
+
+type keyRegistry struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// signingKeys 由 config.LoadJWTKeys 在启动时装载
+var signingKeys = &keyRegistry{keys: map[string]*SigningKey{}}
+
+// NewHMACKey 创建HS256共享密钥
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		KID:       kid,
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   secret,
+		VerifyKey: secret,
+	}
+}
+
+// LoadSigningKeyFile 从文件加载密钥，文件名（去掉扩展名）即为kid
+//
+//	<kid>.key  HS256共享密钥（文件内容即密钥）
+//	<kid>.pem  RSA或Ed25519私钥（RS256 / EdDSA）
+//	<kid>.pub  RSA或Ed25519公钥，仅用于验签
+func LoadSigningKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	kid := strings.TrimSuffix(base, ext)
+	if kid == "" {
+		return nil, fmt.Errorf("密钥文件名无效: %s", base)
+	}
+
+	switch ext {
+	case ".key":
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("HS256密钥长度不能少于32字节: %s", base)
+		}
+		return NewHMACKey(kid, secret), nil
+	case ".pem":
+		if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, SignKey: key, VerifyKey: &key.PublicKey}, nil
+		}
+		if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			edKey := key.(ed25519.PrivateKey)
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, SignKey: edKey, VerifyKey: edKey.Public()}, nil
+		}
+		return nil, fmt.Errorf("无法解析私钥（仅支持RSA/Ed25519）: %s", base)
+	case ".pub":
+		if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, VerifyKey: key}, nil
+		}
+		if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, VerifyKey: key}, nil
+		}
+		return nil, fmt.Errorf("无法解析公钥（仅支持RSA/Ed25519）: %s", base)
+	}
+	return nil, fmt.Errorf("不支持的密钥文件类型: %s", base)
+}
+
+// SetSigningKeys 替换当前密钥集合，activeKID指定用于签发新令牌的密钥
+func SetSigningKeys(keys []*SigningKey, activeKID string) error {
+	keyMap := make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		if _, exists := keyMap[key.KID]; exists {
+			return fmt.Errorf("重复的密钥ID: %s", key.KID)
+		}
+		keyMap[key.KID] = key
+	}
+
+	active, ok := keyMap[activeKID]
+	if !ok {
+		return fmt.Errorf("签名密钥不存在: %s", activeKID)
+	}
+	if !active.CanSign() {
+		return fmt.Errorf("签名密钥缺少私钥: %s", activeKID)
+	}
+
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+	signingKeys.active = active
+	signingKeys.keys = keyMap
+	return nil
+}
+
+// ActiveSigningKey 获取当前签名密钥
+func ActiveSigningKey() *SigningKey {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+	return signingKeys.active
+}
+
+// lookupVerifyKey 根据令牌头中的kid查找验签密钥，并要求算法与密钥严格一致
+func lookupVerifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("令牌缺少kid")
+	}
+
+	signingKeys.mu.RLock()
+	key, ok := signingKeys.keys[kid]
+	signingKeys.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥ID: %s", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("签名算法不匹配: %s", token.Method.Alg())
+	}
+	return key.VerifyKey, nil
+}
+
+// signClaims 使用当前签名密钥签发令牌
+func signClaims(claims jwt.Claims) (string, error) {
+	key := ActiveSigningKey()
+	if key == nil {
+		return "", errors.New("JWT签名密钥未配置")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.SignKey)
+}
+
+// JWKS 返回所有非对称密钥的公钥集合（RFC 7517），共享密钥不会公开
+func JWKS() map[string]interface{} {
+	signingKeys.mu.RLock()
+	kids := make([]string, 0, len(signingKeys.keys))
+	for kid := range signingKeys.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		key := signingKeys.keys[kid]
+		if !key.IsAsymmetric() {
+			continue
+		}
+		jwks = append(jwks, publicJWK(key))
+	}
+	signingKeys.mu.RUnlock()
+
+	return map[string]interface{}{"keys": jwks}
+}
+
+func publicJWK(key *SigningKey) map[string]string {
+	jwk := map[string]string{
+		"kid": key.KID,
+		"alg": key.Method.Alg(),
+		"use": "sig",
+	}
+
+	switch pub := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
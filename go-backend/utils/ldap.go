@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrLDAPNotConfigured      = errors.New("未配置LDAP目录")
+	ErrLDAPInvalidCredentials = errors.New("目录账号或密码错误")
+)
+
+// LDAPEntry 目录中的一条用户记录，属性名统一小写，多值属性只保留第一个值
+type LDAPEntry struct {
+	DN         string
+	Attributes map[string]string
+}
+
+// Get 读取属性值（属性名不区分大小写），属性名为空时返回空字符串
+func (e LDAPEntry) Get(name string) string {
+	if name == "" {
+		return ""
+	}
+	return e.Attributes[strings.ToLower(name)]
+}
+
+// LDAPAttributeMapping 本地字段 -> LDAP 属性名
+type LDAPAttributeMapping struct {
+	ID         string // 唯一标识，默认 entryUUID，为空值时使用 DN
+	Username   string
+	Email      string
+	RealName   string
+	Phone      string
+	StudentID  string
+	Department string
+	Title      string
+	Grade      string
+	Major      string
+	Type       string // 人员类型（如 student / staff），按 RoleMap 映射为角色
+}
+
+// LDAPDirectory LDAP 目录连接配置
+type LDAPDirectory struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	Filter       string
+	StartTLS     bool
+	// InsecureSkipVerify 仅用于自签名证书的测试环境
+	InsecureSkipVerify bool
+	Mapping            LDAPAttributeMapping
+	// RoleMap 人员类型 -> 角色 role_key；DefaultRole 为空表示未匹配的人员不同步
+	RoleMap     map[string]string
+	DefaultRole string
+}
+
+// Attributes 需要从目录读取的属性列表
+func (d *LDAPDirectory) Attributes() []string {
+	m := d.Mapping
+	var attrs []string
+	for _, name := range []string{m.ID, m.Username, m.Email, m.RealName, m.Phone, m.StudentID, m.Department, m.Title, m.Grade, m.Major, m.Type} {
+		if name != "" {
+			attrs = append(attrs, name)
+		}
+	}
+	return attrs
+}
+
+// Role 按人员类型计算角色
+func (d *LDAPDirectory) Role(entry LDAPEntry) string {
+	if role, ok := d.RoleMap[strings.ToLower(entry.Get(d.Mapping.Type))]; ok {
+		return role
+	}
+	return d.DefaultRole
+}
+
+// connect 连接目录并按配置启用 StartTLS、使用服务账号绑定
+func (d *LDAPDirectory) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.URL, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: d.InsecureSkipVerify}))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP失败: %v", err)
+	}
+	conn.SetTimeout(30 * time.Second)
+
+	if d.StartTLS {
+		if err := conn.StartTLS(&tls.Config{InsecureSkipVerify: d.InsecureSkipVerify}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS 失败: %v", err)
+		}
+	}
+	if d.BindDN != "" {
+		if err := conn.Bind(d.BindDN, d.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP 绑定失败: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+// Search 读取目录中符合过滤条件的全部用户（分页读取，避免超过服务端单次返回上限）
+func (d *LDAPDirectory) Search() ([]LDAPEntry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := ldap.NewSearchRequest(d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		d.Filter, d.Attributes(), nil)
+	result, err := conn.SearchWithPaging(request, 500)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP 查询失败: %v", err)
+	}
+
+	entries := make([]LDAPEntry, 0, len(result.Entries))
+	for _, item := range result.Entries {
+		entries = append(entries, newLDAPEntry(item))
+	}
+	return entries, nil
+}
+
+// UserFilter 按用户名查找单个目录账号的过滤条件（在同步过滤条件基础上追加用户名，用户名已转义）
+func (d *LDAPDirectory) UserFilter(username string) string {
+	return fmt.Sprintf("(&%s(%s=%s))", d.Filter, d.Mapping.Username, ldap.EscapeFilter(username))
+}
+
+// Authenticate 使用目录密码认证：先用服务账号按用户名查找条目，再以该条目 DN 和密码绑定。
+// 密码为空时直接拒绝，避免被目录当作匿名绑定放行
+func (d *LDAPDirectory) Authenticate(username, password string) (LDAPEntry, error) {
+	if username == "" || password == "" {
+		return LDAPEntry{}, ErrLDAPInvalidCredentials
+	}
+	conn, err := d.connect()
+	if err != nil {
+		return LDAPEntry{}, err
+	}
+	defer conn.Close()
+
+	request := ldap.NewSearchRequest(d.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		d.UserFilter(username), d.Attributes(), nil)
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return LDAPEntry{}, fmt.Errorf("LDAP 查询失败: %v", err)
+	}
+	// 用户名在目录中不存在或不唯一时都不允许登录
+	if result == nil || len(result.Entries) != 1 {
+		return LDAPEntry{}, ErrLDAPInvalidCredentials
+	}
+
+	entry := newLDAPEntry(result.Entries[0])
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return LDAPEntry{}, ErrLDAPInvalidCredentials
+		}
+		return LDAPEntry{}, fmt.Errorf("LDAP 绑定失败: %v", err)
+	}
+	return entry, nil
+}
+
+// newLDAPEntry 转换为 LDAPEntry，属性名转小写，多值属性只保留第一个值
+func newLDAPEntry(item *ldap.Entry) LDAPEntry {
+	entry := LDAPEntry{DN: item.DN, Attributes: map[string]string{}}
+	for _, attr := range item.Attributes {
+		if len(attr.Values) > 0 {
+			entry.Attributes[strings.ToLower(attr.Name)] = strings.TrimSpace(attr.Values[0])
+		}
+	}
+	return entry
+}
+
+var ldapDirectory = struct {
+	mu        sync.RWMutex
+	directory *LDAPDirectory
+}{}
+
+// SetLDAPDirectory 设置LDAP目录配置
+func SetLDAPDirectory(directory *LDAPDirectory) {
+	ldapDirectory.mu.Lock()
+	defer ldapDirectory.mu.Unlock()
+	ldapDirectory.directory = directory
+}
+
+// GetLDAPDirectory 获取LDAP目录配置
+func GetLDAPDirectory() (*LDAPDirectory, error) {
+	ldapDirectory.mu.RLock()
+	defer ldapDirectory.mu.RUnlock()
+	if ldapDirectory.directory == nil {
+		return nil, ErrLDAPNotConfigured
+	}
+	return ldapDirectory.directory, nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"log"
+	"mime"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// MailMessage 待发送的邮件
+type MailMessage struct {
+	To      []string
+	Subject string
+	Body    string // 纯文本正文
+}
+
+// MailSender 邮件发送器，可替换为SMTP、本地邮件捕获工具或测试桩
+type MailSender interface {
+	Send(msg MailMessage) error
+}
+
+// SMTPSender 通过SMTP发送邮件；本地开发可指向 MailHog/Mailpit 等邮件捕获工具（不需要账号密码）
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send 发送纯文本邮件
+func (s *SMTPSender) Send(msg MailMessage) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	header := []string{
+		"From: " + s.From,
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	body := strings.Join(header, "\r\n") + "\r\n\r\n" + msg.Body
+
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, msg.To, []byte(body))
+}
+
+// LogSender 只把邮件写入日志，未配置SMTP时使用
+type LogSender struct{}
+
+// Send 记录邮件内容
+func (LogSender) Send(msg MailMessage) error {
+	log.Printf("邮件未发送（未配置SMTP）- 收件人: %v, 主题: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+var mailSender = struct {
+	mu     sync.RWMutex
+	sender MailSender
+}{sender: LogSender{}}
+
+// SetMailSender 设置全局邮件发送器
+func SetMailSender(sender MailSender) {
+	mailSender.mu.Lock()
+	defer mailSender.mu.Unlock()
+	mailSender.sender = sender
+}
+
+// GetMailSender 获取当前邮件发送器
+func GetMailSender() MailSender {
+	mailSender.mu.RLock()
+	defer mailSender.mu.RUnlock()
+	return mailSender.sender
+}
@@ -3,11 +3,75 @@ package utils
 import (
 	cryptorand "crypto/rand"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
 	mathrand "math/rand"
+	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// maxPasswordLength bcrypt 只使用前72字节
+const maxPasswordLength = 72
+
+// PasswordPolicy 密码复杂度规则
+type PasswordPolicy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// DefaultPasswordPolicy 默认密码规则：至少8位，包含字母和数字
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     8,
+	RequireLetter: true,
+	RequireDigit:  true,
+}
+
+// ValidatePassword 按规则校验密码，返回第一条不满足的规则
+func ValidatePassword(password string, policy PasswordPolicy) error {
+	if len(password) < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("密码长度不能超过%d位", maxPasswordLength)
+	}
+
+	var hasLetter, hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasLetter, hasUpper = true, true
+		case unicode.IsLower(r):
+			hasLetter, hasLower = true, true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsSpace(r):
+			return errors.New("密码不能包含空白字符")
+		default:
+			hasSymbol = true
+		}
+	}
+
+	switch {
+	case policy.RequireLetter && !hasLetter:
+		return errors.New("密码必须包含字母")
+	case policy.RequireUpper && !hasUpper:
+		return errors.New("密码必须包含大写字母")
+	case policy.RequireLower && !hasLower:
+		return errors.New("密码必须包含小写字母")
+	case policy.RequireDigit && !hasDigit:
+		return errors.New("密码必须包含数字")
+	case policy.RequireSymbol && !hasSymbol:
+		return errors.New("密码必须包含特殊字符")
+	}
+	return nil
+}
+
 // HashPassword 使用bcrypt加密密码
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -30,6 +94,58 @@ func GenerateRandomPassword(length int) string {
 	return string(b)
 }
 
+// GeneratePolicyPassword 生成满足规则的随机密码（每类要求的字符至少一个，长度不少于12位）
+func GeneratePolicyPassword(policy PasswordPolicy) string {
+	const (
+		upper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower   = "abcdefghijkmnpqrstuvwxyz"
+		digits  = "23456789"
+		symbols = "!@#$%^&*-_=+"
+	)
+	length := policy.MinLength
+	if length < 12 {
+		length = 12
+	}
+
+	var required []string
+	if policy.RequireUpper {
+		required = append(required, upper)
+	}
+	if policy.RequireLower || policy.RequireLetter {
+		required = append(required, lower)
+	}
+	if policy.RequireDigit {
+		required = append(required, digits)
+	}
+	if policy.RequireSymbol {
+		required = append(required, symbols)
+	}
+	all := upper + lower + digits
+
+	b := make([]byte, 0, length)
+	for _, charset := range required {
+		b = append(b, charset[randomIndex(len(charset))])
+	}
+	for len(b) < length {
+		b = append(b, all[randomIndex(len(all))])
+	}
+	// 打乱顺序，避免固定位置出现某类字符
+	for i := len(b) - 1; i > 0; i-- {
+		j := randomIndex(i + 1)
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+// randomIndex 使用加密随机数生成 [0, n) 的下标
+func randomIndex(n int) int {
+	v, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return mathrand.Intn(n)
+	}
+	return int(v.Int64())
+}
+
 // GenerateRandomString 生成随机字符串
 func GenerateRandomString(length int) string {
 	b := make([]byte, length)
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"yunmeng-backend/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -54,3 +55,37 @@ func GenerateFileName(originalName string) string {
 	}
 	return fmt.Sprintf("%d%s", timestamp, ext)
 }
+
+// GetDataScope 从上下文中获取当前用户的数据范围，未设置时按最小范围处理（看不到任何院系数据）
+func GetDataScope(c *gin.Context) *models.DataScope {
+	if scope, ok := c.Get("dataScope"); ok {
+		if ds, ok := scope.(*models.DataScope); ok {
+			return ds
+		}
+	}
+	return &models.DataScope{}
+}
+
+// GetImpersonatorID 获取模拟登录的管理员ID，非模拟登录请求返回0
+func GetImpersonatorID(c *gin.Context) uint {
+	if value, ok := c.Get("impersonatorID"); ok {
+		if id, ok := value.(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
+
+// SetAuditEntity 处理器回填本次写操作涉及的实体ID（用于创建类接口，路径中没有ID）
+func SetAuditEntity(c *gin.Context, id uint) {
+	c.Set("auditEntityID", id)
+}
+
+// GetAuditEntityID 获取处理器回填的审计实体ID
+func GetAuditEntityID(c *gin.Context) (uint, bool) {
+	if value, ok := c.Get("auditEntityID"); ok {
+		id, ok := value.(uint)
+		return id, ok
+	}
+	return 0, false
+}
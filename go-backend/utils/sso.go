@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrSSOProviderNotFound = errors.New("统一身份认证方式不存在或未启用")
+
+// SSOIdentity 统一身份认证平台返回的用户身份
+type SSOIdentity struct {
+	Subject    string            // 用户在身份平台中的唯一标识
+	Attributes map[string]string // 身份平台返回的属性（多值属性只保留第一个值）
+	Groups     []string          // 用户所属的组
+}
+
+// SSOCallback 身份平台回调参数：OIDC 使用 Code，CAS 使用 Ticket；
+// State 为发起登录时签发的状态令牌，Nonce 为其中携带的随机数
+type SSOCallback struct {
+	Code   string
+	Ticket string
+	State  string
+	Nonce  string
+}
+
+// SSOProvider 统一身份认证提供方（与账号密码登录并列的认证方式）
+type SSOProvider interface {
+	Key() string
+	Name() string
+	Type() string
+	// AuthURL 返回跳转到身份平台登录页的地址
+	AuthURL(state, nonce string) (string, error)
+	// Authenticate 用回调参数向身份平台换取并校验用户身份
+	Authenticate(callback SSOCallback) (*SSOIdentity, error)
+}
+
+// SSOMapping 身份平台属性、组到本地账号的映射规则
+type SSOMapping struct {
+	// AutoProvision 首次登录且无法关联已有账号时自动创建账号
+	AutoProvision bool `json:"autoProvision"`
+	// LinkBy 首次登录时用于关联已有账号的本地字段：username、email、studentId，为空表示不关联
+	LinkBy string `json:"linkBy"`
+	// Attributes 本地字段 -> 身份平台属性名，本地字段可选：
+	// username、email、realName、phone、studentId、department、grade、major、title
+	Attributes map[string]string `json:"attributes"`
+	// GroupRoles 身份平台组 -> 本地角色 role_key
+	GroupRoles map[string]string `json:"groupRoles"`
+	// DefaultRoles 没有任何组匹配时授予的角色
+	DefaultRoles []string `json:"defaultRoles"`
+}
+
+// Attribute 按映射读取本地字段对应的身份平台属性值
+func (m SSOMapping) Attribute(identity *SSOIdentity, field string) string {
+	name, ok := m.Attributes[field]
+	if !ok || name == "" {
+		return ""
+	}
+	return identity.Attributes[name]
+}
+
+// Roles 根据身份平台组计算应授予的角色，没有匹配时返回默认角色
+func (m SSOMapping) Roles(identity *SSOIdentity) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	for _, group := range identity.Groups {
+		if role, ok := m.GroupRoles[group]; ok && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = append(roles, m.DefaultRoles...)
+	}
+	return roles
+}
+
+// SSORegistration 已装载的认证提供方及其映射规则
+type SSORegistration struct {
+	Provider SSOProvider
+	Mapping  SSOMapping
+}
+
+var ssoProviders = struct {
+	mu    sync.RWMutex
+	items map[string]*SSORegistration
+}{items: map[string]*SSORegistration{}}
+
+// SetSSOProviders 替换当前启用的认证提供方
+func SetSSOProviders(registrations []*SSORegistration) {
+	items := make(map[string]*SSORegistration, len(registrations))
+	for _, registration := range registrations {
+		items[registration.Provider.Key()] = registration
+	}
+
+	ssoProviders.mu.Lock()
+	defer ssoProviders.mu.Unlock()
+	ssoProviders.items = items
+}
+
+// GetSSOProvider 按标识获取认证提供方
+func GetSSOProvider(key string) (*SSORegistration, error) {
+	ssoProviders.mu.RLock()
+	defer ssoProviders.mu.RUnlock()
+	registration, ok := ssoProviders.items[key]
+	if !ok {
+		return nil, ErrSSOProviderNotFound
+	}
+	return registration, nil
+}
+
+// ListSSOProviders 按标识排序返回全部认证提供方
+func ListSSOProviders() []*SSORegistration {
+	ssoProviders.mu.RLock()
+	defer ssoProviders.mu.RUnlock()
+	list := make([]*SSORegistration, 0, len(ssoProviders.items))
+	for _, registration := range ssoProviders.items {
+		list = append(list, registration)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Provider.Key() < list[j].Provider.Key()
+	})
+	return list
+}
+
+// ssoHTTPClient 访问身份平台的HTTP客户端
+var ssoHTTPClient = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// CASProvider CAS 3.0 协议认证（通过 /p3/serviceValidate 校验票据并读取属性）
+type CASProvider struct {
+	ProviderKey string
+	DisplayName string
+	// BaseURL CAS 服务地址，例如 https://cas.example.edu.cn/cas
+	BaseURL string
+	// ServiceURL 登录完成后 CAS 跳回的前端地址，状态令牌以 state 参数附加
+	ServiceURL string
+	// GroupsAttribute 组属性名，默认 groups
+	GroupsAttribute string
+}
+
+func (p *CASProvider) Key() string  { return p.ProviderKey }
+func (p *CASProvider) Name() string { return p.DisplayName }
+func (p *CASProvider) Type() string { return "cas" }
+
+// AuthURL 拼接 CAS 登录地址
+func (p *CASProvider) AuthURL(state, nonce string) (string, error) {
+	query := url.Values{}
+	query.Set("service", p.serviceURL(state))
+	return AppendQuery(strings.TrimSuffix(p.BaseURL, "/")+"/login", query), nil
+}
+
+// Authenticate 校验 CAS 票据；service 参数必须与登录时完全一致
+func (p *CASProvider) Authenticate(callback SSOCallback) (*SSOIdentity, error) {
+	if callback.Ticket == "" {
+		return nil, errors.New("缺少CAS票据")
+	}
+
+	query := url.Values{}
+	query.Set("service", p.serviceURL(callback.State))
+	query.Set("ticket", callback.Ticket)
+	resp, err := ssoHTTPClient.Get(AppendQuery(strings.TrimSuffix(p.BaseURL, "/")+"/p3/serviceValidate", query))
+	if err != nil {
+		return nil, fmt.Errorf("请求CAS票据校验失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var result casServiceResponse
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析CAS响应失败: %v", err)
+	}
+	if result.Failure != nil {
+		return nil, fmt.Errorf("CAS票据校验失败: %s %s", result.Failure.Code, strings.TrimSpace(result.Failure.Message))
+	}
+	if result.Success == nil || strings.TrimSpace(result.Success.User) == "" {
+		return nil, errors.New("CAS响应中缺少用户信息")
+	}
+
+	groupsAttribute := p.GroupsAttribute
+	if groupsAttribute == "" {
+		groupsAttribute = "groups"
+	}
+	identity := &SSOIdentity{
+		Subject:    strings.TrimSpace(result.Success.User),
+		Attributes: map[string]string{},
+	}
+	for _, attr := range result.Success.Attributes.Items {
+		name := attr.XMLName.Local
+		value := strings.TrimSpace(attr.Value)
+		if name == groupsAttribute {
+			identity.Groups = append(identity.Groups, value)
+			continue
+		}
+		if _, exists := identity.Attributes[name]; !exists {
+			identity.Attributes[name] = value
+		}
+	}
+	identity.Attributes["user"] = identity.Subject
+	return identity, nil
+}
+
+func (p *CASProvider) serviceURL(state string) string {
+	query := url.Values{}
+	query.Set("state", state)
+	return AppendQuery(p.ServiceURL, query)
+}
+
+type casServiceResponse struct {
+	XMLName xml.Name `xml:"serviceResponse"`
+	Success *struct {
+		User       string `xml:"user"`
+		Attributes struct {
+			Items []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"attributes"`
+	} `xml:"authenticationSuccess"`
+	Failure *struct {
+		Code    string `xml:"code,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"authenticationFailure"`
+}
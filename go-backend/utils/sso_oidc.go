@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OIDCProvider OpenID Connect 授权码模式认证，端点为空时通过 Issuer 的发现文档获取
+type OIDCProvider struct {
+	ProviderKey  string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	AuthorizationEndpoint string
+	TokenEndpoint         string
+	UserInfoEndpoint      string
+	JWKSURI               string
+
+	// SubjectClaim 作为唯一标识的声明，默认 sub；GroupsClaim 组声明，默认 groups
+	SubjectClaim string
+	GroupsClaim  string
+
+	mu            sync.Mutex
+	loaded        bool
+	jwksKeys      map[string]*rsa.PublicKey
+	jwksFetchedAt time.Time
+}
+
+// jwksMinRefreshInterval 两次拉取 JWKS 的最小间隔，防止携带伪造 kid 的请求反复触发拉取
+const jwksMinRefreshInterval = time.Minute
+
+func (p *OIDCProvider) Key() string  { return p.ProviderKey }
+func (p *OIDCProvider) Name() string { return p.DisplayName }
+func (p *OIDCProvider) Type() string { return "oidc" }
+
+// AuthURL 拼接授权端点地址
+func (p *OIDCProvider) AuthURL(state, nonce string) (string, error) {
+	if err := p.discover(); err != nil {
+		return "", err
+	}
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	return AppendQuery(p.AuthorizationEndpoint, query), nil
+}
+
+// Authenticate 用授权码换取 ID Token，校验签名、签发方、受众和 nonce，并合并 UserInfo 声明
+func (p *OIDCProvider) Authenticate(callback SSOCallback) (*SSOIdentity, error) {
+	if callback.Code == "" {
+		return nil, errors.New("缺少授权码")
+	}
+	if err := p.discover(); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", callback.Code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+	}
+	resp, err := ssoHTTPClient.PostForm(p.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌端点失败: %v", err)
+	}
+	if err := decodeJSONResponse(resp, &token); err != nil {
+		return nil, fmt.Errorf("换取令牌失败: %v", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("身份平台未返回 id_token")
+	}
+
+	claims, err := p.verifyIDToken(token.IDToken, callback.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.UserInfoEndpoint != "" && token.AccessToken != "" {
+		userInfo, err := p.fetchUserInfo(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if sub, _ := userInfo["sub"].(string); sub != "" && sub != claims["sub"] {
+			return nil, errors.New("UserInfo 与 ID Token 的用户不一致")
+		}
+		for key, value := range userInfo {
+			claims[key] = value
+		}
+	}
+
+	return p.identityFromClaims(claims)
+}
+
+func (p *OIDCProvider) verifyIDToken(idToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("不支持的 ID Token 签名算法: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %v", err)
+	}
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return nil, errors.New("ID Token 签发方不匹配")
+	}
+	if !claims.VerifyAudience(p.ClientID, true) {
+		return nil, errors.New("ID Token 受众不匹配")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("ID Token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+func (p *OIDCProvider) fetchUserInfo(accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, p.UserInfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := ssoHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 UserInfo 失败: %v", err)
+	}
+	userInfo := map[string]interface{}{}
+	if err := decodeJSONResponse(resp, &userInfo); err != nil {
+		return nil, fmt.Errorf("获取 UserInfo 失败: %v", err)
+	}
+	return userInfo, nil
+}
+
+// identityFromClaims 将声明转换为统一身份：字符串和数字作为属性，组声明作为组
+func (p *OIDCProvider) identityFromClaims(claims map[string]interface{}) (*SSOIdentity, error) {
+	subjectClaim := p.SubjectClaim
+	if subjectClaim == "" {
+		subjectClaim = "sub"
+	}
+	groupsClaim := p.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	identity := &SSOIdentity{Attributes: map[string]string{}}
+	for key, value := range claims {
+		switch v := value.(type) {
+		case string:
+			identity.Attributes[key] = v
+		case float64:
+			identity.Attributes[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			identity.Attributes[key] = strconv.FormatBool(v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					if key == groupsClaim {
+						identity.Groups = append(identity.Groups, s)
+					} else if _, exists := identity.Attributes[key]; !exists {
+						identity.Attributes[key] = s
+					}
+				}
+			}
+		}
+	}
+	if group, ok := identity.Attributes[groupsClaim]; ok && len(identity.Groups) == 0 {
+		identity.Groups = []string{group}
+	}
+
+	identity.Subject = identity.Attributes[subjectClaim]
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("身份平台未返回用户标识 %s", subjectClaim)
+	}
+	return identity, nil
+}
+
+// discover 读取发现文档补全未配置的端点（只成功加载一次）
+func (p *OIDCProvider) discover() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.loaded {
+		return nil
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		var doc struct {
+			AuthorizationEndpoint string `json:"authorization_endpoint"`
+			TokenEndpoint         string `json:"token_endpoint"`
+			UserInfoEndpoint      string `json:"userinfo_endpoint"`
+			JWKSURI               string `json:"jwks_uri"`
+		}
+		resp, err := ssoHTTPClient.Get(strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration")
+		if err != nil {
+			return fmt.Errorf("获取 OIDC 发现文档失败: %v", err)
+		}
+		if err := decodeJSONResponse(resp, &doc); err != nil {
+			return fmt.Errorf("解析 OIDC 发现文档失败: %v", err)
+		}
+		if p.AuthorizationEndpoint == "" {
+			p.AuthorizationEndpoint = doc.AuthorizationEndpoint
+		}
+		if p.TokenEndpoint == "" {
+			p.TokenEndpoint = doc.TokenEndpoint
+		}
+		if p.UserInfoEndpoint == "" {
+			p.UserInfoEndpoint = doc.UserInfoEndpoint
+		}
+		if p.JWKSURI == "" {
+			p.JWKSURI = doc.JWKSURI
+		}
+	}
+	p.loaded = true
+	return nil
+}
+
+// publicKey 按 kid 查找身份平台公钥，未知 kid 时重新拉取 JWKS（身份平台可能已轮换密钥），
+// 拉取间隔不小于 jwksMinRefreshInterval
+func (p *OIDCProvider) publicKey(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if !p.jwksFetchedAt.IsZero() && time.Since(p.jwksFetchedAt) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("身份平台公钥不存在: %s", kid)
+	}
+	// 拉取失败同样计入间隔
+	p.jwksFetchedAt = time.Now()
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	resp, err := ssoHTTPClient.Get(p.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %v", err)
+	}
+	if err := decodeJSONResponse(resp, &jwks); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, item := range jwks.Keys {
+		if item.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(item.N)
+		e, errE := base64.RawURLEncoding.DecodeString(item.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[item.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.jwksKeys = keys
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("身份平台公钥不存在: %s", kid)
+}
+
+// lookupKey 查找已缓存的公钥，kid 为空且只有一个公钥时直接使用
+func (p *OIDCProvider) lookupKey(kid string) *rsa.PublicKey {
+	if key, ok := p.jwksKeys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(p.jwksKeys) == 1 {
+		for _, key := range p.jwksKeys {
+			return key
+		}
+	}
+	return nil
+}
+
+// decodeJSONResponse 读取并解析JSON响应，非2xx状态视为错误
+func decodeJSONResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// AppendQuery 在地址后追加查询参数
+func AppendQuery(baseURL string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return baseURL + separator + query.Encode()
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOIDCUnknownKidDoesNotRefetchJWKSEveryTime(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys":[{"kid":"k1","kty":"RSA","n":"sXch","e":"AQAB"}]}`))
+	}))
+	defer server.Close()
+
+	provider := &OIDCProvider{ProviderKey: "campus", JWKSURI: server.URL, loaded: true}
+	if _, err := provider.publicKey("k1"); err != nil {
+		t.Fatalf("应能取得公钥: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := provider.publicKey("forged"); err == nil {
+			t.Fatal("未知 kid 应返回错误")
+		}
+	}
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Fatalf("间隔内只应拉取1次 JWKS，实际 %d 次", got)
+	}
+}